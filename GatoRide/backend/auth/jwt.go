@@ -8,7 +8,13 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-var jwtSecret = []byte("your_secret_key")
+// jwtSecret signs and verifies login tokens. It is set from the configuration at startup.
+var jwtSecret []byte
+
+// SetSecret sets the key used to sign and verify login tokens
+func SetSecret(secret string) {
+	jwtSecret = []byte(secret)
+}
 
 type Claims struct {
 	UserID string `json:"user_id"`
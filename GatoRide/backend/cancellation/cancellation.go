@@ -0,0 +1,156 @@
+// Package cancellation decides what cancelling a booking or a ride costs.
+//
+// Cancelling is free until a cut-off before departure. Later cancellations are late: passengers
+// owe a share of the booking's price, and drivers and passengers alike have them counted against
+// their reliability. Too many late cancellations within a window restrict the user for a while.
+// Some cancellations are free whenever they happen, and the rules below are checked in order:
+//
+//	pending_request  the driver had not confirmed the booking yet
+//	ride_changed     the driver changed the ride after the passenger booked
+//	no_passengers    the driver cancels a ride nobody is booked on
+//	free             cancelled before the cut-off
+//	late             anything else
+package cancellation
+
+import (
+	"backend/config"
+	"backend/models"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Rule names the part of the policy a cancellation fell under
+type Rule string
+
+const (
+	RulePendingRequest Rule = "pending_request"
+	RuleRideChanged    Rule = "ride_changed"
+	RuleNoPassengers   Rule = "no_passengers"
+	RuleFree           Rule = "free"
+	RuleLate           Rule = "late"
+)
+
+// Decision is what a cancellation costs and why
+type Decision struct {
+	Rule Rule `json:"rule"`
+	// Late cancellations count against the user's reliability
+	Late bool `json:"late"`
+	// Penalty is what the passenger owes; drivers are never charged
+	Penalty     float64 `json:"penalty"`
+	Explanation string  `json:"explanation"`
+}
+
+// Policy applies the configured cancellation rules
+type Policy struct {
+	FreeBefore         time.Duration
+	LatePenaltyPercent float64
+	LateLimit          int
+	LateWindow         time.Duration
+	RestrictionPeriod  time.Duration
+}
+
+// New returns the policy the configuration describes
+func New(cfg config.CancellationConfig) Policy {
+	return Policy{
+		FreeBefore:         time.Duration(cfg.FreeBefore),
+		LatePenaltyPercent: cfg.LatePenaltyPercent,
+		LateLimit:          cfg.LateLimit,
+		LateWindow:         time.Duration(cfg.LateWindow),
+		RestrictionPeriod:  time.Duration(cfg.RestrictionPeriod),
+	}
+}
+
+// ForBooking decides what the passenger's cancellation of a booking on the ride costs. Rides
+// booked before bookings were recorded have none; their passenger holds a single seat.
+func (p Policy) ForBooking(booking *models.Booking, ride *models.Ride, now time.Time) Decision {
+	price := ride.Price
+	if booking != nil {
+		switch {
+		case booking.Status == models.BookingPending:
+			return Decision{Rule: RulePendingRequest, Explanation: "The driver had not confirmed your booking yet, so cancelling it is free."}
+		case booking.RideChangedAt != nil:
+			return Decision{Rule: RuleRideChanged, Explanation: "The driver changed the ride after you booked it, so cancelling is free."}
+		}
+		price = booking.TotalPrice
+	}
+
+	left := ride.Date.Sub(now)
+	if p.early(left) {
+		return Decision{Rule: RuleFree, Explanation: p.freeExplanation(left)}
+	}
+	penalty := math.Round(price*p.LatePenaltyPercent) / 100
+	return Decision{
+		Rule:    RuleLate,
+		Late:    true,
+		Penalty: penalty,
+		Explanation: fmt.Sprintf("%s Late cancellations cost %g%% of the booking's price, %.2f here, and count against your reliability.",
+			p.lateExplanation(left), p.LatePenaltyPercent, penalty),
+	}
+}
+
+// ForRide decides what the driver's cancellation of the ride costs
+func (p Policy) ForRide(ride *models.Ride, now time.Time) Decision {
+	if len(ride.PassengerIDs) == 0 {
+		return Decision{Rule: RuleNoPassengers, Explanation: "Nobody was booked on the ride, so cancelling it is free."}
+	}
+
+	left := ride.Date.Sub(now)
+	if p.early(left) {
+		return Decision{Rule: RuleFree, Explanation: p.freeExplanation(left)}
+	}
+	return Decision{
+		Rule:        RuleLate,
+		Late:        true,
+		Explanation: p.lateExplanation(left) + " Late cancellations count against your reliability.",
+	}
+}
+
+// Since returns where the window of late cancellations that count towards a restriction starts
+func (p Policy) Since(now time.Time) time.Time {
+	return now.Add(-p.LateWindow)
+}
+
+// RestrictedUntil returns when a user with the given late cancellations inside the window may book
+// and offer rides again, or nil if they are not restricted
+func (p Policy) RestrictedUntil(recentLate int, now time.Time) *time.Time {
+	if recentLate < p.LateLimit {
+		return nil
+	}
+	until := now.Add(p.RestrictionPeriod)
+	return &until
+}
+
+// early reports whether a cancellation with the given time left before departure is free
+func (p Policy) early(left time.Duration) bool {
+	return left >= p.FreeBefore && left > 0
+}
+
+func (p Policy) freeExplanation(left time.Duration) string {
+	return fmt.Sprintf("You cancelled %s before departure, and cancelling is free until %s before.", describe(left), describe(p.FreeBefore))
+}
+
+func (p Policy) lateExplanation(left time.Duration) string {
+	if left <= 0 {
+		return "You cancelled after the departure time."
+	}
+	return fmt.Sprintf("You cancelled %s before departure, but cancelling is only free until %s before.", describe(left), describe(p.FreeBefore))
+}
+
+// describe rounds a duration down to whole days, hours or minutes for explanations
+func describe(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= 48*time.Hour:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d >= 2*time.Hour:
+		return plural(int64(d/time.Hour), "hour")
+	default:
+		return plural(int64(d/time.Minute), "minute")
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"backend/repository"
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var DB *mongo.Database
+
+// Storage backends selectable through STORAGE_BACKEND
+const (
+	StorageMongo  = "mongo"
+	StorageMemory = "memory"
+)
+
+// ✅ Connect to MongoDB
+func ConnectDB(cfg DatabaseConfig) error {
+	clientOptions := options.Client().ApplyURI(cfg.URI)
+	client, err := mongo.Connect(context.TODO(), clientOptions)
+	if err != nil {
+		return fmt.Errorf("MongoDB connection failed: %w", err)
+	}
+
+	// Set global DB variable
+	DB = client.Database(cfg.Name)
+	fmt.Println("✅ Connected to MongoDB:", cfg.Name)
+	return nil
+}
+
+// OpenStore returns the repositories for the configured storage backend
+func OpenStore(cfg *Config) (repository.Store, error) {
+	if cfg.Storage == StorageMemory {
+		log.Println("⚠️ Using in-memory storage. Data is lost when the server stops.")
+		return repository.NewMemoryStore(), nil
+	}
+
+	if err := ConnectDB(cfg.Database); err != nil {
+		return repository.Store{}, err
+	}
+	if err := repository.EnsureIndexes(context.TODO(), DB); err != nil {
+		return repository.Store{}, err
+	}
+	return repository.NewMongoStore(DB), nil
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// RideAlerter tells users about new rides their saved searches match, through email or their
+// inbox, and at most once per search in the configured interval. It also hands other
+// notifications to the inbox.
+type RideAlerter struct {
+	searches      repository.SavedSearchRepository
+	notifications repository.NotificationRepository
+	users         repository.UserRepository
+	blocks        repository.BlockRepository
+	search        config.SearchConfig
+	minInterval   time.Duration
+}
+
+// NewRideAlerter creates a RideAlerter backed by the given repositories
+func NewRideAlerter(searches repository.SavedSearchRepository, notifications repository.NotificationRepository, users repository.UserRepository, blocks repository.BlockRepository, search config.SearchConfig, alerts config.AlertConfig) *RideAlerter {
+	return &RideAlerter{
+		searches:      searches,
+		notifications: notifications,
+		users:         users,
+		blocks:        blocks,
+		search:        search,
+		minInterval:   time.Duration(alerts.MinInterval),
+	}
+}
+
+// Alert alerts every user whose saved search the new ride matches the way SearchRides would
+// match it, and returns how many it alerted. Failures are only logged, since the ride itself
+// was created fine.
+func (a *RideAlerter) Alert(ctx context.Context, ride *models.Ride) int {
+	// Saved searches may widen their radius up to the maximum, so candidates are looked up that far
+	candidates, err := a.searches.FindCandidates(ctx, ride.Date, ride.Seats, ride.Stops(), a.search.MaxRadiusMeters)
+	if err != nil {
+		log.Printf("❌ Failed to find saved searches for ride %s: %v\n", ride.ID.Hex(), err)
+		return 0
+	}
+
+	// Users kept apart from the driver by a block do not hear about their rides
+	hidden, err := a.blocks.BlockedWith(ctx, ride.DriverID)
+	if err != nil {
+		log.Printf("❌ Failed to find users blocked with the driver of ride %s: %v\n", ride.ID.Hex(), err)
+		return 0
+	}
+
+	now := time.Now()
+	alerted := 0
+	for _, saved := range candidates {
+		if saved.UserID == ride.DriverID || slices.Contains(hidden, saved.UserID) {
+			continue
+		}
+		match, ok := matchRide(*ride, saved.From, saved.To, saved.Seats, saved.RadiusMeters, a.search.CorridorMeters)
+		if !ok {
+			continue
+		}
+		// A search alerted within the interval is skipped; claiming it keeps that true even for
+		// rides offered at the same moment
+		if _, err := a.searches.ClaimAlert(ctx, saved.ID, now.Add(-a.minInterval), now); err != nil {
+			if !errors.Is(err, repository.ErrConflict) {
+				log.Printf("❌ Failed to record an alert for saved search %s: %v\n", saved.ID.Hex(), err)
+			}
+			continue
+		}
+		a.deliver(ctx, &saved, &match, now)
+		alerted++
+	}
+	return alerted
+}
+
+// Notify adds the notification to its user's inbox; failures are only logged
+func (a *RideAlerter) Notify(ctx context.Context, notification *models.Notification) {
+	if err := a.notifications.Create(ctx, notification); err != nil {
+		log.Printf("❌ Failed to add a %s notification to the inbox of %s: %v\n", notification.Kind, notification.UserID.Hex(), err)
+	}
+}
+
+// deliver sends one alert through the saved search's channels; failures are only logged
+func (a *RideAlerter) deliver(ctx context.Context, saved *models.SavedSearch, match *RideMatch, now time.Time) {
+	if slices.Contains(saved.Channels, models.AlertInbox) {
+		rideID, searchID := match.ID, saved.ID
+		notification := models.Notification{
+			UserID: saved.UserID,
+			Kind:   models.NotificationNewRide,
+			Message: fmt.Sprintf("New ride from %s to %s departing %s at $%.2f per seat, %d seats free",
+				match.Pickup.Address, match.Dropoff.Address, match.Date.Format("Mon Jan 2 15:04 MST"), match.Price, match.AvailableSeats),
+			RideID:        &rideID,
+			SavedSearchID: &searchID,
+			CreatedAt:     now,
+		}
+		if err := a.notifications.Create(ctx, &notification); err != nil {
+			log.Printf("❌ Failed to add a new ride alert to the inbox of %s: %v\n", saved.UserID.Hex(), err)
+		}
+	}
+
+	if slices.Contains(saved.Channels, models.AlertEmail) {
+		user, err := a.users.FindByID(ctx, saved.UserID)
+		if err != nil {
+			log.Printf("❌ Could not load user %s to alert: %v\n", saved.UserID.Hex(), err)
+			return
+		}
+		if err := utils.SendRideAlertFunc(user.Email, &match.Ride, saved); err != nil {
+			log.Printf("❌ Failed to alert %s about ride %s: %v\n", user.Email, match.ID.Hex(), err)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package controllers
 
 import (
 	"backend/auth"
-	"backend/config"
 	"backend/models"
+	"backend/repository"
 	"backend/utils"
 	"context"
 	"fmt"
@@ -12,12 +12,22 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// AuthController handles signup, email verification, login and logout
+type AuthController struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+}
+
+// NewAuthController creates an AuthController backed by the given repositories
+func NewAuthController(users repository.UserRepository, sessions repository.SessionRepository) *AuthController {
+	return &AuthController{users: users, sessions: sessions}
+}
+
 // ✅ **Signup with Email Verification**
-func Signup(c *gin.Context) {
+func (ac *AuthController) Signup(c *gin.Context) {
 
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
@@ -25,24 +35,13 @@ func Signup(c *gin.Context) {
 		return
 	}
 
-	collection := config.GetCollection("users")
-	if collection == nil {
-		log.Println("❌ Error: Users collection is nil! Ensure DB is connected.")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database not connected"})
-		return
-	}
 	if user.Email == "" || user.Username == "" || user.Password == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Email, Username, and Password are required"})
-		fmt.Println(user.Username)
-		fmt.Println(user.Password)
-		fmt.Println(user.Email)
-		fmt.Println(user)
 		return
 	}
 	// Check if email or username already exists
-	var existingUser models.User
-	errEmail := collection.FindOne(context.TODO(), bson.M{"email": user.Email}).Decode(&existingUser)
-	errUsername := collection.FindOne(context.TODO(), bson.M{"username": user.Username}).Decode(&existingUser)
+	_, errEmail := ac.users.FindByEmail(context.TODO(), user.Email)
+	_, errUsername := ac.users.FindByUsername(context.TODO(), user.Username)
 
 	// Return precise error messages
 	if errEmail == nil && errUsername == nil {
@@ -67,19 +66,17 @@ func Signup(c *gin.Context) {
 	user.VerificationToken = verificationToken
 
 	// Insert user into DB
-	_, err := collection.InsertOne(context.TODO(), user)
+	err := ac.users.Create(context.TODO(), &user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
 		return
 	}
 
 	// Send verification email
-	err = utils.SendVerificationEmail(user.Email, verificationToken)
+	err = utils.SendEmailFunc(user.Email, verificationToken)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
-		fmt.Println(user.Email)
-		fmt.Println(verificationToken)
-		fmt.Println(user)
+		fmt.Println("Failed to send verification email to:", user.Email)
 		return
 	}
 
@@ -87,7 +84,7 @@ func Signup(c *gin.Context) {
 }
 
 // ✅ **Verify Email**
-func VerifyEmail(c *gin.Context) {
+func (ac *AuthController) VerifyEmail(c *gin.Context) {
 	log.Println("Received email verification request.")
 
 	// Extract token from query params
@@ -97,7 +94,6 @@ func VerifyEmail(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing verification token"})
 		return
 	}
-	log.Println("Verification token received:", token)
 
 	// Verify token and extract email
 	email, err := utils.VerifyToken(token)
@@ -108,44 +104,37 @@ func VerifyEmail(c *gin.Context) {
 	}
 	log.Println("Token successfully verified. Email:", email)
 
-	// Get the users collection
-	collection := config.GetCollection("users")
-
 	// Find user by email
-	var user bson.M
-	err = collection.FindOne(context.TODO(), bson.M{"email": email}).Decode(&user)
+	user, err := ac.users.FindByEmail(context.TODO(), email)
 	if err != nil {
 		log.Println("Error: No user found with the given email:", email, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-	log.Println("User found in database:", user)
+	log.Println("User found in database:", user.ID.Hex())
 
 	// Check if the user is already verified
-	if user["is_verified"] == true {
+	if user.IsVerified {
 		log.Println("User already verified:", email)
 		c.JSON(http.StatusOK, gin.H{"message": "Email already verified."})
 		return
 	}
 
 	// Update user verification status
-	updateResult, err := collection.UpdateOne(context.TODO(), bson.M{"email": email}, bson.M{"$set": bson.M{"is_verified": true}})
+	err = ac.users.MarkVerified(context.TODO(), email)
 	if err != nil {
 		log.Println("Error updating user verification status:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verifying email"})
 		return
 	}
 
-	// Log update result
-	log.Println("Update Result:", updateResult)
-
 	// Send successful response
 	log.Println("Email successfully verified for:", email)
 	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully."})
 }
 
 // ✅ **Login User**
-func Login(c *gin.Context) {
+func (ac *AuthController) Login(c *gin.Context) {
 	// Decode request body
 	var creds models.User
 	if err := c.ShouldBindJSON(&creds); err != nil {
@@ -153,11 +142,8 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	collection := config.GetCollection("users")
-	var user models.User
-
 	// Check if user exists in database
-	err := collection.FindOne(context.TODO(), bson.M{"email": creds.Email}).Decode(&user)
+	user, err := ac.users.FindByEmail(context.TODO(), creds.Email)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
@@ -175,6 +161,12 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Suspended users stay logged out until the suspension ends
+	if user.Moderation.Suspended(time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your account is suspended until " + user.Moderation.SuspendedUntil.Format("Jan 2, 2006 15:04 MST")})
+		return
+	}
+
 	// Generate JWT token
 	token, err := auth.GenerateJWT(user.ID.Hex())
 	if err != nil {
@@ -192,12 +184,11 @@ func Login(c *gin.Context) {
 		ExpiresAt: expirationTime,
 	}
 
-	// Store session in MongoDB
-	sessionCollection := config.GetCollection("sessions")
+	// Store session
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err = sessionCollection.InsertOne(ctx, session)
+	err = ac.sessions.Create(ctx, &session)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store session"})
 		return
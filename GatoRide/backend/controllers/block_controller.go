@@ -0,0 +1,170 @@
+// block_controller.go
+
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BlockController handles the users someone blocked. Blocked users are never told: the blocker's
+// rides simply stop showing up for them, and the other way round.
+type BlockController struct {
+	blocks repository.BlockRepository
+	users  repository.UserRepository
+}
+
+// NewBlockController creates a BlockController backed by the given repositories
+func NewBlockController(blocks repository.BlockRepository, users repository.UserRepository) *BlockController {
+	return &BlockController{blocks: blocks, users: users}
+}
+
+// BlockedUser is a user the caller blocked, as ListBlocks shows them
+type BlockedUser struct {
+	UserID    primitive.ObjectID `json:"user_id"`
+	Name      string             `json:"name,omitempty"`
+	Username  string             `json:"username,omitempty"`
+	BlockedAt time.Time          `json:"blocked_at"`
+}
+
+// BlockUser - Blocks the user named by "user_id". Neither sees the other's rides in searches,
+// the home page or the feed any more, and neither can book the other's rides.
+func (bc *BlockController) BlockUser(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	blockedID, ok := bc.otherUser(c, userID)
+	if !ok {
+		return
+	}
+	if _, err := bc.users.FindByID(context.TODO(), blockedID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	block := models.Block{
+		ID:        primitive.NewObjectID(),
+		BlockerID: userID,
+		BlockedID: blockedID,
+		CreatedAt: time.Now(),
+	}
+	err := bc.blocks.Create(context.TODO(), &block)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already blocked this user"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "User blocked", "block": block})
+}
+
+// UnblockUser - Lifts the caller's block on the user named by "user_id"
+func (bc *BlockController) UnblockUser(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	blockedID, ok := bc.otherUser(c, userID)
+	if !ok {
+		return
+	}
+
+	err := bc.blocks.Delete(context.TODO(), userID, blockedID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You have not blocked this user"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
+
+// ListBlocks - Lists the users the caller blocked, oldest block first
+func (bc *BlockController) ListBlocks(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	blocks, err := bc.blocks.FindByBlocker(context.TODO(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blocked users"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	blocked := []BlockedUser{}
+	for _, block := range blocks {
+		entry := BlockedUser{UserID: block.BlockedID, BlockedAt: block.CreatedAt}
+		// Users who deleted their account are still listed, just without a name
+		if user, err := bc.users.FindByID(context.TODO(), block.BlockedID); err == nil {
+			entry.Name, entry.Username = user.Name, user.Username
+		}
+		blocked = append(blocked, entry)
+	}
+	c.JSON(http.StatusOK, gin.H{"blocked_users": blocked})
+}
+
+// otherUser reads the "user_id" the caller blocks or unblocks, which cannot be the caller.
+// It writes the error response itself and reports false otherwise.
+func (bc *BlockController) otherUser(c *gin.Context, userID primitive.ObjectID) (primitive.ObjectID, bool) {
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name the user in user_id"})
+		return primitive.NilObjectID, false
+	}
+	otherID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	if otherID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot block yourself"})
+		return primitive.NilObjectID, false
+	}
+	return otherID, true
+}
+
+// hiddenDrivers returns the users whose rides the caller must not see because either of them
+// blocked the other. Requests without a signed-in caller see every ride.
+func hiddenDrivers(ctx context.Context, c *gin.Context, blocks repository.BlockRepository) ([]primitive.ObjectID, error) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		return nil, nil
+	}
+	return blocks.BlockedWith(ctx, userID)
+}
+
+// blockedFromBooking reports whether the caller and the ride's driver are kept apart by a block,
+// writing the error response itself if so. A caller the driver blocked is told the ride does not
+// exist, just as searches no longer show it, so they never learn about the block.
+func blockedFromBooking(c *gin.Context, blocks repository.BlockRepository, userID primitive.ObjectID, ride *models.Ride) bool {
+	blockedDriver, err1 := blocks.IsBlocked(context.TODO(), userID, ride.DriverID)
+	blockedBy, err2 := blocks.IsBlocked(context.TODO(), ride.DriverID, userID)
+	if err := errors.Join(err1, err2); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ride"})
+		return true
+	}
+	if blockedDriver {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You blocked this driver. Unblock them to book their rides"})
+		return true
+	}
+	if blockedBy {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return true
+	}
+	return false
+}
@@ -3,19 +3,28 @@
 package controllers
 
 import (
-	"backend/config"
+	"backend/lifecycle"
 	"backend/models"
+	"backend/repository"
+	"backend/utils"
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// BookRide - Request a ride directly without requiring driver confirmation
-func BookRide(c *gin.Context) {
+// BookRide - Books seats on a ride. Rides with instant booking are confirmed straight away;
+// otherwise a pending request holds the seats until the driver accepts or rejects it.
+// An optional "seats" query parameter books several seats at once (default 1),
+// "from_stop"/"to_stop" book part of the route between two stops (default pickup to dropoff),
+// and "bags" declares the passenger's luggage, which must fit in the space left (default 0).
+func (rc *RideController) BookRide(c *gin.Context) {
 	userIDStr, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -34,32 +43,63 @@ func BookRide(c *gin.Context) {
 		return
 	}
 
-	rideCollection := config.GetCollection("rides")
-	var ride models.Ride
-	err = rideCollection.FindOne(context.TODO(), bson.M{"_id": rideObjectID}).Decode(&ride)
+	seats, err := strconv.Atoi(c.DefaultQuery("seats", "1"))
+	if err != nil || seats < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Seats must be a positive number"})
+		return
+	}
+
+	// Convert user ID to ObjectID
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	ride, err := rc.rides.FindByID(context.TODO(), rideObjectID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
 		return
 	}
+	if blockedFromBooking(c, rc.blocks, userID, ride) {
+		return
+	}
 
-	// Check if ride status is open
-	if ride.Status != models.StatusOpen {
+	// Check if ride status accepts bookings
+	if !lifecycle.AcceptsBookings(ride.Status) {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Cannot book a ride with status '%s'. Only 'open' rides can be booked", ride.Status),
+			"error":             fmt.Sprintf("Cannot book a ride with status '%s'. Only 'open' rides can be booked", ride.Status),
+			"can_join_waitlist": lifecycle.AcceptsWaitlist(ride.Status),
 		})
 		return
 	}
 
-	// Check if there are enough seats available
-	if ride.Seats <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No seats available"})
+	segment, ok := requestedSegment(c, ride)
+	if !ok {
+		return
+	}
+	bags, ok := requestedBags(c, ride)
+	if !ok {
 		return
 	}
 
-	// Convert user ID to ObjectID
-	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+	// Check if there are enough seats available along the segment
+	free := ride.FreeSeats(segment)
+	if free <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No seats available", "can_join_waitlist": true})
+		return
+	}
+	if free < seats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Only %d seats available", free), "can_join_waitlist": true})
+		return
+	}
+	if ride.FreeLuggage < bags {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Only %d bags fit", ride.FreeLuggage), "can_join_waitlist": true})
+		return
+	}
+
+	// Users who cancelled late too often may not book for a while
+	if restricted(c, rc.users, userID) {
 		return
 	}
 
@@ -71,22 +111,72 @@ func BookRide(c *gin.Context) {
 		}
 	}
 
-	// Update ride: decrease available seats and add passenger
-	update := bson.M{
-		"$inc":  bson.M{"seats": -1},
-		"$push": bson.M{"passenger_ids": userID},
+	// The checks above only produce friendly errors; ReserveSeats re-checks
+	// everything atomically so concurrent requests can never oversell the ride.
+	_, err = rc.rides.ReserveSeats(context.TODO(), rideObjectID, userID, seats, bags, segment)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This ride was just booked by someone else. Not enough seats or luggage space are left."})
+		return
 	}
-
-	// If no seats remain after booking, update status to "booked"
-	if ride.Seats == 1 {
-		update["$set"] = bson.M{"status": models.StatusBooked}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book ride"})
+		return
 	}
 
-	_, err = rideCollection.UpdateOne(context.TODO(), bson.M{"_id": rideObjectID}, update)
-	if err != nil {
+	booking := utils.NewBooking(ride, userID, seats, bags, segment, time.Duration(rc.bookingConfig.RequestTimeout), time.Now())
+	if err := rc.bookings.Create(context.TODO(), &booking); err != nil {
+		// Give the seats back so the ride does not stay reserved without a booking
+		if _, releaseErr := rc.rides.ReleaseSeats(context.TODO(), rideObjectID, userID, seats, bags, segment); releaseErr != nil {
+			log.Printf("❌ Failed to release seats on ride %s after booking error: %v\n", rideID, releaseErr)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book ride"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Ride booked successfully"})
+	// The vehicle tells the passenger what to look for at pickup
+	if booking.Status == models.BookingPending {
+		c.JSON(http.StatusAccepted, gin.H{"message": "Booking request sent to the driver", "booking": booking, "vehicle": ride.Vehicle})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ride booked successfully", "booking": booking, "vehicle": ride.Vehicle})
+}
+
+// requestedSegment reads the "from_stop" and "to_stop" query parameters, which default to the
+// whole route. It writes the error response itself and reports false when they are invalid.
+func requestedSegment(c *gin.Context, ride *models.Ride) (models.Segment, bool) {
+	var err error
+
+	// Stops are numbered from 0 (pickup) to the number of waypoints + 1 (dropoff)
+	segment := ride.FullRoute()
+	if segment.From, err = strconv.Atoi(c.DefaultQuery("from_stop", strconv.Itoa(segment.From))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_stop"})
+		return segment, false
+	}
+	if segment.To, err = strconv.Atoi(c.DefaultQuery("to_stop", strconv.Itoa(segment.To))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to_stop"})
+		return segment, false
+	}
+	if !ride.ValidSegment(segment) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("from_stop must come before to_stop, and stops run from 0 to %d", ride.FullRoute().To),
+		})
+		return segment, false
+	}
+	return segment, true
+}
+
+// requestedBags reads the "bags" query parameter, the luggage the passenger brings, which
+// defaults to none. It writes the error response itself and reports false when it is invalid
+// or more than the car takes at all.
+func requestedBags(c *gin.Context, ride *models.Ride) (int, bool) {
+	bags, err := strconv.Atoi(c.DefaultQuery("bags", "0"))
+	if err != nil || bags < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bags must be zero or a positive number"})
+		return 0, false
+	}
+	if bags > ride.Attributes.LuggageBags {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("This ride takes at most %d bags", ride.Attributes.LuggageBags)})
+		return 0, false
+	}
+	return bags, true
 }
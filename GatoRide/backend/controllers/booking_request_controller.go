@@ -0,0 +1,171 @@
+// booking_request_controller.go
+
+package controllers
+
+import (
+	"backend/lifecycle"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListBookingRequests - Shows the driver the pending booking requests on one of their rides
+func (rc *RideController) ListBookingRequests(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	rideObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
+		return
+	}
+
+	ride, err := rc.rides.FindByID(context.TODO(), rideObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return
+	}
+
+	if ride.DriverID != userObjectID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the driver of this ride"})
+		return
+	}
+
+	requests, err := rc.bookings.Find(context.TODO(), repository.BookingFilter{
+		RideID:   rideObjectID,
+		Statuses: []models.BookingStatus{models.BookingPending},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking requests"})
+		return
+	}
+
+	// ✅ Force JSON to always return an array instead of `null`
+	if requests == nil {
+		requests = []models.Booking{}
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": requests})
+}
+
+// AcceptBookingRequest - Lets the driver confirm a pending booking; its seats are already held
+func (rc *RideController) AcceptBookingRequest(c *gin.Context) {
+	booking, ride, ok := rc.driverBookingRequest(c, models.BookingConfirmed)
+	if !ok {
+		return
+	}
+
+	if !lifecycle.AcceptsBookingCancellations(ride.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Cannot accept requests for a ride with status '%s'", ride.Status),
+		})
+		return
+	}
+
+	// The scheduler may not have expired it yet, but the deadline has passed
+	if booking.ExpiresAt != nil && booking.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This booking request has expired"})
+		return
+	}
+
+	booking, err := rc.bookings.UpdateStatus(context.TODO(), booking.ID, models.BookingPending, models.BookingConfirmed, time.Now())
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This booking request has changed in the meantime. Please try again."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept booking request"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking request accepted", "booking": booking})
+}
+
+// RejectBookingRequest - Lets the driver decline a pending booking and frees its seats
+func (rc *RideController) RejectBookingRequest(c *gin.Context) {
+	booking, _, ok := rc.driverBookingRequest(c, models.BookingRejected)
+	if !ok {
+		return
+	}
+
+	// The seats the request held go back to the ride in the same step, like a cancellation
+	booking, err := rc.releaseBooking(context.TODO(), booking, models.BookingRejected, time.Now())
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This booking request has changed in the meantime. Please try again."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject booking request"})
+		return
+	}
+
+	// The freed seats go to the next passengers on the waitlist, if any
+	if _, err := rc.promoter.Promote(context.TODO(), booking.RideID); err != nil {
+		log.Printf("❌ Failed to promote the waitlist of ride %s: %v\n", booking.RideID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking request rejected", "booking": booking})
+}
+
+// driverBookingRequest loads the booking named in the path and checks that the caller drives
+// its ride and may move it to the given status. It writes the error response itself and
+// reports false when the request cannot go ahead.
+func (rc *RideController) driverBookingRequest(c *gin.Context, to models.BookingStatus) (*models.Booking, *models.Ride, bool) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, nil, false
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return nil, nil, false
+	}
+
+	bookingObjectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID format"})
+		return nil, nil, false
+	}
+
+	booking, err := rc.bookings.FindByID(context.TODO(), bookingObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return nil, nil, false
+	}
+
+	ride, err := rc.rides.FindByID(context.TODO(), booking.RideID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return nil, nil, false
+	}
+
+	if ride.DriverID != userObjectID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the driver of this ride"})
+		return nil, nil, false
+	}
+
+	if err := lifecycle.CheckBooking(booking.Status, to, lifecycle.Driver); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	return booking, ride, true
+}
@@ -3,19 +3,24 @@
 package controllers
 
 import (
-	"backend/config"
+	"backend/lifecycle"
 	"backend/models"
+	"backend/repository"
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// CancelBooking - Allows a passenger to cancel their booking on a ride
-func CancelBooking(c *gin.Context) {
+// CancelBooking - Allows a passenger to cancel their booking on a ride.
+// The booking is identified by "booking_id", or by "ride_id" for the caller's booking on that ride.
+// The response explains which rule of the cancellation policy applied and what it cost.
+func (rc *RideController) CancelBooking(c *gin.Context) {
 	// Extract userID from context (set by auth middleware)
 	userIDStr, exists := c.Get("userID")
 	if !exists {
@@ -23,48 +28,77 @@ func CancelBooking(c *gin.Context) {
 		return
 	}
 
-	// Get ride ID from query parameter
+	// Get booking or ride ID from query parameters
+	bookingID := c.Query("booking_id")
 	rideID := c.Query("ride_id")
-	if rideID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing ride ID"})
+	if bookingID == "" && rideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing ride ID or booking ID"})
 		return
 	}
 
-	// Convert string IDs to ObjectIDs
-	rideObjectID, err := primitive.ObjectIDFromHex(rideID)
+	userObjectID, err := primitive.ObjectIDFromHex(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
 		return
 	}
 
-	userObjectID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	var booking *models.Booking
+	if bookingID != "" {
+		bookingObjectID, err := primitive.ObjectIDFromHex(bookingID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID format"})
+			return
+		}
+
+		booking, err = rc.bookings.FindByID(context.TODO(), bookingObjectID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		if booking.PassengerID != userObjectID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This booking belongs to another passenger"})
+			return
+		}
+		rideID = booking.RideID.Hex()
+	}
+
+	// Convert string IDs to ObjectIDs
+	rideObjectID, err := primitive.ObjectIDFromHex(rideID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
 		return
 	}
 
-	// Get the rides collection
-	rideCollection := config.GetCollection("rides")
-
 	// Find the ride first to verify it exists and the user is a passenger
-	var ride models.Ride
-	err = rideCollection.FindOne(context.TODO(), bson.M{
-		"_id": rideObjectID,
-	}).Decode(&ride)
-
+	ride, err := rc.rides.FindByID(context.TODO(), rideObjectID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
 		return
 	}
 
 	// Check if ride status allows cancellation (only open or booked)
-	if ride.Status != models.StatusOpen && ride.Status != models.StatusBooked {
+	if !lifecycle.AcceptsBookingCancellations(ride.Status) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": fmt.Sprintf("Cannot cancel booking for a ride with status '%s'. Only 'open' or 'booked' rides can be canceled", ride.Status),
 		})
 		return
 	}
 
+	if booking == nil {
+		booking, err = rc.activeBooking(context.TODO(), rideObjectID, userObjectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+			return
+		}
+	}
+
+	if booking != nil {
+		if err := lifecycle.CheckBooking(booking.Status, models.BookingCancelled, lifecycle.Passenger); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// Check if user is a passenger on this ride
 	isPassenger := false
 	for _, passengerID := range ride.PassengerIDs {
@@ -79,26 +113,66 @@ func CancelBooking(c *gin.Context) {
 		return
 	}
 
-	// Update the ride: remove passenger and increase available seats
-	updateResult, err := rideCollection.UpdateOne(
-		context.TODO(),
-		bson.M{"_id": rideObjectID},
-		bson.M{
-			"$pull": bson.M{"passenger_ids": userObjectID},
-			"$inc":  bson.M{"seats": 1},
-		},
-	)
+	// The policy looks at the booking as it was before the cancellation
+	now := time.Now()
+	decision := rc.cancellation.ForBooking(booking, ride, now)
 
+	if booking != nil {
+		booking, err = rc.releaseBooking(context.TODO(), booking, models.BookingCancelled, now)
+	} else {
+		// Rides booked before bookings were recorded list the passenger with a single seat
+		_, err = rc.rides.ReleaseSeats(context.TODO(), rideObjectID, userObjectID, 1, 0, ride.FullRoute())
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This booking has changed in the meantime. Please try again."})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
 		return
 	}
 
-	if updateResult.ModifiedCount == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride"})
-		return
+	// The freed seats go to the next passengers on the waitlist, if any
+	if _, err := rc.promoter.Promote(context.TODO(), rideObjectID); err != nil {
+		log.Printf("❌ Failed to promote the waitlist of ride %s: %v\n", rideID, err)
 	}
 
+	// A late cancellation counts against the passenger and may restrict them
+	outcome := rc.recordCancellation(context.TODO(), userObjectID, decision, now)
+
 	fmt.Println("Booking canceled successfully for user:", userIDStr)
-	c.JSON(http.StatusOK, gin.H{"message": "Your booking has been canceled successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Your booking has been canceled successfully", "booking": booking, "cancellation": outcome})
+}
+
+// releaseBooking moves a booking that holds seats to a status that does not, giving its seats
+// and luggage space back. The seats are released first, which only succeeds once for a listed
+// passenger, so concurrent cancellations cannot release them twice; if the booking then turns
+// out to have changed, the seats are taken again and the booking is left as it was. Either way
+// a failure leaves booking and ride agreeing, and its error is returned.
+func (rc *RideController) releaseBooking(ctx context.Context, booking *models.Booking, to models.BookingStatus, at time.Time) (*models.Booking, error) {
+	if _, err := rc.rides.ReleaseSeats(ctx, booking.RideID, booking.PassengerID, booking.Seats, booking.Bags, booking.Segment()); err != nil {
+		return nil, err
+	}
+
+	released, err := rc.bookings.UpdateStatus(ctx, booking.ID, booking.Status, to, at)
+	if err != nil {
+		if _, reserveErr := rc.rides.ReserveSeats(ctx, booking.RideID, booking.PassengerID, booking.Seats, booking.Bags, booking.Segment()); reserveErr != nil {
+			log.Printf("❌ Booking %s kept its status but its seats could not be taken again: %v\n", booking.ID.Hex(), reserveErr)
+		}
+		return nil, err
+	}
+	return released, nil
+}
+
+// activeBooking returns the passenger's booking that holds seats on a ride, or nil if there is none
+func (rc *RideController) activeBooking(ctx context.Context, rideID, passengerID primitive.ObjectID) (*models.Booking, error) {
+	bookings, err := rc.bookings.Find(ctx, repository.BookingFilter{
+		RideID:      rideID,
+		PassengerID: passengerID,
+		Statuses:    []models.BookingStatus{models.BookingPending, models.BookingConfirmed},
+	})
+	if err != nil || len(bookings) == 0 {
+		return nil, err
+	}
+	return &bookings[0], nil
 }
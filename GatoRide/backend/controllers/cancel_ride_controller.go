@@ -3,26 +3,32 @@
 package controllers
 
 import (
-	"backend/config"
+	"backend/lifecycle"
 	"backend/models"
+	"backend/repository"
+	"backend/utils"
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// CancelRide - Allows a driver to cancel a ride they've offered
-func CancelRide(c *gin.Context) {
-	// Extract userID from context (set by auth middleware)
-	userIDStr, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
+// maxAlternatives limits the rides suggested to each passenger of a cancelled ride
+const maxAlternatives = 3
 
+// CancelRide - Allows a driver to cancel a ride they've offered. The body gives a "reason", one
+// of models.CancellationReasons, and a "note", which "other" requires. Every passenger's
+// booking is cancelled by the driver, and each passenger is told why along with other rides on
+// their route that day. The response explains which rule of the cancellation policy applied.
+func (rc *RideController) CancelRide(c *gin.Context) {
 	// Get ride ID from query parameter
 	rideID := c.Query("ride_id")
 	if rideID == "" {
@@ -30,56 +36,251 @@ func CancelRide(c *gin.Context) {
 		return
 	}
 
-	// Convert string IDs to ObjectIDs
-	rideObjectID, err := primitive.ObjectIDFromHex(rideID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
+	ride, ok := rc.driverRide(c, rideID)
+	if !ok {
+		return
+	}
+	if ride.Status == models.StatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "This ride is already cancelled", "cancellation": ride.Cancellation})
+		return
+	}
+	if !allowTransition(c, ride, models.StatusCancelled) {
 		return
 	}
 
-	userObjectID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	cancellation, ok := bindCancellation(c, time.Now())
+	if !ok {
+		return
+	}
+
+	outcome, notified, err := rc.cancelRide(context.TODO(), ride, cancellation)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The ride was updated by someone else. Please try again."})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel ride"})
 		return
 	}
 
-	// Get the rides collection
-	rideCollection := config.GetCollection("rides")
+	fmt.Println("Ride canceled successfully by driver:", c.GetString("userID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Your ride has been canceled successfully", "cancellation": outcome, "passengers_notified": notified})
+}
+
+// bindCancellation reads why the driver is cancelling from the request body: a "reason", one of
+// models.CancellationReasons, and a "note", which "other" requires. It writes the error response
+// itself and reports false when the body does not say why.
+func bindCancellation(c *gin.Context, at time.Time) (models.RideCancellation, bool) {
+	var req struct {
+		Reason models.CancellationReason `json:"reason"`
+		Note   string                    `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !slices.Contains(models.CancellationReasons, req.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give a reason for cancelling the ride", "reasons": models.CancellationReasons})
+		return models.RideCancellation{}, false
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Reason == models.ReasonOther && req.Note == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Explain in a note why you are cancelling the ride"})
+		return models.RideCancellation{}, false
+	}
+	if len(req.Note) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Notes can be at most %d characters", maxNotesLength)})
+		return models.RideCancellation{}, false
+	}
+	return models.RideCancellation{Reason: req.Reason, Note: req.Note, At: at}, true
+}
+
+// cancelRide cancels a ride for its driver, counts it against them by the cancellation policy
+// and lets its passengers go. It returns what the cancellation cost and how many passengers it
+// told, or ErrConflict if the ride's status changed since it was read.
+func (rc *RideController) cancelRide(ctx context.Context, ride *models.Ride, cancellation models.RideCancellation) (CancellationOutcome, int, error) {
+	// The update only applies if nobody changed the status since we read it
+	if err := rc.rides.Cancel(ctx, ride.ID, ride.Status, cancellation); err != nil {
+		return CancellationOutcome{}, 0, err
+	}
+
+	// The policy looks at the ride as it was, passengers included
+	outcome := rc.recordCancellation(ctx, ride.DriverID, rc.cancellation.ForRide(ride, cancellation.At), cancellation.At)
 
-	// Find the ride first to verify it exists and the user is the driver
-	var ride models.Ride
-	err = rideCollection.FindOne(context.TODO(), bson.M{
-		"_id": rideObjectID,
-	}).Decode(&ride)
+	ride.Status = models.StatusCancelled
+	ride.Cancellation = &cancellation
+	return outcome, rc.CancelPassengers(ctx, ride, cancellation.At), nil
+}
 
+// CancelPassengers cancels the bookings of everyone on a cancelled ride and closes its waitlist,
+// telling each passenger and each user waiting for a seat and suggesting other rides. It returns
+// how many passengers it told; failures are logged, not returned, since the ride itself is
+// already cancelled. The cleanup job lets the passengers of expired rides go through it too.
+func (rc *RideController) CancelPassengers(ctx context.Context, ride *models.Ride, at time.Time) int {
+	bookings, err := rc.bookings.Find(ctx, repository.BookingFilter{
+		RideID:   ride.ID,
+		Statuses: []models.BookingStatus{models.BookingPending, models.BookingConfirmed},
+	})
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
-		return
+		log.Printf("❌ Failed to fetch the bookings on cancelled ride %s: %v\n", ride.ID.Hex(), err)
 	}
 
-	// Check if user is the driver of this ride
-	if ride.DriverID != userObjectID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the driver of this ride"})
-		return
+	// Rides booked before bookings were recorded list passengers without one, holding a single seat
+	for _, passengerID := range ride.PassengerIDs {
+		if !slices.ContainsFunc(bookings, func(b models.Booking) bool { return b.PassengerID == passengerID }) {
+			bookings = append(bookings, models.Booking{RideID: ride.ID, PassengerID: passengerID, Seats: 1, ToStop: ride.FullRoute().To})
+		}
 	}
 
-	// Update the ride status to cancelled
-	updateResult, err := rideCollection.UpdateOne(
-		context.TODO(),
-		bson.M{"_id": rideObjectID},
-		bson.M{"$set": bson.M{"status": models.StatusCancelled}},
-	)
+	notified := 0
+	for i := range bookings {
+		booking := &bookings[i]
+		if !booking.ID.IsZero() {
+			if _, err := rc.bookings.UpdateStatus(ctx, booking.ID, booking.Status, models.BookingCancelledByDriver, at); err != nil {
+				// The passenger cancelled in the meantime, so there is nothing to tell them
+				log.Printf("❌ Failed to cancel booking %s on cancelled ride %s: %v\n", booking.ID.Hex(), ride.ID.Hex(), err)
+				continue
+			}
+		}
+
+		alternatives := rc.alternatives(ctx, ride, booking)
+		rc.notifyRideCancelled(ctx, ride, booking.PassengerID, alternatives, at)
+		notified++
+	}
 
+	rc.closeWaitlist(ctx, ride, at)
+	return notified
+}
+
+// closeWaitlist takes everyone waiting for a seat on a cancelled ride off its waitlist and tells
+// them, suggesting other rides for the stretch they waited for; failures are only logged
+func (rc *RideController) closeWaitlist(ctx context.Context, ride *models.Ride, at time.Time) {
+	entries, err := rc.waitlist.Find(ctx, repository.WaitlistFilter{
+		RideID:   ride.ID,
+		Statuses: []models.WaitlistStatus{models.WaitlistWaiting},
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel ride"})
+		log.Printf("❌ Failed to fetch the waitlist of cancelled ride %s: %v\n", ride.ID.Hex(), err)
 		return
 	}
 
-	if updateResult.ModifiedCount == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride status"})
-		return
+	for _, entry := range entries {
+		if _, err := rc.waitlist.UpdateStatus(ctx, entry.ID, models.WaitlistWaiting, models.WaitlistClosed, at); err != nil {
+			// They left the waitlist in the meantime, so there is nothing to tell them
+			log.Printf("❌ Failed to close waitlist entry %s on cancelled ride %s: %v\n", entry.ID.Hex(), ride.ID.Hex(), err)
+			continue
+		}
+		wanted := models.Booking{
+			RideID:      ride.ID,
+			PassengerID: entry.PassengerID,
+			Seats:       entry.Seats,
+			Bags:        entry.Bags,
+			FromStop:    entry.FromStop,
+			ToStop:      entry.ToStop,
+		}
+		rc.notifyRideCancelled(ctx, ride, entry.PassengerID, rc.alternatives(ctx, ride, &wanted), at)
+	}
+}
+
+// removeRide cancels a ride an admin took down after a report and tells its passengers, the way
+// CancelRide does. The driver's cancellation record is left alone. It returns how many passengers
+// it told, or ErrConflict if the ride is no longer open or booked.
+func (rc *RideController) removeRide(ctx context.Context, ride *models.Ride, at time.Time) (int, error) {
+	if lifecycle.Check(ride.Status, models.StatusCancelled, lifecycle.Admin) != nil {
+		return 0, repository.ErrConflict
 	}
+	cancellation := models.RideCancellation{Reason: models.ReasonRemoved, At: at}
+	if err := rc.rides.Cancel(ctx, ride.ID, ride.Status, cancellation); err != nil {
+		return 0, err
+	}
+	ride.Status = models.StatusCancelled
+	ride.Cancellation = &cancellation
+	return rc.CancelPassengers(ctx, ride, at), nil
+}
 
-	fmt.Println("Ride canceled successfully by driver:", userIDStr)
-	c.JSON(http.StatusOK, gin.H{"message": "Your ride has been canceled successfully"})
+// alternatives finds other rides for a passenger of a cancelled ride: open rides still to depart
+// the same day (UTC, like the feed) that match the stretch they booked the way SearchRides
+// would, closest first. Drivers kept apart from the passenger by a block are left out, as in
+// searches.
+func (rc *RideController) alternatives(ctx context.Context, ride *models.Ride, booking *models.Booking) []models.Ride {
+	segment := booking.Segment()
+	if !ride.ValidSegment(segment) {
+		return nil
+	}
+	stops := ride.Stops()
+	day := time.Date(ride.Date.Year(), ride.Date.Month(), ride.Date.Day(), 0, 0, 0, 0, time.UTC)
+	from := day
+	if now := time.Now(); now.After(from) {
+		from = now
+	}
+
+	hidden, err := rc.blocks.BlockedWith(ctx, booking.PassengerID)
+	if err != nil {
+		log.Printf("❌ Failed to fetch the blocks of passenger %s: %v\n", booking.PassengerID.Hex(), err)
+		return nil
+	}
+	matches, err := findMatches(ctx, rc.rides, stops[segment.From], stops[segment.To], booking.Seats,
+		rc.search.MatchRadiusMeters, rc.search.CorridorMeters, repository.RideFilter{
+			Statuses:         []models.RideStatus{models.StatusOpen},
+			DateFrom:         from,
+			DateTo:           day.AddDate(0, 0, 1),
+			ExcludeDriverIDs: hidden,
+		})
+	if err != nil {
+		log.Printf("❌ Failed to find alternatives to cancelled ride %s: %v\n", ride.ID.Hex(), err)
+		return nil
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].PickupDistanceMeters+matches[i].DropoffDistanceMeters < matches[j].PickupDistanceMeters+matches[j].DropoffDistanceMeters
+	})
+	alternatives := []models.Ride{}
+	for _, match := range matches {
+		if match.ID == ride.ID || slices.Contains(match.PassengerIDs, booking.PassengerID) {
+			continue
+		}
+		alternatives = append(alternatives, match.Ride)
+		if len(alternatives) == maxAlternatives {
+			break
+		}
+	}
+	return alternatives
+}
+
+// notifyRideCancelled tells a passenger through their inbox and by email that the driver
+// cancelled their ride, an admin removed it or it never started; failures are only logged
+func (rc *RideController) notifyRideCancelled(ctx context.Context, ride *models.Ride, passengerID primitive.ObjectID, alternatives []models.Ride, at time.Time) {
+	departure := ride.Date.Format("Mon Jan 2 15:04 MST")
+	var message string
+	switch ride.Cancellation.Reason {
+	case models.ReasonRemoved:
+		message = fmt.Sprintf("Your ride from %s to %s departing %s was taken down by our moderators.",
+			ride.Pickup.Address, ride.Dropoff.Address, departure)
+	case models.ReasonExpired:
+		message = fmt.Sprintf("Your ride from %s to %s departing %s never started, so it was cancelled.",
+			ride.Pickup.Address, ride.Dropoff.Address, departure)
+	default:
+		message = fmt.Sprintf("The driver cancelled your ride from %s to %s departing %s (%s).",
+			ride.Pickup.Address, ride.Dropoff.Address, departure,
+			strings.ReplaceAll(string(ride.Cancellation.Reason), "_", " "))
+	}
+	switch {
+	case len(alternatives) == 1:
+		message += " Another ride on your route that day still has seats."
+	case len(alternatives) > 1:
+		message += fmt.Sprintf(" %d other rides on your route that day still have seats.", len(alternatives))
+	}
+	rideID := ride.ID
+	rc.alerter.Notify(ctx, &models.Notification{
+		UserID:    passengerID,
+		Kind:      models.NotificationRideCancelled,
+		Message:   message,
+		RideID:    &rideID,
+		CreatedAt: at,
+	})
+
+	passenger, err := rc.users.FindByID(ctx, passengerID)
+	if err != nil {
+		log.Printf("❌ Could not load passenger %s of ride %s: %v\n", passengerID.Hex(), ride.ID.Hex(), err)
+		return
+	}
+	if err := utils.SendRideCancelledFunc(passenger.Email, ride, alternatives); err != nil {
+		log.Printf("❌ Failed to tell %s about cancelled ride %s: %v\n", passenger.Email, ride.ID.Hex(), err)
+	}
 }
@@ -0,0 +1,102 @@
+// cancellation.go
+
+package controllers
+
+import (
+	"backend/cancellation"
+	"backend/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CancellationOutcome is what a cancellation cost, returned with the cancellation response
+type CancellationOutcome struct {
+	cancellation.Decision
+	// ReliabilityScore is the user's score after the cancellation, from 0 to 100
+	ReliabilityScore *int `json:"reliability_score,omitempty"`
+	// RestrictedUntil is set when this cancellation restricted the user
+	RestrictedUntil *time.Time `json:"restricted_until,omitempty"`
+}
+
+// recordCancellation counts the decided cancellation against the user's reliability, restricting
+// them when they have cancelled late too often. The cancellation has already happened by then,
+// so failures are only logged.
+func (rc *RideController) recordCancellation(ctx context.Context, userID primitive.ObjectID, decision cancellation.Decision, now time.Time) CancellationOutcome {
+	outcome := CancellationOutcome{Decision: decision}
+
+	user, err := rc.users.RecordCancellation(ctx, userID, repository.CancellationRecord{
+		At:      now,
+		Late:    decision.Late,
+		Penalty: decision.Penalty,
+		Since:   rc.cancellation.Since(now),
+	})
+	if err != nil {
+		log.Printf("❌ Failed to record the cancellation of user %s: %v\n", userID.Hex(), err)
+		return outcome
+	}
+
+	if decision.Late {
+		if until := rc.cancellation.RestrictedUntil(len(user.Reliability.RecentLate), now); until != nil {
+			if err := rc.users.Restrict(ctx, userID, *until); err != nil {
+				log.Printf("❌ Failed to restrict user %s after a late cancellation: %v\n", userID.Hex(), err)
+			} else {
+				outcome.RestrictedUntil = until
+				outcome.Explanation += fmt.Sprintf(" You have cancelled late %d times recently, so you cannot book or offer rides until %s.",
+					len(user.Reliability.RecentLate), until.Format(time.RFC1123))
+			}
+		}
+	}
+
+	commitments, err := countCommitments(ctx, rc.rides, rc.bookings, userID)
+	if err != nil {
+		log.Printf("❌ Failed to count the rides and bookings of user %s: %v\n", userID.Hex(), err)
+		return outcome
+	}
+	score := user.Reliability.Score(commitments)
+	outcome.ReliabilityScore = &score
+	return outcome
+}
+
+// countCommitments counts the rides the user offered and the bookings they made, which their
+// reliability score is measured against
+func countCommitments(ctx context.Context, rides repository.RideRepository, bookings repository.BookingRepository, userID primitive.ObjectID) (int, error) {
+	offered, err := rides.Find(ctx, repository.RideFilter{DriverID: userID})
+	if err != nil {
+		return 0, err
+	}
+	booked, err := bookings.Find(ctx, repository.BookingFilter{PassengerID: userID})
+	if err != nil {
+		return 0, err
+	}
+	return len(offered) + len(booked), nil
+}
+
+// restricted reports whether the user may not book or offer rides because they cancelled late
+// too often. It writes the error response itself when they may not.
+func restricted(c *gin.Context, users repository.UserRepository, userID primitive.ObjectID) bool {
+	user, err := users.FindByID(context.TODO(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return true
+	}
+
+	if user.Reliability.Restricted(time.Now()) {
+		until := *user.Reliability.RestrictedUntil
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":            fmt.Sprintf("You cancelled late too often and cannot book or offer rides until %s", until.Format(time.RFC1123)),
+			"restricted_until": until,
+		})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,420 @@
+// driver_controller.go
+
+package controllers
+
+import (
+	"backend/config"
+	"backend/filestore"
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxLicenceFieldLength limits the licence number and the region that issued it
+const maxLicenceFieldLength = 50
+
+// documentTypes are the content types drivers may upload their documents as
+var documentTypes = []string{"image/jpeg", "image/png", "application/pdf"}
+
+// DriverController handles the licence and insurance users submit to offer rides, and the admins
+// who review them
+type DriverController struct {
+	drivers       repository.DriverVerificationRepository
+	users         repository.UserRepository
+	notifications repository.NotificationRepository
+	documents     filestore.Store
+	config        config.DocumentConfig
+}
+
+// NewDriverController creates a DriverController backed by the given repositories, keeping
+// uploaded documents in the given store
+func NewDriverController(drivers repository.DriverVerificationRepository, users repository.UserRepository, notifications repository.NotificationRepository, documents filestore.Store, documentConfig config.DocumentConfig) *DriverController {
+	return &DriverController{
+		drivers:       drivers,
+		users:         users,
+		notifications: notifications,
+		documents:     documents,
+		config:        documentConfig,
+	}
+}
+
+// SubmitVerification - Submits the caller's driver's licence and insurance for an admin to
+// review. Takes a multipart form with "licence_number", "licence_region", "licence_expires_at"
+// and "insurance_expires_at" (YYYY-MM-DD), and scans of both as the files "licence" and
+// "insurance" (JPEG, PNG or PDF). Submitting again, e.g. with renewed documents, replaces the
+// earlier submission and waits for review anew.
+func (dc *DriverController) SubmitVerification(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	// Room for both files plus the other fields; larger requests fail while the form is read
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 2*int64(dc.config.MaxUploadBytes)+1<<20)
+	if err := c.Request.ParseMultipartForm(int64(dc.config.MaxUploadBytes)); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Each document can be at most %d bytes", dc.config.MaxUploadBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send your documents as a multipart form"})
+		return
+	}
+	defer c.Request.MultipartForm.RemoveAll()
+
+	now := time.Now()
+	verification := models.DriverVerification{
+		UserID:        userID,
+		Status:        models.DriverPending,
+		LicenceNumber: strings.ToUpper(strings.TrimSpace(c.PostForm("licence_number"))),
+		LicenceRegion: strings.TrimSpace(c.PostForm("licence_region")),
+		SubmittedAt:   now,
+	}
+	for _, field := range []string{verification.LicenceNumber, verification.LicenceRegion} {
+		if field == "" || len(field) > maxLicenceFieldLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Licence number and region are required and can be at most %d characters", maxLicenceFieldLength),
+			})
+			return
+		}
+	}
+
+	var err error
+	verification.LicenceExpiresAt, err = time.Parse("2006-01-02", c.PostForm("licence_expires_at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid licence expiry date. Expected format: YYYY-MM-DD"})
+		return
+	}
+	verification.InsuranceExpiresAt, err = time.Parse("2006-01-02", c.PostForm("insurance_expires_at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid insurance expiry date. Expected format: YYYY-MM-DD"})
+		return
+	}
+	if verification.Expired(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your licence and insurance must not have expired"})
+		return
+	}
+
+	// Documents are read in full before any is stored, so a bad second file stores nothing
+	type upload struct {
+		document models.DriverDocument
+		content  []byte
+	}
+	var uploads []upload
+	for _, kind := range []string{models.DocumentLicence, models.DocumentInsurance} {
+		header, err := c.FormFile(kind)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Upload a scan of your %s", kind)})
+			return
+		}
+		content, ok := dc.readDocument(c, header)
+		if !ok {
+			return
+		}
+		uploads = append(uploads, upload{
+			document: models.DriverDocument{
+				Kind:        kind,
+				Key:         fmt.Sprintf("drivers/%s/%s-%d", userID.Hex(), kind, now.UnixNano()),
+				ContentType: http.DetectContentType(content),
+				Size:        int64(len(content)),
+				UploadedAt:  now,
+			},
+			content: content,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(dc.config.Timeout))
+	defer cancel()
+
+	for _, u := range uploads {
+		if err := dc.documents.Put(ctx, u.document.Key, u.document.ContentType, bytes.NewReader(u.content)); err != nil {
+			log.Printf("❌ Failed to store the %s of %s: %v\n", u.document.Kind, userID.Hex(), err)
+			dc.deleteDocuments(verification.Documents)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store your documents"})
+			return
+		}
+		verification.Documents = append(verification.Documents, u.document)
+	}
+
+	previous, err := dc.drivers.FindByUser(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		dc.deleteDocuments(verification.Documents)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch your driver verification"})
+		return
+	}
+	if err := dc.drivers.Save(ctx, &verification); err != nil {
+		dc.deleteDocuments(verification.Documents)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save your driver verification"})
+		return
+	}
+	// The replaced submission's scans are not needed anymore
+	if previous != nil {
+		dc.deleteDocuments(previous.Documents)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Your documents were submitted for review", "verification": verification})
+}
+
+// readDocument reads an uploaded document, checking its size and that it is an image or PDF.
+// It writes the error response itself and reports false otherwise.
+func (dc *DriverController) readDocument(c *gin.Context, header *multipart.FileHeader) ([]byte, bool) {
+	if header.Size > int64(dc.config.MaxUploadBytes) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Each document can be at most %d bytes", dc.config.MaxUploadBytes)})
+		return nil, false
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read the uploaded document"})
+		return nil, false
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, int64(dc.config.MaxUploadBytes)+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read the uploaded document"})
+		return nil, false
+	}
+	if len(content) > dc.config.MaxUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Each document can be at most %d bytes", dc.config.MaxUploadBytes)})
+		return nil, false
+	}
+	// The type is judged by the content, not by what the client claims
+	if !slices.Contains(documentTypes, http.DetectContentType(content)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Documents must be JPEG or PNG images, or PDFs"})
+		return nil, false
+	}
+	return content, true
+}
+
+// deleteDocuments removes stored documents; failures are only logged, leaving stray files at worst
+func (dc *DriverController) deleteDocuments(documents []models.DriverDocument) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(dc.config.Timeout))
+	defer cancel()
+	for _, document := range documents {
+		if err := dc.documents.Delete(ctx, document.Key); err != nil {
+			log.Printf("❌ Failed to delete document %s: %v\n", document.Key, err)
+		}
+	}
+}
+
+// GetVerification - Returns the caller's driver verification and where its review stands
+func (dc *DriverController) GetVerification(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	verification, err := dc.drivers.FindByUser(context.TODO(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You have not submitted your driver documents yet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch your driver verification"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"verification": verification, "expired": verification.Expired(time.Now())})
+}
+
+// ListVerifications - Lists driver verifications for admins, oldest submission first. The
+// "status" query picks which (default "pending").
+func (dc *DriverController) ListVerifications(c *gin.Context) {
+	status := models.DriverStatus(c.DefaultQuery("status", string(models.DriverPending)))
+	if status != models.DriverPending && status != models.DriverApproved && status != models.DriverRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Use pending, approved or rejected"})
+		return
+	}
+
+	verifications, err := dc.drivers.Find(context.TODO(), repository.DriverFilter{Statuses: []models.DriverStatus{status}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch driver verifications"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if verifications == nil {
+		verifications = []models.DriverVerification{}
+	}
+	c.JSON(http.StatusOK, gin.H{"verifications": verifications})
+}
+
+// ApproveDriver - Lets an admin approve a user's pending driver verification, allowing them to
+// offer rides until their documents expire
+func (dc *DriverController) ApproveDriver(c *gin.Context) {
+	verification, ok := dc.pendingVerification(c)
+	if !ok {
+		return
+	}
+	if verification.Expired(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "These documents have expired. Reject them so the user submits renewed ones"})
+		return
+	}
+	dc.review(c, verification, models.DriverApproved, "")
+}
+
+// RejectDriver - Lets an admin reject a user's pending driver verification. The body gives the
+// "reason", which the user is told.
+func (dc *DriverController) RejectDriver(c *gin.Context) {
+	verification, ok := dc.pendingVerification(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Give the user a reason of at most %d characters", maxNotesLength)})
+		return
+	}
+	dc.review(c, verification, models.DriverRejected, req.Reason)
+}
+
+// pendingVerification loads the verification of the user in the path and checks it waits for
+// review. It writes the error response itself and reports false otherwise.
+func (dc *DriverController) pendingVerification(c *gin.Context) (*models.DriverVerification, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return nil, false
+	}
+	verification, err := dc.drivers.FindByUser(context.TODO(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This user has not submitted driver documents"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch driver verification"})
+		return nil, false
+	}
+	if verification.Status != models.DriverPending {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("This verification was already %s", verification.Status)})
+		return nil, false
+	}
+	return verification, true
+}
+
+// review records the admin's decision and tells the user, in their inbox and by email
+func (dc *DriverController) review(c *gin.Context, verification *models.DriverVerification, status models.DriverStatus, reason string) {
+	reviewerID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	// The decision only applies to the submission the admin looked at
+	now := time.Now()
+	reviewed, err := dc.drivers.Review(context.TODO(), verification.UserID, verification.SubmittedAt, status, reviewerID, reason, now)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The user submitted new documents or another admin reviewed them. Please reload."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review driver verification"})
+		return
+	}
+
+	message := "You are verified as a driver and can offer rides."
+	if status == models.DriverRejected {
+		message = "Your driver documents were rejected: " + reason
+	}
+	if err := dc.notifications.Create(context.TODO(), &models.Notification{
+		UserID:    reviewed.UserID,
+		Kind:      models.NotificationDriverReview,
+		Message:   message,
+		CreatedAt: now,
+	}); err != nil {
+		log.Printf("❌ Failed to add the driver review to the inbox of %s: %v\n", reviewed.UserID.Hex(), err)
+	}
+	if user, err := dc.users.FindByID(context.TODO(), reviewed.UserID); err != nil {
+		log.Printf("❌ Could not load user %s: %v\n", reviewed.UserID.Hex(), err)
+	} else if err := utils.SendDriverReviewFunc(user.Email, reviewed); err != nil {
+		log.Printf("❌ Failed to tell %s about their driver review: %v\n", user.Email, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Driver %s", status), "verification": reviewed})
+}
+
+// GetDocument - Lets an admin download one of a user's driver documents, "licence" or
+// "insurance"
+func (dc *DriverController) GetDocument(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+	verification, err := dc.drivers.FindByUser(context.TODO(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This user has not submitted driver documents"})
+		return
+	}
+	document := verification.Document(c.Param("kind"))
+	if document == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(dc.config.Timeout))
+	defer cancel()
+	content, contentType, err := dc.documents.Open(ctx, document.Key)
+	if errors.Is(err, filestore.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to open document %s: %v\n", document.Key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document"})
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, document.Size, contentType, content, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", document.Kind),
+	})
+}
+
+// verifiedDriver checks that an admin approved the user as a driver and that their documents
+// are still valid when the ride departs. It writes the error response itself and reports false
+// otherwise.
+func verifiedDriver(c *gin.Context, drivers repository.DriverVerificationRepository, userID primitive.ObjectID, departure time.Time) bool {
+	verification, err := drivers.FindByUser(context.TODO(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Submit your driver's licence and insurance to offer rides"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check your driver verification"})
+		return false
+	}
+
+	switch verification.Status {
+	case models.DriverPending:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your driver documents are still being reviewed"})
+		return false
+	case models.DriverRejected:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your driver documents were rejected: " + verification.RejectionReason})
+		return false
+	}
+	if now := time.Now(); verification.Expired(now) || verification.Expired(departure) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "Your licence or insurance expires before the ride. Submit your renewed documents",
+			"expires_at": verification.ExpiresAt(),
+		})
+		return false
+	}
+	return true
+}
@@ -1,23 +1,25 @@
 package controllers
 
 import (
-	"backend/config"
 	"backend/models"
-	"backend/tests/mocks"
+	"backend/repository"
 	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // FetchRideFeed retrieves ride feed based on user-provided location and date
-func FetchRideFeed(c *gin.Context) {
+func (rc *RideController) FetchRideFeed(c *gin.Context) {
 	var request struct {
 		Latitude  float64   `json:"latitude" binding:"required"`
 		Longitude float64   `json:"longitude" binding:"required"`
 		Date      time.Time `json:"date" binding:"required"`
+		// RadiusMeters overrides the default feed radius when set
+		RadiusMeters float64 `json:"radius_m"`
+		PageRequest
 	}
 
 	// Bind JSON request body to struct
@@ -26,64 +28,50 @@ func FetchRideFeed(c *gin.Context) {
 		return
 	}
 
-	// Use mock collection in tests
-	var collection mocks.CollectionInterface
-	if gin.Mode() == gin.TestMode {
-		collection = mocks.MockGetCollection("rides")
-	} else {
-		collection = config.GetCollection("rides")
+	radius, err := searchRadius("radius_m", request.RadiusMeters, rc.search.FeedRadiusMeters, rc.search.MaxRadiusMeters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Fetch rides based on provided location and date
-	rides, err := FetchRideFeedData(collection, request.Latitude, request.Longitude, request.Date)
+	page, err := request.page(rc.search, nearbySortFields, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hidden, err := hiddenDrivers(context.TODO(), c, rc.blocks)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ride feed"})
 		return
 	}
 
-	// ✅ Force JSON to always return an array instead of `null`
-	var rideResponse []interface{}
-	for _, ride := range rides {
-		rideResponse = append(rideResponse, ride)
+	// Fetch rides based on provided location and date
+	rides, err := rc.FetchRideFeedData(context.TODO(), request.Latitude, request.Longitude, request.Date, radius, hidden, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ride feed"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"rides": rideResponse})
+	// ✅ nearbyPage always returns an array instead of `null`
+	c.JSON(http.StatusOK, rides)
 }
 
-// FetchRideFeedData retrieves rides based on the given location and date
-func FetchRideFeedData(collection mocks.CollectionInterface, lat float64, lon float64, date time.Time) ([]models.Ride, error) {
-	radius := 10.0 // Search radius in km
-
-	// Approximate degrees per kilometer (1° latitude ≈ 111 km)
-	degreeOffset := radius / 111
-
-	// Define bounding box filter and date constraint
-	filter := bson.M{
-		"pickup.latitude":  bson.M{"$gte": lat - degreeOffset, "$lte": lat + degreeOffset},
-		"pickup.longitude": bson.M{"$gte": lon - degreeOffset, "$lte": lon + degreeOffset},
-		"status":           "open",
-		"created_at": bson.M{
-			"$gte": time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
-			"$lt":  time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, time.UTC),
-		},
-	}
-
-	// Query MongoDB for rides matching the criteria
-	cursor, err := collection.Find(context.TODO(), filter)
+// FetchRideFeedData retrieves a page of rides created on the given date whose pickup lies within
+// radius metres of the given location, leaving out the rides of the hidden drivers
+func (rc *RideController) FetchRideFeedData(ctx context.Context, lat float64, lon float64, date time.Time, radius float64, hidden []primitive.ObjectID, page repository.Page) (NearbyPage, error) {
+	found, err := rc.rides.FindNear(ctx, repository.NearQuery{
+		Field:             repository.NearPickup,
+		Point:             models.Location{Latitude: lat, Longitude: lon},
+		MaxDistanceMeters: radius,
+	}, repository.RideFilter{
+		Statuses:         []models.RideStatus{models.StatusOpen},
+		CreatedFrom:      time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+		CreatedTo:        time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, time.UTC),
+		ExcludeDriverIDs: hidden,
+	})
 	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.TODO())
-
-	// Decode rides
-	var rides []models.Ride
-	for cursor.Next(context.TODO()) {
-		var ride models.Ride
-		if err := cursor.Decode(&ride); err != nil {
-			return nil, err
-		}
-		rides = append(rides, ride)
+		return NearbyPage{}, err
 	}
-
-	return rides, nil
+	return nearbyPage(found, page), nil
 }
@@ -1,20 +1,18 @@
 package controllers
 
 import (
-	"backend/config"
 	"backend/models"
-	"backend/tests/mocks"
+	"backend/repository"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func HomeHandler(c *gin.Context) {
+func (uc *UserController) HomeHandler(c *gin.Context) {
 	// Extract userID from context
 	userIDStr, exists := c.Get("userID")
 	if !exists {
@@ -29,14 +27,9 @@ func HomeHandler(c *gin.Context) {
 		return
 	}
 
-	// Get users collection
-	userCollection := config.GetCollection("users")
-
-	// Query MongoDB using ObjectID
-	var user models.User
-	err = userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	user, err := uc.users.FindByID(context.TODO(), userID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
@@ -45,120 +38,64 @@ func HomeHandler(c *gin.Context) {
 	}
 
 	// Check if user location is set
-	fmt.Println("User Data:", user)
+	fmt.Println("User Data:", user.ID.Hex(), user.Location)
 	if user.Location.Latitude == 0 && user.Location.Longitude == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User location not set"})
 		return
 	}
 
-	// Fetch nearby rides using geospatial query
-	rides, err := FetchNearbyRides(user.Location.Latitude, user.Location.Longitude)
+	// Optional query parameters: "radius_m" widens or narrows the search, and "sort", "order",
+	// "limit" and "cursor" page through the results
+	var query struct {
+		RadiusMeters float64 `form:"radius_m"`
+		PageRequest
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	radius, err := searchRadius("radius_m", query.RadiusMeters, uc.search.HomeRadiusMeters, uc.search.MaxRadiusMeters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rides", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Return rides
-	c.JSON(http.StatusOK, gin.H{"rides": rides})
-}
-
-func FetchNearbyRides(lat float64, lon float64) ([]models.Ride, error) {
-	collection := config.GetCollection("rides")
-	radius := 1110.0 // 10 km search radius
-
-	// Approximate degrees per kilometer (1° latitude ≈ 111 km)
-	degreeOffset := radius / 111
-
-	// Define bounding box filter
-	filter := bson.M{
-		"pickup.latitude":  bson.M{"$gte": lat - degreeOffset, "$lte": lat + degreeOffset},
-		"pickup.longitude": bson.M{"$gte": lon - degreeOffset, "$lte": lon + degreeOffset},
-		"status":           "open",
+	page, err := query.page(uc.search, nearbySortFields, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Query MongoDB for nearby rides
-	cursor, err := collection.Find(context.TODO(), filter)
+	// Rides of drivers kept apart from the user by a block never show up
+	hidden, err := uc.blocks.BlockedWith(context.TODO(), userID)
 	if err != nil {
-		return nil, err
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rides", "details": err.Error()})
+		return
 	}
-	defer cursor.Close(context.TODO())
 
-	// Decode rides
-	var rides []models.Ride
-	for cursor.Next(context.TODO()) {
-		var ride models.Ride
-		if err := cursor.Decode(&ride); err != nil {
-			return nil, err
-		}
-		rides = append(rides, ride)
+	// Fetch nearby rides
+	rides, err := uc.FetchNearbyRides(context.TODO(), user.Location.Latitude, user.Location.Longitude, radius, hidden, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rides", "details": err.Error()})
+		return
 	}
 
-	return rides, nil
-}
-
-func HomeHandlerWithMocks(userCollection mocks.CollectionInterfaces, ridesCollection mocks.CollectionInterfaces) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userIDStr, exists := c.Get("userID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			return
-		}
-
-		userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
-			return
-		}
-
-		var user models.User
-		err = userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-
-		if user.Location.Latitude == 0 && user.Location.Longitude == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User location not set"})
-			return
-		}
-
-		// ✅ Use the new mock-friendly function
-		rides, err := FetchNearbyRidesWithMocks(ridesCollection, user.Location.Latitude, user.Location.Longitude)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rides"})
-			return
-		}
-
-		c.JSON(http.StatusOK, gin.H{"rides": rides})
-	}
+	// Return rides
+	c.JSON(http.StatusOK, rides)
 }
 
-func FetchNearbyRidesWithMocks(ridesCollection mocks.CollectionInterfaces, lat float64, lon float64) ([]models.Ride, error) {
-	radius := 10.0               // 10 km search radius
-	degreeOffset := radius / 111 // Approximate conversion
-
-	filter := bson.M{
-		"pickup.latitude":  bson.M{"$gte": lat - degreeOffset, "$lte": lat + degreeOffset},
-		"pickup.longitude": bson.M{"$gte": lon - degreeOffset, "$lte": lon + degreeOffset},
-		"status":           "open",
-	}
-
-	// Query the mock collection
-	cursor, err := ridesCollection.Find(context.TODO(), filter)
+// FetchNearbyRides returns a page of open rides whose pickup lies within radius metres of the given
+// point, leaving out the rides of the hidden drivers
+func (uc *UserController) FetchNearbyRides(ctx context.Context, lat float64, lon float64, radius float64, hidden []primitive.ObjectID, page repository.Page) (NearbyPage, error) {
+	found, err := uc.rides.FindNear(ctx, repository.NearQuery{
+		Field:             repository.NearPickup,
+		Point:             models.Location{Latitude: lat, Longitude: lon},
+		MaxDistanceMeters: radius,
+	}, repository.RideFilter{
+		Statuses:         []models.RideStatus{models.StatusOpen},
+		ExcludeDriverIDs: hidden,
+	})
 	if err != nil {
-		return nil, err
+		return NearbyPage{}, err
 	}
-	defer cursor.Close(context.TODO())
-
-	// Decode rides
-	var rides []models.Ride
-	for cursor.Next(context.TODO()) {
-		var ride models.Ride
-		if err := cursor.Decode(&ride); err != nil {
-			return nil, err
-		}
-		rides = append(rides, ride)
-	}
-
-	return rides, nil
+	return nearbyPage(found, page), nil
 }
@@ -0,0 +1,94 @@
+// notification_controller.go
+
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListNotifications - Lists the caller's inbox, newest first. "unread=true" leaves out what was
+// already read, and "limit" and "cursor" page through it.
+func (ac *AlertController) ListNotifications(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	var query struct {
+		Unread bool `form:"unread"`
+		PageRequest
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	page, err := query.page(ac.search, []repository.SortField{repository.SortCreated}, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifications, more, err := ac.notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: userID, Unread: query.Unread}, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	response := gin.H{"notifications": notifications, "has_more": more}
+	if more {
+		last := notifications[len(notifications)-1]
+		response["next_cursor"] = page.CursorAt(float64(last.CreatedAt.UnixMilli()), last.ID).Encode()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// MarkNotificationRead - Marks one notification in the caller's inbox read
+func (ac *AlertController) MarkNotificationRead(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	notificationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID format"})
+		return
+	}
+	notification, err := ac.notifications.MarkRead(context.TODO(), notificationID, userID, time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notification": notification})
+}
+
+// MarkAllNotificationsRead - Marks everything in the caller's inbox read
+func (ac *AlertController) MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	marked, err := ac.notifications.MarkAllRead(context.TODO(), userID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked read", "marked": marked})
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"backend/config"
+	"backend/repository"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PageRequest is how a caller asks for one page of a listing. Cursor is the "next_cursor" of
+// the previous page and must be used with the same sort and order.
+type PageRequest struct {
+	Sort   string `json:"sort" form:"sort"`   // one of the listing's sort fields; the first is the default
+	Order  string `json:"order" form:"order"` // "asc" or "desc"
+	Limit  int    `json:"limit" form:"limit"` // results per page, up to the configured maximum
+	Cursor string `json:"cursor" form:"cursor"`
+}
+
+// page checks the request against the sort fields a listing supports and the configured page
+// sizes, and turns it into a repository.Page. The error is meant for the caller.
+func (p PageRequest) page(search config.SearchConfig, fields []repository.SortField, descending bool) (repository.Page, error) {
+	page := repository.Page{Sort: fields[0], Descending: descending, Limit: search.PageSize}
+
+	if p.Sort != "" {
+		page.Sort = repository.SortField(p.Sort)
+		if !slices.Contains(fields, page.Sort) {
+			names := make([]string, len(fields))
+			for i, field := range fields {
+				names[i] = string(field)
+			}
+			return page, fmt.Errorf("sort must be one of: %s", strings.Join(names, ", "))
+		}
+	}
+
+	switch p.Order {
+	case "":
+	case "asc":
+		page.Descending = false
+	case "desc":
+		page.Descending = true
+	default:
+		return page, errors.New("order must be 'asc' or 'desc'")
+	}
+
+	if p.Limit < 0 || p.Limit > search.MaxPageSize {
+		return page, fmt.Errorf("limit must be between 1 and %d", search.MaxPageSize)
+	}
+	if p.Limit > 0 {
+		page.Limit = p.Limit
+	}
+
+	if p.Cursor != "" {
+		cursor, err := repository.DecodeCursor(p.Cursor, page.Sort, page.Descending)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+	}
+	return page, nil
+}
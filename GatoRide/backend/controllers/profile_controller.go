@@ -3,15 +3,33 @@ package controllers
 import (
 	"backend/config"
 	"backend/models"
+	"backend/repository"
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetUserProfile(c *gin.Context) {
+// UserController handles profile, location and home feed requests
+type UserController struct {
+	users    repository.UserRepository
+	rides    repository.RideRepository
+	bookings repository.BookingRepository
+	blocks   repository.BlockRepository
+	search   config.SearchConfig
+}
+
+// userRideSortFields are the orders a user's own ride lists offer, the default first
+var userRideSortFields = []repository.SortField{repository.SortDeparture, repository.SortPrice}
+
+// NewUserController creates a UserController backed by the given repositories
+func NewUserController(users repository.UserRepository, rides repository.RideRepository, bookings repository.BookingRepository, blocks repository.BlockRepository, search config.SearchConfig) *UserController {
+	return &UserController{users: users, rides: rides, bookings: bookings, blocks: blocks, search: search}
+}
+
+func (uc *UserController) GetUserProfile(c *gin.Context) {
 	userIDStr, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -24,9 +42,7 @@ func GetUserProfile(c *gin.Context) {
 		return
 	}
 
-	userCollection := config.GetCollection("users")
-	var user models.User
-	err = userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	user, err := uc.users.FindByID(context.TODO(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user profile"})
 		return
@@ -36,10 +52,20 @@ func GetUserProfile(c *gin.Context) {
 	user.Password = ""
 	user.VerificationToken = ""
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	commitments, err := countCommitments(context.TODO(), uc.rides, uc.bookings, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":              user,
+		"reliability_score": user.Reliability.Score(commitments),
+		"rating":            user.Rating.Summary(),
+	})
 }
 
-func GetUserRides(c *gin.Context) {
+func (uc *UserController) GetUserRides(c *gin.Context) {
 	userIDStr, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -52,28 +78,87 @@ func GetUserRides(c *gin.Context) {
 		return
 	}
 
-	rideCollection := config.GetCollection("rides")
+	// Each list pages on its own: "sort", "order" and "limit" apply to all three, and each has
+	// its own cursor. Rides sort by "departure" (the default) or "price", newest first unless
+	// "order" is "asc"; bookings always sort by when they were made.
+	var query struct {
+		Sort           string `form:"sort"`
+		Order          string `form:"order"`
+		Limit          int    `form:"limit"`
+		OfferedCursor  string `form:"offered_cursor"`
+		TakenCursor    string `form:"taken_cursor"`
+		BookingsCursor string `form:"bookings_cursor"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	offeredPage, err1 := PageRequest{Sort: query.Sort, Order: query.Order, Limit: query.Limit, Cursor: query.OfferedCursor}.
+		page(uc.search, userRideSortFields, true)
+	takenPage, err2 := PageRequest{Sort: query.Sort, Order: query.Order, Limit: query.Limit, Cursor: query.TakenCursor}.
+		page(uc.search, userRideSortFields, true)
+	bookingsPage, err3 := PageRequest{Order: query.Order, Limit: query.Limit, Cursor: query.BookingsCursor}.
+		page(uc.search, []repository.SortField{repository.SortCreated}, true)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Rides offered
-	ridesOfferedCursor, err1 := rideCollection.Find(context.TODO(), bson.M{"driver_id": userID})
-	if err1 != nil {
+	ridesOffered, moreOffered, err := uc.rides.FindPage(context.TODO(), repository.RideFilter{DriverID: userID}, offeredPage)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offered rides"})
 		return
 	}
-	var ridesOffered []models.Ride
-	_ = ridesOfferedCursor.All(context.TODO(), &ridesOffered)
 
 	// Rides taken
-	ridesTakenCursor, err2 := rideCollection.Find(context.TODO(), bson.M{"passenger_ids": userID})
-	if err2 != nil {
+	ridesTaken, moreTaken, err := uc.rides.FindPage(context.TODO(), repository.RideFilter{PassengerID: userID}, takenPage)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch taken rides"})
 		return
 	}
-	var ridesTaken []models.Ride
-	_ = ridesTakenCursor.All(context.TODO(), &ridesTaken)
+
+	// Bookings, including cancelled ones
+	bookings, moreBookings, err := uc.bookings.FindPage(context.TODO(), repository.BookingFilter{PassengerID: userID}, bookingsPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+
+	// ✅ Force JSON to always return arrays instead of `null`
+	if ridesOffered == nil {
+		ridesOffered = []models.Ride{}
+	}
+	if ridesTaken == nil {
+		ridesTaken = []models.Ride{}
+	}
+	if bookings == nil {
+		bookings = []models.Booking{}
+	}
+
+	nextCursors := gin.H{}
+	if moreOffered {
+		last := &ridesOffered[len(ridesOffered)-1]
+		nextCursors["rides_offered"] = offeredPage.CursorAt(repository.RideSortValue(last, offeredPage.Sort), last.ID).Encode()
+	}
+	if moreTaken {
+		last := &ridesTaken[len(ridesTaken)-1]
+		nextCursors["rides_taken"] = takenPage.CursorAt(repository.RideSortValue(last, takenPage.Sort), last.ID).Encode()
+	}
+	if moreBookings {
+		last := bookings[len(bookings)-1]
+		nextCursors["bookings"] = bookingsPage.CursorAt(float64(last.CreatedAt.UnixMilli()), last.ID).Encode()
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"rides_offered": ridesOffered,
 		"rides_taken":   ridesTaken,
+		"bookings":      bookings,
+		"has_more": gin.H{
+			"rides_offered": moreOffered,
+			"rides_taken":   moreTaken,
+			"bookings":      moreBookings,
+		},
+		"next_cursors": nextCursors,
 	})
 }
@@ -0,0 +1,586 @@
+// report_controller.go
+
+package controllers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxSuspensionDays limits how long one suspension lasts
+const maxSuspensionDays = 365
+
+// ReportController handles the reports users file about unsafe drivers, fake rides, harassment
+// and the like, and the admins who work through them. Everything an admin does about a report
+// is recorded in the moderation log.
+type ReportController struct {
+	reports        repository.ReportRepository
+	moderationLog  repository.ModerationLogRepository
+	users          repository.UserRepository
+	rides          repository.RideRepository
+	reviews        repository.ReviewRepository
+	sessions       repository.SessionRepository
+	notifications  repository.NotificationRepository
+	rideController *RideController
+	search         config.SearchConfig
+}
+
+// NewReportController creates a ReportController backed by the given repositories. Reported
+// rides are removed through rideController, which tells their passengers.
+func NewReportController(reports repository.ReportRepository, moderationLog repository.ModerationLogRepository, users repository.UserRepository, rides repository.RideRepository, reviews repository.ReviewRepository, sessions repository.SessionRepository, notifications repository.NotificationRepository, rideController *RideController, search config.SearchConfig) *ReportController {
+	return &ReportController{
+		reports:        reports,
+		moderationLog:  moderationLog,
+		users:          users,
+		rides:          rides,
+		reviews:        reviews,
+		sessions:       sessions,
+		notifications:  notifications,
+		rideController: rideController,
+		search:         search,
+	}
+}
+
+// SubmitReport - Reports a user, a ride or a review to the admins. Takes the "target" (user, ride
+// or review), its "target_id", a "category", one of models.ReportCategories, and a
+// "description" of what happened. Users cannot report themselves or what they posted, nor report
+// the same thing again while their earlier report is still being handled.
+func (rc *ReportController) SubmitReport(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Target      models.ReportTarget   `json:"target"`
+		TargetID    string                `json:"target_id"`
+		Category    models.ReportCategory `json:"category"`
+		Description string                `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !slices.Contains(models.ReportTargets, req.Target) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Say whether you are reporting a user, a ride or a review", "targets": models.ReportTargets})
+		return
+	}
+	targetID, err := primitive.ObjectIDFromHex(req.TargetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID format"})
+		return
+	}
+	if !slices.Contains(models.ReportCategories, req.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pick a category for your report", "categories": models.ReportCategories})
+		return
+	}
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Description == "" || len(req.Description) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Describe what happened in at most %d characters", maxNotesLength)})
+		return
+	}
+
+	subjectID, ok := rc.subject(c, req.Target, targetID)
+	if !ok {
+		return
+	}
+	if subjectID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report yourself or what you posted"})
+		return
+	}
+
+	pending, _, err := rc.reports.FindPage(context.TODO(), repository.ReportFilter{
+		Statuses:   []models.ReportStatus{models.ReportOpen, models.ReportInvestigating},
+		ReporterID: userID,
+		TargetID:   targetID,
+	}, repository.Page{Sort: repository.SortCreated, Limit: 1})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report"})
+		return
+	}
+	if len(pending) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already reported this. Our moderators are looking into it.", "report_id": pending[0].ID})
+		return
+	}
+
+	now := time.Now()
+	report := models.Report{
+		ID:          primitive.NewObjectID(),
+		ReporterID:  userID,
+		Target:      req.Target,
+		TargetID:    targetID,
+		SubjectID:   subjectID,
+		Category:    req.Category,
+		Description: req.Description,
+		Status:      models.ReportOpen,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := rc.reports.Create(context.TODO(), &report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report"})
+		return
+	}
+
+	log.Printf("🚩 User %s reported %s %s for %s\n", userID.Hex(), report.Target, targetID.Hex(), report.Category)
+	c.JSON(http.StatusCreated, gin.H{"message": "Thanks for your report. Our moderators will look into it.", "report": report})
+}
+
+// subject finds the user answerable for what is reported: the user themselves, the driver of a
+// ride or the author of a review. Reviews can only be reported once revealed, as nobody else
+// sees them before. It writes the error response itself and reports false otherwise.
+func (rc *ReportController) subject(c *gin.Context, target models.ReportTarget, targetID primitive.ObjectID) (primitive.ObjectID, bool) {
+	var subjectID primitive.ObjectID
+	var err error
+	switch target {
+	case models.ReportUser:
+		_, err = rc.users.FindByID(context.TODO(), targetID)
+		subjectID = targetID
+	case models.ReportRide:
+		var ride *models.Ride
+		if ride, err = rc.rides.FindByID(context.TODO(), targetID); err == nil {
+			subjectID = ride.DriverID
+		}
+	case models.ReportReview:
+		var review *models.Review
+		if review, err = rc.reviews.FindByID(context.TODO(), targetID); err == nil {
+			if review.RevealedAt == nil {
+				err = repository.ErrNotFound
+			}
+			subjectID = review.ReviewerID
+		}
+	}
+
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("The %s you are reporting was not found", target)})
+		return primitive.NilObjectID, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report"})
+		return primitive.NilObjectID, false
+	}
+	return subjectID, true
+}
+
+// ListReports - Lists the reports the caller filed and where each stands, newest first unless
+// "order" is "asc"
+func (rc *ReportController) ListReports(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	page, ok := rc.page(c, true)
+	if !ok {
+		return
+	}
+
+	reports, more, err := rc.reports.FindPage(context.TODO(), repository.ReportFilter{ReporterID: userID}, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if reports == nil {
+		reports = []models.Report{}
+	}
+	// Reporters are not told which admin handled their report
+	for i := range reports {
+		reports[i].AssigneeID = nil
+	}
+	c.JSON(http.StatusOK, reportPage(page, reports, more))
+}
+
+// ListReportQueue - Lists the reports with the given "status" (open by default), oldest first
+// unless "order" is "desc" so the longest waiting are handled first. "subject_id" narrows the
+// queue down to reports about one user.
+func (rc *ReportController) ListReportQueue(c *gin.Context) {
+	status := models.ReportStatus(c.DefaultQuery("status", string(models.ReportOpen)))
+	if status != models.ReportOpen && status != models.ReportInvestigating && status != models.ReportResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Use open, investigating or resolved"})
+		return
+	}
+	filter := repository.ReportFilter{Statuses: []models.ReportStatus{status}}
+	if subject := c.Query("subject_id"); subject != "" {
+		subjectID, err := primitive.ObjectIDFromHex(subject)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subject ID format"})
+			return
+		}
+		filter.SubjectID = subjectID
+	}
+	page, ok := rc.page(c, false)
+	if !ok {
+		return
+	}
+
+	reports, more, err := rc.reports.FindPage(context.TODO(), filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if reports == nil {
+		reports = []models.Report{}
+	}
+	c.JSON(http.StatusOK, reportPage(page, reports, more))
+}
+
+// GetReport - Shows an admin a report, the user it is about along with their standing, and
+// everything admins did about it so far
+func (rc *ReportController) GetReport(c *gin.Context) {
+	report, ok := rc.report(c)
+	if !ok {
+		return
+	}
+
+	actions, _, err := rc.moderationLog.FindPage(context.TODO(), repository.ModerationFilter{ReportID: report.ID}, repository.Page{Sort: repository.SortCreated})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the moderation log"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if actions == nil {
+		actions = []models.ModerationAction{}
+	}
+
+	response := gin.H{"report": report, "actions": actions}
+	// Users who deleted their account are shown without a profile
+	if subject, err := rc.users.FindByID(context.TODO(), report.SubjectID); err == nil {
+		response["subject"] = gin.H{
+			"id":         subject.ID,
+			"name":       subject.Name,
+			"username":   subject.Username,
+			"moderation": subject.Moderation,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// InvestigateReport - Lets an admin take on an open report, moving it to investigating
+func (rc *ReportController) InvestigateReport(c *gin.Context) {
+	adminID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	report, ok := rc.report(c)
+	if !ok {
+		return
+	}
+	if report.Status != models.ReportOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("This report is already %s", report.Status)})
+		return
+	}
+
+	now := time.Now()
+	report, ok = rc.moveReport(c, report, models.ReportInvestigating, adminID, "", now)
+	if !ok {
+		return
+	}
+	rc.audit(context.TODO(), &models.ModerationAction{
+		ReportID:  report.ID,
+		AdminID:   adminID,
+		Action:    models.ActionInvestigate,
+		SubjectID: report.SubjectID,
+		CreatedAt: now,
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Report under investigation", "report": report})
+}
+
+// TakeAction - Lets an admin act against the subject of a report that is not resolved yet. The
+// body gives the "action", one of models.ModerationActions, and a "note" explaining it, which the
+// subject is told:
+//   - warn adds a warning to the user's record and their inbox
+//   - suspend logs the user out and keeps them from logging in for "days" days
+//   - remove_ride cancels a reported ride and tells its passengers
+func (rc *ReportController) TakeAction(c *gin.Context) {
+	adminID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	report, ok := rc.report(c)
+	if !ok {
+		return
+	}
+	if report.Status == models.ReportResolved {
+		c.JSON(http.StatusConflict, gin.H{"error": "This report is already resolved"})
+		return
+	}
+
+	var req struct {
+		Action models.ModerationActionKind `json:"action"`
+		Note   string                      `json:"note"`
+		Days   int                         `json:"days"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !slices.Contains(models.ModerationActions, req.Action) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pick an action to take", "actions": models.ModerationActions})
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" || len(req.Note) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Explain the action in a note of at most %d characters", maxNotesLength)})
+		return
+	}
+
+	now := time.Now()
+	entry := models.ModerationAction{
+		ReportID:  report.ID,
+		AdminID:   adminID,
+		Action:    req.Action,
+		SubjectID: report.SubjectID,
+		Note:      req.Note,
+		CreatedAt: now,
+	}
+	response := gin.H{}
+
+	switch req.Action {
+	case models.ActionWarn:
+		if !rc.moderate(c, rc.users.Warn(context.TODO(), report.SubjectID)) {
+			return
+		}
+		rc.notify(report.SubjectID, nil, fmt.Sprintf("You received a warning from our moderators after a report about %s: %s",
+			strings.ReplaceAll(string(report.Category), "_", " "), req.Note), now)
+		response["message"] = "User warned"
+
+	case models.ActionSuspend:
+		if req.Days < 1 || req.Days > maxSuspensionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Suspend the user for 1 to %d days", maxSuspensionDays)})
+			return
+		}
+		until := now.AddDate(0, 0, req.Days)
+		if !rc.moderate(c, rc.users.Suspend(context.TODO(), report.SubjectID, until)) {
+			return
+		}
+		entry.SuspendedUntil = &until
+		// Signing them out of every device makes the suspension take effect right away
+		if err := rc.sessions.DeleteByUser(context.TODO(), report.SubjectID.Hex()); err != nil {
+			log.Printf("❌ Failed to log out suspended user %s: %v\n", report.SubjectID.Hex(), err)
+		}
+		if subject, err := rc.users.FindByID(context.TODO(), report.SubjectID); err == nil {
+			if err := utils.SendAccountSuspendedFunc(subject.Email, until, req.Note); err != nil {
+				log.Printf("❌ Failed to tell %s about their suspension: %v\n", subject.Email, err)
+			}
+		}
+		response["message"] = "User suspended"
+		response["suspended_until"] = until
+
+	case models.ActionRemoveRide:
+		if report.Target != models.ReportRide {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only reported rides can be removed"})
+			return
+		}
+		ride, err := rc.rides.FindByID(context.TODO(), report.TargetID)
+		if !rc.moderate(c, err) {
+			return
+		}
+		notified, err := rc.rideController.removeRide(context.TODO(), ride, now)
+		if errors.Is(err, repository.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This ride is no longer open or booked and cannot be removed"})
+			return
+		}
+		if !rc.moderate(c, err) {
+			return
+		}
+		entry.RideID = &ride.ID
+		rc.notify(ride.DriverID, &ride.ID, fmt.Sprintf("Our moderators took down your ride from %s to %s after a report: %s",
+			ride.Pickup.Address, ride.Dropoff.Address, req.Note), now)
+		response["message"] = "Ride removed"
+		response["passengers_notified"] = notified
+	}
+
+	rc.audit(context.TODO(), &entry)
+	response["action"] = entry
+	c.JSON(http.StatusOK, response)
+}
+
+// moderate checks the outcome of acting on a report's subject. It writes the error response
+// itself and reports false if the action failed.
+func (rc *ReportController) moderate(c *gin.Context, err error) bool {
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "What this report is about no longer exists. Resolve the report instead."})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to take action"})
+		return false
+	}
+	return true
+}
+
+// ResolveReport - Lets an admin close a report that is not resolved yet, whether or not they
+// took action. The body gives the "resolution", which the reporter is told.
+func (rc *ReportController) ResolveReport(c *gin.Context) {
+	adminID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	report, ok := rc.report(c)
+	if !ok {
+		return
+	}
+	if report.Status == models.ReportResolved {
+		c.JSON(http.StatusConflict, gin.H{"error": "This report is already resolved"})
+		return
+	}
+
+	var req struct {
+		Resolution string `json:"resolution"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	req.Resolution = strings.TrimSpace(req.Resolution)
+	if req.Resolution == "" || len(req.Resolution) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Tell the reporter how it was resolved in at most %d characters", maxNotesLength)})
+		return
+	}
+
+	now := time.Now()
+	report, ok = rc.moveReport(c, report, models.ReportResolved, adminID, req.Resolution, now)
+	if !ok {
+		return
+	}
+	rc.audit(context.TODO(), &models.ModerationAction{
+		ReportID:  report.ID,
+		AdminID:   adminID,
+		Action:    models.ActionResolve,
+		SubjectID: report.SubjectID,
+		Note:      req.Resolution,
+		CreatedAt: now,
+	})
+	rc.notify(report.ReporterID, nil, fmt.Sprintf("Our moderators closed your report about a %s: %s", report.Target, req.Resolution), now)
+	c.JSON(http.StatusOK, gin.H{"message": "Report resolved", "report": report})
+}
+
+// ListModerationLog - Lists what admins did about reports, newest first unless "order" is "asc".
+// "report_id", "subject_id" and "admin_id" narrow it down to one report, the user acted against
+// or the admin who acted.
+func (rc *ReportController) ListModerationLog(c *gin.Context) {
+	var filter repository.ModerationFilter
+	for param, id := range map[string]*primitive.ObjectID{
+		"report_id":  &filter.ReportID,
+		"subject_id": &filter.SubjectID,
+		"admin_id":   &filter.AdminID,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s format", param)})
+			return
+		}
+		*id = parsed
+	}
+	page, ok := rc.page(c, true)
+	if !ok {
+		return
+	}
+
+	actions, more, err := rc.moderationLog.FindPage(context.TODO(), filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the moderation log"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if actions == nil {
+		actions = []models.ModerationAction{}
+	}
+
+	response := gin.H{"actions": actions, "has_more": more}
+	if more {
+		last := actions[len(actions)-1]
+		response["next_cursor"] = page.CursorAt(float64(last.CreatedAt.UnixMilli()), last.ID).Encode()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// report loads the report with the ID in the path. It writes the error response itself and
+// reports false if there is none.
+func (rc *ReportController) report(c *gin.Context) (*models.Report, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID format"})
+		return nil, false
+	}
+	report, err := rc.reports.FindByID(context.TODO(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report"})
+		return nil, false
+	}
+	return report, true
+}
+
+// moveReport moves the report along the queue, as long as no other admin moved it since it was
+// read. It writes the error response itself and reports false otherwise.
+func (rc *ReportController) moveReport(c *gin.Context, report *models.Report, to models.ReportStatus, adminID primitive.ObjectID, resolution string, at time.Time) (*models.Report, bool) {
+	moved, err := rc.reports.UpdateStatus(context.TODO(), report.ID, report.Status, to, adminID, resolution, at)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another admin updated this report. Please reload."})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report"})
+		return nil, false
+	}
+	return moved, true
+}
+
+// audit records what an admin did in the moderation log. The action already took effect, so a
+// failure to record it is only logged, with everything the entry held.
+func (rc *ReportController) audit(ctx context.Context, entry *models.ModerationAction) {
+	entry.ID = primitive.NewObjectID()
+	log.Printf("🛡️ Admin %s: %s on report %s about user %s\n", entry.AdminID.Hex(), entry.Action, entry.ReportID.Hex(), entry.SubjectID.Hex())
+	if err := rc.moderationLog.Create(ctx, entry); err != nil {
+		log.Printf("❌ Failed to record moderation action %+v: %v\n", *entry, err)
+	}
+}
+
+// notify adds a moderation message to the user's inbox; failures are only logged
+func (rc *ReportController) notify(userID primitive.ObjectID, rideID *primitive.ObjectID, message string, at time.Time) {
+	if err := rc.notifications.Create(context.TODO(), &models.Notification{
+		UserID:    userID,
+		Kind:      models.NotificationModeration,
+		Message:   message,
+		RideID:    rideID,
+		CreatedAt: at,
+	}); err != nil {
+		log.Printf("❌ Failed to add a moderation notification to the inbox of %s: %v\n", userID.Hex(), err)
+	}
+}
+
+// page reads the paging parameters of a report listing, which sorts by creation time. It writes
+// the error response itself and reports false if they are invalid.
+func (rc *ReportController) page(c *gin.Context, descending bool) (repository.Page, bool) {
+	var query PageRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return repository.Page{}, false
+	}
+	page, err := query.page(rc.search, []repository.SortField{repository.SortCreated}, descending)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return repository.Page{}, false
+	}
+	return page, true
+}
+
+// reportPage is the response listing one page of reports
+func reportPage(page repository.Page, reports []models.Report, more bool) gin.H {
+	response := gin.H{"reports": reports, "has_more": more}
+	if more {
+		last := reports[len(reports)-1]
+		response["next_cursor"] = page.CursorAt(float64(last.CreatedAt.UnixMilli()), last.ID).Encode()
+	}
+	return response
+}
@@ -0,0 +1,271 @@
+// review_controller.go
+
+package controllers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReviewController handles the reviews drivers and passengers write about each other once a
+// ride is completed
+type ReviewController struct {
+	reviews       repository.ReviewRepository
+	rides         repository.RideRepository
+	users         repository.UserRepository
+	notifications repository.NotificationRepository
+	window        time.Duration
+	search        config.SearchConfig
+}
+
+// NewReviewController creates a ReviewController backed by the given repositories
+func NewReviewController(reviews repository.ReviewRepository, rides repository.RideRepository, users repository.UserRepository, notifications repository.NotificationRepository, reviewConfig config.ReviewConfig, search config.SearchConfig) *ReviewController {
+	return &ReviewController{
+		reviews:       reviews,
+		rides:         rides,
+		users:         users,
+		notifications: notifications,
+		window:        time.Duration(reviewConfig.Window),
+		search:        search,
+	}
+}
+
+// SubmitReview - Reviews someone the caller shared a completed ride with: passengers review the
+// driver, and the driver reviews a passenger named by "reviewee_id". Takes a "rating" from 1 to
+// 5 and an optional "comment". Each may review each other once per ride, until the review window
+// closes. The review stays hidden until the other side reviews back or the window closes.
+func (rc *ReviewController) SubmitReview(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	ride, ok := rc.completedRide(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req struct {
+		RevieweeID string `json:"reviewee_id"`
+		Rating     int    `json:"rating"`
+		Comment    string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review data", "details": err.Error()})
+		return
+	}
+	if req.Rating < models.MinRating || req.Rating > models.MaxRating {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Rate the ride from %d to %d", models.MinRating, models.MaxRating)})
+		return
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if len(req.Comment) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Comments can be at most %d characters", maxNotesLength)})
+		return
+	}
+
+	now := time.Now()
+	revealAt := ride.CompletionTime().Add(rc.window)
+	if !now.Before(revealAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The time to review this ride is over"})
+		return
+	}
+
+	// Drivers and passengers review each other, never someone in their own role
+	review := models.Review{
+		ID:         primitive.NewObjectID(),
+		RideID:     ride.ID,
+		ReviewerID: userID,
+		Rating:     req.Rating,
+		Comment:    req.Comment,
+		CreatedAt:  now,
+		RevealAt:   revealAt,
+	}
+	switch {
+	case userID == ride.DriverID:
+		revieweeID, err := primitive.ObjectIDFromHex(req.RevieweeID)
+		if err != nil || !slices.Contains(ride.PassengerIDs, revieweeID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name the passenger you are reviewing in reviewee_id"})
+			return
+		}
+		review.Role, review.RevieweeID = models.ReviewByDriver, revieweeID
+	case slices.Contains(ride.PassengerIDs, userID):
+		if req.RevieweeID != "" && req.RevieweeID != ride.DriverID.Hex() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Passengers can only review the driver"})
+			return
+		}
+		review.Role, review.RevieweeID = models.ReviewByPassenger, ride.DriverID
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the driver and passengers of a ride can review it"})
+		return
+	}
+
+	err := rc.reviews.Create(context.TODO(), &review)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already reviewed this person for this ride"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+		return
+	}
+
+	// A review answering one already written reveals both
+	replies, _, err := rc.reviews.FindPage(context.TODO(), repository.ReviewFilter{
+		RideID:     ride.ID,
+		ReviewerID: review.RevieweeID,
+		RevieweeID: userID,
+	}, repository.Page{Sort: repository.SortCreated})
+	if err != nil {
+		log.Printf("❌ Failed to look for the reply to review %s: %v\n", review.ID.Hex(), err)
+	}
+	if len(replies) > 0 {
+		for _, id := range []primitive.ObjectID{replies[0].ID, review.ID} {
+			if _, err := utils.RevealReview(context.TODO(), rc.reviews, rc.users, id, now); err != nil {
+				log.Printf("❌ Failed to reveal review %s: %v\n", id.Hex(), err)
+			}
+		}
+		review.RevealedAt = &now
+	}
+
+	rc.notifyReviewed(ride, &review, now)
+	c.JSON(http.StatusCreated, gin.H{"message": "Review saved", "review": review, "revealed": review.RevealedAt != nil})
+}
+
+// notifyReviewed tells the reviewee in their inbox that they were reviewed; failures are only logged
+func (rc *ReviewController) notifyReviewed(ride *models.Ride, review *models.Review, at time.Time) {
+	reviewer := "A passenger"
+	if review.Role == models.ReviewByDriver {
+		reviewer = "Your driver"
+	}
+	message := fmt.Sprintf("%s reviewed your ride from %s to %s. Both reviews are now visible.",
+		reviewer, ride.Pickup.Address, ride.Dropoff.Address)
+	if review.RevealedAt == nil {
+		message = fmt.Sprintf("%s reviewed your ride from %s to %s. Review them back by %s to see what they said.",
+			reviewer, ride.Pickup.Address, ride.Dropoff.Address, review.RevealAt.Format("Jan 2"))
+	}
+
+	rideID := ride.ID
+	if err := rc.notifications.Create(context.TODO(), &models.Notification{
+		UserID:    review.RevieweeID,
+		Kind:      models.NotificationReview,
+		Message:   message,
+		RideID:    &rideID,
+		CreatedAt: at,
+	}); err != nil {
+		log.Printf("❌ Failed to add a review notification to the inbox of %s: %v\n", review.RevieweeID.Hex(), err)
+	}
+}
+
+// ListRideReviews - Lists the reviews of a ride the caller drove or took: every review that was
+// revealed, and the caller's own
+func (rc *ReviewController) ListRideReviews(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	ride, ok := rc.completedRide(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if userID != ride.DriverID && !slices.Contains(ride.PassengerIDs, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the driver and passengers of a ride can see its reviews"})
+		return
+	}
+
+	all, _, err := rc.reviews.FindPage(context.TODO(), repository.ReviewFilter{RideID: ride.ID}, repository.Page{Sort: repository.SortCreated})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	reviews := []models.Review{}
+	for _, review := range all {
+		if review.RevealedAt != nil || review.ReviewerID == userID {
+			reviews = append(reviews, review)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews, "reviews_close_at": ride.CompletionTime().Add(rc.window)})
+}
+
+// ListUserReviews - Lists the revealed reviews a user received, newest first unless "order" is
+// "asc", along with their rating
+func (rc *ReviewController) ListUserReviews(c *gin.Context) {
+	if _, ok := callerID(c); !ok {
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var query PageRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	page, err := query.page(rc.search, []repository.SortField{repository.SortCreated}, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := rc.users.FindByID(context.TODO(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	revealed := true
+	reviews, more, err := rc.reviews.FindPage(context.TODO(), repository.ReviewFilter{RevieweeID: userID, Revealed: &revealed}, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if reviews == nil {
+		reviews = []models.Review{}
+	}
+
+	response := gin.H{"rating": user.Rating.Summary(), "reviews": reviews, "has_more": more}
+	if more {
+		last := reviews[len(reviews)-1]
+		response["next_cursor"] = page.CursorAt(float64(last.CreatedAt.UnixMilli()), last.ID).Encode()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// completedRide loads the ride with the given ID and checks it is completed, which reviews need.
+// It writes the error response itself and reports false otherwise.
+func (rc *ReviewController) completedRide(c *gin.Context, rideID string) (*models.Ride, bool) {
+	id, err := primitive.ObjectIDFromHex(rideID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
+		return nil, false
+	}
+	ride, err := rc.rides.FindByID(context.TODO(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ride"})
+		return nil, false
+	}
+	if ride.Status != models.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Rides can be reviewed once they are completed"})
+		return nil, false
+	}
+	return ride, true
+}
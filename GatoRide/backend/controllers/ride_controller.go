@@ -1,19 +1,135 @@
 package controllers
 
 import (
+	"backend/cancellation"
 	"backend/config"
 	"backend/models"
+	"backend/repository"
+	"backend/routing"
+	"backend/utils"
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// maxWaypoints limits the intermediate stops a ride may have
+const maxWaypoints = 8
+
+// validWaypoints reports whether every intermediate stop has coordinates and there are not too many
+func validWaypoints(waypoints []models.Location) bool {
+	if len(waypoints) > maxWaypoints {
+		return false
+	}
+	for _, stop := range waypoints {
+		if stop.Latitude == 0 || stop.Longitude == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// maxLuggageBags limits the luggage space a driver may offer
+const maxLuggageBags = 20
+
+// validAttributes reports whether the luggage space is in range and the music preference is
+// one drivers may set, if any
+func validAttributes(attributes models.RideAttributes) bool {
+	if attributes.LuggageBags < 0 || attributes.LuggageBags > maxLuggageBags {
+		return false
+	}
+	return attributes.Music == "" || slices.Contains(models.MusicPreferences, attributes.Music)
+}
+
+// maxPathPoints limits the points of a path the driver draws
+const maxPathPoints = 5000
+
+// RideController handles offering, searching, booking and cancelling rides
+type RideController struct {
+	rides         repository.RideRepository
+	users         repository.UserRepository
+	bookings      repository.BookingRepository
+	waitlist      repository.WaitlistRepository
+	vehicles      repository.VehicleRepository
+	drivers       repository.DriverVerificationRepository
+	blocks        repository.BlockRepository
+	promoter      *utils.WaitlistPromoter
+	router        routing.Provider
+	alerter       *RideAlerter
+	cancellation  cancellation.Policy
+	search        config.SearchConfig
+	bookingConfig config.BookingConfig
+}
+
+// NewRideController creates a RideController backed by the given repositories, planning ride
+// paths with the given routing provider, telling saved searches about new rides through alerter
+// and charging for cancellations by the given policy
+func NewRideController(rides repository.RideRepository, users repository.UserRepository, bookings repository.BookingRepository, waitlist repository.WaitlistRepository, vehicles repository.VehicleRepository, drivers repository.DriverVerificationRepository, blocks repository.BlockRepository, search config.SearchConfig, bookingConfig config.BookingConfig, router routing.Provider, alerter *RideAlerter, policy cancellation.Policy) *RideController {
+	return &RideController{
+		rides:         rides,
+		users:         users,
+		bookings:      bookings,
+		waitlist:      waitlist,
+		vehicles:      vehicles,
+		drivers:       drivers,
+		blocks:        blocks,
+		promoter:      utils.NewWaitlistPromoter(rides, bookings, waitlist, users, time.Duration(bookingConfig.RequestTimeout)),
+		router:        router,
+		alerter:       alerter,
+		cancellation:  policy,
+		search:        search,
+		bookingConfig: bookingConfig,
+	}
+}
+
+// ridePath returns the path a ride through the given stops follows: the one the driver drew,
+// or else one planned by the routing provider. A drawn path must pass within the search
+// corridor of every stop; otherwise the error response is written and ok is false.
+func (rc *RideController) ridePath(c *gin.Context, stops []models.Location, drawn []models.Point) (*models.RoutePath, bool) {
+	if drawn == nil {
+		return planPath(c.Request.Context(), rc.router, stops), true
+	}
+
+	if len(drawn) < 2 || len(drawn) > maxPathPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A path needs between 2 and %d points", maxPathPoints)})
+		return nil, false
+	}
+	for _, point := range drawn {
+		if point.Latitude == 0 || point.Longitude == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Every point of the path needs a location"})
+			return nil, false
+		}
+	}
+	for _, stop := range stops {
+		if models.ProjectOntoPath(stop, drawn).Distance > rc.search.CorridorMeters {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The path must pass by every stop of the ride"})
+			return nil, false
+		}
+	}
+	return rc.router.Measure(drawn), true
+}
+
+// planPath asks the routing provider for a path through the stops. A failed plan is only
+// logged and gives nil, since rides are still matched at their stops.
+func planPath(ctx context.Context, router routing.Provider, stops []models.Location) *models.RoutePath {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	path, err := router.Route(ctx, stops)
+	if err != nil {
+		log.Printf("⚠️ Could not plan a path through %d stops: %v\n", len(stops), err)
+		return nil
+	}
+	return path
+}
+
 // UpdateUserLocation updates the last known location of a user
-func UpdateUserLocation(c *gin.Context) {
+func (uc *UserController) UpdateUserLocation(c *gin.Context) {
 	userIDStr, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -33,26 +149,20 @@ func UpdateUserLocation(c *gin.Context) {
 		return
 	}
 
-	collection := config.GetCollection("users")
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"location": location}}
-
-	result, err := collection.UpdateOne(context.TODO(), filter, update)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update location"})
+	_, err = uc.users.Update(context.TODO(), userID, repository.UserUpdate{Location: &location})
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-
-	// Debugging: Check if the update was acknowledged
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update location"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Location updated successfully"})
 }
 
-func ProvideRide(c *gin.Context) {
+func (rc *RideController) ProvideRide(c *gin.Context) {
 	// Extract userID from context
 	userIDStr, exists := c.Get("userID")
 	if !exists {
@@ -67,13 +177,27 @@ func ProvideRide(c *gin.Context) {
 		return
 	}
 
+	// Users who cancelled late too often may not offer rides for a while
+	if restricted(c, rc.users, userID) {
+		return
+	}
+
 	// Define a struct to decode only the fields we expect
 	type RideRequest struct {
-		Pickup  models.Location `json:"pickup"`
-		Dropoff models.Location `json:"dropoff"`
-		Price   float64         `json:"price"`
-		Seats   int             `json:"seats"`
-		Date    time.Time       `json:"date"`
+		Pickup      models.Location   `json:"pickup"`
+		Dropoff     models.Location   `json:"dropoff"`
+		Waypoints   []models.Location `json:"waypoints"` // intermediate stops, in driving order
+		Price       float64           `json:"price"`
+		Seats       int               `json:"seats"`
+		Date        time.Time         `json:"date"`
+		InstantBook *bool             `json:"instant_book"` // defaults to true
+		Notes       string            `json:"notes"`
+		// Path is the road route the driver will take; when left out one is planned for them
+		Path []models.Point `json:"path"`
+		// Attributes say how much luggage fits and what is allowed on board
+		Attributes models.RideAttributes `json:"attributes"`
+		// VehicleID is one of the driver's vehicles, which must take the seats offered
+		VehicleID string `json:"vehicle_id"`
 	}
 
 	var rideReq RideRequest
@@ -87,6 +211,31 @@ func ProvideRide(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pickup/dropoff location, price, seats, or date"})
 		return
 	}
+	if !validWaypoints(rideReq.Waypoints) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Every waypoint needs a location, and a ride can have at most %d", maxWaypoints)})
+		return
+	}
+	if len(rideReq.Notes) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Notes can be at most %d characters", maxNotesLength)})
+		return
+	}
+	if !validAttributes(rideReq.Attributes) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Luggage space can be at most %d bags, and music one of %v", maxLuggageBags, models.MusicPreferences),
+		})
+		return
+	}
+	// Only drivers an admin verified, with documents valid for the ride, may offer it
+	if !verifiedDriver(c, rc.drivers, userID, rideReq.Date) {
+		return
+	}
+	vehicle, ok := driverVehicle(c, rc.vehicles, userID, rideReq.VehicleID, rideReq.Seats)
+	if !ok {
+		return
+	}
+
+	// Bookings are confirmed instantly unless the driver asks to approve passengers
+	instantBook := rideReq.InstantBook == nil || *rideReq.InstantBook
 
 	// Create ride object
 	ride := models.Ride{
@@ -94,45 +243,48 @@ func ProvideRide(c *gin.Context) {
 		DriverID:     userID,
 		Pickup:       rideReq.Pickup,
 		Dropoff:      rideReq.Dropoff,
+		Waypoints:    rideReq.Waypoints,
 		Status:       models.StatusOpen,
 		Price:        rideReq.Price,
 		Seats:        rideReq.Seats,
 		Date:         rideReq.Date,
 		CreatedAt:    time.Now(),
 		PassengerIDs: []primitive.ObjectID{},
+		InstantBook:  &instantBook,
+		Notes:        rideReq.Notes,
+		Attributes:   rideReq.Attributes,
+		FreeLuggage:  rideReq.Attributes.LuggageBags,
+		VehicleID:    &vehicle.ID,
+		Vehicle:      &vehicle.VehicleDetails,
+	}
+	ride.SegmentSeats = ride.FreeSeatsBySegment()
+
+	path, ok := rc.ridePath(c, ride.Stops(), rideReq.Path)
+	if !ok {
+		return
 	}
+	ride.Path = path
 
-	// Get MongoDB collection
-	collection := config.GetCollection("rides")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// **🔹 Check for existing ride**
-	filter := bson.M{
-		"driver_id":         userID,
-		"pickup.latitude":   ride.Pickup.Latitude,
-		"pickup.longitude":  ride.Pickup.Longitude,
-		"dropoff.latitude":  ride.Dropoff.Latitude,
-		"dropoff.longitude": ride.Dropoff.Longitude,
-		"status":            "open", // Only prevent duplicate if ride is still open
-		"date": bson.M{
-			"$eq": ride.Date,
-		},
-	}
-	existingRide := models.Ride{}
-	err = collection.FindOne(ctx, filter).Decode(&existingRide)
+	// **🔹 Check for existing ride** (only an open ride counts as a duplicate)
+	_, err = rc.rides.FindOpenDuplicate(ctx, &ride)
 	if err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "A similar ride already exists"})
 		return
 	}
 
 	// Insert ride into database
-	_, err = collection.InsertOne(ctx, ride)
+	err = rc.rides.Create(ctx, &ride)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to provide ride", "details": err.Error()})
 		return
 	}
 
+	// Let passengers who saved a matching search know
+	rc.alerter.Alert(context.TODO(), &ride)
+
 	// Return success response
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Ride provided successfully",
@@ -0,0 +1,115 @@
+// ride_lifecycle_controller.go
+
+package controllers
+
+import (
+	"backend/lifecycle"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StartRide - Lets the driver mark a ride as under way
+func (rc *RideController) StartRide(c *gin.Context) {
+	ride, ok := rc.changeRideStatus(c, c.Param("id"), models.StatusOngoing)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ride started", "ride_id": ride.ID.Hex(), "status": ride.Status})
+}
+
+// CompleteRide - Lets the driver mark an ongoing ride as finished
+func (rc *RideController) CompleteRide(c *gin.Context) {
+	ride, ok := rc.changeRideStatus(c, c.Param("id"), models.StatusCompleted)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ride completed", "ride_id": ride.ID.Hex(), "status": ride.Status})
+}
+
+// changeRideStatus moves the caller's ride to the given status on the driver's behalf.
+// It writes the error response itself and reports false when the change did not happen.
+func (rc *RideController) changeRideStatus(c *gin.Context, rideID string, to models.RideStatus) (*models.Ride, bool) {
+	ride, ok := rc.driverRide(c, rideID)
+	if !ok || !allowTransition(c, ride, to) {
+		return nil, false
+	}
+
+	// The update only applies if nobody changed the status since we read it. Completion also
+	// records when, since the review window runs from then.
+	now := time.Now()
+	var err error
+	if to == models.StatusCompleted {
+		err = rc.rides.Complete(context.TODO(), ride.ID, ride.Status, now)
+	} else {
+		err = rc.rides.UpdateStatus(context.TODO(), ride.ID, ride.Status, to)
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The ride was updated by someone else. Please try again."})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride status"})
+		return nil, false
+	}
+
+	ride.Status = to
+	if to == models.StatusCompleted {
+		ride.CompletedAt = &now
+	}
+	return ride, true
+}
+
+// driverRide loads the ride with the given ID and checks the caller drives it.
+// It writes the error response itself and reports false otherwise.
+func (rc *RideController) driverRide(c *gin.Context, rideID string) (*models.Ride, bool) {
+	// Extract userID from context (set by auth middleware)
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	// Convert string IDs to ObjectIDs
+	rideObjectID, err := primitive.ObjectIDFromHex(rideID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
+		return nil, false
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return nil, false
+	}
+
+	// Find the ride first to verify it exists and the user is the driver
+	ride, err := rc.rides.FindByID(context.TODO(), rideObjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return nil, false
+	}
+
+	if ride.DriverID != userObjectID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the driver of this ride"})
+		return nil, false
+	}
+	return ride, true
+}
+
+// allowTransition checks the driver may move the ride to the given status, writing the error
+// response itself when they may not
+func allowTransition(c *gin.Context, ride *models.Ride, to models.RideStatus) bool {
+	// ✅ Only transitions allowed by the ride lifecycle go through
+	if err := lifecycle.Check(ride.Status, to, lifecycle.Driver); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
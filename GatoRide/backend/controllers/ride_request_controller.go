@@ -0,0 +1,639 @@
+// ride_request_controller.go
+
+package controllers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repository"
+	"backend/routing"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RideRequestController handles the demand side: passengers ask for rides nobody offers yet,
+// drivers answer with offers, and accepting an offer creates the ride with the passenger booked
+type RideRequestController struct {
+	requests repository.RideRequestRepository
+	offers   repository.RideOfferRepository
+	rides    repository.RideRepository
+	bookings repository.BookingRepository
+	users    repository.UserRepository
+	vehicles repository.VehicleRepository
+	drivers  repository.DriverVerificationRepository
+	router   routing.Provider
+	search   config.SearchConfig
+}
+
+// NewRideRequestController creates a RideRequestController backed by the given repositories,
+// planning the paths of rides created from offers with the given routing provider
+func NewRideRequestController(requests repository.RideRequestRepository, offers repository.RideOfferRepository, rides repository.RideRepository, bookings repository.BookingRepository, users repository.UserRepository, vehicles repository.VehicleRepository, drivers repository.DriverVerificationRepository, search config.SearchConfig, router routing.Provider) *RideRequestController {
+	return &RideRequestController{
+		requests: requests,
+		offers:   offers,
+		rides:    rides,
+		bookings: bookings,
+		users:    users,
+		vehicles: vehicles,
+		drivers:  drivers,
+		router:   router,
+		search:   search,
+	}
+}
+
+// requestSortFields are the orders nearby ride requests can be listed in, the default first
+var requestSortFields = []repository.SortField{repository.SortDistance, repository.SortDeparture}
+
+// RideRequestDetails is a ride request with the offers the caller may see: all of them for the
+// passenger who posted it, and their own for a driver
+type RideRequestDetails struct {
+	models.RideRequest
+	Offers []models.RideOffer `json:"offers"`
+}
+
+// NearbyRideRequest is a ride request listed for drivers, with how far its pickup is from them
+type NearbyRideRequest struct {
+	models.RideRequest
+	DistanceMeters float64 `json:"distance_m"`
+}
+
+// CreateRideRequest - Posts a request for a ride between two places, leaving any time in a
+// window, for drivers to make offers on
+func (rq *RideRequestController) CreateRideRequest(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+	if restricted(c, rq.users, userID) {
+		return
+	}
+
+	var req struct {
+		Pickup        models.Location `json:"pickup"`
+		Dropoff       models.Location `json:"dropoff"`
+		DepartureFrom time.Time       `json:"departure_from"`
+		DepartureTo   time.Time       `json:"departure_to"`
+		Seats         int             `json:"seats"` // defaults to 1
+		MaxPrice      float64         `json:"max_price"`
+		Notes         string          `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride request data", "details": err.Error()})
+		return
+	}
+	if req.Seats == 0 {
+		req.Seats = 1
+	}
+
+	if req.Pickup.Latitude == 0 || req.Pickup.Longitude == 0 || req.Dropoff.Latitude == 0 || req.Dropoff.Longitude == 0 || req.MaxPrice <= 0 || req.Seats < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pickup/dropoff location, max price, or seats"})
+		return
+	}
+	if req.DepartureFrom.IsZero() || req.DepartureTo.Before(req.DepartureFrom) || !req.DepartureTo.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "departure_from and departure_to must form a window that ends in the future"})
+		return
+	}
+	if req.DepartureTo.Sub(req.DepartureFrom) > maxSearchWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The departure window can be at most %d days", int(maxSearchWindow.Hours()/24))})
+		return
+	}
+	if len(req.Notes) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Notes can be at most %d characters", maxNotesLength)})
+		return
+	}
+
+	request := models.RideRequest{
+		ID:            primitive.NewObjectID(),
+		PassengerID:   userID,
+		Pickup:        req.Pickup,
+		Dropoff:       req.Dropoff,
+		DepartureFrom: req.DepartureFrom,
+		DepartureTo:   req.DepartureTo,
+		Seats:         req.Seats,
+		MaxPrice:      req.MaxPrice,
+		Notes:         req.Notes,
+		Status:        models.RequestOpen,
+		CreatedAt:     time.Now(),
+	}
+	if err := rq.requests.Create(context.TODO(), &request); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post ride request"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Ride request posted. Drivers can now make offers", "request": request})
+}
+
+// ListRideRequests - Lists the caller's own ride requests, newest first. An optional "status"
+// query parameter keeps only requests with that status.
+func (rq *RideRequestController) ListRideRequests(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.RideRequestFilter{PassengerID: userID}
+	if status := c.Query("status"); status != "" {
+		filter.Statuses = []models.RideRequestStatus{models.RideRequestStatus(status)}
+	}
+	requests, err := rq.requests.Find(context.TODO(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ride requests"})
+		return
+	}
+
+	// ✅ Always an array instead of `null`
+	newestFirst := make([]models.RideRequest, 0, len(requests))
+	for i := len(requests) - 1; i >= 0; i-- {
+		newestFirst = append(newestFirst, requests[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": newestFirst})
+}
+
+// NearbyRideRequests - Lists open ride requests by other passengers whose pickup lies near the
+// "latitude" and "longitude" query parameters, for drivers looking for passengers. Takes
+// "radius_m" and the usual "sort" (distance or departure), "order", "limit" and "cursor".
+func (rq *RideRequestController) NearbyRideRequests(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	var query struct {
+		Latitude     float64 `form:"latitude" binding:"required"`
+		Longitude    float64 `form:"longitude" binding:"required"`
+		RadiusMeters float64 `form:"radius_m"`
+		PageRequest
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	radius, err := searchRadius("radius_m", query.RadiusMeters, rq.search.HomeRadiusMeters, rq.search.MaxRadiusMeters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := query.page(rq.search, requestSortFields, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	found, err := rq.requests.FindNear(context.TODO(), models.Location{Latitude: query.Latitude, Longitude: query.Longitude}, radius, repository.RideRequestFilter{
+		Statuses:        []models.RideRequestStatus{models.RequestOpen},
+		WindowEndsAfter: time.Now(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ride requests"})
+		return
+	}
+
+	requests := make([]NearbyRideRequest, 0, len(found))
+	for _, result := range found {
+		if result.Request.PassengerID != userID {
+			requests = append(requests, NearbyRideRequest{RideRequest: result.Request, DistanceMeters: result.DistanceMeters})
+		}
+	}
+	key := func(request NearbyRideRequest) (float64, primitive.ObjectID) {
+		if page.Sort == repository.SortDistance {
+			return request.DistanceMeters, request.ID
+		}
+		return float64(request.DepartureFrom.UnixMilli()), request.ID
+	}
+	total := len(requests)
+	requests, more := repository.Paginate(requests, page, key)
+
+	response := gin.H{"requests": requests, "total": total, "has_more": more}
+	if more {
+		response["next_cursor"] = page.CursorAt(key(requests[len(requests)-1])).Encode()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRideRequest - Shows a ride request with its offers: every offer to the passenger who
+// posted it, and only their own to a driver
+func (rq *RideRequestController) GetRideRequest(c *gin.Context) {
+	userID, request, ok := rq.pathRequest(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.RideOfferFilter{RequestID: request.ID}
+	if request.PassengerID != userID {
+		filter.DriverID = userID
+	}
+	offers, err := rq.offers.Find(context.TODO(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offers"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if offers == nil {
+		offers = []models.RideOffer{}
+	}
+	c.JSON(http.StatusOK, RideRequestDetails{RideRequest: *request, Offers: offers})
+}
+
+// CancelRideRequest - Withdraws the caller's open ride request; offers still waiting on it expire
+func (rq *RideRequestController) CancelRideRequest(c *gin.Context) {
+	userID, request, ok := rq.pathRequest(c)
+	if !ok {
+		return
+	}
+	if request.PassengerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only cancel your own ride requests"})
+		return
+	}
+
+	cancelled, err := rq.requests.UpdateStatus(context.TODO(), request.ID, models.RequestOpen, models.RequestCancelled, time.Now())
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Cannot cancel a ride request with status '%s'", request.Status)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel ride request"})
+		return
+	}
+
+	utils.CloseOffers(context.TODO(), rq.offers, request.ID, models.OfferExpired, primitive.NilObjectID)
+	c.JSON(http.StatusOK, gin.H{"message": "Ride request cancelled", "request": cancelled})
+}
+
+// MakeOffer - Offers the passenger of an open ride request a ride: a price per seat no higher
+// than they asked, a departure inside their window and, optionally, more seats than they need,
+// which stay open for others once the passenger accepts
+func (rq *RideRequestController) MakeOffer(c *gin.Context) {
+	userID, request, ok := rq.pathRequest(c)
+	if !ok {
+		return
+	}
+	if restricted(c, rq.users, userID) {
+		return
+	}
+
+	var req struct {
+		Price float64   `json:"price"`
+		Date  time.Time `json:"date"`
+		Seats int       `json:"seats"` // defaults to the seats the passenger asked for
+		Notes string    `json:"notes"`
+		// Attributes say how much luggage fits and what is allowed on board
+		Attributes models.RideAttributes `json:"attributes"`
+		// VehicleID is one of the driver's vehicles, which must take the seats offered
+		VehicleID string `json:"vehicle_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer data", "details": err.Error()})
+		return
+	}
+	if req.Seats == 0 {
+		req.Seats = request.Seats
+	}
+
+	if request.PassengerID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot make an offer on your own ride request"})
+		return
+	}
+	if request.Status != models.RequestOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot make an offer on a ride request with status '%s'", request.Status)})
+		return
+	}
+	if req.Price <= 0 || req.Price > request.MaxPrice {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The price must be positive and at most the passenger's maximum of %.2f", request.MaxPrice)})
+		return
+	}
+	if req.Date.Before(request.DepartureFrom) || req.Date.After(request.DepartureTo) || !req.Date.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The departure must be in the future and inside the passenger's window"})
+		return
+	}
+	if req.Seats < request.Seats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The ride needs at least the %d seats the passenger asked for", request.Seats)})
+		return
+	}
+	if len(req.Notes) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Notes can be at most %d characters", maxNotesLength)})
+		return
+	}
+	if !validAttributes(req.Attributes) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Luggage space can be at most %d bags, and music one of %v", maxLuggageBags, models.MusicPreferences),
+		})
+		return
+	}
+	// Only drivers an admin verified, with documents valid for the ride, may offer it
+	if !verifiedDriver(c, rq.drivers, userID, req.Date) {
+		return
+	}
+	vehicle, ok := driverVehicle(c, rq.vehicles, userID, req.VehicleID, req.Seats)
+	if !ok {
+		return
+	}
+
+	pending, err := rq.offers.Find(context.TODO(), repository.RideOfferFilter{
+		RequestID: request.ID,
+		DriverID:  userID,
+		Statuses:  []models.OfferStatus{models.OfferPending},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offers"})
+		return
+	}
+	if len(pending) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have an offer waiting on this request. Withdraw it to make a new one"})
+		return
+	}
+
+	offer := models.RideOffer{
+		ID:         primitive.NewObjectID(),
+		RequestID:  request.ID,
+		DriverID:   userID,
+		Price:      req.Price,
+		Date:       req.Date,
+		Seats:      req.Seats,
+		Notes:      req.Notes,
+		Attributes: req.Attributes,
+		VehicleID:  &vehicle.ID,
+		Vehicle:    &vehicle.VehicleDetails,
+		Status:     models.OfferPending,
+		CreatedAt:  time.Now(),
+	}
+	if err := rq.offers.Create(context.TODO(), &offer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to make offer"})
+		return
+	}
+
+	rq.notify(context.TODO(), request.PassengerID, func(email string) error {
+		return utils.SendRideOfferFunc(email, request, &offer)
+	})
+	c.JSON(http.StatusCreated, gin.H{"message": "Offer sent to the passenger", "offer": offer})
+}
+
+// AcceptOffer - The passenger takes a driver's offer. The ride is created from the request and
+// the offer, with the passenger's seats booked and confirmed; every other offer is declined.
+func (rq *RideRequestController) AcceptOffer(c *gin.Context) {
+	userID, offer, request, ok := rq.pathOffer(c)
+	if !ok {
+		return
+	}
+	if request.PassengerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the passenger who posted the request can accept offers"})
+		return
+	}
+	if request.Status != models.RequestOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot accept offers on a ride request with status '%s'", request.Status)})
+		return
+	}
+	if restricted(c, rq.users, userID) {
+		return
+	}
+	if offer.Status != models.OfferPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot accept an offer with status '%s'", offer.Status)})
+		return
+	}
+	if !offer.Date.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This offer's departure has already passed"})
+		return
+	}
+	if !rq.driverStillVerified(c, offer) {
+		return
+	}
+
+	now := time.Now()
+	ride := models.Ride{
+		ID:           primitive.NewObjectID(),
+		DriverID:     offer.DriverID,
+		Pickup:       request.Pickup,
+		Dropoff:      request.Dropoff,
+		Status:       models.StatusOpen,
+		Price:        offer.Price,
+		Seats:        offer.Seats,
+		Date:         offer.Date,
+		CreatedAt:    now,
+		PassengerIDs: []primitive.ObjectID{},
+		Notes:        offer.Notes,
+		Attributes:   offer.Attributes,
+		FreeLuggage:  offer.Attributes.LuggageBags,
+		VehicleID:    offer.VehicleID,
+		Vehicle:      offer.Vehicle,
+	}
+	ride.SegmentSeats = ride.FreeSeatsBySegment()
+	ride.Path = planPath(c.Request.Context(), rq.router, ride.Stops())
+
+	if err := rq.rides.Create(context.TODO(), &ride); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the ride"})
+		return
+	}
+	booked, err := rq.rides.ReserveSeats(context.TODO(), ride.ID, userID, request.Seats, 0, ride.FullRoute())
+	if err != nil {
+		rq.discard(&ride, nil)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book the ride"})
+		return
+	}
+
+	// Accepting the offer is the passenger's approval, so the booking is confirmed straight away
+	booking := models.Booking{
+		ID:           primitive.NewObjectID(),
+		RideID:       ride.ID,
+		PassengerID:  userID,
+		Seats:        request.Seats,
+		FromStop:     ride.FullRoute().From,
+		ToStop:       ride.FullRoute().To,
+		Status:       models.BookingConfirmed,
+		PricePerSeat: ride.Price,
+		TotalPrice:   ride.Price * float64(request.Seats),
+		CreatedAt:    now,
+	}
+	if err := rq.bookings.Create(context.TODO(), &booking); err != nil {
+		rq.discard(&ride, nil)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book the ride"})
+		return
+	}
+
+	// The offer and then the request are claimed last, so a driver withdrawing or the passenger
+	// accepting another offer at the same time leaves exactly one ride behind
+	accepted, err := rq.offers.UpdateStatus(context.TODO(), offer.ID, models.OfferPending, models.OfferAccepted, now)
+	if err != nil {
+		rq.discard(&ride, &booking)
+		if errors.Is(err, repository.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The driver changed this offer in the meantime. Please check your offers."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept offer"})
+		return
+	}
+	fulfilled, err := rq.requests.Fulfil(context.TODO(), request.ID, ride.ID, booking.ID, now)
+	if err != nil {
+		rq.discard(&ride, &booking)
+		if _, expireErr := rq.offers.UpdateStatus(context.TODO(), offer.ID, models.OfferAccepted, models.OfferExpired, now); expireErr != nil {
+			log.Printf("❌ Offer %s stays accepted without a ride: %v\n", offer.ID.Hex(), expireErr)
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This ride request is no longer open. Please check your requests."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept offer"})
+		return
+	}
+
+	utils.CloseOffers(context.TODO(), rq.offers, request.ID, models.OfferDeclined, offer.ID)
+	rq.notify(context.TODO(), offer.DriverID, func(email string) error {
+		return utils.SendOfferAcceptedFunc(email, booked, &booking)
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Offer accepted. You are booked on the ride",
+		"request": fulfilled,
+		"offer":   accepted,
+		"ride":    booked,
+		"booking": booking,
+	})
+}
+
+// DeclineOffer - The passenger turns down a driver's offer on their request
+func (rq *RideRequestController) DeclineOffer(c *gin.Context) {
+	userID, offer, request, ok := rq.pathOffer(c)
+	if !ok {
+		return
+	}
+	if request.PassengerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the passenger who posted the request can decline offers"})
+		return
+	}
+	rq.closeOffer(c, offer, models.OfferDeclined, "Offer declined")
+}
+
+// WithdrawOffer - The driver takes back an offer the passenger has not answered yet
+func (rq *RideRequestController) WithdrawOffer(c *gin.Context) {
+	userID, offer, _, ok := rq.pathOffer(c)
+	if !ok {
+		return
+	}
+	if offer.DriverID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only withdraw your own offers"})
+		return
+	}
+	rq.closeOffer(c, offer, models.OfferWithdrawn, "Offer withdrawn")
+}
+
+// closeOffer moves a pending offer to the given status and writes the response
+func (rq *RideRequestController) closeOffer(c *gin.Context, offer *models.RideOffer, to models.OfferStatus, message string) {
+	updated, err := rq.offers.UpdateStatus(context.TODO(), offer.ID, models.OfferPending, to, time.Now())
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Only pending offers can be %s; this one is %s", to, offer.Status)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update offer"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "offer": updated})
+}
+
+// driverStillVerified checks that the driver who made the offer may still offer the ride, since
+// their documents can lapse or be rejected while the offer waits. It writes the error response
+// itself and reports false otherwise.
+func (rq *RideRequestController) driverStillVerified(c *gin.Context, offer *models.RideOffer) bool {
+	verification, err := rq.drivers.FindByUser(context.TODO(), offer.DriverID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the driver's verification"})
+		return false
+	}
+	if err != nil || verification.Status != models.DriverApproved || verification.Expired(time.Now()) || verification.Expired(offer.Date) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The driver can no longer offer this ride. Please pick another offer."})
+		return false
+	}
+	return true
+}
+
+// discard removes a ride created for an offer that could not be accepted after all, along
+// with the passenger's booking if it was made; failures are only logged
+func (rq *RideRequestController) discard(ride *models.Ride, booking *models.Booking) {
+	if booking != nil {
+		if err := rq.bookings.Delete(context.TODO(), booking.ID); err != nil {
+			log.Printf("❌ Failed to remove booking %s of a discarded ride: %v\n", booking.ID.Hex(), err)
+		}
+	}
+	if err := rq.rides.Delete(context.TODO(), ride.ID); err != nil {
+		log.Printf("❌ Failed to remove ride %s after a failed offer: %v\n", ride.ID.Hex(), err)
+	}
+}
+
+// notify emails a user through send; failures are only logged
+func (rq *RideRequestController) notify(ctx context.Context, userID primitive.ObjectID, send func(email string) error) {
+	user, err := rq.users.FindByID(ctx, userID)
+	if err != nil {
+		log.Printf("❌ Could not load user %s to notify: %v\n", userID.Hex(), err)
+		return
+	}
+	if err := send(user.Email); err != nil {
+		log.Printf("❌ Failed to notify %s: %v\n", user.Email, err)
+	}
+}
+
+// pathRequest loads the ride request named in the path along with the caller's ID.
+// It writes the error response itself and reports false when the request cannot go ahead.
+func (rq *RideRequestController) pathRequest(c *gin.Context) (primitive.ObjectID, *models.RideRequest, bool) {
+	userID, ok := callerID(c)
+	if !ok {
+		return primitive.NilObjectID, nil, false
+	}
+
+	requestID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride request ID format"})
+		return primitive.NilObjectID, nil, false
+	}
+	request, err := rq.requests.FindByID(context.TODO(), requestID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride request not found"})
+		return primitive.NilObjectID, nil, false
+	}
+	return userID, request, true
+}
+
+// pathOffer loads the offer named in the path and the request it answers, along with the
+// caller's ID. It writes the error response itself and reports false when the request cannot go ahead.
+func (rq *RideRequestController) pathOffer(c *gin.Context) (primitive.ObjectID, *models.RideOffer, *models.RideRequest, bool) {
+	userID, ok := callerID(c)
+	if !ok {
+		return primitive.NilObjectID, nil, nil, false
+	}
+
+	offerID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer ID format"})
+		return primitive.NilObjectID, nil, nil, false
+	}
+	offer, err := rq.offers.FindByID(context.TODO(), offerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Offer not found"})
+		return primitive.NilObjectID, nil, nil, false
+	}
+	request, err := rq.requests.FindByID(context.TODO(), offer.RequestID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride request not found"})
+		return primitive.NilObjectID, nil, nil, false
+	}
+	return userID, offer, request, true
+}
+
+// callerID returns the authenticated user's ID. It writes the error response itself and
+// reports false when there is none or it is malformed.
+func callerID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return primitive.NilObjectID, false
+	}
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
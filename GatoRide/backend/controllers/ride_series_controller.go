@@ -0,0 +1,561 @@
+// ride_series_controller.go
+
+package controllers
+
+import (
+	"backend/config"
+	"backend/lifecycle"
+	"backend/models"
+	"backend/recurrence"
+	"backend/repository"
+	"backend/routing"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RideSeriesController handles recurring ride offers. A single occurrence is an ordinary
+// ride, so it is cancelled through CancelRide like any other ride.
+type RideSeriesController struct {
+	series         repository.RideSeriesRepository
+	rides          repository.RideRepository
+	users          repository.UserRepository
+	vehicles       repository.VehicleRepository
+	drivers        repository.DriverVerificationRepository
+	notifications  repository.NotificationRepository
+	rideController *RideController
+	router         routing.Provider
+	scheduler      config.SchedulerConfig
+}
+
+// NewRideSeriesController creates a RideSeriesController backed by the given repositories,
+// cancelling the rides of a series through rideController the way CancelRide does and planning
+// the path its rides follow with the given routing provider
+func NewRideSeriesController(series repository.RideSeriesRepository, rides repository.RideRepository, users repository.UserRepository, vehicles repository.VehicleRepository, drivers repository.DriverVerificationRepository, notifications repository.NotificationRepository, rideController *RideController, scheduler config.SchedulerConfig, router routing.Provider) *RideSeriesController {
+	return &RideSeriesController{
+		series:         series,
+		rides:          rides,
+		users:          users,
+		vehicles:       vehicles,
+		drivers:        drivers,
+		notifications:  notifications,
+		rideController: rideController,
+		router:         router,
+		scheduler:      scheduler,
+	}
+}
+
+// rideSeriesRequest is the body of CreateSeries and UpdateSeries
+type rideSeriesRequest struct {
+	Pickup      models.Location   `json:"pickup"`
+	Dropoff     models.Location   `json:"dropoff"`
+	Waypoints   []models.Location `json:"waypoints"`
+	Price       float64           `json:"price"`
+	Seats       int               `json:"seats"`
+	InstantBook *bool             `json:"instant_book"` // defaults to true
+	Recurrence  models.Recurrence `json:"recurrence"`
+	// Attributes say how much luggage fits and what is allowed on board
+	Attributes models.RideAttributes `json:"attributes"`
+	// VehicleID is one of the driver's vehicles, which must take the seats offered
+	VehicleID string `json:"vehicle_id"`
+}
+
+// bind reads and validates the request body, writing the error response itself
+func (req *rideSeriesRequest) bind(c *gin.Context) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride series data", "details": err.Error()})
+		return false
+	}
+	if req.Pickup.Latitude == 0 || req.Pickup.Longitude == 0 || req.Dropoff.Latitude == 0 || req.Dropoff.Longitude == 0 || req.Price <= 0 || req.Seats <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pickup/dropoff location, price, or seats"})
+		return false
+	}
+	if !validWaypoints(req.Waypoints) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Every waypoint needs a location, and a ride can have at most %d", maxWaypoints)})
+		return false
+	}
+	if !validAttributes(req.Attributes) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Luggage space can be at most %d bags, and music one of %v", maxLuggageBags, models.MusicPreferences),
+		})
+		return false
+	}
+	if err := recurrence.Normalize(&req.Recurrence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurrence", "details": err.Error()})
+		return false
+	}
+	return true
+}
+
+// apply copies the request and the vehicle it names onto a series
+func (req *rideSeriesRequest) apply(series *models.RideSeries, vehicle *models.Vehicle) {
+	series.Pickup = req.Pickup
+	series.Dropoff = req.Dropoff
+	series.Waypoints = req.Waypoints
+	series.Price = req.Price
+	series.Seats = req.Seats
+	series.InstantBook = req.InstantBook == nil || *req.InstantBook
+	series.Recurrence = req.Recurrence
+	series.Attributes = req.Attributes
+	series.VehicleID = &vehicle.ID
+	series.Vehicle = &vehicle.VehicleDetails
+}
+
+// CreateSeries - Offers the same ride on a weekly pattern; rides for the coming window are created right away
+func (sc *RideSeriesController) CreateSeries(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Users who cancelled late too often may not offer rides for a while
+	if restricted(c, sc.users, userID) {
+		return
+	}
+
+	var req rideSeriesRequest
+	if !req.bind(c) {
+		return
+	}
+	if !sc.verifiedDriver(c, userID, req.Recurrence) {
+		return
+	}
+	vehicle, ok := driverVehicle(c, sc.vehicles, userID, req.VehicleID, req.Seats)
+	if !ok {
+		return
+	}
+
+	series := models.RideSeries{
+		ID:        primitive.NewObjectID(),
+		DriverID:  userID,
+		Status:    models.SeriesActive,
+		CreatedAt: time.Now(),
+	}
+	req.apply(&series, vehicle)
+	series.Path = planPath(c.Request.Context(), sc.router, series.Stops())
+
+	if err := sc.series.Create(context.TODO(), &series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ride series"})
+		return
+	}
+
+	created, err := sc.generate(&series)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ride series saved but rides could not be generated", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Ride series created successfully",
+		"series":        series,
+		"rides_created": created,
+	})
+}
+
+// ListSeries - Returns the caller's ride series, including cancelled ones
+func (sc *RideSeriesController) ListSeries(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	series, err := sc.series.Find(context.TODO(), repository.SeriesFilter{DriverID: userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ride series"})
+		return
+	}
+	if series == nil {
+		series = []models.RideSeries{}
+	}
+	c.JSON(http.StatusOK, gin.H{"series": series})
+}
+
+// UpdateSeries - Replaces the whole series. Upcoming rides nobody has booked and that were not
+// edited on their own are recreated from the new pattern. Booked ones are kept and take the new
+// terms, and their passengers are told as UpdateRide tells them.
+func (sc *RideSeriesController) UpdateSeries(c *gin.Context) {
+	series, ok := sc.driverSeries(c)
+	if !ok {
+		return
+	}
+
+	var req rideSeriesRequest
+	if !req.bind(c) {
+		return
+	}
+	if !sc.verifiedDriver(c, series.DriverID, req.Recurrence) {
+		return
+	}
+	vehicle, ok := driverVehicle(c, sc.vehicles, series.DriverID, req.VehicleID, req.Seats)
+	if !ok {
+		return
+	}
+	req.apply(series, vehicle)
+	series.Path = planPath(c.Request.Context(), sc.router, series.Stops())
+	series.GeneratedUntil = time.Time{}
+
+	if err := sc.series.Replace(context.TODO(), series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride series"})
+		return
+	}
+
+	upcoming, err := sc.upcomingRides(series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rides of the series"})
+		return
+	}
+
+	now := time.Now()
+	removed, kept, notified := 0, 0, 0
+	for i := range upcoming {
+		ride := &upcoming[i]
+		if ride.Detached || (ride.Status != models.StatusOpen && ride.Status != models.StatusBooked) {
+			continue
+		}
+		if ride.Status == models.StatusOpen {
+			// Only deletes if nobody has booked in the meantime
+			err := sc.rides.DeleteUnbooked(context.TODO(), ride.ID)
+			if err == nil {
+				removed++
+				continue
+			}
+			if !errors.Is(err, repository.ErrConflict) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rides of the series"})
+				return
+			}
+		}
+
+		// Booked rides are kept and take the series' new terms
+		told, err := sc.updateBookedRide(context.TODO(), series, ride, now)
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rides of the series"})
+			return
+		}
+		kept++
+		notified += told
+	}
+
+	created, err := sc.generate(series)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ride series saved but rides could not be generated", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Ride series updated successfully",
+		"series":              series,
+		"rides_removed":       removed,
+		"rides_kept":          kept,
+		"rides_created":       created,
+		"notified_passengers": notified,
+	})
+}
+
+// CancelSeries - Stops the series and cancels every upcoming ride generated from it. The body
+// gives the reason like CancelRide's, and each ride is cancelled as CancelRide would: passengers
+// are let go and told, and the cancellation policy applies to every ride. The response lists what
+// the cancellations of booked rides cost.
+func (sc *RideSeriesController) CancelSeries(c *gin.Context) {
+	series, ok := sc.driverSeries(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	cancellation, ok := bindCancellation(c, now)
+	if !ok {
+		return
+	}
+
+	series.Status = models.SeriesCancelled
+	if err := sc.series.Replace(context.TODO(), series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel ride series"})
+		return
+	}
+
+	upcoming, err := sc.upcomingRides(series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rides of the series"})
+		return
+	}
+
+	cancelled, notified := 0, 0
+	outcomes := []CancellationOutcome{}
+	for i := range upcoming {
+		ride := &upcoming[i]
+		if lifecycle.Check(ride.Status, models.StatusCancelled, lifecycle.Driver) != nil {
+			continue
+		}
+		booked := len(ride.PassengerIDs) > 0
+		outcome, told, err := sc.rideController.cancelRide(context.TODO(), ride, cancellation)
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel rides of the series"})
+			return
+		}
+		cancelled++
+		notified += told
+		if booked {
+			outcomes = append(outcomes, outcome)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Ride series canceled successfully",
+		"rides_cancelled":     cancelled,
+		"passengers_notified": notified,
+		"cancellations":       outcomes,
+	})
+}
+
+// UpdateOccurrence - Edits one ride of the series without touching the others. Its passengers
+// are told about the change as UpdateRide tells them.
+func (sc *RideSeriesController) UpdateOccurrence(c *gin.Context) {
+	series, ok := sc.driverSeries(c)
+	if !ok {
+		return
+	}
+
+	rideID, err := primitive.ObjectIDFromHex(c.Param("ride_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
+		return
+	}
+
+	ride, err := sc.rides.FindByID(context.TODO(), rideID)
+	if err != nil || ride.SeriesID == nil || *ride.SeriesID != series.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found in this series"})
+		return
+	}
+
+	var req struct {
+		Pickup        *models.Location `json:"pickup"`
+		Dropoff       *models.Location `json:"dropoff"`
+		Price         *float64         `json:"price"`
+		Seats         *int             `json:"seats"`
+		DepartureTime *string          `json:"departure_time"` // "08:45" on the occurrence's day
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride data", "details": err.Error()})
+		return
+	}
+	if (req.Price != nil && *req.Price <= 0) || (req.Seats != nil && *req.Seats <= 0) ||
+		(req.Pickup != nil && (req.Pickup.Latitude == 0 || req.Pickup.Longitude == 0)) ||
+		(req.Dropoff != nil && (req.Dropoff.Latitude == 0 || req.Dropoff.Longitude == 0)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pickup/dropoff location, price, or seats"})
+		return
+	}
+	if req.Seats != nil && ride.Vehicle != nil && *req.Seats > ride.Vehicle.Seats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Your %s %s takes at most %d passengers", ride.Vehicle.Make, ride.Vehicle.Model, ride.Vehicle.Seats)})
+		return
+	}
+
+	// An occurrence edited on its own is left alone by later series edits
+	detached := true
+	update := repository.RideUpdate{
+		Pickup:   req.Pickup,
+		Dropoff:  req.Dropoff,
+		Price:    req.Price,
+		Seats:    req.Seats,
+		Detached: &detached,
+	}
+	if req.DepartureTime != nil {
+		pattern := series.Recurrence
+		pattern.DepartureTime = *req.DepartureTime
+		departure, err := recurrence.DepartureOn(pattern, ride.OccurrenceDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if departure.Before(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The new departure time has already passed"})
+			return
+		}
+		update.Date = &departure
+	}
+
+	updated, changes, notified, err := sc.editRide(context.TODO(), ride, update, time.Now())
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only open or booked rides can be edited, and seats only while nobody has booked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Ride updated successfully",
+		"ride":                updated,
+		"changes":             changes,
+		"notified_passengers": notified,
+	})
+}
+
+// updateBookedRide carries the series' new terms onto one of its rides that passengers have
+// booked, and returns how many of them were told. The seats they hold stay as they are, and so
+// do the stops when the series' waypoints changed, since bookings are made between stops.
+func (sc *RideSeriesController) updateBookedRide(ctx context.Context, series *models.RideSeries, ride *models.Ride, now time.Time) (int, error) {
+	update := repository.RideUpdate{Price: &series.Price}
+	departure, err := recurrence.DepartureOn(series.Recurrence, ride.OccurrenceDate)
+	if err != nil {
+		return 0, err
+	}
+	if departure.After(now) {
+		update.Date = &departure
+	}
+	if slices.Equal(ride.Waypoints, series.Waypoints) {
+		update.Pickup, update.Dropoff, update.Path = &series.Pickup, &series.Dropoff, series.Path
+	}
+	_, _, notified, err := sc.editRide(ctx, ride, update, now)
+	return notified, err
+}
+
+// editRide makes an edit to one of the series' rides the way UpdateRide does: fields that keep
+// their value are dropped, moved stops get a newly planned path unless the update brings one,
+// and passengers are told about material changes so they can cancel without penalty. It
+// returns the updated ride, the changes passengers were told about and how many were told.
+func (sc *RideSeriesController) editRide(ctx context.Context, ride *models.Ride, update repository.RideUpdate, now time.Time) (*models.Ride, []string, int, error) {
+	var changes []string
+	if update.Pickup != nil && *update.Pickup == ride.Pickup {
+		update.Pickup = nil
+	} else if update.Pickup != nil {
+		changes = append(changes, "pickup")
+	}
+	if update.Dropoff != nil && *update.Dropoff == ride.Dropoff {
+		update.Dropoff = nil
+	} else if update.Dropoff != nil {
+		changes = append(changes, "dropoff")
+	}
+	if update.Date != nil && update.Date.Equal(ride.Date) {
+		update.Date = nil
+	} else if update.Date != nil {
+		changes = append(changes, "departure time")
+	}
+	if update.Price != nil && *update.Price == ride.Price {
+		update.Price = nil
+	} else if update.Price != nil {
+		changes = append(changes, "price")
+	}
+
+	// A new route follows moved stops; the old path no longer passes them
+	if update.Pickup == nil && update.Dropoff == nil {
+		update.Path = nil
+	} else if update.Path == nil {
+		moved := *ride
+		if update.Pickup != nil {
+			moved.Pickup = *update.Pickup
+		}
+		if update.Dropoff != nil {
+			moved.Dropoff = *update.Dropoff
+		}
+		update.Path = planPath(ctx, sc.router, moved.Stops())
+		if update.Path == nil {
+			update.Path = &models.RoutePath{}
+		}
+	}
+	update.UpdatedAt = &now
+
+	updated, err := sc.rides.Update(ctx, ride.ID, update)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	notified := 0
+	if len(changes) > 0 {
+		notified = sc.rideController.notifyRideChanged(ctx, updated, changes, now)
+	}
+	return updated, changes, notified, nil
+}
+
+// driverSeries loads the active series named in the path and checks that the caller owns it.
+// It writes the error response itself and reports false when the request cannot go ahead.
+func (sc *RideSeriesController) driverSeries(c *gin.Context) (*models.RideSeries, bool) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	seriesID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID format"})
+		return nil, false
+	}
+
+	series, err := sc.series.FindByID(context.TODO(), seriesID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride series not found"})
+		return nil, false
+	}
+
+	if series.DriverID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the driver of this ride series"})
+		return nil, false
+	}
+
+	if series.Status != models.SeriesActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This ride series has been canceled"})
+		return nil, false
+	}
+
+	return series, true
+}
+
+// verifiedDriver checks that the driver may offer every ride the pattern generates for the
+// coming window, which holds when their documents are still valid on the last one. It writes
+// the error response itself and reports false otherwise.
+func (sc *RideSeriesController) verifiedDriver(c *gin.Context, driverID primitive.ObjectID, pattern models.Recurrence) bool {
+	now := time.Now()
+	occurrences, err := recurrence.Occurrences(pattern, now, now.Add(time.Duration(sc.scheduler.RecurringWindow)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurrence", "details": err.Error()})
+		return false
+	}
+	last := now
+	if len(occurrences) > 0 {
+		last = occurrences[len(occurrences)-1].Departure
+	}
+	return verifiedDriver(c, sc.drivers, driverID, last)
+}
+
+// upcomingRides returns the series' rides that have not departed yet
+func (sc *RideSeriesController) upcomingRides(seriesID primitive.ObjectID) ([]models.Ride, error) {
+	return sc.rides.Find(context.TODO(), repository.RideFilter{SeriesID: seriesID, DateFrom: time.Now()})
+}
+
+// generate creates the series' rides for the configured window
+func (sc *RideSeriesController) generate(series *models.RideSeries) (int, error) {
+	until := time.Now().Add(time.Duration(sc.scheduler.RecurringWindow))
+	return utils.GenerateSeriesRides(context.TODO(), sc.series, sc.rides, sc.drivers, sc.notifications, series, until)
+}
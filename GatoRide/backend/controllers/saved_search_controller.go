@@ -0,0 +1,170 @@
+// saved_search_controller.go
+
+package controllers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxSavedSearchWindow limits the departure window of a saved search, which may look further
+// ahead than a single search
+const maxSavedSearchWindow = 31 * 24 * time.Hour
+
+// AlertController handles saved searches and the in-app inbox their alerts go to. The alerts
+// themselves are sent by RideAlerter when rides are offered.
+type AlertController struct {
+	searches      repository.SavedSearchRepository
+	notifications repository.NotificationRepository
+	search        config.SearchConfig
+	alerts        config.AlertConfig
+}
+
+// NewAlertController creates an AlertController backed by the given repositories
+func NewAlertController(searches repository.SavedSearchRepository, notifications repository.NotificationRepository, search config.SearchConfig, alerts config.AlertConfig) *AlertController {
+	return &AlertController{searches: searches, notifications: notifications, search: search, alerts: alerts}
+}
+
+// SaveSearch - Saves a ride search so the caller is alerted whenever a matching ride is offered.
+// Takes "from", "to", "departure_from", "departure_to", "seats" (default 1), "radius_m" and
+// "channels" ("email" and/or "inbox", default both).
+func (ac *AlertController) SaveSearch(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		From          models.Location `json:"from"`
+		To            models.Location `json:"to"`
+		DepartureFrom time.Time       `json:"departure_from"`
+		DepartureTo   time.Time       `json:"departure_to"`
+		Seats         int             `json:"seats"`
+		RadiusMeters  float64         `json:"radius_m"`
+		Channels      []string        `json:"channels"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search data", "details": err.Error()})
+		return
+	}
+	if req.Seats == 0 {
+		req.Seats = 1
+	}
+	if req.Channels == nil {
+		req.Channels = []string{models.AlertEmail, models.AlertInbox}
+	}
+
+	if req.From.Latitude == 0 || req.From.Longitude == 0 || req.To.Latitude == 0 || req.To.Longitude == 0 || req.Seats < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from/to location or seats"})
+		return
+	}
+	if req.DepartureFrom.IsZero() || req.DepartureTo.Before(req.DepartureFrom) || !req.DepartureTo.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "departure_from and departure_to must form a window that ends in the future"})
+		return
+	}
+	if req.DepartureTo.Sub(req.DepartureFrom) > maxSavedSearchWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The departure window can be at most %d days", int(maxSavedSearchWindow.Hours()/24))})
+		return
+	}
+	radius, err := searchRadius("radius_m", req.RadiusMeters, ac.search.MatchRadiusMeters, ac.search.MaxRadiusMeters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var channels []string
+	for _, channel := range req.Channels {
+		if channel != models.AlertEmail && channel != models.AlertInbox {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("channels can only hold %q and %q", models.AlertEmail, models.AlertInbox)})
+			return
+		}
+		if !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A saved search needs at least one channel to alert through"})
+		return
+	}
+
+	saved, err := ac.searches.FindByUser(context.TODO(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved searches"})
+		return
+	}
+	if len(saved) >= ac.alerts.MaxSavedSearches {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("You can save at most %d searches. Delete one first", ac.alerts.MaxSavedSearches)})
+		return
+	}
+
+	search := models.SavedSearch{
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		From:          req.From,
+		To:            req.To,
+		DepartureFrom: req.DepartureFrom,
+		DepartureTo:   req.DepartureTo,
+		Seats:         req.Seats,
+		RadiusMeters:  radius,
+		Channels:      channels,
+		CreatedAt:     time.Now(),
+	}
+	if err := ac.searches.Create(context.TODO(), &search); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Search saved. You will be alerted about matching rides", "search": search})
+}
+
+// ListSavedSearches - Lists the caller's saved searches, oldest first
+func (ac *AlertController) ListSavedSearches(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	searches, err := ac.searches.FindByUser(context.TODO(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved searches"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if searches == nil {
+		searches = []models.SavedSearch{}
+	}
+	c.JSON(http.StatusOK, gin.H{"searches": searches})
+}
+
+// DeleteSavedSearch - Deletes one of the caller's saved searches, which stops its alerts
+func (ac *AlertController) DeleteSavedSearch(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	searchID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID format"})
+		return
+	}
+	search, err := ac.searches.FindByID(context.TODO(), searchID)
+	if err != nil || search.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	}
+
+	if err := ac.searches.Delete(context.TODO(), searchID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted"})
+}
@@ -1,75 +1,471 @@
 package controllers
 
 import (
-	"backend/config"
 	"backend/models"
+	"backend/repository"
 	"context"
+	"errors"
+	"fmt"
 	"math"
 	"net/http"
+	"slices"
 	"time"
+	_ "time/tzdata" // time zones must resolve even on hosts without a zoneinfo database
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-const maxDistance = 0.05 // roughly ~5km range for latitude/longitude
-
+// SearchRideRequest describes the trip a passenger is looking for. The departure window is
+// either a day (Date, in TimeZone), optionally around a preferred Time with FlexHours either
+// side, or an explicit DepartureAfter/DepartureBefore range of up to a week. Given together,
+// the range narrows the day.
 type SearchRideRequest struct {
 	From  models.Location `json:"from" binding:"required"`
 	To    models.Location `json:"to" binding:"required"`
-	Date  string          `json:"date" binding:"required"`  // "YYYY-MM-DD"
+	Date  string          `json:"date"`                     // "YYYY-MM-DD"
 	Seats int             `json:"seats" binding:"required"` // required number of seats
+	// Time is the preferred departure on Date, as "15:04"; results closest to it rank first
+	Time string `json:"time"`
+	// FlexHours widens the search to this many hours either side of Time, or of the whole Date
+	FlexHours float64 `json:"flex_hours"`
+	// TimeZone is the IANA zone Date and Time are in, e.g. "America/New_York"; defaults to UTC
+	TimeZone string `json:"time_zone"`
+	// RadiusMeters is how far from From and To a stop may be; 0 uses the configured default
+	RadiusMeters float64 `json:"radius_m"`
+	// CorridorMeters is how far from a ride's path From and To may be for the driver to detour
+	// and pick the passenger up on the way; 0 uses the configured default
+	CorridorMeters float64 `json:"corridor_m"`
+	// Optional filters: the price per seat, and the earliest and latest departure
+	MinPrice        *float64   `json:"min_price"`
+	MaxPrice        *float64   `json:"max_price"`
+	DepartureAfter  *time.Time `json:"departure_after"`
+	DepartureBefore *time.Time `json:"departure_before"`
+	// Optional attribute filters: room for this many bags, pets, smoking and air conditioning
+	// allowed or not, and the driver's music preference
+	LuggageBags     int                    `json:"luggage_bags"`
+	PetsAllowed     *bool                  `json:"pets_allowed"`
+	SmokingAllowed  *bool                  `json:"smoking_allowed"`
+	AirConditioning *bool                  `json:"air_conditioning"`
+	Music           models.MusicPreference `json:"music"`
+	// Sorts by "time" (the default with a preferred Time, closest first), "distance" (the
+	// default otherwise, closest overall detour first), "departure", "price" or "rating" (the
+	// driver's, best first unless "order" is "asc")
+	PageRequest
+}
+
+// maxSearchWindow limits how many days of departures one search covers
+const maxSearchWindow = 7 * 24 * time.Hour
+
+// maxFlexHours limits how far either side of the preferred time a search may reach
+const maxFlexHours = 72
+
+// searchSortFields are the orders SearchRides offers, the default first
+var searchSortFields = []repository.SortField{repository.SortDistance, repository.SortDeparture, repository.SortPrice, repository.SortTime, repository.SortRating}
+
+// timedSearchSortFields are the same orders with closeness to the preferred time as the default
+var timedSearchSortFields = []repository.SortField{repository.SortTime, repository.SortDistance, repository.SortDeparture, repository.SortPrice, repository.SortRating}
+
+// DepartureWindow is the range of departures a search covered, and the preferred time if any
+type DepartureWindow struct {
+	From      time.Time  `json:"from"`
+	To        time.Time  `json:"to"`
+	Preferred *time.Time `json:"preferred,omitempty"`
+}
+
+// departureWindow works out which departures a search request covers. The error is meant for the caller.
+func departureWindow(req *SearchRideRequest) (DepartureWindow, error) {
+	var window DepartureWindow
+
+	loc := time.UTC
+	if req.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(req.TimeZone); err != nil {
+			return window, fmt.Errorf("unknown time zone %q", req.TimeZone)
+		}
+	}
+	if req.FlexHours < 0 || req.FlexHours > maxFlexHours {
+		return window, fmt.Errorf("flex_hours must be between 0 and %d", maxFlexHours)
+	}
+	flex := time.Duration(req.FlexHours * float64(time.Hour))
+
+	switch {
+	case req.Date != "":
+		date, err := time.ParseInLocation("2006-01-02", req.Date, loc)
+		if err != nil {
+			return window, errors.New("Invalid date format. Use YYYY-MM-DD")
+		}
+		window.From = date
+		window.To = date.AddDate(0, 0, 1)
+
+		if req.Time != "" {
+			clock, err := time.Parse("15:04", req.Time)
+			if err != nil {
+				return window, errors.New("Invalid time format. Use HH:MM")
+			}
+			preferred := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+			window.Preferred = &preferred
+			if flex > 0 {
+				window.From, window.To = preferred.Add(-flex), preferred.Add(flex)
+			}
+		} else if flex > 0 {
+			window.From, window.To = window.From.Add(-flex), window.To.Add(flex)
+		}
+
+		// An explicit range narrows the day
+		if req.DepartureAfter != nil && req.DepartureAfter.After(window.From) {
+			window.From = *req.DepartureAfter
+		}
+		if req.DepartureBefore != nil && req.DepartureBefore.Before(window.To) {
+			window.To = *req.DepartureBefore
+		}
+		if !window.From.Before(window.To) {
+			return window, errors.New("The departure window does not overlap the search date")
+		}
+
+	case req.DepartureAfter != nil && req.DepartureBefore != nil:
+		if req.Time != "" || req.FlexHours != 0 {
+			return window, errors.New("time and flex_hours need a date")
+		}
+		window.From, window.To = *req.DepartureAfter, *req.DepartureBefore
+		if !window.From.Before(window.To) {
+			return window, errors.New("departure_after must be before departure_before")
+		}
+		if window.To.Sub(window.From) > maxSearchWindow {
+			return window, fmt.Errorf("The departure window can be at most %d days", int(maxSearchWindow.Hours()/24))
+		}
+
+	default:
+		return window, errors.New("Give a date, or both departure_after and departure_before")
+	}
+	return window, nil
+}
+
+// nearbySortFields are the orders the home page and feed offer, the default first
+var nearbySortFields = []repository.SortField{repository.SortDistance, repository.SortDeparture, repository.SortPrice}
+
+// RideMatch is a ride found by SearchRides, with the stretch of it that suits the passenger
+type RideMatch struct {
+	models.Ride
+	FromStop       int `json:"from_stop"`
+	ToStop         int `json:"to_stop"`
+	AvailableSeats int `json:"available_seats"`
+	// MatchedBy is MatchedAtStops or MatchedOnCorridor
+	MatchedBy string `json:"matched_by"`
+	// Distances in metres from the passenger's start to where they are picked up and from where
+	// they are dropped off to their destination
+	PickupDistanceMeters  float64 `json:"pickup_distance_m"`
+	DropoffDistanceMeters float64 `json:"dropoff_distance_m"`
+	// PickupPoint and DropoffPoint are where the driver leaves their path for a corridor match
+	PickupPoint  *models.Point `json:"pickup_point,omitempty"`
+	DropoffPoint *models.Point `json:"dropoff_point,omitempty"`
+	// The driver's estimated extra driving for a corridor match: off the path to the passenger
+	// and back, at both ends
+	DetourMeters  float64 `json:"detour_m"`
+	DetourMinutes float64 `json:"detour_min"`
+	// MinutesFromPreferred is how much later (or, when negative, earlier) than the passenger's
+	// preferred time the ride departs. Only set when the search named a preferred time.
+	MinutesFromPreferred *float64 `json:"minutes_from_preferred,omitempty"`
+	// DriverRating is what passengers made of the driver so far
+	DriverRating models.RatingSummary `json:"driver_rating"`
+}
+
+// How a ride was matched to a passenger's trip
+const (
+	MatchedAtStops    = "stops"    // the passenger boards and leaves at stops of the ride
+	MatchedOnCorridor = "corridor" // the driver detours from their path to pick up and drop off
+)
+
+// NearbyRide is a ride listed on the home page or feed, with how far its pickup is from the user
+type NearbyRide struct {
+	models.Ride
+	DistanceMeters float64 `json:"distance_m"`
+}
+
+// NearbyPage is one page of rides for the home page or feed
+type NearbyPage struct {
+	Rides      []NearbyRide `json:"rides"`
+	Total      int          `json:"total"`
+	HasMore    bool         `json:"has_more"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// nearbyPage sorts proximity search results, cuts out the requested page and turns it into its
+// response form, with the rides always as a non-nil slice
+func nearbyPage(found []repository.RideDistance, page repository.Page) NearbyPage {
+	key := func(ride NearbyRide) (float64, primitive.ObjectID) {
+		if page.Sort == repository.SortDistance {
+			return ride.DistanceMeters, ride.ID
+		}
+		return repository.RideSortValue(&ride.Ride, page.Sort), ride.ID
+	}
+
+	rides := make([]NearbyRide, 0, len(found))
+	for _, ride := range found {
+		rides = append(rides, NearbyRide{Ride: ride.Ride, DistanceMeters: ride.DistanceMeters})
+	}
+	total := len(rides)
+	rides, more := repository.Paginate(rides, page, key)
+
+	result := NearbyPage{Rides: rides, Total: total, HasMore: more}
+	if more {
+		result.NextCursor = page.CursorAt(key(rides[len(rides)-1])).Encode()
+	}
+	return result
+}
+
+// searchRadius returns the radius a caller asked for in the named field, or the default when
+// they gave none. It fails when the radius is negative or larger than the configured maximum.
+func searchRadius(field string, requested, fallback, maxRadius float64) (float64, error) {
+	if requested == 0 {
+		return fallback, nil
+	}
+	if requested < 0 || requested > maxRadius {
+		return 0, fmt.Errorf("%s must be between 0 and %.0f metres", field, maxRadius)
+	}
+	return requested, nil
+}
+
+// matchRide matches a ride to the passenger's trip, preferring its stops, which cost the driver
+// no detour, over a pickup and dropoff along its path
+func matchRide(ride models.Ride, from, to models.Location, seats int, radius, corridor float64) (RideMatch, bool) {
+	if segment, fromDistance, toDistance, ok := matchSegment(ride, from, to, seats, radius); ok {
+		return RideMatch{
+			Ride:                  ride,
+			FromStop:              segment.From,
+			ToStop:                segment.To,
+			AvailableSeats:        ride.FreeSeats(segment),
+			MatchedBy:             MatchedAtStops,
+			PickupDistanceMeters:  fromDistance,
+			DropoffDistanceMeters: toDistance,
+		}, true
+	}
+	return matchCorridor(ride, from, to, seats, corridor)
+}
+
+// matchCorridor matches a passenger whose start and end both lie within the corridor around the
+// ride's path, start first. They hold seats from the last stop before the pickup to the first
+// stop after the dropoff.
+func matchCorridor(ride models.Ride, from, to models.Location, seats int, corridor float64) (RideMatch, bool) {
+	if !ride.Path.Usable() {
+		return RideMatch{}, false
+	}
+	pickup := models.ProjectOntoPath(from, ride.Path.Points)
+	dropoff := models.ProjectOntoPath(to, ride.Path.Points)
+	if pickup.Distance > corridor || dropoff.Distance > corridor || pickup.Along >= dropoff.Along {
+		return RideMatch{}, false
+	}
+
+	stops := ride.Stops()
+	along := make([]float64, len(stops))
+	for i, stop := range stops {
+		along[i] = models.ProjectOntoPath(stop, ride.Path.Points).Along
+	}
+	segment := models.Segment{From: 0, To: len(stops) - 1}
+	for i := 1; i < len(stops)-1; i++ {
+		if along[i] <= pickup.Along {
+			segment.From = i
+		}
+	}
+	for i := len(stops) - 2; i > segment.From; i-- {
+		if along[i] >= dropoff.Along {
+			segment.To = i
+		}
+	}
+	if ride.FreeSeats(segment) < seats {
+		return RideMatch{}, false
+	}
+
+	match := RideMatch{
+		Ride:                  ride,
+		FromStop:              segment.From,
+		ToStop:                segment.To,
+		AvailableSeats:        ride.FreeSeats(segment),
+		MatchedBy:             MatchedOnCorridor,
+		PickupDistanceMeters:  pickup.Distance,
+		DropoffDistanceMeters: dropoff.Distance,
+		PickupPoint:           &pickup.Point,
+		DropoffPoint:          &dropoff.Point,
+		DetourMeters:          2 * (pickup.Distance + dropoff.Distance),
+	}
+	if speed := ride.Path.SpeedMetersPerSecond(); speed > 0 {
+		match.DetourMinutes = match.DetourMeters / speed / 60
+	}
+	return match, true
+}
+
+// matchSegment picks the pair of stops, in driving order, closest to the passenger's start and
+// end that still has enough free seats between them, along with both distances
+func matchSegment(ride models.Ride, from, to models.Location, seats int, radius float64) (models.Segment, float64, float64, bool) {
+	stops := ride.Stops()
+
+	best, found := models.Segment{}, false
+	bestFrom, bestTo := 0.0, 0.0
+	for i := 0; i < len(stops)-1; i++ {
+		fromDistance := models.DistanceMeters(from, stops[i])
+		if fromDistance > radius {
+			continue
+		}
+		for j := i + 1; j < len(stops); j++ {
+			segment := models.Segment{From: i, To: j}
+			toDistance := models.DistanceMeters(to, stops[j])
+			if toDistance > radius || ride.FreeSeats(segment) < seats {
+				continue
+			}
+			if !found || fromDistance+toDistance < bestFrom+bestTo {
+				best, found = segment, true
+				bestFrom, bestTo = fromDistance, toDistance
+			}
+		}
+	}
+	return best, bestFrom, bestTo, found
 }
 
-func isNearby(loc1, loc2 models.Location) bool {
-	return math.Abs(loc1.Latitude-loc2.Latitude) <= maxDistance &&
-		math.Abs(loc1.Longitude-loc2.Longitude) <= maxDistance
+// findMatches finds the rides matching the filter that suit a passenger's trip, the search
+// behind SearchRides. It always returns a non-nil slice when there is no error.
+func findMatches(ctx context.Context, rides repository.RideRepository, from, to models.Location, seats int, radius, corridor float64, filter repository.RideFilter) ([]RideMatch, error) {
+	// Rides stopping or passing anywhere near the start; seats are checked per segment below
+	found, err := rides.FindNear(ctx, repository.NearQuery{
+		Field:             repository.NearRoute,
+		Point:             from,
+		MaxDistanceMeters: max(radius, corridor),
+	}, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Any stop can serve as the passenger's start, and any later stop as their end; failing
+	// that, the driver may pick them up along the way
+	matches := []RideMatch{}
+	for _, ride := range found {
+		if match, ok := matchRide(ride.Ride, from, to, seats, radius, corridor); ok {
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
 }
 
-func SearchRides(c *gin.Context) {
+func (rc *RideController) SearchRides(c *gin.Context) {
 	var req SearchRideRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	// Parse date and get start and end of that day
-	date, err := time.Parse("2006-01-02", req.Date)
+	window, err := departureWindow(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := repository.RideFilter{
+		Statuses: []models.RideStatus{models.StatusOpen},
+		DateFrom: window.From,
+		DateTo:   window.To,
+	}
+	if req.MinPrice != nil {
+		filter.MinPrice = *req.MinPrice
+	}
+	if req.MaxPrice != nil {
+		filter.MaxPrice = *req.MaxPrice
+	}
+	// Rides always cost something, so a maximum price must be positive
+	if filter.MinPrice < 0 || (req.MaxPrice != nil && (filter.MaxPrice <= 0 || filter.MaxPrice < filter.MinPrice)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price range"})
+		return
+	}
+	if req.LuggageBags < 0 || (req.Music != "" && !slices.Contains(models.MusicPreferences, req.Music)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid luggage or music filter"})
+		return
+	}
+	filter.MinFreeLuggage = req.LuggageBags
+	filter.PetsAllowed = req.PetsAllowed
+	filter.SmokingAllowed = req.SmokingAllowed
+	filter.AirConditioning = req.AirConditioning
+	filter.Music = req.Music
+
+	radius, err := searchRadius("radius_m", req.RadiusMeters, rc.search.MatchRadiusMeters, rc.search.MaxRadiusMeters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	corridor, err := searchRadius("corridor_m", req.CorridorMeters, rc.search.CorridorMeters, rc.search.MaxRadiusMeters)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	startOfDay := date
-	endOfDay := date.Add(24 * time.Hour)
 
-	collection := config.GetCollection("rides")
+	sortFields := searchSortFields
+	if window.Preferred != nil {
+		sortFields = timedSearchSortFields
+	}
+	// Best rated drivers come first unless the caller asks otherwise
+	page, err := req.page(rc.search, sortFields, req.Sort == string(repository.SortRating))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Fetch all open rides within that day
-	filter := bson.M{
-		"status": "open",
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
-		"seats": bson.M{"$gte": req.Seats}, // Only consider rides with enough available seats
+	// Rides of drivers kept apart from the caller by a block never show up
+	filter.ExcludeDriverIDs, err = hiddenDrivers(context.TODO(), c, rc.blocks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return
 	}
 
-	cursor, err := collection.Find(context.TODO(), filter)
+	matchingRides, err := findMatches(context.TODO(), rc.rides, req.From, req.To, req.Seats, radius, corridor, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
 		return
 	}
-	defer cursor.Close(context.TODO())
+	if window.Preferred != nil {
+		for i := range matchingRides {
+			minutes := matchingRides[i].Date.Sub(*window.Preferred).Minutes()
+			matchingRides[i].MinutesFromPreferred = &minutes
+		}
+	}
 
-	var matchingRides []models.Ride
-	for cursor.Next(context.TODO()) {
-		var ride models.Ride
-		if err := cursor.Decode(&ride); err == nil {
-			if isNearby(ride.Pickup, req.From) && isNearby(ride.Dropoff, req.To) {
-				matchingRides = append(matchingRides, ride)
-			}
+	// Without a preferred time, closeness is measured from the start of the window
+	reference := window.From
+	if window.Preferred != nil {
+		reference = *window.Preferred
+	}
+	key := func(match RideMatch) (float64, primitive.ObjectID) {
+		switch page.Sort {
+		case repository.SortDistance:
+			return match.PickupDistanceMeters + match.DropoffDistanceMeters, match.ID
+		case repository.SortTime:
+			return math.Abs(float64(match.Date.Sub(reference).Milliseconds())), match.ID
+		case repository.SortRating:
+			return match.DriverRating.Average, match.ID
 		}
+		return repository.RideSortValue(&match.Ride, page.Sort), match.ID
+	}
+	// Every match is rated before paging, since the ratings may decide the order
+	rc.rateDrivers(context.TODO(), matchingRides)
+	total := len(matchingRides)
+	matchingRides, more := repository.Paginate(matchingRides, page, key)
+
+	response := gin.H{"rides": matchingRides, "total": total, "has_more": more, "window": window}
+	if more {
+		response["next_cursor"] = page.CursorAt(key(matchingRides[len(matchingRides)-1])).Encode()
 	}
+	c.JSON(http.StatusOK, response)
+}
 
-	c.JSON(http.StatusOK, gin.H{"rides": matchingRides})
+// rateDrivers fills in the rating of each match's driver, looking every driver up once. A driver
+// who cannot be found is shown unrated.
+func (rc *RideController) rateDrivers(ctx context.Context, matches []RideMatch) {
+	ratings := map[primitive.ObjectID]models.RatingSummary{}
+	for i := range matches {
+		driverID := matches[i].DriverID
+		rating, ok := ratings[driverID]
+		if !ok {
+			if driver, err := rc.users.FindByID(ctx, driverID); err == nil {
+				rating = driver.Rating.Summary()
+			}
+			ratings[driverID] = rating
+		}
+		matches[i].DriverRating = rating
+	}
 }
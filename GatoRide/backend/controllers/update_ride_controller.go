@@ -0,0 +1,245 @@
+// update_ride_controller.go
+
+package controllers
+
+import (
+	"backend/lifecycle"
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxNotesLength limits the driver's free-text notes on a ride
+const maxNotesLength = 500
+
+// UpdateRide - Lets the driver change an open or booked ride without losing its bookings.
+// Passengers holding a seat are told about changes to the route, departure or price, and
+// their bookings are marked so they can cancel without penalty.
+func (rc *RideController) UpdateRide(c *gin.Context) {
+	// Extract userID from context (set by auth middleware)
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	rideID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	// Only the fields present in the body change; seats is the total the driver offers
+	var req struct {
+		Pickup  *models.Location `json:"pickup"`
+		Dropoff *models.Location `json:"dropoff"`
+		Price   *float64         `json:"price"`
+		Seats   *int             `json:"seats"`
+		Date    *time.Time       `json:"date"`
+		Notes   *string          `json:"notes"`
+		Path    []models.Point   `json:"path"` // a new drawn path; moving a stop plans a new one otherwise
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride data", "details": err.Error()})
+		return
+	}
+	if req.Pickup == nil && req.Dropoff == nil && req.Price == nil && req.Seats == nil && req.Date == nil && req.Notes == nil && req.Path == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+	if (req.Price != nil && *req.Price <= 0) || (req.Seats != nil && *req.Seats <= 0) ||
+		(req.Pickup != nil && (req.Pickup.Latitude == 0 || req.Pickup.Longitude == 0)) ||
+		(req.Dropoff != nil && (req.Dropoff.Latitude == 0 || req.Dropoff.Longitude == 0)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pickup/dropoff location, price, or seats"})
+		return
+	}
+	if req.Date != nil && !req.Date.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The new departure date must be in the future"})
+		return
+	}
+	if req.Notes != nil && len(*req.Notes) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Notes can be at most %d characters", maxNotesLength)})
+		return
+	}
+
+	ride, err := rc.rides.FindByID(context.TODO(), rideID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return
+	}
+	if ride.DriverID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the driver of this ride"})
+		return
+	}
+	if !lifecycle.AcceptsEdits(ride.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Cannot edit a ride with status '%s'. Only 'open' or 'booked' rides can be changed", ride.Status),
+		})
+		return
+	}
+	if req.Seats != nil && ride.Vehicle != nil && *req.Seats > ride.Vehicle.Seats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Your %s %s takes at most %d passengers", ride.Vehicle.Make, ride.Vehicle.Model, ride.Vehicle.Seats)})
+		return
+	}
+
+	now := time.Now()
+	update := repository.RideUpdate{Notes: req.Notes, UpdatedAt: &now}
+	var changes []string // material changes passengers are told about
+	if req.Pickup != nil && *req.Pickup != ride.Pickup {
+		update.Pickup = req.Pickup
+		changes = append(changes, "pickup")
+	}
+	if req.Dropoff != nil && *req.Dropoff != ride.Dropoff {
+		update.Dropoff = req.Dropoff
+		changes = append(changes, "dropoff")
+	}
+	if req.Date != nil && !req.Date.Equal(ride.Date) {
+		update.Date = req.Date
+		changes = append(changes, "departure time")
+	}
+	if req.Price != nil && *req.Price != ride.Price {
+		update.Price = req.Price
+		changes = append(changes, "price")
+	}
+
+	// A new route follows moved stops; the old path no longer passes them
+	if update.Pickup != nil || update.Dropoff != nil || req.Path != nil {
+		moved := *ride
+		if update.Pickup != nil {
+			moved.Pickup = *update.Pickup
+		}
+		if update.Dropoff != nil {
+			moved.Dropoff = *update.Dropoff
+		}
+		path, ok := rc.ridePath(c, moved.Stops(), req.Path)
+		if !ok {
+			return
+		}
+		if path == nil {
+			path = &models.RoutePath{}
+		}
+		update.Path = path
+		if req.Path != nil {
+			changes = append(changes, "route")
+		}
+	}
+
+	if req.Seats != nil {
+		held, err := rc.seatsHeldBySegment(context.TODO(), ride)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+			return
+		}
+		if booked := slices.Max(held); *req.Seats < booked {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Seats cannot drop below the %d already booked", booked)})
+			return
+		}
+		// Free and held seats add up to the same total on every segment
+		update.AddSeats = *req.Seats - (ride.FreeSeatsBySegment()[0] + held[0])
+	}
+
+	// A driver's own edit takes a generated ride out of later series edits
+	if ride.SeriesID != nil {
+		detached := true
+		update.Detached = &detached
+	}
+
+	updated, err := rc.rides.Update(context.TODO(), rideID, update)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The ride was updated by someone else. Please try again."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride"})
+		return
+	}
+
+	// Added seats go to passengers on the waitlist first
+	if update.AddSeats > 0 {
+		if _, err := rc.promoter.Promote(context.TODO(), rideID); err != nil {
+			log.Printf("❌ Failed to promote the waitlist of ride %s: %v\n", rideID.Hex(), err)
+		}
+	}
+
+	notified := 0
+	if len(changes) > 0 {
+		notified = rc.notifyRideChanged(context.TODO(), updated, changes, now)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Ride updated successfully",
+		"ride":                updated,
+		"changes":             changes,
+		"notified_passengers": notified,
+	})
+}
+
+// seatsHeldBySegment returns how many seats passengers hold between each pair of consecutive stops
+func (rc *RideController) seatsHeldBySegment(ctx context.Context, ride *models.Ride) ([]int, error) {
+	bookings, err := rc.bookings.Find(ctx, repository.BookingFilter{
+		RideID:   ride.ID,
+		Statuses: []models.BookingStatus{models.BookingPending, models.BookingConfirmed},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	held := make([]int, len(ride.Waypoints)+1)
+	hold := func(seats int, segment models.Segment) {
+		for i := segment.From; i < segment.To && i < len(held); i++ {
+			held[i] += seats
+		}
+	}
+	withBooking := map[primitive.ObjectID]bool{}
+	for _, booking := range bookings {
+		hold(booking.Seats, booking.Segment())
+		withBooking[booking.PassengerID] = true
+	}
+	// Passengers who booked before bookings were recorded hold one seat for the whole route
+	for _, passengerID := range ride.PassengerIDs {
+		if !withBooking[passengerID] {
+			hold(1, ride.FullRoute())
+		}
+	}
+	return held, nil
+}
+
+// notifyRideChanged marks the ride's active bookings as changed and emails their passengers.
+// It returns how many passengers were notified; failures are logged, not returned, since the
+// ride itself has already been updated.
+func (rc *RideController) notifyRideChanged(ctx context.Context, ride *models.Ride, changes []string, at time.Time) int {
+	if _, err := rc.bookings.MarkRideChanged(ctx, ride.ID, at); err != nil {
+		log.Printf("❌ Failed to mark bookings on ride %s as changed: %v\n", ride.ID.Hex(), err)
+	}
+
+	notified := 0
+	for _, passengerID := range ride.PassengerIDs {
+		passenger, err := rc.users.FindByID(ctx, passengerID)
+		if err != nil {
+			log.Printf("❌ Could not load passenger %s of ride %s: %v\n", passengerID.Hex(), ride.ID.Hex(), err)
+			continue
+		}
+		if err := utils.SendRideUpdateFunc(passenger.Email, ride, changes); err != nil {
+			log.Printf("❌ Failed to notify %s about ride %s: %v\n", passenger.Email, ride.ID.Hex(), err)
+			continue
+		}
+		notified++
+	}
+	return notified
+}
@@ -0,0 +1,172 @@
+// vehicle_controller.go
+
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxVehicles           = 5  // vehicles one user may register
+	maxVehicleSeats       = 8  // passenger seats, not counting the driver
+	maxVehicleFieldLength = 50 // make, model, colour and plate
+	maxFuelEconomy        = 50 // litres per 100 km
+)
+
+// VehicleController handles the vehicles users register to offer rides in
+type VehicleController struct {
+	vehicles repository.VehicleRepository
+}
+
+// NewVehicleController creates a VehicleController backed by the given repository
+func NewVehicleController(vehicles repository.VehicleRepository) *VehicleController {
+	return &VehicleController{vehicles: vehicles}
+}
+
+// RegisterVehicle - Registers a vehicle the caller can offer rides in. Takes "make", "model",
+// "colour", "plate", "seats" (passengers it takes) and an optional "fuel_economy" in L/100 km.
+func (vc *VehicleController) RegisterVehicle(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	var req models.VehicleDetails
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle data", "details": err.Error()})
+		return
+	}
+	req.Make = strings.TrimSpace(req.Make)
+	req.Model = strings.TrimSpace(req.Model)
+	req.Colour = strings.TrimSpace(req.Colour)
+	req.Plate = normalizePlate(req.Plate)
+
+	for _, field := range []string{req.Make, req.Model, req.Colour, req.Plate} {
+		if field == "" || len(field) > maxVehicleFieldLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Make, model, colour and plate are required and can be at most %d characters", maxVehicleFieldLength),
+			})
+			return
+		}
+	}
+	if req.Seats < 1 || req.Seats > maxVehicleSeats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A vehicle takes between 1 and %d passengers", maxVehicleSeats)})
+		return
+	}
+	if req.FuelEconomy != nil && (*req.FuelEconomy <= 0 || *req.FuelEconomy > maxFuelEconomy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Fuel economy must be between 0 and %d L/100 km", maxFuelEconomy)})
+		return
+	}
+
+	registered, err := vc.vehicles.FindByOwner(context.TODO(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicles"})
+		return
+	}
+	if len(registered) >= maxVehicles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("You can register at most %d vehicles. Remove one first", maxVehicles)})
+		return
+	}
+	for _, vehicle := range registered {
+		if vehicle.Plate == req.Plate {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already registered a vehicle with this plate"})
+			return
+		}
+	}
+
+	vehicle := models.Vehicle{
+		ID:             primitive.NewObjectID(),
+		OwnerID:        userID,
+		VehicleDetails: req,
+		CreatedAt:      time.Now(),
+	}
+	if err := vc.vehicles.Create(context.TODO(), &vehicle); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register vehicle"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Vehicle registered", "vehicle": vehicle})
+}
+
+// ListVehicles - Lists the caller's vehicles, oldest first
+func (vc *VehicleController) ListVehicles(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	vehicles, err := vc.vehicles.FindByOwner(context.TODO(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicles"})
+		return
+	}
+	// ✅ Force JSON to always return an array instead of `null`
+	if vehicles == nil {
+		vehicles = []models.Vehicle{}
+	}
+	c.JSON(http.StatusOK, gin.H{"vehicles": vehicles})
+}
+
+// RemoveVehicle - Removes one of the caller's vehicles. Rides already offered in it keep its
+// details.
+func (vc *VehicleController) RemoveVehicle(c *gin.Context) {
+	userID, ok := callerID(c)
+	if !ok {
+		return
+	}
+
+	vehicleID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID format"})
+		return
+	}
+	vehicle, err := vc.vehicles.FindByID(context.TODO(), vehicleID)
+	if err != nil || vehicle.OwnerID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+		return
+	}
+
+	if err := vc.vehicles.Delete(context.TODO(), vehicleID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vehicle"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Vehicle removed"})
+}
+
+// normalizePlate upper-cases a licence plate and collapses its spacing, so the same plate
+// typed differently compares equal
+func normalizePlate(plate string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(plate), " "))
+}
+
+// driverVehicle loads the vehicle a driver offers a ride in and checks it is theirs and has
+// room for the seats offered. It writes the error response itself and reports false otherwise.
+func driverVehicle(c *gin.Context, vehicles repository.VehicleRepository, driverID primitive.ObjectID, vehicleID string, seats int) (*models.Vehicle, bool) {
+	if vehicleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Choose one of your vehicles for the ride. Register one first if you have none"})
+		return nil, false
+	}
+	id, err := primitive.ObjectIDFromHex(vehicleID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID format"})
+		return nil, false
+	}
+	vehicle, err := vehicles.FindByID(context.TODO(), id)
+	if err != nil || vehicle.OwnerID != driverID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+		return nil, false
+	}
+	if seats > vehicle.Seats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Your %s %s takes at most %d passengers", vehicle.Make, vehicle.Model, vehicle.Seats)})
+		return nil, false
+	}
+	return vehicle, true
+}
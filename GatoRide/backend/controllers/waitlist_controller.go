@@ -0,0 +1,248 @@
+// waitlist_controller.go
+
+package controllers
+
+import (
+	"backend/lifecycle"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JoinWaitlist - Puts the caller in line for seats on a ride that has too few free.
+// Takes the same "seats", "from_stop", "to_stop" and "bags" query parameters as BookRide. When seats
+// free up they are booked for waiting passengers in the order they joined.
+func (rc *RideController) JoinWaitlist(c *gin.Context) {
+	userID, ride, ok := rc.waitlistRide(c)
+	if !ok || blockedFromBooking(c, rc.blocks, userID, ride) {
+		return
+	}
+
+	seats, err := strconv.Atoi(c.DefaultQuery("seats", "1"))
+	if err != nil || seats < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Seats must be a positive number"})
+		return
+	}
+
+	if ride.DriverID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot join the waitlist of your own ride"})
+		return
+	}
+	if restricted(c, rc.users, userID) {
+		return
+	}
+	if !lifecycle.AcceptsWaitlist(ride.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Cannot join the waitlist of a ride with status '%s'", ride.Status),
+		})
+		return
+	}
+
+	segment, ok := requestedSegment(c, ride)
+	if !ok {
+		return
+	}
+	bags, ok := requestedBags(c, ride)
+	if !ok {
+		return
+	}
+	if ride.Status == models.StatusOpen && ride.FreeSeats(segment) >= seats && ride.FreeLuggage >= bags {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This ride still has enough free seats. Book it instead"})
+		return
+	}
+	if slices.Contains(ride.PassengerIDs, userID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already booked this ride"})
+		return
+	}
+
+	existing, err := rc.waitingEntry(context.TODO(), ride.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already on the waitlist for this ride"})
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		ID:          primitive.NewObjectID(),
+		RideID:      ride.ID,
+		PassengerID: userID,
+		Seats:       seats,
+		Bags:        bags,
+		FromStop:    segment.From,
+		ToStop:      segment.To,
+		Status:      models.WaitlistWaiting,
+		CreatedAt:   time.Now(),
+	}
+	if err := rc.waitlist.Create(context.TODO(), &entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		return
+	}
+
+	// Counting the entry on the ride keeps freed seats for the waitlist instead of reopening it
+	if _, err := rc.rides.AdjustWaitlist(context.TODO(), ride.ID, 1); err != nil {
+		if deleteErr := rc.waitlist.Delete(context.TODO(), entry.ID); deleteErr != nil {
+			log.Printf("❌ Failed to remove waitlist entry %s after error: %v\n", entry.ID.Hex(), deleteErr)
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The ride was updated in the meantime. Please try again."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		return
+	}
+
+	position, err := rc.waitlistPosition(context.TODO(), &entry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "You joined the waitlist", "entry": entry, "position": position})
+}
+
+// LeaveWaitlist - Takes the caller off the waitlist of a ride
+func (rc *RideController) LeaveWaitlist(c *gin.Context) {
+	userID, ride, ok := rc.waitlistRide(c)
+	if !ok {
+		return
+	}
+
+	entry, err := rc.waitingEntry(context.TODO(), ride.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not on the waitlist for this ride"})
+		return
+	}
+
+	// Claiming the entry first means it is taken off the ride's count only once
+	entry, err = rc.waitlist.UpdateStatus(context.TODO(), entry.ID, models.WaitlistWaiting, models.WaitlistLeft, time.Now())
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Your waitlist place has changed in the meantime. Please check your bookings."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave waitlist"})
+		return
+	}
+
+	if _, err := rc.rides.AdjustWaitlist(context.TODO(), ride.ID, -1); err != nil {
+		log.Printf("❌ Left waitlist entry %s but ride %s still counts it: %v\n", entry.ID.Hex(), ride.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "You left the waitlist", "entry": entry})
+}
+
+// GetWaitlist - Shows the driver everyone waiting on their ride, and a passenger their own place in line
+func (rc *RideController) GetWaitlist(c *gin.Context) {
+	userID, ride, ok := rc.waitlistRide(c)
+	if !ok {
+		return
+	}
+
+	if ride.DriverID == userID {
+		entries, err := rc.waitlist.Find(context.TODO(), repository.WaitlistFilter{
+			RideID:   ride.ID,
+			Statuses: []models.WaitlistStatus{models.WaitlistWaiting},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+			return
+		}
+		// ✅ Force JSON to always return an array instead of `null`
+		if entries == nil {
+			entries = []models.WaitlistEntry{}
+		}
+		c.JSON(http.StatusOK, gin.H{"waitlist": entries})
+		return
+	}
+
+	entry, err := rc.waitingEntry(context.TODO(), ride.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not on the waitlist for this ride"})
+		return
+	}
+	position, err := rc.waitlistPosition(context.TODO(), entry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entry": entry, "position": position})
+}
+
+// waitlistRide loads the ride named in the path along with the caller's ID.
+// It writes the error response itself and reports false when the request cannot go ahead.
+func (rc *RideController) waitlistRide(c *gin.Context) (primitive.ObjectID, *models.Ride, bool) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return primitive.NilObjectID, nil, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, nil, false
+	}
+
+	rideID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID format"})
+		return primitive.NilObjectID, nil, false
+	}
+
+	ride, err := rc.rides.FindByID(context.TODO(), rideID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return primitive.NilObjectID, nil, false
+	}
+	return userID, ride, true
+}
+
+// waitingEntry returns the passenger's place in line on a ride, or nil if they are not waiting
+func (rc *RideController) waitingEntry(ctx context.Context, rideID, passengerID primitive.ObjectID) (*models.WaitlistEntry, error) {
+	entries, err := rc.waitlist.Find(ctx, repository.WaitlistFilter{
+		RideID:      rideID,
+		PassengerID: passengerID,
+		Statuses:    []models.WaitlistStatus{models.WaitlistWaiting},
+	})
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// waitlistPosition returns the entry's 1-based place among everyone still waiting on the ride
+func (rc *RideController) waitlistPosition(ctx context.Context, entry *models.WaitlistEntry) (int, error) {
+	entries, err := rc.waitlist.Find(ctx, repository.WaitlistFilter{
+		RideID:   entry.RideID,
+		Statuses: []models.WaitlistStatus{models.WaitlistWaiting},
+	})
+	if err != nil {
+		return 0, err
+	}
+	for i, waiting := range entries {
+		if waiting.ID == entry.ID {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,97 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Blob keeps files as block blobs in an Azure Blob Storage container, through its REST API
+// (https://learn.microsoft.com/rest/api/storageservices/blob-service-rest-api). The SAS token
+// must grant read, write and delete on the container.
+type Blob struct {
+	ContainerURL string
+	SASToken     string
+	Client       *http.Client
+}
+
+// url addresses the blob stored under the key, signed with the SAS token
+func (b *Blob) url(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.TrimRight(b.ContainerURL, "/") + "/" + strings.Join(segments, "/") + "?" + strings.TrimPrefix(b.SASToken, "?")
+}
+
+// do sends a request for the blob and fails unless the answer has one of the expected statuses
+func (b *Blob) do(ctx context.Context, method, key string, body io.Reader, header http.Header, expected ...int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.url(key), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("x-ms-version", "2021-08-06")
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		// The error quotes the URL, which carries the SAS token, so only its cause is kept
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("%s blob %s: %w", method, key, err)
+	}
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("%s blob %s: status %d", method, key, resp.StatusCode)
+}
+
+func (b *Blob) Put(ctx context.Context, key, contentType string, content io.Reader) error {
+	// Put Blob needs the length up front, so the content is read whole; uploads are small
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(ctx, http.MethodPut, key, bytes.NewReader(data), http.Header{
+		"X-Ms-Blob-Type": {"BlockBlob"},
+		"Content-Type":   {contentType},
+	}, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (b *Blob) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	resp, err := b.do(ctx, http.MethodGet, key, nil, nil, http.StatusOK)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+func (b *Blob) Delete(ctx context.Context, key string) error {
+	resp, err := b.do(ctx, http.MethodDelete, key, nil, nil, http.StatusAccepted)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,85 @@
+// Package filestore keeps uploaded files, such as drivers' documents, under string keys
+package filestore
+
+import (
+	"backend/config"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when no file is stored under a key
+var ErrNotFound = errors.New("file not found")
+
+// Store saves and reads back files. Keys are slash-separated paths like "drivers/<id>/licence".
+type Store interface {
+	Put(ctx context.Context, key, contentType string, content io.Reader) error
+	// Open returns the file's content and its content type; the caller closes the reader
+	Open(ctx context.Context, key string) (io.ReadCloser, string, error)
+	// Delete removes the file; deleting a missing file is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the store chosen in the configuration
+func New(cfg config.DocumentConfig) Store {
+	switch cfg.Store {
+	case config.DocumentStoreBlob:
+		return &Blob{
+			ContainerURL: cfg.BlobContainerURL,
+			SASToken:     cfg.BlobSASToken,
+			Client:       &http.Client{Timeout: time.Duration(cfg.Timeout)},
+		}
+	case config.DocumentStoreMemory:
+		return NewMemory()
+	}
+	return &Local{Dir: cfg.LocalDir}
+}
+
+// Memory keeps files in process memory. Nothing survives a restart; it is meant for tests and
+// for running the server locally.
+type Memory struct {
+	mu    sync.Mutex
+	files map[string]memoryFile
+}
+
+type memoryFile struct {
+	contentType string
+	content     []byte
+}
+
+// NewMemory returns an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{files: map[string]memoryFile{}}
+}
+
+func (m *Memory) Put(ctx context.Context, key, contentType string, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[key] = memoryFile{contentType: contentType, content: data}
+	return nil
+}
+
+func (m *Memory) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[key]
+	if !ok {
+		return nil, "", ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(file.content)), file.contentType, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, key)
+	return nil
+}
@@ -0,0 +1,71 @@
+package lifecycle
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+)
+
+// bookingTransitions lists, for every booking status, the statuses it may move to and who may
+// move it there. A pending request holds its seats until the driver answers or it expires.
+//
+//	pending ──(driver accepts)──▶ confirmed ──(passenger cancels)──▶ cancelled
+//	pending ──(driver rejects)──▶ rejected
+//	pending ──(nobody answers in time)──▶ expired
+//	pending ──(passenger withdraws)──▶ cancelled
+//	pending / confirmed ──(driver cancels the ride, or it never starts)──▶ cancelled_by_driver
+var bookingTransitions = map[models.BookingStatus]map[models.BookingStatus][]Actor{
+	models.BookingPending: {
+		models.BookingConfirmed:         {Driver},
+		models.BookingRejected:          {Driver},
+		models.BookingExpired:           {System},
+		models.BookingCancelled:         {Passenger},
+		models.BookingCancelledByDriver: {Driver, System},
+	},
+	models.BookingConfirmed: {
+		models.BookingCancelled:         {Passenger},
+		models.BookingCancelledByDriver: {Driver, System},
+	},
+}
+
+// BookingTransitionError explains why a booking status change was rejected
+type BookingTransitionError struct {
+	From  models.BookingStatus
+	To    models.BookingStatus
+	Actor Actor
+	Err   error // ErrInvalidTransition or ErrNotPermitted
+}
+
+func (e *BookingTransitionError) Error() string {
+	if errors.Is(e.Err, ErrNotPermitted) {
+		return fmt.Sprintf("a %s cannot move a booking from '%s' to '%s'", e.Actor, e.From, e.To)
+	}
+	if len(bookingTransitions[e.From]) == 0 {
+		return fmt.Sprintf("booking is already %s and can no longer change", e.From)
+	}
+	return fmt.Sprintf("cannot move a booking from '%s' to '%s'", e.From, e.To)
+}
+
+func (e *BookingTransitionError) Unwrap() error {
+	return e.Err
+}
+
+// CheckBooking reports whether the actor may move a booking from one status to another.
+// It returns a *BookingTransitionError when the change is not allowed.
+func CheckBooking(from, to models.BookingStatus, actor Actor) error {
+	actors, ok := bookingTransitions[from][to]
+	if !ok {
+		return &BookingTransitionError{From: from, To: to, Actor: actor, Err: ErrInvalidTransition}
+	}
+	for _, allowed := range actors {
+		if allowed == actor {
+			return nil
+		}
+	}
+	return &BookingTransitionError{From: from, To: to, Actor: actor, Err: ErrNotPermitted}
+}
+
+// HoldsSeats reports whether a booking in this status keeps its seats taken on the ride
+func HoldsSeats(status models.BookingStatus) bool {
+	return status == models.BookingPending || status == models.BookingConfirmed
+}
@@ -0,0 +1,124 @@
+// Package lifecycle defines how a ride moves between statuses and who may move it.
+//
+//	open ──(last seat taken)──▶ booked ──(seat released, nobody waiting)──▶ open
+//	open / booked ──(driver starts)──▶ ongoing ──(driver completes)──▶ completed
+//	open / booked ──(driver or admin cancels, or departure passes)──▶ cancelled
+//
+// completed and cancelled are final.
+package lifecycle
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+)
+
+// Actor is whoever triggers a status change
+type Actor string
+
+const (
+	Driver    Actor = "driver"    // the driver who offered the ride
+	Passenger Actor = "passenger" // a passenger on the ride
+	System    Actor = "system"    // seat bookkeeping and the cleanup scheduler
+	Admin     Actor = "admin"     // an admin taking a ride down after a report
+)
+
+var (
+	// ErrInvalidTransition is returned when no rule moves a ride between the two statuses
+	ErrInvalidTransition = errors.New("invalid ride status transition")
+	// ErrNotPermitted is returned when the transition exists but the actor may not trigger it
+	ErrNotPermitted = errors.New("ride status transition not permitted")
+)
+
+// transitions lists, for every status, the statuses it may move to and who may move it there.
+// The open ⇄ booked moves happen inside the repository's atomic seat updates.
+var transitions = map[models.RideStatus]map[models.RideStatus][]Actor{
+	models.StatusOpen: {
+		models.StatusBooked:    {System},
+		models.StatusOngoing:   {Driver},
+		models.StatusCancelled: {Driver, System, Admin},
+	},
+	models.StatusBooked: {
+		models.StatusOpen:      {System},
+		models.StatusOngoing:   {Driver},
+		models.StatusCancelled: {Driver, System, Admin},
+	},
+	models.StatusOngoing: {
+		models.StatusCompleted: {Driver},
+	},
+}
+
+// TransitionError explains why a status change was rejected
+type TransitionError struct {
+	From  models.RideStatus
+	To    models.RideStatus
+	Actor Actor
+	Err   error // ErrInvalidTransition or ErrNotPermitted
+}
+
+func (e *TransitionError) Error() string {
+	if errors.Is(e.Err, ErrNotPermitted) {
+		return fmt.Sprintf("a %s cannot move a ride from '%s' to '%s'", e.Actor, e.From, e.To)
+	}
+	if IsFinal(e.From) {
+		return fmt.Sprintf("ride is already %s and can no longer change", e.From)
+	}
+	return fmt.Sprintf("cannot move a ride from '%s' to '%s'", e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
+// Check reports whether the actor may move a ride from one status to another.
+// It returns a *TransitionError when the change is not allowed.
+func Check(from, to models.RideStatus, actor Actor) error {
+	actors, ok := transitions[from][to]
+	if !ok {
+		return &TransitionError{From: from, To: to, Actor: actor, Err: ErrInvalidTransition}
+	}
+	for _, allowed := range actors {
+		if allowed == actor {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to, Actor: actor, Err: ErrNotPermitted}
+}
+
+// IsFinal reports whether a ride in this status can never change again
+func IsFinal(status models.RideStatus) bool {
+	return len(transitions[status]) == 0
+}
+
+// AcceptsBookings reports whether passengers may book seats on a ride in this status
+func AcceptsBookings(status models.RideStatus) bool {
+	return status == models.StatusOpen
+}
+
+// AcceptsBookingCancellations reports whether passengers may give their seat back
+func AcceptsBookingCancellations(status models.RideStatus) bool {
+	return status == models.StatusOpen || status == models.StatusBooked
+}
+
+// AcceptsWaitlist reports whether passengers may wait for a seat on a ride in this status
+func AcceptsWaitlist(status models.RideStatus) bool {
+	return status == models.StatusOpen || status == models.StatusBooked
+}
+
+// AcceptsEdits reports whether the driver may still change the details of a ride in this status
+func AcceptsEdits(status models.RideStatus) bool {
+	return status == models.StatusOpen || status == models.StatusBooked
+}
+
+// AfterSeatChange returns the status a ride should have once its free seat count or waitlist
+// changes. seats is the most free seats on any segment: a ride with no seat left anywhere is
+// booked, and one with a seat again is reopened unless passengers are still waiting for seats.
+func AfterSeatChange(status models.RideStatus, seats, waiting int) models.RideStatus {
+	switch {
+	case status == models.StatusOpen && seats <= 0:
+		return models.StatusBooked
+	case status == models.StatusBooked && seats > 0 && waiting <= 0:
+		return models.StatusOpen
+	}
+	return status
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"backend/config"
+	"backend/repository"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminMiddleware lets through only the users the configuration names as admins. It runs after
+// AuthMiddleware, which identifies the user.
+func AdminMiddleware(users repository.UserRepository, admin config.AdminConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		user, err := users.FindByID(ctx, userID)
+		if err != nil || !user.IsVerified || !admin.IsAdmin(user.Email) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can do this"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
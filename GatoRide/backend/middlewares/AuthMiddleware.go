@@ -2,21 +2,19 @@ package middlewares
 
 import (
 	"backend/auth"
-	"backend/config"
-	"backend/models"
+	"backend/repository"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // AuthMiddleware validates JWT and checks session storage
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(sessions repository.SessionRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
@@ -35,16 +33,13 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Verify session in MongoDB
-		sessionCollection := config.GetCollection("sessions")
+		// Verify session in storage
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		var session models.Session
-
-		err = sessionCollection.FindOne(ctx, bson.M{"token": tokenString}).Decode(&session)
+		session, err := sessions.FindByToken(ctx, tokenString)
 		if err != nil {
-			if err == mongo.ErrNoDocuments {
+			if errors.Is(err, repository.ErrNotFound) {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found. Please log in again."})
 			} else {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate session"})
@@ -57,7 +52,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		if time.Now().Unix() > session.ExpiresAt {
 			fmt.Println("🔴 Session Expired!")
 			fmt.Println("🔵 Current Time:", time.Now().Unix(), " | 🔴 Expiration Time:", session.ExpiresAt)
-			sessionCollection.DeleteOne(ctx, bson.M{"token": tokenString})
+			sessions.DeleteByToken(ctx, tokenString)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired. Please log in again."})
 			c.Abort()
 			return
@@ -14,6 +14,9 @@ var fileLogger *log.Logger
 
 func init() {
 	// Open or create log file
+	if err := os.MkdirAll("logs", 0755); err != nil {
+		log.Fatal("❌ Failed to create log directory:", err)
+	}
 	logFile, err := os.OpenFile("logs/server.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		log.Fatal("❌ Failed to open log file:", err)
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// EarthRadiusMeters is the mean radius MongoDB also uses for spherical distances
+const EarthRadiusMeters = 6378100.0
+
+// GeoJSON is a GeoJSON geometry, the shape MongoDB's 2dsphere indexes work with.
+// Coordinates are [longitude, latitude] for a Point and a list of those for a MultiPoint or
+// LineString. A GeometryCollection has Geometries instead.
+type GeoJSON struct {
+	Type        string      `bson:"type" json:"type"`
+	Coordinates interface{} `bson:"coordinates,omitempty" json:"coordinates,omitempty"`
+	Geometries  []GeoJSON   `bson:"geometries,omitempty" json:"geometries,omitempty"`
+}
+
+// Point is a bare coordinate, used for the many points of a route path
+type Point struct {
+	Latitude  float64 `bson:"latitude" json:"latitude"`
+	Longitude float64 `bson:"longitude" json:"longitude"`
+}
+
+// Location returns the point as a Location without an address
+func (p Point) Location() Location {
+	return Location{Latitude: p.Latitude, Longitude: p.Longitude}
+}
+
+// RoutePath is the road route a ride follows through its stops, with its length and driving time
+type RoutePath struct {
+	Points          []Point `bson:"points" json:"points"`
+	DistanceMeters  float64 `bson:"distance_m" json:"distance_m"`
+	DurationSeconds float64 `bson:"duration_s" json:"duration_s"`
+}
+
+// Usable reports whether the path has enough points to measure distances along
+func (p *RoutePath) Usable() bool {
+	return p != nil && len(p.Points) >= 2
+}
+
+// SpeedMetersPerSecond returns the average driving speed along the path, or 0 if unknown
+func (p *RoutePath) SpeedMetersPerSecond() float64 {
+	if p == nil || p.DurationSeconds <= 0 {
+		return 0
+	}
+	return p.DistanceMeters / p.DurationSeconds
+}
+
+// PathLengthMeters returns the great-circle length of a polyline
+func PathLengthMeters(points []Point) float64 {
+	length := 0.0
+	for i := 1; i < len(points); i++ {
+		length += DistanceMeters(points[i-1].Location(), points[i].Location())
+	}
+	return length
+}
+
+// Projection is where a location meets a path: the closest point on it, how far away that is,
+// and how far along the path from its start the point lies, all in metres
+type Projection struct {
+	Point    Point
+	Distance float64
+	Along    float64
+}
+
+// ProjectOntoPath finds the point of a path closest to a location. Each leg is treated as flat
+// around its start, which is accurate to well under a percent for legs of a few kilometres.
+func ProjectOntoPath(l Location, path []Point) Projection {
+	best := Projection{Distance: math.Inf(1)}
+	walked := 0.0
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		legLength := DistanceMeters(a.Location(), b.Location())
+
+		// Offsets from a in degrees, with longitude scaled so both axes have the same length
+		scale := math.Cos(a.Latitude * math.Pi / 180)
+		bx, by := (b.Longitude-a.Longitude)*scale, b.Latitude-a.Latitude
+		px, py := (l.Longitude-a.Longitude)*scale, l.Latitude-a.Latitude
+		t := 0.0
+		if squared := bx*bx + by*by; squared > 0 {
+			t = math.Max(0, math.Min(1, (px*bx+py*by)/squared))
+		}
+
+		closest := Point{
+			Latitude:  a.Latitude + t*(b.Latitude-a.Latitude),
+			Longitude: a.Longitude + t*(b.Longitude-a.Longitude),
+		}
+		if distance := DistanceMeters(l, closest.Location()); distance < best.Distance {
+			best = Projection{Point: closest, Distance: distance, Along: walked + t*legLength}
+		}
+		walked += legLength
+	}
+	return best
+}
+
+// GeoPoint returns the location as a GeoJSON point
+func (l Location) GeoPoint() GeoJSON {
+	return GeoJSON{Type: "Point", Coordinates: []float64{l.Longitude, l.Latitude}}
+}
+
+// MarshalBSON stores the location with a GeoJSON copy of its coordinates under "geo",
+// which the 2dsphere indexes are built on. Reading it back ignores the copy.
+func (l Location) MarshalBSON() ([]byte, error) {
+	type location Location
+	return bson.Marshal(struct {
+		Location location `bson:",inline"`
+		Geo      GeoJSON  `bson:"geo"`
+	}{location(l), l.GeoPoint()})
+}
+
+// DistanceMeters returns the great-circle distance between two locations
+func DistanceMeters(a, b Location) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Route returns every stop of the ride as a GeoJSON MultiPoint. A ride with a path is returned
+// as a GeometryCollection of its stops and the path as a LineString.
+func (r *Ride) Route() GeoJSON {
+	stops := r.Stops()
+	coordinates := make([][]float64, len(stops))
+	for i, stop := range stops {
+		coordinates[i] = []float64{stop.Longitude, stop.Latitude}
+	}
+	route := GeoJSON{Type: "MultiPoint", Coordinates: coordinates}
+	if !r.Path.Usable() {
+		return route
+	}
+
+	line := make([][]float64, len(r.Path.Points))
+	for i, point := range r.Path.Points {
+		line[i] = []float64{point.Longitude, point.Latitude}
+	}
+	return GeoJSON{Type: "GeometryCollection", Geometries: []GeoJSON{route, {Type: "LineString", Coordinates: line}}}
+}
+
+// MarshalBSON stores the ride with its Route under "route", so a single 2dsphere index
+// finds rides passing near a point at any stop or anywhere along their path
+func (r Ride) MarshalBSON() ([]byte, error) {
+	type ride Ride
+	return bson.Marshal(struct {
+		Ride  ride    `bson:",inline"`
+		Route GeoJSON `bson:"route"`
+	}{ride(r), r.Route()})
+}
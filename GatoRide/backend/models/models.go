@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -16,6 +17,72 @@ type User struct {
 	IsVerified        bool               `bson:"is_verified" json:"is_verified"`
 	VerificationToken string             `bson:"verification_token" json:"-"`
 	Location          Location           `bson:"location" json:"location"`
+	Reliability       Reliability        `bson:"reliability" json:"reliability"`
+	// Rating adds up the reviews the user received that were revealed
+	Rating Rating `bson:"rating" json:"-"`
+	// Moderation records what admins did about reports on the user
+	Moderation Moderation `bson:"moderation" json:"moderation"`
+}
+
+// Moderation is a user's standing with the admins who handle reports
+type Moderation struct {
+	Warnings int `bson:"warnings" json:"warnings"`
+	// SuspendedUntil keeps the user from logging in until then
+	SuspendedUntil *time.Time `bson:"suspended_until,omitempty" json:"suspended_until,omitempty"`
+}
+
+// Suspended reports whether the user is suspended at the given time
+func (m Moderation) Suspended(at time.Time) bool {
+	return m.SuspendedUntil != nil && at.Before(*m.SuspendedUntil)
+}
+
+// Rating adds up the stars of the reviews a user received
+type Rating struct {
+	Stars int `bson:"stars"`
+	Count int `bson:"count"`
+}
+
+// RatingSummary is how a user's rating is shown to others
+type RatingSummary struct {
+	Average float64 `json:"average"` // to one decimal; 0 without reviews
+	Count   int     `json:"count"`
+}
+
+// Summary gives the average stars and how many reviews they come from
+func (r Rating) Summary() RatingSummary {
+	if r.Count == 0 {
+		return RatingSummary{}
+	}
+	return RatingSummary{Average: math.Round(10*float64(r.Stars)/float64(r.Count)) / 10, Count: r.Count}
+}
+
+// Reliability records how often a user cancels bookings and rides. Cancelling late too often
+// restricts the user from booking or offering rides for a while.
+type Reliability struct {
+	Cancellations     int `bson:"cancellations" json:"cancellations"`
+	LateCancellations int `bson:"late_cancellations" json:"late_cancellations"`
+	// Penalties adds up what the user owes for cancelling bookings late
+	Penalties float64 `bson:"penalties" json:"penalties"`
+	// RecentLate holds when the late cancellations inside the policy's window happened
+	RecentLate      []time.Time `bson:"recent_late,omitempty" json:"recent_late,omitempty"`
+	RestrictedUntil *time.Time  `bson:"restricted_until,omitempty" json:"restricted_until,omitempty"`
+}
+
+// Restricted reports whether the user may not book or offer rides at the given time
+func (r Reliability) Restricted(at time.Time) bool {
+	return r.RestrictedUntil != nil && at.Before(*r.RestrictedUntil)
+}
+
+// Score rates the user from 0 to 100 by the share of their commitments, the rides they offered
+// and the bookings they made, that they did not cancel late. Users without any score 100.
+func (r Reliability) Score(commitments int) int {
+	if commitments < r.LateCancellations {
+		commitments = r.LateCancellations
+	}
+	if commitments == 0 {
+		return 100
+	}
+	return int(math.Round(100 * float64(commitments-r.LateCancellations) / float64(commitments)))
 }
 
 type RideStatus string
@@ -37,16 +104,605 @@ type Location struct {
 
 // Ride represents a ride request
 type Ride struct {
-	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	DriverID     primitive.ObjectID   `bson:"driver_id" json:"driver_id"`
-	Pickup       Location             `bson:"pickup" json:"pickup"`
-	Dropoff      Location             `bson:"dropoff" json:"dropoff"`
-	Status       RideStatus           `bson:"status" json:"status"`
-	Price        float64              `bson:"price" json:"price"`
-	Seats        int                  `bson:"seats" json:"seats"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DriverID primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	Pickup   Location           `bson:"pickup" json:"pickup"`
+	Dropoff  Location           `bson:"dropoff" json:"dropoff"`
+	// Waypoints are the intermediate stops between Pickup and Dropoff, in driving order
+	Waypoints []Location `bson:"waypoints,omitempty" json:"waypoints,omitempty"`
+	Status    RideStatus `bson:"status" json:"status"`
+	Price     float64    `bson:"price" json:"price"`
+	// Seats is how many seats are free on every segment, i.e. for the whole route
+	Seats int `bson:"seats" json:"seats"`
+	// SegmentSeats holds the free seats between each pair of consecutive stops. Rides
+	// created before stops existed have none and a single segment with Seats free.
+	SegmentSeats []int                `bson:"segment_seats,omitempty" json:"segment_seats,omitempty"`
 	Date         time.Time            `bson:"date" json:"date"`
 	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
 	PassengerIDs []primitive.ObjectID `bson:"passenger_ids,omitempty" json:"passenger_ids,omitempty"`
+	// InstantBook confirms bookings without the driver's approval. Rides created
+	// before the setting existed have no value and book instantly.
+	InstantBook *bool `bson:"instant_book,omitempty" json:"instant_book,omitempty"`
+	// SeriesID and OccurrenceDate link a ride generated from a recurring series to it
+	SeriesID       *primitive.ObjectID `bson:"series_id,omitempty" json:"series_id,omitempty"`
+	OccurrenceDate string              `bson:"occurrence_date,omitempty" json:"occurrence_date,omitempty"`
+	// Detached marks an occurrence edited on its own, which later series edits leave alone
+	Detached bool `bson:"detached,omitempty" json:"detached,omitempty"`
+	// Notes is free text from the driver, e.g. luggage space or where exactly to meet
+	Notes     string     `bson:"notes,omitempty" json:"notes,omitempty"`
+	UpdatedAt *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"` // last edit by the driver
+	// WaitlistCount is how many passengers are waiting for a seat; the ride stays booked while any are
+	WaitlistCount int `bson:"waitlist_count,omitempty" json:"waitlist_count,omitempty"`
+	// Path is the road route through every stop, drawn by the driver or planned by the routing
+	// provider. Rides without one are only matched at their stops.
+	Path *RoutePath `bson:"path,omitempty" json:"path,omitempty"`
+	// Cancellation says why the driver cancelled the ride
+	Cancellation *RideCancellation `bson:"cancellation,omitempty" json:"cancellation,omitempty"`
+	// CompletedAt is when the driver marked the ride completed
+	CompletedAt *time.Time `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	// Attributes are what the driver allows on board and how much luggage fits
+	Attributes RideAttributes `bson:"attributes" json:"attributes"`
+	// FreeLuggage is how many of Attributes.LuggageBags passengers have not claimed yet. Bags
+	// ride the whole route, so unlike seats they are not counted per segment.
+	FreeLuggage int `bson:"free_luggage" json:"free_luggage"`
+	// VehicleID is the driver's vehicle the ride is offered in, and Vehicle its details as they
+	// were then, so passengers know what to look for at pickup
+	VehicleID *primitive.ObjectID `bson:"vehicle_id,omitempty" json:"vehicle_id,omitempty"`
+	Vehicle   *VehicleDetails     `bson:"vehicle,omitempty" json:"vehicle,omitempty"`
+}
+
+// CompletionTime is when the ride was completed. Rides completed before completion times were
+// recorded fall back to their departure.
+func (r *Ride) CompletionTime() time.Time {
+	if r.CompletedAt != nil {
+		return *r.CompletedAt
+	}
+	return r.Date
+}
+
+// MusicPreference is what the driver plays during the ride
+type MusicPreference string
+
+const (
+	MusicQuiet      MusicPreference = "quiet"      // no music
+	MusicDriver     MusicPreference = "driver"     // the driver picks
+	MusicPassengers MusicPreference = "passengers" // passengers may pick
+)
+
+// MusicPreferences lists every music preference a driver may set
+var MusicPreferences = []MusicPreference{MusicQuiet, MusicDriver, MusicPassengers}
+
+// RideAttributes describe what a ride allows beyond seats. Rides created before attributes
+// existed have no luggage space and allow neither pets nor smoking.
+type RideAttributes struct {
+	LuggageBags     int             `bson:"luggage_bags" json:"luggage_bags"` // bags the car takes in all
+	PetsAllowed     bool            `bson:"pets_allowed" json:"pets_allowed"`
+	SmokingAllowed  bool            `bson:"smoking_allowed" json:"smoking_allowed"`
+	AirConditioning bool            `bson:"air_conditioning" json:"air_conditioning"`
+	Music           MusicPreference `bson:"music,omitempty" json:"music,omitempty"`
+}
+
+// CancellationReason is why a driver cancelled a ride
+type CancellationReason string
+
+const (
+	ReasonVehicleProblem CancellationReason = "vehicle_problem" // the car broke down or is unavailable
+	ReasonEmergency      CancellationReason = "emergency"       // a personal or family emergency
+	ReasonScheduleChange CancellationReason = "schedule_change" // the driver's plans changed
+	ReasonWeather        CancellationReason = "weather"         // unsafe weather or road conditions
+	ReasonOther          CancellationReason = "other"           // explained in the note
+	// ReasonRemoved is for rides an admin took down after a report; drivers cannot give it
+	ReasonRemoved CancellationReason = "removed"
+	// ReasonExpired is for rides the scheduler cancelled because they never started
+	ReasonExpired CancellationReason = "expired"
+)
+
+// CancellationReasons lists every reason a driver may give
+var CancellationReasons = []CancellationReason{ReasonVehicleProblem, ReasonEmergency, ReasonScheduleChange, ReasonWeather, ReasonOther}
+
+// RideCancellation records why and when the driver cancelled a ride
+type RideCancellation struct {
+	Reason CancellationReason `bson:"reason" json:"reason"`
+	Note   string             `bson:"note,omitempty" json:"note,omitempty"`
+	At     time.Time          `bson:"at" json:"at"`
+}
+
+// BooksInstantly reports whether bookings on the ride skip the driver's approval
+func (r *Ride) BooksInstantly() bool {
+	return r.InstantBook == nil || *r.InstantBook
+}
+
+// Segment is a forward stretch of a ride between two stops, given as indices into Stops
+type Segment struct {
+	From int
+	To   int
+}
+
+// Stops returns every stop of the ride in order: pickup, waypoints, dropoff
+func (r *Ride) Stops() []Location {
+	stops := make([]Location, 0, len(r.Waypoints)+2)
+	stops = append(stops, r.Pickup)
+	stops = append(stops, r.Waypoints...)
+	return append(stops, r.Dropoff)
+}
+
+// FullRoute returns the segment from pickup to dropoff
+func (r *Ride) FullRoute() Segment {
+	return Segment{From: 0, To: len(r.Waypoints) + 1}
+}
+
+// ValidSegment reports whether the segment runs forward between two stops of the ride
+func (r *Ride) ValidSegment(s Segment) bool {
+	return s.From >= 0 && s.From < s.To && s.To <= len(r.Waypoints)+1
+}
+
+// FreeSeatsBySegment returns the free seats between each pair of consecutive stops
+func (r *Ride) FreeSeatsBySegment() []int {
+	count := len(r.Waypoints) + 1
+	if len(r.SegmentSeats) == count {
+		return append([]int(nil), r.SegmentSeats...)
+	}
+	seats := make([]int, count)
+	for i := range seats {
+		seats[i] = r.Seats
+	}
+	return seats
+}
+
+// FreeSeats returns how many seats are free along the whole segment
+func (r *Ride) FreeSeats(s Segment) int {
+	if !r.ValidSegment(s) {
+		return 0
+	}
+	bySegment := r.FreeSeatsBySegment()
+	free := bySegment[s.From]
+	for _, seats := range bySegment[s.From+1 : s.To] {
+		if seats < free {
+			free = seats
+		}
+	}
+	return free
+}
+
+// Recurrence describes when a recurring ride departs, similar to a weekly RRULE
+type Recurrence struct {
+	Weekdays       []string `bson:"weekdays" json:"weekdays"`               // "mon" ... "sun"
+	DepartureTime  string   `bson:"departure_time" json:"departure_time"`   // "08:15" in TimeZone
+	TimeZone       string   `bson:"time_zone" json:"time_zone"`             // IANA name, e.g. "America/New_York"
+	StartDate      string   `bson:"start_date" json:"start_date"`           // "2006-01-02", inclusive
+	EndDate        string   `bson:"end_date" json:"end_date"`               // "2006-01-02", inclusive
+	ExceptionDates []string `bson:"exception_dates" json:"exception_dates"` // days without a ride
+}
+
+type SeriesStatus string
+
+const (
+	SeriesActive    SeriesStatus = "active"    // Rides are still being generated
+	SeriesCancelled SeriesStatus = "cancelled" // Driver cancelled the whole series
+)
+
+// RideSeries is a recurring ride offer that rides are generated from
+type RideSeries struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DriverID    primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	Pickup      Location           `bson:"pickup" json:"pickup"`
+	Dropoff     Location           `bson:"dropoff" json:"dropoff"`
+	Waypoints   []Location         `bson:"waypoints,omitempty" json:"waypoints,omitempty"`
+	Price       float64            `bson:"price" json:"price"`
+	Seats       int                `bson:"seats" json:"seats"`
+	InstantBook bool               `bson:"instant_book" json:"instant_book"`
+	Recurrence  Recurrence         `bson:"recurrence" json:"recurrence"`
+	Status      SeriesStatus       `bson:"status" json:"status"`
+	// Attributes, VehicleID and Vehicle are copied onto every ride of the series
+	Attributes RideAttributes      `bson:"attributes" json:"attributes"`
+	VehicleID  *primitive.ObjectID `bson:"vehicle_id,omitempty" json:"vehicle_id,omitempty"`
+	Vehicle    *VehicleDetails     `bson:"vehicle,omitempty" json:"vehicle,omitempty"`
+	// Path is the road route through every stop, planned once for the series and copied onto
+	// each of its rides
+	Path *RoutePath `bson:"path,omitempty" json:"path,omitempty"`
+	// GeneratedUntil is the end of the window rides have already been created for
+	GeneratedUntil time.Time `bson:"generated_until" json:"generated_until"`
+	// HeldSince is when generation stopped because the driver may no longer drive the next
+	// occurrence; the driver is told once per hold
+	HeldSince *time.Time `bson:"held_since,omitempty" json:"held_since,omitempty"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// Stops returns every stop of the series' rides in order: pickup, waypoints, dropoff
+func (s *RideSeries) Stops() []Location {
+	stops := make([]Location, 0, len(s.Waypoints)+2)
+	stops = append(stops, s.Pickup)
+	stops = append(stops, s.Waypoints...)
+	return append(stops, s.Dropoff)
+}
+
+type BookingStatus string
+
+const (
+	BookingPending   BookingStatus = "pending"   // Waiting for the driver; seats are held meanwhile
+	BookingConfirmed BookingStatus = "confirmed" // Seats are held for the passenger
+	BookingRejected  BookingStatus = "rejected"  // Driver declined the request
+	BookingExpired   BookingStatus = "expired"   // Driver did not answer the request in time
+	BookingCancelled BookingStatus = "cancelled" // Passenger gave the seats back
+	// The ride the booking was on was cancelled by its driver, or because it never started
+	BookingCancelledByDriver BookingStatus = "cancelled_by_driver"
+)
+
+// Booking is a passenger's reservation of one or more seats on a ride
+type Booking struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RideID       primitive.ObjectID `bson:"ride_id" json:"ride_id"`
+	PassengerID  primitive.ObjectID `bson:"passenger_id" json:"passenger_id"`
+	Seats        int                `bson:"seats" json:"seats"`
+	Bags         int                `bson:"bags,omitempty" json:"bags,omitempty"` // luggage the passenger brings
+	FromStop     int                `bson:"from_stop" json:"from_stop"`           // index into the ride's stops
+	ToStop       int                `bson:"to_stop" json:"to_stop"`
+	Status       BookingStatus      `bson:"status" json:"status"`
+	PricePerSeat float64            `bson:"price_per_seat" json:"price_per_seat"` // ride price when the booking was made
+	TotalPrice   float64            `bson:"total_price" json:"total_price"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt    *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`     // pending requests only
+	RespondedAt  *time.Time         `bson:"responded_at,omitempty" json:"responded_at,omitempty"` // when the request was accepted, rejected or expired
+	CancelledAt  *time.Time         `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	// RideChangedAt is when the driver last materially changed the ride after this booking was
+	// made, which entitles the passenger to cancel without penalty
+	RideChangedAt *time.Time `bson:"ride_changed_at,omitempty" json:"ride_changed_at,omitempty"`
+}
+
+// Segment returns the stretch of the ride the booking holds seats on. Bookings made before
+// rides had stops covered the single segment from pickup to dropoff.
+func (b *Booking) Segment() Segment {
+	if b.ToStop == 0 {
+		return Segment{From: 0, To: 1}
+	}
+	return Segment{From: b.FromStop, To: b.ToStop}
+}
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting  WaitlistStatus = "waiting"  // In line for a seat
+	WaitlistPromoted WaitlistStatus = "promoted" // Got a booking when a seat freed up
+	WaitlistLeft     WaitlistStatus = "left"     // Passenger gave up their place
+	WaitlistClosed   WaitlistStatus = "closed"   // The ride was cancelled before a seat freed up
+)
+
+// WaitlistEntry is a passenger's place in line for seats on a full ride
+type WaitlistEntry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RideID      primitive.ObjectID `bson:"ride_id" json:"ride_id"`
+	PassengerID primitive.ObjectID `bson:"passenger_id" json:"passenger_id"`
+	Seats       int                `bson:"seats" json:"seats"`
+	Bags        int                `bson:"bags,omitempty" json:"bags,omitempty"`
+	FromStop    int                `bson:"from_stop" json:"from_stop"`
+	ToStop      int                `bson:"to_stop" json:"to_stop"`
+	Status      WaitlistStatus     `bson:"status" json:"status"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ResolvedAt  *time.Time         `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"` // when the passenger was promoted, left or the ride was cancelled
+}
+
+// Segment returns the stretch of the ride the passenger is waiting for
+func (w *WaitlistEntry) Segment() Segment {
+	return Segment{From: w.FromStop, To: w.ToStop}
+}
+
+type RideRequestStatus string
+
+const (
+	RequestOpen      RideRequestStatus = "open"      // Waiting for drivers' offers
+	RequestFulfilled RideRequestStatus = "fulfilled" // Passenger accepted an offer and is booked on its ride
+	RequestCancelled RideRequestStatus = "cancelled" // Passenger withdrew the request
+	RequestExpired   RideRequestStatus = "expired"   // The departure window passed without an accepted offer
+)
+
+// RideRequest is a passenger asking for a ride nobody offers yet. Drivers answer it with offers,
+// and accepting one creates the ride with the passenger booked on it.
+type RideRequest struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PassengerID primitive.ObjectID `bson:"passenger_id" json:"passenger_id"`
+	Pickup      Location           `bson:"pickup" json:"pickup"`
+	Dropoff     Location           `bson:"dropoff" json:"dropoff"`
+	// The passenger can leave any time between DepartureFrom and DepartureTo
+	DepartureFrom time.Time         `bson:"departure_from" json:"departure_from"`
+	DepartureTo   time.Time         `bson:"departure_to" json:"departure_to"`
+	Seats         int               `bson:"seats" json:"seats"`
+	MaxPrice      float64           `bson:"max_price" json:"max_price"` // per seat
+	Notes         string            `bson:"notes,omitempty" json:"notes,omitempty"`
+	Status        RideRequestStatus `bson:"status" json:"status"`
+	CreatedAt     time.Time         `bson:"created_at" json:"created_at"`
+	ResolvedAt    *time.Time        `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"` // when it was fulfilled, cancelled or expired
+	// RideID and BookingID are set once the request is fulfilled
+	RideID    *primitive.ObjectID `bson:"ride_id,omitempty" json:"ride_id,omitempty"`
+	BookingID *primitive.ObjectID `bson:"booking_id,omitempty" json:"booking_id,omitempty"`
+}
+
+type OfferStatus string
+
+const (
+	OfferPending   OfferStatus = "pending"   // Waiting for the passenger
+	OfferAccepted  OfferStatus = "accepted"  // Passenger took it; the ride was created
+	OfferDeclined  OfferStatus = "declined"  // Passenger turned it down or took another offer
+	OfferWithdrawn OfferStatus = "withdrawn" // Driver took it back
+	OfferExpired   OfferStatus = "expired"   // The request expired or was cancelled first
+)
+
+// RideOffer is a driver's answer to a ride request: the ride they would create for the passenger
+type RideOffer struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RequestID primitive.ObjectID `bson:"request_id" json:"request_id"`
+	DriverID  primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	Price     float64            `bson:"price" json:"price"` // per seat
+	Date      time.Time          `bson:"date" json:"date"`
+	// Seats is how many seats the ride will have in all; the ones the passenger does not take
+	// stay open for others to book
+	Seats int    `bson:"seats" json:"seats"`
+	Notes string `bson:"notes,omitempty" json:"notes,omitempty"`
+	// Attributes, VehicleID and Vehicle are what the ride will be offered with once accepted
+	Attributes  RideAttributes      `bson:"attributes" json:"attributes"`
+	VehicleID   *primitive.ObjectID `bson:"vehicle_id,omitempty" json:"vehicle_id,omitempty"`
+	Vehicle     *VehicleDetails     `bson:"vehicle,omitempty" json:"vehicle,omitempty"`
+	Status      OfferStatus         `bson:"status" json:"status"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	RespondedAt *time.Time          `bson:"responded_at,omitempty" json:"responded_at,omitempty"`
+}
+
+// Channels a saved search can alert its user through
+const (
+	AlertEmail = "email" // an email to the user's address
+	AlertInbox = "inbox" // a notification in the in-app inbox
+)
+
+// VehicleDetails describe a car the way passengers need to recognise it
+type VehicleDetails struct {
+	Make   string `bson:"make" json:"make"`
+	Model  string `bson:"model" json:"model"`
+	Colour string `bson:"colour" json:"colour"`
+	Plate  string `bson:"plate" json:"plate"`
+	// Seats is how many passengers the car takes, not counting the driver
+	Seats int `bson:"seats" json:"seats"`
+	// FuelEconomy is the car's consumption in litres per 100 km, if the owner gave it
+	FuelEconomy *float64 `bson:"fuel_economy,omitempty" json:"fuel_economy,omitempty"`
+}
+
+// Vehicle is a car a user registered to offer rides in
+type Vehicle struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OwnerID        primitive.ObjectID `bson:"owner_id" json:"owner_id"`
+	VehicleDetails `bson:",inline"`
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+}
+
+// DriverStatus is where a user's driver verification stands
+type DriverStatus string
+
+const (
+	DriverPending  DriverStatus = "pending"  // submitted, waiting for an admin
+	DriverApproved DriverStatus = "approved" // the user may offer rides while their documents are valid
+	DriverRejected DriverStatus = "rejected" // an admin turned the submission down; the user may submit again
+)
+
+// Kinds of document a driver uploads
+const (
+	DocumentLicence   = "licence"
+	DocumentInsurance = "insurance"
+)
+
+// DriverDocument is an uploaded scan kept in the document store under Key
+type DriverDocument struct {
+	Kind        string    `bson:"kind" json:"kind"`
+	Key         string    `bson:"key" json:"-"`
+	ContentType string    `bson:"content_type" json:"content_type"`
+	Size        int64     `bson:"size" json:"size"`
+	UploadedAt  time.Time `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+// DriverVerification holds the licence and insurance a user submitted to be allowed to offer
+// rides, and what an admin decided about them. Each user has at most one; submitting again
+// replaces it.
+type DriverVerification struct {
+	UserID             primitive.ObjectID  `bson:"_id" json:"user_id"`
+	Status             DriverStatus        `bson:"status" json:"status"`
+	LicenceNumber      string              `bson:"licence_number" json:"licence_number"`
+	LicenceRegion      string              `bson:"licence_region" json:"licence_region"`
+	LicenceExpiresAt   time.Time           `bson:"licence_expires_at" json:"licence_expires_at"`
+	InsuranceExpiresAt time.Time           `bson:"insurance_expires_at" json:"insurance_expires_at"`
+	Documents          []DriverDocument    `bson:"documents" json:"documents"`
+	SubmittedAt        time.Time           `bson:"submitted_at" json:"submitted_at"`
+	ReviewedAt         *time.Time          `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	ReviewedBy         *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	RejectionReason    string              `bson:"rejection_reason,omitempty" json:"rejection_reason,omitempty"`
+	// WarnedFor is the expiry the driver was last warned about, so they are warned once per expiry
+	WarnedFor *time.Time `bson:"warned_for,omitempty" json:"-"`
+}
+
+// ExpiresAt is when the first of the driver's documents expires
+func (v DriverVerification) ExpiresAt() time.Time {
+	if v.InsuranceExpiresAt.Before(v.LicenceExpiresAt) {
+		return v.InsuranceExpiresAt
+	}
+	return v.LicenceExpiresAt
+}
+
+// Expired reports whether any of the driver's documents is no longer valid at the given time
+func (v DriverVerification) Expired(at time.Time) bool {
+	return !at.Before(v.ExpiresAt())
+}
+
+// Document returns the uploaded document of the given kind, if there is one
+func (v DriverVerification) Document(kind string) *DriverDocument {
+	for i := range v.Documents {
+		if v.Documents[i].Kind == kind {
+			return &v.Documents[i]
+		}
+	}
+	return nil
+}
+
+// SavedSearch is a ride search a user wants to hear about: whenever a matching ride is
+// offered, they are alerted through its channels
+type SavedSearch struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	From   Location           `bson:"from" json:"from"`
+	To     Location           `bson:"to" json:"to"`
+	// Rides departing between DepartureFrom and DepartureTo match
+	DepartureFrom time.Time `bson:"departure_from" json:"departure_from"`
+	DepartureTo   time.Time `bson:"departure_to" json:"departure_to"`
+	Seats         int       `bson:"seats" json:"seats"`
+	RadiusMeters  float64   `bson:"radius_m" json:"radius_m"` // pickup/dropoff tolerance, like SearchRides
+	Channels      []string  `bson:"channels" json:"channels"` // AlertEmail and/or AlertInbox
+	CreatedAt     time.Time `bson:"created_at" json:"created_at"`
+	// LastAlertAt is when the user was last alerted, which limits how often they are
+	LastAlertAt *time.Time `bson:"last_alert_at,omitempty" json:"last_alert_at,omitempty"`
+	AlertCount  int        `bson:"alert_count" json:"alert_count"`
+}
+
+// ReviewRole is the part the reviewer played on the ride
+type ReviewRole string
+
+const (
+	ReviewByDriver    ReviewRole = "driver"    // the driver reviews a passenger
+	ReviewByPassenger ReviewRole = "passenger" // a passenger reviews the driver
+)
+
+// Ratings a review may give
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// Review is what a driver or passenger of a completed ride thinks of the other. It stays
+// hidden from everyone but its author until the other side reviews back or RevealAt passes.
+type Review struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RideID     primitive.ObjectID `bson:"ride_id" json:"ride_id"`
+	ReviewerID primitive.ObjectID `bson:"reviewer_id" json:"reviewer_id"`
+	RevieweeID primitive.ObjectID `bson:"reviewee_id" json:"reviewee_id"`
+	Role       ReviewRole         `bson:"role" json:"role"`
+	Rating     int                `bson:"rating" json:"rating"`
+	Comment    string             `bson:"comment,omitempty" json:"comment,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	// RevealAt is when the review window closes, showing the review even without a reply
+	RevealAt   time.Time  `bson:"reveal_at" json:"reveal_at"`
+	RevealedAt *time.Time `bson:"revealed_at,omitempty" json:"revealed_at,omitempty"`
+}
+
+// Block keeps two users apart: neither sees the other's rides or can book them. Only the
+// blocker knows about it.
+type Block struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BlockerID primitive.ObjectID `bson:"blocker_id" json:"blocker_id"`
+	BlockedID primitive.ObjectID `bson:"blocked_id" json:"blocked_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ReportTarget is the kind of thing a report is about
+type ReportTarget string
+
+const (
+	ReportUser   ReportTarget = "user"
+	ReportRide   ReportTarget = "ride"
+	ReportReview ReportTarget = "review"
+)
+
+// ReportTargets lists everything users can report
+var ReportTargets = []ReportTarget{ReportUser, ReportRide, ReportReview}
+
+// ReportCategory is what a report accuses its subject of
+type ReportCategory string
+
+const (
+	CategoryUnsafeDriving ReportCategory = "unsafe_driving" // speeding, driving impaired or an unsafe vehicle
+	CategoryFakeListing   ReportCategory = "fake_listing"   // a ride that was never going to happen
+	CategoryHarassment    ReportCategory = "harassment"     // threats, insults or unwanted contact
+	CategoryInappropriate ReportCategory = "inappropriate"  // offensive ride notes or review comments
+	CategoryScam          ReportCategory = "scam"           // asking for payment outside the app and the like
+	CategoryOther         ReportCategory = "other"          // explained in the description
+)
+
+// ReportCategories lists every category a report may have
+var ReportCategories = []ReportCategory{CategoryUnsafeDriving, CategoryFakeListing, CategoryHarassment, CategoryInappropriate, CategoryScam, CategoryOther}
+
+// ReportStatus is where a report is in the moderation queue
+type ReportStatus string
+
+const (
+	ReportOpen          ReportStatus = "open"          // waiting for an admin
+	ReportInvestigating ReportStatus = "investigating" // an admin took it on
+	ReportResolved      ReportStatus = "resolved"      // closed, with or without action
+)
+
+// Report is a user's complaint about another user, a ride or a review, queued for the admins
+type Report struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReporterID primitive.ObjectID `bson:"reporter_id" json:"reporter_id"`
+	Target     ReportTarget       `bson:"target" json:"target"`
+	TargetID   primitive.ObjectID `bson:"target_id" json:"target_id"`
+	// SubjectID is the user answerable for the target: the user reported, the driver of the
+	// ride or the author of the review
+	SubjectID   primitive.ObjectID `bson:"subject_id" json:"subject_id"`
+	Category    ReportCategory     `bson:"category" json:"category"`
+	Description string             `bson:"description" json:"description"`
+	Status      ReportStatus       `bson:"status" json:"status"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	// AssigneeID is the admin who last moved the report along
+	AssigneeID *primitive.ObjectID `bson:"assignee_id,omitempty" json:"assignee_id,omitempty"`
+	Resolution string              `bson:"resolution,omitempty" json:"resolution,omitempty"`
+	ResolvedAt *time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+}
+
+// ModerationActionKind is something an admin did about a report
+type ModerationActionKind string
+
+const (
+	ActionInvestigate ModerationActionKind = "investigate" // took the report on
+	ActionWarn        ModerationActionKind = "warn"        // warned the subject
+	ActionSuspend     ModerationActionKind = "suspend"     // suspended the subject for a while
+	ActionRemoveRide  ModerationActionKind = "remove_ride" // cancelled the reported ride
+	ActionResolve     ModerationActionKind = "resolve"     // closed the report
+)
+
+// ModerationActions lists the actions admins take against a report's subject, as opposed to
+// moving the report through the queue
+var ModerationActions = []ModerationActionKind{ActionWarn, ActionSuspend, ActionRemoveRide}
+
+// ModerationAction is an entry in the audit log of what admins did. Entries are only ever added.
+type ModerationAction struct {
+	ID       primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	ReportID primitive.ObjectID   `bson:"report_id" json:"report_id"`
+	AdminID  primitive.ObjectID   `bson:"admin_id" json:"admin_id"`
+	Action   ModerationActionKind `bson:"action" json:"action"`
+	// SubjectID is the user the action concerned
+	SubjectID primitive.ObjectID  `bson:"subject_id" json:"subject_id"`
+	RideID    *primitive.ObjectID `bson:"ride_id,omitempty" json:"ride_id,omitempty"`
+	Note      string              `bson:"note,omitempty" json:"note,omitempty"`
+	// SuspendedUntil is when a suspension ends
+	SuspendedUntil *time.Time `bson:"suspended_until,omitempty" json:"suspended_until,omitempty"`
+	CreatedAt      time.Time  `bson:"created_at" json:"created_at"`
+}
+
+type NotificationKind string
+
+const (
+	NotificationNewRide       NotificationKind = "new_ride"           // a ride matching a saved search was offered
+	NotificationRideCancelled NotificationKind = "ride_cancelled"     // the driver cancelled a ride the user booked
+	NotificationDriverReview  NotificationKind = "driver_review"      // an admin approved or rejected the user as a driver
+	NotificationDocuments     NotificationKind = "documents_expiring" // the user's driver documents expire soon
+	NotificationReview        NotificationKind = "review"             // someone on a ride the user took reviewed them
+	NotificationModeration    NotificationKind = "moderation"         // an admin warned the user or closed their report
+)
+
+// Notification is a message in a user's in-app inbox
+type Notification struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Kind          NotificationKind    `bson:"kind" json:"kind"`
+	Message       string              `bson:"message" json:"message"`
+	RideID        *primitive.ObjectID `bson:"ride_id,omitempty" json:"ride_id,omitempty"`
+	SavedSearchID *primitive.ObjectID `bson:"saved_search_id,omitempty" json:"saved_search_id,omitempty"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	ReadAt        *time.Time          `bson:"read_at,omitempty" json:"read_at,omitempty"`
 }
 
 type Session struct {
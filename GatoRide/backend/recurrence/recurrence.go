@@ -0,0 +1,160 @@
+// Package recurrence expands a weekly ride pattern into concrete departure times.
+package recurrence
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones must resolve even on hosts without a zoneinfo database
+)
+
+// DateLayout is the format of start, end and exception dates
+const DateLayout = "2006-01-02"
+
+// MaxSpan limits how far a series may run, so one request cannot create years of rides
+const MaxSpan = 366 * 24 * time.Hour
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Occurrence is one departure of a series
+type Occurrence struct {
+	Date      string    // calendar day in the series' time zone, in DateLayout
+	Departure time.Time // departure instant
+}
+
+// Normalize lowercases weekday names and defaults the time zone to UTC, then validates the pattern
+func Normalize(r *models.Recurrence) error {
+	for i, day := range r.Weekdays {
+		r.Weekdays[i] = strings.ToLower(strings.TrimSpace(day))
+	}
+	if r.TimeZone == "" {
+		r.TimeZone = "UTC"
+	}
+	return Validate(*r)
+}
+
+// Validate reports every problem with a pattern at once
+func Validate(r models.Recurrence) error {
+	var problems []string
+
+	if len(r.Weekdays) == 0 {
+		problems = append(problems, "at least one weekday is required")
+	}
+	for _, day := range r.Weekdays {
+		if _, ok := weekdayNames[day]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown weekday %q (use mon, tue, wed, thu, fri, sat or sun)", day))
+		}
+	}
+	if _, _, err := parseClock(r.DepartureTime); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := time.LoadLocation(r.TimeZone); err != nil {
+		problems = append(problems, fmt.Sprintf("unknown time zone %q", r.TimeZone))
+	}
+
+	start, startErr := time.Parse(DateLayout, r.StartDate)
+	end, endErr := time.Parse(DateLayout, r.EndDate)
+	if startErr != nil {
+		problems = append(problems, "start_date must look like 2006-01-02")
+	}
+	if endErr != nil {
+		problems = append(problems, "end_date must look like 2006-01-02")
+	}
+	if startErr == nil && endErr == nil {
+		if end.Before(start) {
+			problems = append(problems, "end_date must not be before start_date")
+		} else if end.Sub(start) > MaxSpan {
+			problems = append(problems, "a series can run for at most one year")
+		}
+	}
+	for _, date := range r.ExceptionDates {
+		if _, err := time.Parse(DateLayout, date); err != nil {
+			problems = append(problems, fmt.Sprintf("exception date %q must look like 2006-01-02", date))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Occurrences returns the departures of a valid pattern that fall in [from, to), in order.
+// Exception dates are skipped.
+func Occurrences(r models.Recurrence, from, to time.Time) ([]Occurrence, error) {
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.ParseInLocation(DateLayout, r.StartDate, loc)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.ParseInLocation(DateLayout, r.EndDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	days := map[time.Weekday]bool{}
+	for _, day := range r.Weekdays {
+		days[weekdayNames[day]] = true
+	}
+	skip := map[string]bool{}
+	for _, date := range r.ExceptionDates {
+		skip[date] = true
+	}
+
+	// Walk calendar days in the series' zone so departures keep their local time across DST changes
+	var occurrences []Occurrence
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateLayout)
+		if !days[day.Weekday()] || skip[date] {
+			continue
+		}
+		departure, err := DepartureOn(r, date)
+		if err != nil {
+			return nil, err
+		}
+		if departure.Before(from) || !departure.Before(to) {
+			continue
+		}
+		occurrences = append(occurrences, Occurrence{Date: date, Departure: departure})
+	}
+	return occurrences, nil
+}
+
+// DepartureOn returns the departure instant on the given calendar day using the pattern's
+// departure time and time zone
+func DepartureOn(r models.Recurrence, date string) (time.Time, error) {
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, err := time.ParseInLocation(DateLayout, date, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	hour, minute, err := parseClock(r.DepartureTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc), nil
+}
+
+func parseClock(clock string) (int, int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, 0, fmt.Errorf("departure_time must look like 08:15, got %q", clock)
+	}
+	return t.Hour(), t.Minute(), nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryBlockRepository struct {
+	mu     sync.Mutex
+	blocks []models.Block // creation order
+}
+
+// NewMemoryBlockRepository returns an empty in-memory BlockRepository
+func NewMemoryBlockRepository() BlockRepository {
+	return &memoryBlockRepository{}
+}
+
+func (r *memoryBlockRepository) Create(ctx context.Context, block *models.Block) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if block.ID.IsZero() {
+		block.ID = primitive.NewObjectID()
+	}
+	for _, existing := range r.blocks {
+		if existing.ID == block.ID || (existing.BlockerID == block.BlockerID && existing.BlockedID == block.BlockedID) {
+			return ErrConflict
+		}
+	}
+	r.blocks = append(r.blocks, *block)
+	return nil
+}
+
+func (r *memoryBlockRepository) IsBlocked(ctx context.Context, blockerID, blockedID primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, block := range r.blocks {
+		if block.BlockerID == blockerID && block.BlockedID == blockedID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryBlockRepository) FindByBlocker(ctx context.Context, blockerID primitive.ObjectID) ([]models.Block, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var blocks []models.Block
+	for _, block := range r.blocks {
+		if block.BlockerID == blockerID {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
+
+func (r *memoryBlockRepository) BlockedWith(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []primitive.ObjectID
+	for _, block := range r.blocks {
+		switch userID {
+		case block.BlockerID:
+			users = append(users, block.BlockedID)
+		case block.BlockedID:
+			users = append(users, block.BlockerID)
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryBlockRepository) Delete(ctx context.Context, blockerID, blockedID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, block := range r.blocks {
+		if block.BlockerID == blockerID && block.BlockedID == blockedID {
+			r.blocks = append(r.blocks[:i], r.blocks[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryBookingRepository struct {
+	mu       sync.Mutex
+	bookings map[primitive.ObjectID]*models.Booking
+	order    []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryBookingRepository returns an empty in-memory BookingRepository
+func NewMemoryBookingRepository() BookingRepository {
+	return &memoryBookingRepository{bookings: map[primitive.ObjectID]*models.Booking{}}
+}
+
+func (r *memoryBookingRepository) Create(ctx context.Context, booking *models.Booking) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if booking.ID.IsZero() {
+		booking.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.bookings[booking.ID]; exists {
+		return fmt.Errorf("booking %s already exists", booking.ID.Hex())
+	}
+	r.bookings[booking.ID] = copyBooking(booking)
+	r.order = append(r.order, booking.ID)
+	return nil
+}
+
+func (r *memoryBookingRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Booking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	booking, ok := r.bookings[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyBooking(booking), nil
+}
+
+func (r *memoryBookingRepository) Find(ctx context.Context, filter BookingFilter) ([]models.Booking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var bookings []models.Booking
+	for _, id := range r.order {
+		if booking := r.bookings[id]; bookingMatches(booking, filter) {
+			bookings = append(bookings, *copyBooking(booking))
+		}
+	}
+	return bookings, nil
+}
+
+func (r *memoryBookingRepository) FindPage(ctx context.Context, filter BookingFilter, page Page) ([]models.Booking, bool, error) {
+	bookings, err := r.Find(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	bookings, more := Paginate(bookings, page, func(booking models.Booking) (float64, primitive.ObjectID) {
+		return float64(booking.CreatedAt.UnixMilli()), booking.ID
+	})
+	return bookings, more, nil
+}
+
+func (r *memoryBookingRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.BookingStatus, at time.Time) (*models.Booking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	booking, ok := r.bookings[id]
+	if !ok || booking.Status != from || from == to {
+		return nil, ErrConflict
+	}
+	booking.Status = to
+	if to == models.BookingCancelled || to == models.BookingCancelledByDriver {
+		booking.CancelledAt = &at
+	} else {
+		booking.RespondedAt = &at
+	}
+	return copyBooking(booking), nil
+}
+
+func (r *memoryBookingRepository) MarkRideChanged(ctx context.Context, rideID primitive.ObjectID, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, booking := range r.bookings {
+		if booking.RideID == rideID && (booking.Status == models.BookingPending || booking.Status == models.BookingConfirmed) {
+			booking.RideChangedAt = copyTime(&at)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryBookingRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.bookings[id]; !ok {
+		return nil
+	}
+	delete(r.bookings, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// bookingMatches applies a BookingFilter the same way bookingFilterToBSON does in MongoDB
+func bookingMatches(booking *models.Booking, f BookingFilter) bool {
+	if !f.RideID.IsZero() && booking.RideID != f.RideID {
+		return false
+	}
+	if !f.PassengerID.IsZero() && booking.PassengerID != f.PassengerID {
+		return false
+	}
+	if !f.ExpiresBefore.IsZero() && (booking.ExpiresAt == nil || !booking.ExpiresAt.Before(f.ExpiresBefore)) {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		for _, status := range f.Statuses {
+			if booking.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// copyBooking returns a copy that shares no pointers with the stored booking
+func copyBooking(booking *models.Booking) *models.Booking {
+	c := *booking
+	c.ExpiresAt = copyTime(booking.ExpiresAt)
+	c.RespondedAt = copyTime(booking.RespondedAt)
+	c.CancelledAt = copyTime(booking.CancelledAt)
+	c.RideChangedAt = copyTime(booking.RideChangedAt)
+	return &c
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryDriverVerificationRepository struct {
+	mu            sync.Mutex
+	verifications map[primitive.ObjectID]*models.DriverVerification
+}
+
+// NewMemoryDriverVerificationRepository returns an empty in-memory DriverVerificationRepository
+func NewMemoryDriverVerificationRepository() DriverVerificationRepository {
+	return &memoryDriverVerificationRepository{verifications: map[primitive.ObjectID]*models.DriverVerification{}}
+}
+
+func (r *memoryDriverVerificationRepository) Save(ctx context.Context, verification *models.DriverVerification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.verifications[verification.UserID] = copyDriverVerification(verification)
+	return nil
+}
+
+func (r *memoryDriverVerificationRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.DriverVerification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification, ok := r.verifications[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyDriverVerification(verification), nil
+}
+
+func (r *memoryDriverVerificationRepository) Find(ctx context.Context, filter DriverFilter) ([]models.DriverVerification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var verifications []models.DriverVerification
+	for _, verification := range r.verifications {
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, verification.Status) {
+			continue
+		}
+		if !filter.ExpiresBefore.IsZero() && !verification.ExpiresAt().Before(filter.ExpiresBefore) {
+			continue
+		}
+		verifications = append(verifications, *copyDriverVerification(verification))
+	}
+	sort.Slice(verifications, func(i, j int) bool {
+		if !verifications[i].SubmittedAt.Equal(verifications[j].SubmittedAt) {
+			return verifications[i].SubmittedAt.Before(verifications[j].SubmittedAt)
+		}
+		return verifications[i].UserID.Hex() < verifications[j].UserID.Hex()
+	})
+	return verifications, nil
+}
+
+func (r *memoryDriverVerificationRepository) Review(ctx context.Context, userID primitive.ObjectID, submittedAt time.Time, status models.DriverStatus, reviewerID primitive.ObjectID, reason string, at time.Time) (*models.DriverVerification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification, ok := r.verifications[userID]
+	if !ok || verification.Status != models.DriverPending || !verification.SubmittedAt.Equal(submittedAt) {
+		return nil, ErrConflict
+	}
+	verification.Status = status
+	verification.ReviewedAt = &at
+	verification.ReviewedBy = &reviewerID
+	verification.RejectionReason = reason
+	return copyDriverVerification(verification), nil
+}
+
+func (r *memoryDriverVerificationRepository) ClaimExpiryWarning(ctx context.Context, userID primitive.ObjectID, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification, ok := r.verifications[userID]
+	if !ok || (verification.WarnedFor != nil && verification.WarnedFor.Equal(expiresAt)) {
+		return ErrConflict
+	}
+	verification.WarnedFor = &expiresAt
+	return nil
+}
+
+func (r *memoryDriverVerificationRepository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.verifications, userID)
+	return nil
+}
+
+// copyDriverVerification returns a copy that shares no pointers or slices with the stored one
+func copyDriverVerification(verification *models.DriverVerification) *models.DriverVerification {
+	c := *verification
+	c.Documents = slices.Clone(verification.Documents)
+	c.ReviewedAt = copyTime(verification.ReviewedAt)
+	c.WarnedFor = copyTime(verification.WarnedFor)
+	if verification.ReviewedBy != nil {
+		reviewerID := *verification.ReviewedBy
+		c.ReviewedBy = &reviewerID
+	}
+	return &c
+}
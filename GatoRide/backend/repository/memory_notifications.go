@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryNotificationRepository struct {
+	mu            sync.Mutex
+	notifications map[primitive.ObjectID]*models.Notification
+	order         []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryNotificationRepository returns an empty in-memory NotificationRepository
+func NewMemoryNotificationRepository() NotificationRepository {
+	return &memoryNotificationRepository{notifications: map[primitive.ObjectID]*models.Notification{}}
+}
+
+func (r *memoryNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.notifications[notification.ID]; exists {
+		return fmt.Errorf("notification %s already exists", notification.ID.Hex())
+	}
+	r.notifications[notification.ID] = copyNotification(notification)
+	r.order = append(r.order, notification.ID)
+	return nil
+}
+
+func (r *memoryNotificationRepository) FindPage(ctx context.Context, filter NotificationFilter, page Page) ([]models.Notification, bool, error) {
+	r.mu.Lock()
+	var notifications []models.Notification
+	for _, id := range r.order {
+		notification := r.notifications[id]
+		if !filter.UserID.IsZero() && notification.UserID != filter.UserID {
+			continue
+		}
+		if filter.Unread && notification.ReadAt != nil {
+			continue
+		}
+		notifications = append(notifications, *copyNotification(notification))
+	}
+	r.mu.Unlock()
+
+	notifications, more := Paginate(notifications, page, func(notification models.Notification) (float64, primitive.ObjectID) {
+		return float64(notification.CreatedAt.UnixMilli()), notification.ID
+	})
+	return notifications, more, nil
+}
+
+func (r *memoryNotificationRepository) MarkRead(ctx context.Context, id, userID primitive.ObjectID, at time.Time) (*models.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.notifications[id]
+	if !ok || notification.UserID != userID {
+		return nil, ErrNotFound
+	}
+	if notification.ReadAt == nil {
+		notification.ReadAt = &at
+	}
+	return copyNotification(notification), nil
+}
+
+func (r *memoryNotificationRepository) MarkAllRead(ctx context.Context, userID primitive.ObjectID, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var marked int64
+	for _, notification := range r.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			readAt := at
+			notification.ReadAt = &readAt
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (r *memoryNotificationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.notifications[id]; !ok {
+		return nil
+	}
+	delete(r.notifications, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// copyNotification returns a copy that shares no pointers with the stored notification
+func copyNotification(notification *models.Notification) *models.Notification {
+	c := *notification
+	c.ReadAt = copyTime(notification.ReadAt)
+	if notification.RideID != nil {
+		rideID := *notification.RideID
+		c.RideID = &rideID
+	}
+	if notification.SavedSearchID != nil {
+		searchID := *notification.SavedSearchID
+		c.SavedSearchID = &searchID
+	}
+	return &c
+}
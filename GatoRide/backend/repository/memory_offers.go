@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryRideOfferRepository struct {
+	mu     sync.Mutex
+	offers map[primitive.ObjectID]*models.RideOffer
+	order  []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryRideOfferRepository returns an empty in-memory RideOfferRepository
+func NewMemoryRideOfferRepository() RideOfferRepository {
+	return &memoryRideOfferRepository{offers: map[primitive.ObjectID]*models.RideOffer{}}
+}
+
+func (r *memoryRideOfferRepository) Create(ctx context.Context, offer *models.RideOffer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if offer.ID.IsZero() {
+		offer.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.offers[offer.ID]; exists {
+		return fmt.Errorf("ride offer %s already exists", offer.ID.Hex())
+	}
+	r.offers[offer.ID] = copyRideOffer(offer)
+	r.order = append(r.order, offer.ID)
+	return nil
+}
+
+func (r *memoryRideOfferRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideOffer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	offer, ok := r.offers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyRideOffer(offer), nil
+}
+
+func (r *memoryRideOfferRepository) Find(ctx context.Context, filter RideOfferFilter) ([]models.RideOffer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var offers []models.RideOffer
+	for _, id := range r.order {
+		if offer := r.offers[id]; rideOfferMatches(offer, filter) {
+			offers = append(offers, *copyRideOffer(offer))
+		}
+	}
+	return offers, nil
+}
+
+func (r *memoryRideOfferRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.OfferStatus, at time.Time) (*models.RideOffer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	offer, ok := r.offers[id]
+	if !ok || offer.Status != from || from == to {
+		return nil, ErrConflict
+	}
+	offer.Status = to
+	offer.RespondedAt = &at
+	return copyRideOffer(offer), nil
+}
+
+func (r *memoryRideOfferRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.offers[id]; !ok {
+		return nil
+	}
+	delete(r.offers, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// rideOfferMatches applies a RideOfferFilter the same way rideOfferFilterToBSON does in MongoDB
+func rideOfferMatches(offer *models.RideOffer, f RideOfferFilter) bool {
+	if !f.RequestID.IsZero() && offer.RequestID != f.RequestID {
+		return false
+	}
+	if !f.DriverID.IsZero() && offer.DriverID != f.DriverID {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		for _, status := range f.Statuses {
+			if offer.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// copyRideOffer returns a copy that shares no pointers with the stored offer
+func copyRideOffer(offer *models.RideOffer) *models.RideOffer {
+	c := *offer
+	c.RespondedAt = copyTime(offer.RespondedAt)
+	if offer.VehicleID != nil {
+		vehicleID := *offer.VehicleID
+		c.VehicleID = &vehicleID
+	}
+	if offer.Vehicle != nil {
+		c.Vehicle = &copyVehicle(&models.Vehicle{VehicleDetails: *offer.Vehicle}).VehicleDetails
+	}
+	return &c
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryReportRepository struct {
+	mu      sync.Mutex
+	reports map[primitive.ObjectID]*models.Report
+	order   []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryReportRepository returns an empty in-memory ReportRepository
+func NewMemoryReportRepository() ReportRepository {
+	return &memoryReportRepository{reports: map[primitive.ObjectID]*models.Report{}}
+}
+
+func (r *memoryReportRepository) Create(ctx context.Context, report *models.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if report.ID.IsZero() {
+		report.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.reports[report.ID]; exists {
+		return fmt.Errorf("report %s already exists", report.ID.Hex())
+	}
+	r.reports[report.ID] = copyReport(report)
+	r.order = append(r.order, report.ID)
+	return nil
+}
+
+func (r *memoryReportRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report, ok := r.reports[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyReport(report), nil
+}
+
+func (r *memoryReportRepository) FindPage(ctx context.Context, filter ReportFilter, page Page) ([]models.Report, bool, error) {
+	r.mu.Lock()
+	var reports []models.Report
+	for _, id := range r.order {
+		report := r.reports[id]
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, report.Status) {
+			continue
+		}
+		if !filter.ReporterID.IsZero() && report.ReporterID != filter.ReporterID {
+			continue
+		}
+		if !filter.TargetID.IsZero() && report.TargetID != filter.TargetID {
+			continue
+		}
+		if !filter.SubjectID.IsZero() && report.SubjectID != filter.SubjectID {
+			continue
+		}
+		reports = append(reports, *copyReport(report))
+	}
+	r.mu.Unlock()
+
+	reports, more := Paginate(reports, page, func(report models.Report) (float64, primitive.ObjectID) {
+		return float64(report.CreatedAt.UnixMilli()), report.ID
+	})
+	return reports, more, nil
+}
+
+func (r *memoryReportRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.ReportStatus, adminID primitive.ObjectID, resolution string, at time.Time) (*models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report, ok := r.reports[id]
+	if !ok || report.Status != from {
+		return nil, ErrConflict
+	}
+	report.Status = to
+	report.UpdatedAt = at
+	report.AssigneeID = &adminID
+	if to == models.ReportResolved {
+		report.Resolution = resolution
+		report.ResolvedAt = &at
+	}
+	return copyReport(report), nil
+}
+
+func (r *memoryReportRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.reports[id]; !ok {
+		return nil
+	}
+	delete(r.reports, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// copyReport returns a copy that shares no pointers with the stored report
+func copyReport(report *models.Report) *models.Report {
+	c := *report
+	c.ResolvedAt = copyTime(report.ResolvedAt)
+	if report.AssigneeID != nil {
+		assigneeID := *report.AssigneeID
+		c.AssigneeID = &assigneeID
+	}
+	return &c
+}
+
+type memoryModerationLogRepository struct {
+	mu      sync.Mutex
+	entries []models.ModerationAction // creation order
+}
+
+// NewMemoryModerationLogRepository returns an empty in-memory ModerationLogRepository
+func NewMemoryModerationLogRepository() ModerationLogRepository {
+	return &memoryModerationLogRepository{}
+}
+
+func (r *memoryModerationLogRepository) Create(ctx context.Context, action *models.ModerationAction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if action.ID.IsZero() {
+		action.ID = primitive.NewObjectID()
+	}
+	r.entries = append(r.entries, *copyModerationAction(action))
+	return nil
+}
+
+func (r *memoryModerationLogRepository) FindPage(ctx context.Context, filter ModerationFilter, page Page) ([]models.ModerationAction, bool, error) {
+	r.mu.Lock()
+	var entries []models.ModerationAction
+	for i := range r.entries {
+		entry := &r.entries[i]
+		if !filter.ReportID.IsZero() && entry.ReportID != filter.ReportID {
+			continue
+		}
+		if !filter.SubjectID.IsZero() && entry.SubjectID != filter.SubjectID {
+			continue
+		}
+		if !filter.AdminID.IsZero() && entry.AdminID != filter.AdminID {
+			continue
+		}
+		entries = append(entries, *copyModerationAction(entry))
+	}
+	r.mu.Unlock()
+
+	entries, more := Paginate(entries, page, func(entry models.ModerationAction) (float64, primitive.ObjectID) {
+		return float64(entry.CreatedAt.UnixMilli()), entry.ID
+	})
+	return entries, more, nil
+}
+
+// copyModerationAction returns a copy that shares no pointers with the stored entry
+func copyModerationAction(action *models.ModerationAction) *models.ModerationAction {
+	c := *action
+	c.SuspendedUntil = copyTime(action.SuspendedUntil)
+	if action.RideID != nil {
+		rideID := *action.RideID
+		c.RideID = &rideID
+	}
+	return &c
+}
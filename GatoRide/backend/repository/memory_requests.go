@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryRideRequestRepository struct {
+	mu       sync.Mutex
+	requests map[primitive.ObjectID]*models.RideRequest
+	order    []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryRideRequestRepository returns an empty in-memory RideRequestRepository
+func NewMemoryRideRequestRepository() RideRequestRepository {
+	return &memoryRideRequestRepository{requests: map[primitive.ObjectID]*models.RideRequest{}}
+}
+
+func (r *memoryRideRequestRepository) Create(ctx context.Context, request *models.RideRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if request.ID.IsZero() {
+		request.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.requests[request.ID]; exists {
+		return fmt.Errorf("ride request %s already exists", request.ID.Hex())
+	}
+	r.requests[request.ID] = copyRideRequest(request)
+	r.order = append(r.order, request.ID)
+	return nil
+}
+
+func (r *memoryRideRequestRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	request, ok := r.requests[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyRideRequest(request), nil
+}
+
+func (r *memoryRideRequestRepository) Find(ctx context.Context, filter RideRequestFilter) ([]models.RideRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var requests []models.RideRequest
+	for _, id := range r.order {
+		if request := r.requests[id]; rideRequestMatches(request, filter) {
+			requests = append(requests, *copyRideRequest(request))
+		}
+	}
+	return requests, nil
+}
+
+func (r *memoryRideRequestRepository) FindNear(ctx context.Context, point models.Location, maxDistance float64, filter RideRequestFilter) ([]RideRequestDistance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []RideRequestDistance
+	for _, id := range r.order {
+		request := r.requests[id]
+		if !rideRequestMatches(request, filter) {
+			continue
+		}
+		if distance := models.DistanceMeters(point, request.Pickup); distance <= maxDistance {
+			results = append(results, RideRequestDistance{Request: *copyRideRequest(request), DistanceMeters: distance})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].DistanceMeters < results[j].DistanceMeters
+	})
+	return results, nil
+}
+
+func (r *memoryRideRequestRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.RideRequestStatus, at time.Time) (*models.RideRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	request, ok := r.requests[id]
+	if !ok || request.Status != from || from == to {
+		return nil, ErrConflict
+	}
+	request.Status = to
+	request.ResolvedAt = &at
+	return copyRideRequest(request), nil
+}
+
+func (r *memoryRideRequestRepository) Fulfil(ctx context.Context, id, rideID, bookingID primitive.ObjectID, at time.Time) (*models.RideRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	request, ok := r.requests[id]
+	if !ok || request.Status != models.RequestOpen {
+		return nil, ErrConflict
+	}
+	request.Status = models.RequestFulfilled
+	request.ResolvedAt = &at
+	request.RideID = &rideID
+	request.BookingID = &bookingID
+	return copyRideRequest(request), nil
+}
+
+func (r *memoryRideRequestRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.requests[id]; !ok {
+		return nil
+	}
+	delete(r.requests, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// rideRequestMatches applies a RideRequestFilter the same way rideRequestFilterToBSON does in MongoDB
+func rideRequestMatches(request *models.RideRequest, f RideRequestFilter) bool {
+	if !f.PassengerID.IsZero() && request.PassengerID != f.PassengerID {
+		return false
+	}
+	if !f.WindowEndsBefore.IsZero() && !request.DepartureTo.Before(f.WindowEndsBefore) {
+		return false
+	}
+	if !f.WindowEndsAfter.IsZero() && !request.DepartureTo.After(f.WindowEndsAfter) {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		for _, status := range f.Statuses {
+			if request.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// copyRideRequest returns a copy that shares no pointers with the stored request
+func copyRideRequest(request *models.RideRequest) *models.RideRequest {
+	c := *request
+	c.ResolvedAt = copyTime(request.ResolvedAt)
+	if request.RideID != nil {
+		rideID := *request.RideID
+		c.RideID = &rideID
+	}
+	if request.BookingID != nil {
+		bookingID := *request.BookingID
+		c.BookingID = &bookingID
+	}
+	return &c
+}
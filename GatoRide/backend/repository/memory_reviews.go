@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryReviewRepository struct {
+	mu      sync.Mutex
+	reviews map[primitive.ObjectID]*models.Review
+	order   []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryReviewRepository returns an empty in-memory ReviewRepository
+func NewMemoryReviewRepository() ReviewRepository {
+	return &memoryReviewRepository{reviews: map[primitive.ObjectID]*models.Review{}}
+}
+
+func (r *memoryReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if review.ID.IsZero() {
+		review.ID = primitive.NewObjectID()
+	}
+	for _, existing := range r.reviews {
+		if existing.ID == review.ID || (existing.RideID == review.RideID && existing.ReviewerID == review.ReviewerID && existing.RevieweeID == review.RevieweeID) {
+			return ErrConflict
+		}
+	}
+	r.reviews[review.ID] = copyReview(review)
+	r.order = append(r.order, review.ID)
+	return nil
+}
+
+func (r *memoryReviewRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Review, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	review, ok := r.reviews[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyReview(review), nil
+}
+
+func (r *memoryReviewRepository) FindPage(ctx context.Context, filter ReviewFilter, page Page) ([]models.Review, bool, error) {
+	r.mu.Lock()
+	var reviews []models.Review
+	for _, id := range r.order {
+		review := r.reviews[id]
+		if !filter.RideID.IsZero() && review.RideID != filter.RideID {
+			continue
+		}
+		if !filter.ReviewerID.IsZero() && review.ReviewerID != filter.ReviewerID {
+			continue
+		}
+		if !filter.RevieweeID.IsZero() && review.RevieweeID != filter.RevieweeID {
+			continue
+		}
+		if filter.Revealed != nil && (review.RevealedAt != nil) != *filter.Revealed {
+			continue
+		}
+		if !filter.RevealBefore.IsZero() && (review.RevealedAt != nil || !review.RevealAt.Before(filter.RevealBefore)) {
+			continue
+		}
+		reviews = append(reviews, *copyReview(review))
+	}
+	r.mu.Unlock()
+
+	reviews, more := Paginate(reviews, page, func(review models.Review) (float64, primitive.ObjectID) {
+		return float64(review.CreatedAt.UnixMilli()), review.ID
+	})
+	return reviews, more, nil
+}
+
+func (r *memoryReviewRepository) Reveal(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.Review, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	review, ok := r.reviews[id]
+	if !ok || review.RevealedAt != nil {
+		return nil, ErrConflict
+	}
+	review.RevealedAt = &at
+	return copyReview(review), nil
+}
+
+func (r *memoryReviewRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.reviews[id]; !ok {
+		return nil
+	}
+	delete(r.reviews, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// copyReview returns a copy that shares no pointers with the stored review
+func copyReview(review *models.Review) *models.Review {
+	c := *review
+	c.RevealedAt = copyTime(review.RevealedAt)
+	return &c
+}
@@ -0,0 +1,456 @@
+package repository
+
+import (
+	"backend/lifecycle"
+	"backend/models"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryRideRepository keeps rides in process memory. Every method holds the
+// mutex for its whole read-check-write, which gives conditional updates the same
+// atomicity a single MongoDB document update has.
+type memoryRideRepository struct {
+	mu    sync.Mutex
+	rides map[primitive.ObjectID]*models.Ride
+	order []primitive.ObjectID // insertion order, so results are stable like a collection scan
+}
+
+// NewMemoryRideRepository returns an empty in-memory RideRepository
+func NewMemoryRideRepository() RideRepository {
+	return &memoryRideRepository{rides: map[primitive.ObjectID]*models.Ride{}}
+}
+
+func (r *memoryRideRepository) Create(ctx context.Context, ride *models.Ride) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ride.ID.IsZero() {
+		ride.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.rides[ride.ID]; exists {
+		return fmt.Errorf("ride %s already exists", ride.ID.Hex())
+	}
+	r.rides[ride.ID] = copyRide(ride)
+	r.order = append(r.order, ride.ID)
+	return nil
+}
+
+func (r *memoryRideRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyRide(ride), nil
+}
+
+func (r *memoryRideRepository) Find(ctx context.Context, filter RideFilter) ([]models.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rides []models.Ride
+	for _, id := range r.order {
+		if ride := r.rides[id]; rideMatches(ride, filter) {
+			rides = append(rides, *copyRide(ride))
+		}
+	}
+	return rides, nil
+}
+
+func (r *memoryRideRepository) FindPage(ctx context.Context, filter RideFilter, page Page) ([]models.Ride, bool, error) {
+	rides, err := r.Find(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	rides, more := Paginate(rides, page, func(ride models.Ride) (float64, primitive.ObjectID) {
+		return RideSortValue(&ride, page.Sort), ride.ID
+	})
+	return rides, more, nil
+}
+
+func (r *memoryRideRepository) FindNear(ctx context.Context, near NearQuery, filter RideFilter) ([]RideDistance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []RideDistance
+	for _, id := range r.order {
+		ride := r.rides[id]
+		if !rideMatches(ride, filter) {
+			continue
+		}
+		if distance := distanceFrom(ride, near); distance <= near.MaxDistanceMeters {
+			results = append(results, RideDistance{Ride: *copyRide(ride), DistanceMeters: distance})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].DistanceMeters < results[j].DistanceMeters
+	})
+	return results, nil
+}
+
+func (r *memoryRideRepository) FindOpenDuplicate(ctx context.Context, ride *models.Ride) (*models.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.order {
+		existing := r.rides[id]
+		if existing.DriverID == ride.DriverID &&
+			existing.Status == models.StatusOpen &&
+			existing.Pickup.Latitude == ride.Pickup.Latitude &&
+			existing.Pickup.Longitude == ride.Pickup.Longitude &&
+			existing.Dropoff.Latitude == ride.Dropoff.Latitude &&
+			existing.Dropoff.Longitude == ride.Dropoff.Longitude &&
+			existing.Date.Equal(ride.Date) {
+			return copyRide(existing), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRideRepository) ReserveSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[rideID]
+	if !ok || ride.Status != models.StatusOpen || ride.FreeSeats(segment) < seats || ride.FreeLuggage < bags || hasPassenger(ride, userID) {
+		return nil, ErrConflict
+	}
+
+	ride.PassengerIDs = append(ride.PassengerIDs, userID)
+	ride.FreeLuggage -= bags
+	adjustSegmentSeats(ride, segment, -seats)
+	return copyRide(ride), nil
+}
+
+func (r *memoryRideRepository) ReleaseSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[rideID]
+	if !ok || (ride.Status != models.StatusOpen && ride.Status != models.StatusBooked) || !hasPassenger(ride, userID) || !ride.ValidSegment(segment) {
+		return nil, ErrConflict
+	}
+
+	passengers := ride.PassengerIDs[:0]
+	for _, passengerID := range ride.PassengerIDs {
+		if passengerID != userID {
+			passengers = append(passengers, passengerID)
+		}
+	}
+	ride.PassengerIDs = passengers
+	ride.FreeLuggage += bags
+	adjustSegmentSeats(ride, segment, seats)
+	return copyRide(ride), nil
+}
+
+func (r *memoryRideRepository) AdjustWaitlist(ctx context.Context, rideID primitive.ObjectID, delta int) (*models.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[rideID]
+	if !ok || (ride.Status != models.StatusOpen && ride.Status != models.StatusBooked) || ride.WaitlistCount+delta < 0 {
+		return nil, ErrConflict
+	}
+	ride.WaitlistCount += delta
+	adjustSegmentSeats(ride, ride.FullRoute(), 0)
+	return copyRide(ride), nil
+}
+
+func (r *memoryRideRepository) ClaimWaitlistSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[rideID]
+	if !ok || (ride.Status != models.StatusOpen && ride.Status != models.StatusBooked) ||
+		ride.WaitlistCount <= 0 || ride.FreeSeats(segment) < seats || ride.FreeLuggage < bags || hasPassenger(ride, userID) {
+		return nil, ErrConflict
+	}
+
+	ride.PassengerIDs = append(ride.PassengerIDs, userID)
+	ride.WaitlistCount--
+	ride.FreeLuggage -= bags
+	adjustSegmentSeats(ride, segment, -seats)
+	return copyRide(ride), nil
+}
+
+func (r *memoryRideRepository) UpdateStatus(ctx context.Context, rideID primitive.ObjectID, from, to models.RideStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Mirrors MongoDB's ModifiedCount: setting a status to its current value is not a change
+	ride, ok := r.rides[rideID]
+	if !ok || ride.Status != from || from == to {
+		return ErrConflict
+	}
+	ride.Status = to
+	return nil
+}
+
+func (r *memoryRideRepository) Cancel(ctx context.Context, rideID primitive.ObjectID, from models.RideStatus, cancellation models.RideCancellation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[rideID]
+	if !ok || ride.Status != from || from == models.StatusCancelled {
+		return ErrConflict
+	}
+	ride.Status = models.StatusCancelled
+	ride.Cancellation = &cancellation
+	ride.PassengerIDs = nil
+	return nil
+}
+
+func (r *memoryRideRepository) Complete(ctx context.Context, rideID primitive.ObjectID, from models.RideStatus, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[rideID]
+	if !ok || ride.Status != from || from == models.StatusCompleted {
+		return ErrConflict
+	}
+	ride.Status = models.StatusCompleted
+	ride.CompletedAt = &at
+	return nil
+}
+
+func (r *memoryRideRepository) Update(ctx context.Context, id primitive.ObjectID, update RideUpdate) (*models.Ride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[id]
+	if !ok || (ride.Status != models.StatusOpen && ride.Status != models.StatusBooked) {
+		return nil, ErrConflict
+	}
+	if update.Seats != nil && len(ride.PassengerIDs) > 0 {
+		return nil, ErrConflict
+	}
+	if update.AddSeats != 0 && ride.FreeSeats(ride.FullRoute())+update.AddSeats < 0 {
+		return nil, ErrConflict
+	}
+
+	if update.Pickup != nil {
+		ride.Pickup = *update.Pickup
+	}
+	if update.Dropoff != nil {
+		ride.Dropoff = *update.Dropoff
+	}
+	if update.Price != nil {
+		ride.Price = *update.Price
+	}
+	if update.Seats != nil {
+		ride.Seats = *update.Seats
+		ride.SegmentSeats = nil // every segment now has Seats free
+	}
+	if update.Date != nil {
+		ride.Date = *update.Date
+	}
+	if update.Detached != nil {
+		ride.Detached = *update.Detached
+	}
+	if update.Notes != nil {
+		ride.Notes = *update.Notes
+	}
+	if update.AddSeats != 0 {
+		adjustSegmentSeats(ride, ride.FullRoute(), update.AddSeats)
+	}
+	if update.UpdatedAt != nil {
+		ride.UpdatedAt = copyTime(update.UpdatedAt)
+	}
+	if update.Path != nil {
+		ride.Path = copyPath(update.Path)
+		if len(ride.Path.Points) == 0 {
+			ride.Path = nil
+		}
+	}
+	return copyRide(ride), nil
+}
+
+func (r *memoryRideRepository) DeleteUnbooked(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[id]
+	if !ok || ride.Status != models.StatusOpen || len(ride.PassengerIDs) > 0 {
+		return ErrConflict
+	}
+	r.remove(id)
+	return nil
+}
+
+func (r *memoryRideRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rides[id]; ok {
+		r.remove(id)
+	}
+	return nil
+}
+
+// remove deletes a stored ride; the caller holds the mutex
+func (r *memoryRideRepository) remove(id primitive.ObjectID) {
+	delete(r.rides, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// rideMatches applies a RideFilter the same way rideFilterToBSON does in MongoDB
+func rideMatches(ride *models.Ride, f RideFilter) bool {
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, status := range f.Statuses {
+			if ride.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.DriverID.IsZero() && ride.DriverID != f.DriverID {
+		return false
+	}
+	if slices.Contains(f.ExcludeDriverIDs, ride.DriverID) {
+		return false
+	}
+	if !f.PassengerID.IsZero() && !hasPassenger(ride, f.PassengerID) {
+		return false
+	}
+	if f.MinSeats > 0 && ride.Seats < f.MinSeats {
+		return false
+	}
+	if (f.MinPrice > 0 && ride.Price < f.MinPrice) || (f.MaxPrice > 0 && ride.Price > f.MaxPrice) {
+		return false
+	}
+	if !inTimeRange(ride.Date, f.DateFrom, f.DateTo) || !inTimeRange(ride.CreatedAt, f.CreatedFrom, f.CreatedTo) {
+		return false
+	}
+	if !f.SeriesID.IsZero() && (ride.SeriesID == nil || *ride.SeriesID != f.SeriesID) {
+		return false
+	}
+	if f.MinFreeLuggage > 0 && ride.FreeLuggage < f.MinFreeLuggage {
+		return false
+	}
+	attributes := ride.Attributes
+	if (f.PetsAllowed != nil && attributes.PetsAllowed != *f.PetsAllowed) ||
+		(f.SmokingAllowed != nil && attributes.SmokingAllowed != *f.SmokingAllowed) ||
+		(f.AirConditioning != nil && attributes.AirConditioning != *f.AirConditioning) {
+		return false
+	}
+	if f.Music != "" && attributes.Music != f.Music {
+		return false
+	}
+	return true
+}
+
+// distanceFrom measures from the query point to the stops of the ride it names, like $geoNear
+func distanceFrom(ride *models.Ride, near NearQuery) float64 {
+	switch near.Field {
+	case NearPickup:
+		return models.DistanceMeters(near.Point, ride.Pickup)
+	case NearDropoff:
+		return models.DistanceMeters(near.Point, ride.Dropoff)
+	}
+	closest := math.Inf(1)
+	for _, stop := range ride.Stops() {
+		closest = math.Min(closest, models.DistanceMeters(near.Point, stop))
+	}
+	if ride.Path.Usable() {
+		closest = math.Min(closest, models.ProjectOntoPath(near.Point, ride.Path.Points).Distance)
+	}
+	return closest
+}
+
+// adjustSegmentSeats adds delta free seats to every segment in the range, then refreshes
+// the whole-route seat count and the status the same way the MongoDB pipelines do
+func adjustSegmentSeats(ride *models.Ride, segment models.Segment, delta int) {
+	bySegment := ride.FreeSeatsBySegment()
+	for i := segment.From; i < segment.To; i++ {
+		bySegment[i] += delta
+	}
+	ride.SegmentSeats = bySegment
+
+	fewest, most := bySegment[0], bySegment[0]
+	for _, seats := range bySegment {
+		fewest = min(fewest, seats)
+		most = max(most, seats)
+	}
+	ride.Seats = fewest
+	ride.Status = lifecycle.AfterSeatChange(ride.Status, most, ride.WaitlistCount)
+}
+
+func inTimeRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
+
+func hasPassenger(ride *models.Ride, userID primitive.ObjectID) bool {
+	for _, passengerID := range ride.PassengerIDs {
+		if passengerID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// copyRide returns a copy that shares no slices with the stored ride
+func copyRide(ride *models.Ride) *models.Ride {
+	c := *ride
+	if ride.PassengerIDs != nil {
+		c.PassengerIDs = append([]primitive.ObjectID{}, ride.PassengerIDs...)
+	}
+	c.Waypoints = append([]models.Location(nil), ride.Waypoints...)
+	c.SegmentSeats = append([]int(nil), ride.SegmentSeats...)
+	if ride.InstantBook != nil {
+		instantBook := *ride.InstantBook
+		c.InstantBook = &instantBook
+	}
+	if ride.SeriesID != nil {
+		seriesID := *ride.SeriesID
+		c.SeriesID = &seriesID
+	}
+	c.UpdatedAt = copyTime(ride.UpdatedAt)
+	c.CompletedAt = copyTime(ride.CompletedAt)
+	c.Path = copyPath(ride.Path)
+	if ride.Cancellation != nil {
+		cancellation := *ride.Cancellation
+		c.Cancellation = &cancellation
+	}
+	if ride.VehicleID != nil {
+		vehicleID := *ride.VehicleID
+		c.VehicleID = &vehicleID
+	}
+	if ride.Vehicle != nil {
+		c.Vehicle = &copyVehicle(&models.Vehicle{VehicleDetails: *ride.Vehicle}).VehicleDetails
+	}
+	return &c
+}
+
+func copyPath(path *models.RoutePath) *models.RoutePath {
+	if path == nil {
+		return nil
+	}
+	c := *path
+	c.Points = append([]models.Point(nil), path.Points...)
+	return &c
+}
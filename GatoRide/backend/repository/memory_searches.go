@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memorySavedSearchRepository struct {
+	mu       sync.Mutex
+	searches map[primitive.ObjectID]*models.SavedSearch
+	order    []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemorySavedSearchRepository returns an empty in-memory SavedSearchRepository
+func NewMemorySavedSearchRepository() SavedSearchRepository {
+	return &memorySavedSearchRepository{searches: map[primitive.ObjectID]*models.SavedSearch{}}
+}
+
+func (r *memorySavedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if search.ID.IsZero() {
+		search.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.searches[search.ID]; exists {
+		return fmt.Errorf("saved search %s already exists", search.ID.Hex())
+	}
+	r.searches[search.ID] = copySavedSearch(search)
+	r.order = append(r.order, search.ID)
+	return nil
+}
+
+func (r *memorySavedSearchRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.SavedSearch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	search, ok := r.searches[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copySavedSearch(search), nil
+}
+
+func (r *memorySavedSearchRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.SavedSearch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var searches []models.SavedSearch
+	for _, id := range r.order {
+		if search := r.searches[id]; search.UserID == userID {
+			searches = append(searches, *copySavedSearch(search))
+		}
+	}
+	return searches, nil
+}
+
+func (r *memorySavedSearchRepository) FindCandidates(ctx context.Context, departure time.Time, seats int, points []models.Location, maxDistance float64) ([]models.SavedSearch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var searches []models.SavedSearch
+	for _, id := range r.order {
+		search := r.searches[id]
+		if departure.Before(search.DepartureFrom) || departure.After(search.DepartureTo) || search.Seats > seats {
+			continue
+		}
+		for _, point := range points {
+			if models.DistanceMeters(point, search.From) <= maxDistance {
+				searches = append(searches, *copySavedSearch(search))
+				break
+			}
+		}
+	}
+	return searches, nil
+}
+
+func (r *memorySavedSearchRepository) ClaimAlert(ctx context.Context, id primitive.ObjectID, notBefore, at time.Time) (*models.SavedSearch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	search, ok := r.searches[id]
+	if !ok || (search.LastAlertAt != nil && search.LastAlertAt.After(notBefore)) {
+		return nil, ErrConflict
+	}
+	search.LastAlertAt = &at
+	search.AlertCount++
+	return copySavedSearch(search), nil
+}
+
+func (r *memorySavedSearchRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.searches[id]; !ok {
+		return nil
+	}
+	delete(r.searches, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// copySavedSearch returns a copy that shares no pointers or slices with the stored search
+func copySavedSearch(search *models.SavedSearch) *models.SavedSearch {
+	c := *search
+	c.Channels = append([]string(nil), search.Channels...)
+	c.LastAlertAt = copyTime(search.LastAlertAt)
+	return &c
+}
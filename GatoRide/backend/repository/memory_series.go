@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryRideSeriesRepository struct {
+	mu     sync.Mutex
+	series map[primitive.ObjectID]*models.RideSeries
+	order  []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryRideSeriesRepository returns an empty in-memory RideSeriesRepository
+func NewMemoryRideSeriesRepository() RideSeriesRepository {
+	return &memoryRideSeriesRepository{series: map[primitive.ObjectID]*models.RideSeries{}}
+}
+
+func (r *memoryRideSeriesRepository) Create(ctx context.Context, series *models.RideSeries) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if series.ID.IsZero() {
+		series.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.series[series.ID]; exists {
+		return fmt.Errorf("ride series %s already exists", series.ID.Hex())
+	}
+	r.series[series.ID] = copySeries(series)
+	r.order = append(r.order, series.ID)
+	return nil
+}
+
+func (r *memoryRideSeriesRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideSeries, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	series, ok := r.series[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copySeries(series), nil
+}
+
+func (r *memoryRideSeriesRepository) Find(ctx context.Context, filter SeriesFilter) ([]models.RideSeries, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found []models.RideSeries
+	for _, id := range r.order {
+		series := r.series[id]
+		if !filter.DriverID.IsZero() && series.DriverID != filter.DriverID {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !containsSeriesStatus(filter.Statuses, series.Status) {
+			continue
+		}
+		found = append(found, *copySeries(series))
+	}
+	return found, nil
+}
+
+func (r *memoryRideSeriesRepository) Replace(ctx context.Context, series *models.RideSeries) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.series[series.ID]; !ok {
+		return ErrNotFound
+	}
+	r.series[series.ID] = copySeries(series)
+	return nil
+}
+
+func (r *memoryRideSeriesRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.series[id]; !ok {
+		return nil
+	}
+	delete(r.series, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func containsSeriesStatus(statuses []models.SeriesStatus, status models.SeriesStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// copySeries returns a copy that shares no slices with the stored series
+func copySeries(series *models.RideSeries) *models.RideSeries {
+	c := *series
+	c.Recurrence.Weekdays = append([]string(nil), series.Recurrence.Weekdays...)
+	c.Recurrence.ExceptionDates = append([]string(nil), series.Recurrence.ExceptionDates...)
+	c.Path = copyPath(series.Path)
+	if series.HeldSince != nil {
+		heldSince := *series.HeldSince
+		c.HeldSince = &heldSince
+	}
+	if series.VehicleID != nil {
+		vehicleID := *series.VehicleID
+		c.VehicleID = &vehicleID
+	}
+	if series.Vehicle != nil {
+		c.Vehicle = &copyVehicle(&models.Vehicle{VehicleDetails: *series.Vehicle}).VehicleDetails
+	}
+	return &c
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"sync"
+)
+
+type memorySessionRepository struct {
+	mu       sync.Mutex
+	sessions []models.Session
+}
+
+// NewMemorySessionRepository returns an empty in-memory SessionRepository
+func NewMemorySessionRepository() SessionRepository {
+	return &memorySessionRepository{}
+}
+
+func (r *memorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions = append(r.sessions, *session)
+	return nil
+}
+
+func (r *memorySessionRepository) FindByToken(ctx context.Context, token string) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.Token == token {
+			found := session
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memorySessionRepository) DeleteByToken(ctx context.Context, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, session := range r.sessions {
+		if session.Token == token {
+			r.sessions = append(r.sessions[:i], r.sessions[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memorySessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.sessions[:0]
+	for _, session := range r.sessions {
+		if session.UserID != userID {
+			kept = append(kept, session)
+		}
+	}
+	r.sessions = kept
+	return nil
+}
@@ -0,0 +1,24 @@
+package repository
+
+// NewMemoryStore returns a Store whose repositories live in process memory.
+// Nothing is persisted; it is meant for tests and for running the server locally without MongoDB.
+func NewMemoryStore() Store {
+	return Store{
+		Rides:         NewMemoryRideRepository(),
+		Series:        NewMemoryRideSeriesRepository(),
+		Bookings:      NewMemoryBookingRepository(),
+		Waitlist:      NewMemoryWaitlistRepository(),
+		Requests:      NewMemoryRideRequestRepository(),
+		Offers:        NewMemoryRideOfferRepository(),
+		Searches:      NewMemorySavedSearchRepository(),
+		Notifications: NewMemoryNotificationRepository(),
+		Vehicles:      NewMemoryVehicleRepository(),
+		Drivers:       NewMemoryDriverVerificationRepository(),
+		Reviews:       NewMemoryReviewRepository(),
+		Blocks:        NewMemoryBlockRepository(),
+		Reports:       NewMemoryReportRepository(),
+		ModerationLog: NewMemoryModerationLogRepository(),
+		Users:         NewMemoryUserRepository(),
+		Sessions:      NewMemorySessionRepository(),
+	}
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUserRepository struct {
+	mu    sync.Mutex
+	users map[primitive.ObjectID]*models.User
+	order []primitive.ObjectID
+}
+
+// NewMemoryUserRepository returns an empty in-memory UserRepository
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{users: map[primitive.ObjectID]*models.User{}}
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.users[user.ID]; exists {
+		return fmt.Errorf("user %s already exists", user.ID.Hex())
+	}
+	r.users[user.ID] = copyUser(user)
+	r.order = append(r.order, user.ID)
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findFirst(func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *memoryUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findFirst(func(u *models.User) bool { return u.Username == username })
+}
+
+func (r *memoryUserRepository) MarkVerified(ctx context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.order {
+		if user := r.users[id]; user.Email == email {
+			user.IsVerified = true
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, id primitive.ObjectID, update UserUpdate) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if update.Name != nil {
+		user.Name = *update.Name
+	}
+	if update.Username != nil {
+		user.Username = *update.Username
+	}
+	if update.Location != nil {
+		user.Location = *update.Location
+	}
+	return copyUser(user), nil
+}
+
+func (r *memoryUserRepository) RecordCancellation(ctx context.Context, id primitive.ObjectID, record CancellationRecord) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	reliability := &user.Reliability
+	reliability.Cancellations++
+	reliability.Penalties += record.Penalty
+
+	var recent []time.Time
+	for _, at := range reliability.RecentLate {
+		if !at.Before(record.Since) {
+			recent = append(recent, at)
+		}
+	}
+	if record.Late {
+		reliability.LateCancellations++
+		recent = append(recent, record.At)
+	}
+	reliability.RecentLate = recent
+	return copyUser(user), nil
+}
+
+func (r *memoryUserRepository) Restrict(ctx context.Context, id primitive.ObjectID, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if current := user.Reliability.RestrictedUntil; current == nil || current.Before(until) {
+		user.Reliability.RestrictedUntil = &until
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) AddRating(ctx context.Context, id primitive.ObjectID, stars int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Rating.Stars += stars
+	user.Rating.Count++
+	return nil
+}
+
+func (r *memoryUserRepository) Warn(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Moderation.Warnings++
+	return nil
+}
+
+func (r *memoryUserRepository) Suspend(ctx context.Context, id primitive.ObjectID, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Moderation.SuspendedUntil = &until
+	return nil
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return nil
+	}
+	delete(r.users, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) findFirst(match func(*models.User) bool) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.order {
+		if user := r.users[id]; match(user) {
+			return copyUser(user), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func copyUser(user *models.User) *models.User {
+	copied := *user
+	copied.Reliability.RecentLate = append([]time.Time(nil), user.Reliability.RecentLate...)
+	copied.Reliability.RestrictedUntil = copyTime(user.Reliability.RestrictedUntil)
+	copied.Moderation.SuspendedUntil = copyTime(user.Moderation.SuspendedUntil)
+	return &copied
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryVehicleRepository struct {
+	mu       sync.Mutex
+	vehicles map[primitive.ObjectID]*models.Vehicle
+	order    []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryVehicleRepository returns an empty in-memory VehicleRepository
+func NewMemoryVehicleRepository() VehicleRepository {
+	return &memoryVehicleRepository{vehicles: map[primitive.ObjectID]*models.Vehicle{}}
+}
+
+func (r *memoryVehicleRepository) Create(ctx context.Context, vehicle *models.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if vehicle.ID.IsZero() {
+		vehicle.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.vehicles[vehicle.ID]; exists {
+		return fmt.Errorf("vehicle %s already exists", vehicle.ID.Hex())
+	}
+	r.vehicles[vehicle.ID] = copyVehicle(vehicle)
+	r.order = append(r.order, vehicle.ID)
+	return nil
+}
+
+func (r *memoryVehicleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Vehicle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vehicle, ok := r.vehicles[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyVehicle(vehicle), nil
+}
+
+func (r *memoryVehicleRepository) FindByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Vehicle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var vehicles []models.Vehicle
+	for _, id := range r.order {
+		if vehicle := r.vehicles[id]; vehicle.OwnerID == ownerID {
+			vehicles = append(vehicles, *copyVehicle(vehicle))
+		}
+	}
+	return vehicles, nil
+}
+
+func (r *memoryVehicleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.vehicles[id]; !ok {
+		return nil
+	}
+	delete(r.vehicles, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// copyVehicle returns a copy that shares no pointers with the stored vehicle
+func copyVehicle(vehicle *models.Vehicle) *models.Vehicle {
+	c := *vehicle
+	if vehicle.FuelEconomy != nil {
+		economy := *vehicle.FuelEconomy
+		c.FuelEconomy = &economy
+	}
+	return &c
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryWaitlistRepository struct {
+	mu      sync.Mutex
+	entries map[primitive.ObjectID]*models.WaitlistEntry
+	order   []primitive.ObjectID // insertion order, which is also creation order
+}
+
+// NewMemoryWaitlistRepository returns an empty in-memory WaitlistRepository
+func NewMemoryWaitlistRepository() WaitlistRepository {
+	return &memoryWaitlistRepository{entries: map[primitive.ObjectID]*models.WaitlistEntry{}}
+}
+
+func (r *memoryWaitlistRepository) Create(ctx context.Context, entry *models.WaitlistEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.entries[entry.ID]; exists {
+		return fmt.Errorf("waitlist entry %s already exists", entry.ID.Hex())
+	}
+	r.entries[entry.ID] = copyWaitlistEntry(entry)
+	r.order = append(r.order, entry.ID)
+	return nil
+}
+
+func (r *memoryWaitlistRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.WaitlistEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyWaitlistEntry(entry), nil
+}
+
+func (r *memoryWaitlistRepository) Find(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var entries []models.WaitlistEntry
+	for _, id := range r.order {
+		if entry := r.entries[id]; waitlistEntryMatches(entry, filter) {
+			entries = append(entries, *copyWaitlistEntry(entry))
+		}
+	}
+	return entries, nil
+}
+
+func (r *memoryWaitlistRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.WaitlistStatus, at time.Time) (*models.WaitlistEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok || entry.Status != from || from == to {
+		return nil, ErrConflict
+	}
+	entry.Status = to
+	entry.ResolvedAt = &at
+	return copyWaitlistEntry(entry), nil
+}
+
+func (r *memoryWaitlistRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[id]; !ok {
+		return nil
+	}
+	delete(r.entries, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// waitlistEntryMatches applies a WaitlistFilter the same way waitlistFilterToBSON does in MongoDB
+func waitlistEntryMatches(entry *models.WaitlistEntry, f WaitlistFilter) bool {
+	if !f.RideID.IsZero() && entry.RideID != f.RideID {
+		return false
+	}
+	if !f.PassengerID.IsZero() && entry.PassengerID != f.PassengerID {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		for _, status := range f.Statuses {
+			if entry.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// copyWaitlistEntry returns a copy that shares no pointers with the stored entry
+func copyWaitlistEntry(entry *models.WaitlistEntry) *models.WaitlistEntry {
+	c := *entry
+	c.ResolvedAt = copyTime(entry.ResolvedAt)
+	return &c
+}
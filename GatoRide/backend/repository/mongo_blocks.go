@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoBlockRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoBlockRepository returns a BlockRepository backed by the given collection
+func NewMongoBlockRepository(collection *mongo.Collection) BlockRepository {
+	return &mongoBlockRepository{collection: collection}
+}
+
+func (r *mongoBlockRepository) Create(ctx context.Context, block *models.Block) error {
+	if block.ID.IsZero() {
+		block.ID = primitive.NewObjectID()
+	}
+	// The unique index from EnsureIndexes turns blocking the same user twice into a duplicate key
+	_, err := r.collection.InsertOne(ctx, block)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *mongoBlockRepository) IsBlocked(ctx context.Context, blockerID, blockedID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"blocker_id": blockerID, "blocked_id": blockedID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *mongoBlockRepository) FindByBlocker(ctx context.Context, blockerID primitive.ObjectID) ([]models.Block, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"blocker_id": blockerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var blocks []models.Block
+	if err := cursor.All(ctx, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (r *mongoBlockRepository) BlockedWith(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"blocker_id": userID},
+		bson.M{"blocked_id": userID},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var blocks []models.Block
+	if err := cursor.All(ctx, &blocks); err != nil {
+		return nil, err
+	}
+	users := make([]primitive.ObjectID, 0, len(blocks))
+	for _, block := range blocks {
+		if block.BlockerID == userID {
+			users = append(users, block.BlockedID)
+		} else {
+			users = append(users, block.BlockerID)
+		}
+	}
+	return users, nil
+}
+
+func (r *mongoBlockRepository) Delete(ctx context.Context, blockerID, blockedID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"blocker_id": blockerID, "blocked_id": blockedID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
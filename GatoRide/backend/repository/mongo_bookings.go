@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoBookingRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoBookingRepository returns a BookingRepository backed by the given collection
+func NewMongoBookingRepository(collection *mongo.Collection) BookingRepository {
+	return &mongoBookingRepository{collection: collection}
+}
+
+func (r *mongoBookingRepository) Create(ctx context.Context, booking *models.Booking) error {
+	if booking.ID.IsZero() {
+		booking.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, booking)
+	return err
+}
+
+func (r *mongoBookingRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Booking, error) {
+	var booking models.Booking
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&booking)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+func (r *mongoBookingRepository) Find(ctx context.Context, filter BookingFilter) ([]models.Booking, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bookingFilterToBSON(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bookings []models.Booking
+	if err := cursor.All(ctx, &bookings); err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+func (r *mongoBookingRepository) FindPage(ctx context.Context, filter BookingFilter, page Page) ([]models.Booking, bool, error) {
+	return findPage[models.Booking](ctx, r.collection, bookingFilterToBSON(filter), sortKey{field: "created_at", isDate: true}, page)
+}
+
+func (r *mongoBookingRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.BookingStatus, at time.Time) (*models.Booking, error) {
+	if from == to {
+		return nil, ErrConflict
+	}
+	timestampField := "responded_at"
+	if to == models.BookingCancelled || to == models.BookingCancelledByDriver {
+		timestampField = "cancelled_at"
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var booking models.Booking
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": from},
+		bson.M{"$set": bson.M{"status": to, timestampField: at}},
+		opts,
+	).Decode(&booking)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+func (r *mongoBookingRepository) MarkRideChanged(ctx context.Context, rideID primitive.ObjectID, at time.Time) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"ride_id": rideID, "status": bson.M{"$in": bson.A{models.BookingPending, models.BookingConfirmed}}},
+		bson.M{"$set": bson.M{"ride_changed_at": at}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *mongoBookingRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func bookingFilterToBSON(f BookingFilter) bson.M {
+	filter := bson.M{}
+	if !f.RideID.IsZero() {
+		filter["ride_id"] = f.RideID
+	}
+	if !f.PassengerID.IsZero() {
+		filter["passenger_id"] = f.PassengerID
+	}
+	if !f.ExpiresBefore.IsZero() {
+		filter["expires_at"] = bson.M{"$lt": f.ExpiresBefore}
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	return filter
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoDriverVerificationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoDriverVerificationRepository returns a DriverVerificationRepository backed by the given
+// collection, keyed by user
+func NewMongoDriverVerificationRepository(collection *mongo.Collection) DriverVerificationRepository {
+	return &mongoDriverVerificationRepository{collection: collection}
+}
+
+func (r *mongoDriverVerificationRepository) Save(ctx context.Context, verification *models.DriverVerification) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": verification.UserID}, verification, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *mongoDriverVerificationRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.DriverVerification, error) {
+	var verification models.DriverVerification
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&verification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+func (r *mongoDriverVerificationRepository) Find(ctx context.Context, filter DriverFilter) ([]models.DriverVerification, error) {
+	query := bson.M{}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	if !filter.ExpiresBefore.IsZero() {
+		query["$or"] = bson.A{
+			bson.M{"licence_expires_at": bson.M{"$lt": filter.ExpiresBefore}},
+			bson.M{"insurance_expires_at": bson.M{"$lt": filter.ExpiresBefore}},
+		}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "submitted_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var verifications []models.DriverVerification
+	if err := cursor.All(ctx, &verifications); err != nil {
+		return nil, err
+	}
+	return verifications, nil
+}
+
+func (r *mongoDriverVerificationRepository) Review(ctx context.Context, userID primitive.ObjectID, submittedAt time.Time, status models.DriverStatus, reviewerID primitive.ObjectID, reason string, at time.Time) (*models.DriverVerification, error) {
+	set := bson.M{"status": status, "reviewed_at": at, "reviewed_by": reviewerID}
+	update := bson.M{"$set": set}
+	if reason != "" {
+		set["rejection_reason"] = reason
+	} else {
+		update["$unset"] = bson.M{"rejection_reason": ""}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var verification models.DriverVerification
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID, "status": models.DriverPending, "submitted_at": submittedAt},
+		update, opts,
+	).Decode(&verification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+func (r *mongoDriverVerificationRepository) ClaimExpiryWarning(ctx context.Context, userID primitive.ObjectID, expiresAt time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "warned_for": bson.M{"$ne": expiresAt}},
+		bson.M{"$set": bson.M{"warned_for": expiresAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoDriverVerificationRepository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoNotificationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoNotificationRepository returns a NotificationRepository backed by the given collection
+func NewMongoNotificationRepository(collection *mongo.Collection) NotificationRepository {
+	return &mongoNotificationRepository{collection: collection}
+}
+
+func (r *mongoNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, notification)
+	return err
+}
+
+func (r *mongoNotificationRepository) FindPage(ctx context.Context, filter NotificationFilter, page Page) ([]models.Notification, bool, error) {
+	query := bson.M{}
+	if !filter.UserID.IsZero() {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Unread {
+		query["read_at"] = bson.M{"$exists": false}
+	}
+	return findPage[models.Notification](ctx, r.collection, query, sortKey{field: "created_at", isDate: true}, page)
+}
+
+func (r *mongoNotificationRepository) MarkRead(ctx context.Context, id, userID primitive.ObjectID, at time.Time) (*models.Notification, error) {
+	// $ifNull keeps the first read time when the notification is read again
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"read_at": bson.M{"$ifNull": bson.A{"$read_at", at}}}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var notification models.Notification
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "user_id": userID}, update, opts).Decode(&notification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+func (r *mongoNotificationRepository) MarkAllRead(ctx context.Context, userID primitive.ObjectID, at time.Time) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "read_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"read_at": at}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *mongoNotificationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
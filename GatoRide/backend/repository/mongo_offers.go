@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRideOfferRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRideOfferRepository returns a RideOfferRepository backed by the given collection
+func NewMongoRideOfferRepository(collection *mongo.Collection) RideOfferRepository {
+	return &mongoRideOfferRepository{collection: collection}
+}
+
+func (r *mongoRideOfferRepository) Create(ctx context.Context, offer *models.RideOffer) error {
+	if offer.ID.IsZero() {
+		offer.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, offer)
+	return err
+}
+
+func (r *mongoRideOfferRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideOffer, error) {
+	var offer models.RideOffer
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&offer)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func (r *mongoRideOfferRepository) Find(ctx context.Context, filter RideOfferFilter) ([]models.RideOffer, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, rideOfferFilterToBSON(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var offers []models.RideOffer
+	if err := cursor.All(ctx, &offers); err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+func (r *mongoRideOfferRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.OfferStatus, at time.Time) (*models.RideOffer, error) {
+	if from == to {
+		return nil, ErrConflict
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var offer models.RideOffer
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": from},
+		bson.M{"$set": bson.M{"status": to, "responded_at": at}},
+		opts,
+	).Decode(&offer)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func (r *mongoRideOfferRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func rideOfferFilterToBSON(f RideOfferFilter) bson.M {
+	filter := bson.M{}
+	if !f.RequestID.IsZero() {
+		filter["request_id"] = f.RequestID
+	}
+	if !f.DriverID.IsZero() {
+		filter["driver_id"] = f.DriverID
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	return filter
+}
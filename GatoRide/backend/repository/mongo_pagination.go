@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sortKey is the stored field a SortField orders by, and whether it holds a date
+type sortKey struct {
+	field  string
+	isDate bool
+}
+
+var rideSortKeys = map[SortField]sortKey{
+	SortDeparture: {field: "date", isDate: true},
+	SortPrice:     {field: "price"},
+	SortCreated:   {field: "created_at", isDate: true},
+}
+
+// findPage runs a keyset-paginated query: it sorts by the key and then _id, starts after the
+// page's cursor and reads one document past the limit to tell whether more follow
+func findPage[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, key sortKey, page Page) ([]T, bool, error) {
+	direction := 1
+	after := "$gt"
+	if page.Descending {
+		direction, after = -1, "$lt"
+	}
+
+	if page.After != nil {
+		var value interface{} = page.After.Value
+		if key.isDate {
+			value = time.UnixMilli(int64(page.After.Value))
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{key.field: bson.M{after: value}},
+			bson.M{key.field: value, "_id": bson.M{after: page.After.ID}},
+		}}}}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: key.field, Value: direction}, {Key: "_id", Value: direction}})
+	if page.Limit > 0 {
+		opts.SetLimit(int64(page.Limit) + 1)
+	}
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []T
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, false, err
+	}
+	if page.Limit > 0 && len(items) > page.Limit {
+		return items[:page.Limit], true, nil
+	}
+	return items, false, nil
+}
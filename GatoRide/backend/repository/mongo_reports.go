@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoReportRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoReportRepository returns a ReportRepository backed by the given collection
+func NewMongoReportRepository(collection *mongo.Collection) ReportRepository {
+	return &mongoReportRepository{collection: collection}
+}
+
+func (r *mongoReportRepository) Create(ctx context.Context, report *models.Report) error {
+	if report.ID.IsZero() {
+		report.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, report)
+	return err
+}
+
+func (r *mongoReportRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Report, error) {
+	var report models.Report
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&report)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *mongoReportRepository) FindPage(ctx context.Context, filter ReportFilter, page Page) ([]models.Report, bool, error) {
+	query := bson.M{}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	if !filter.ReporterID.IsZero() {
+		query["reporter_id"] = filter.ReporterID
+	}
+	if !filter.TargetID.IsZero() {
+		query["target_id"] = filter.TargetID
+	}
+	if !filter.SubjectID.IsZero() {
+		query["subject_id"] = filter.SubjectID
+	}
+	return findPage[models.Report](ctx, r.collection, query, sortKey{field: "created_at", isDate: true}, page)
+}
+
+func (r *mongoReportRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.ReportStatus, adminID primitive.ObjectID, resolution string, at time.Time) (*models.Report, error) {
+	set := bson.M{"status": to, "updated_at": at, "assignee_id": adminID}
+	if to == models.ReportResolved {
+		set["resolution"] = resolution
+		set["resolved_at"] = at
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var report models.Report
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": from}, bson.M{"$set": set}, opts).Decode(&report)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *mongoReportRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+type mongoModerationLogRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoModerationLogRepository returns a ModerationLogRepository backed by the given collection
+func NewMongoModerationLogRepository(collection *mongo.Collection) ModerationLogRepository {
+	return &mongoModerationLogRepository{collection: collection}
+}
+
+func (r *mongoModerationLogRepository) Create(ctx context.Context, action *models.ModerationAction) error {
+	if action.ID.IsZero() {
+		action.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, action)
+	return err
+}
+
+func (r *mongoModerationLogRepository) FindPage(ctx context.Context, filter ModerationFilter, page Page) ([]models.ModerationAction, bool, error) {
+	query := bson.M{}
+	if !filter.ReportID.IsZero() {
+		query["report_id"] = filter.ReportID
+	}
+	if !filter.SubjectID.IsZero() {
+		query["subject_id"] = filter.SubjectID
+	}
+	if !filter.AdminID.IsZero() {
+		query["admin_id"] = filter.AdminID
+	}
+	return findPage[models.ModerationAction](ctx, r.collection, query, sortKey{field: "created_at", isDate: true}, page)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRideRequestRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRideRequestRepository returns a RideRequestRepository backed by the given collection
+func NewMongoRideRequestRepository(collection *mongo.Collection) RideRequestRepository {
+	return &mongoRideRequestRepository{collection: collection}
+}
+
+func (r *mongoRideRequestRepository) Create(ctx context.Context, request *models.RideRequest) error {
+	if request.ID.IsZero() {
+		request.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, request)
+	return err
+}
+
+func (r *mongoRideRequestRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideRequest, error) {
+	var request models.RideRequest
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&request)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *mongoRideRequestRepository) Find(ctx context.Context, filter RideRequestFilter) ([]models.RideRequest, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, rideRequestFilterToBSON(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var requests []models.RideRequest
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *mongoRideRequestRepository) FindNear(ctx context.Context, point models.Location, maxDistance float64, filter RideRequestFilter) ([]RideRequestDistance, error) {
+	pipeline := mongo.Pipeline{{{Key: "$geoNear", Value: bson.M{
+		"near":          point.GeoPoint(),
+		"key":           "pickup.geo",
+		"distanceField": "distance",
+		"maxDistance":   maxDistance,
+		"spherical":     true,
+		"query":         rideRequestFilterToBSON(filter),
+	}}}}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []RideRequestDistance
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *mongoRideRequestRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.RideRequestStatus, at time.Time) (*models.RideRequest, error) {
+	if from == to {
+		return nil, ErrConflict
+	}
+	return r.update(ctx, bson.M{"_id": id, "status": from}, bson.M{"status": to, "resolved_at": at})
+}
+
+func (r *mongoRideRequestRepository) Fulfil(ctx context.Context, id, rideID, bookingID primitive.ObjectID, at time.Time) (*models.RideRequest, error) {
+	return r.update(ctx, bson.M{"_id": id, "status": models.RequestOpen}, bson.M{
+		"status":      models.RequestFulfilled,
+		"resolved_at": at,
+		"ride_id":     rideID,
+		"booking_id":  bookingID,
+	})
+}
+
+// update sets fields on the request matching the filter and returns it, or ErrConflict if none matches
+func (r *mongoRideRequestRepository) update(ctx context.Context, filter, set bson.M) (*models.RideRequest, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var request models.RideRequest
+	err := r.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&request)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *mongoRideRequestRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func rideRequestFilterToBSON(f RideRequestFilter) bson.M {
+	filter := bson.M{}
+	if !f.PassengerID.IsZero() {
+		filter["passenger_id"] = f.PassengerID
+	}
+	windowEnd := bson.M{}
+	if !f.WindowEndsBefore.IsZero() {
+		windowEnd["$lt"] = f.WindowEndsBefore
+	}
+	if !f.WindowEndsAfter.IsZero() {
+		windowEnd["$gt"] = f.WindowEndsAfter
+	}
+	if len(windowEnd) > 0 {
+		filter["departure_to"] = windowEnd
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	return filter
+}
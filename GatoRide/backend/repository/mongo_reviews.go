@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoReviewRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoReviewRepository returns a ReviewRepository backed by the given collection
+func NewMongoReviewRepository(collection *mongo.Collection) ReviewRepository {
+	return &mongoReviewRepository{collection: collection}
+}
+
+func (r *mongoReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	if review.ID.IsZero() {
+		review.ID = primitive.NewObjectID()
+	}
+	// The upsert only inserts when the pair has no review on the ride yet; the unique index from
+	// EnsureIndexes settles two inserts racing each other
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"ride_id": review.RideID, "reviewer_id": review.ReviewerID, "reviewee_id": review.RevieweeID},
+		bson.M{"$setOnInsert": review},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if result.UpsertedCount == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoReviewRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Review, error) {
+	var review models.Review
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&review)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *mongoReviewRepository) FindPage(ctx context.Context, filter ReviewFilter, page Page) ([]models.Review, bool, error) {
+	query := bson.M{}
+	if !filter.RideID.IsZero() {
+		query["ride_id"] = filter.RideID
+	}
+	if !filter.ReviewerID.IsZero() {
+		query["reviewer_id"] = filter.ReviewerID
+	}
+	if !filter.RevieweeID.IsZero() {
+		query["reviewee_id"] = filter.RevieweeID
+	}
+	if filter.Revealed != nil {
+		query["revealed_at"] = bson.M{"$exists": *filter.Revealed}
+	}
+	if !filter.RevealBefore.IsZero() {
+		query["revealed_at"] = bson.M{"$exists": false}
+		query["reveal_at"] = bson.M{"$lt": filter.RevealBefore}
+	}
+	return findPage[models.Review](ctx, r.collection, query, sortKey{field: "created_at", isDate: true}, page)
+}
+
+func (r *mongoReviewRepository) Reveal(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.Review, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var review models.Review
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "revealed_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revealed_at": at}},
+		opts,
+	).Decode(&review)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *mongoReviewRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,542 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRideRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRideRepository returns a RideRepository backed by the given collection
+func NewMongoRideRepository(collection *mongo.Collection) RideRepository {
+	return &mongoRideRepository{collection: collection}
+}
+
+func (r *mongoRideRepository) Create(ctx context.Context, ride *models.Ride) error {
+	if ride.ID.IsZero() {
+		ride.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, ride)
+	return err
+}
+
+func (r *mongoRideRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Ride, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoRideRepository) Find(ctx context.Context, filter RideFilter) ([]models.Ride, error) {
+	cursor, err := r.collection.Find(ctx, rideFilterToBSON(filter))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rides []models.Ride
+	for cursor.Next(ctx) {
+		var ride models.Ride
+		if err := cursor.Decode(&ride); err != nil {
+			return nil, err
+		}
+		rides = append(rides, ride)
+	}
+	return rides, cursor.Err()
+}
+
+func (r *mongoRideRepository) FindPage(ctx context.Context, filter RideFilter, page Page) ([]models.Ride, bool, error) {
+	key, ok := rideSortKeys[page.Sort]
+	if !ok {
+		key = rideSortKeys[SortDeparture]
+	}
+	return findPage[models.Ride](ctx, r.collection, rideFilterToBSON(filter), key, page)
+}
+
+func (r *mongoRideRepository) FindNear(ctx context.Context, near NearQuery, filter RideFilter) ([]RideDistance, error) {
+	// $geoNear must open the pipeline; it sorts by distance and applies the filter as its query
+	pipeline := mongo.Pipeline{{{Key: "$geoNear", Value: bson.M{
+		"near":          near.Point.GeoPoint(),
+		"key":           string(near.Field),
+		"distanceField": "distance",
+		"maxDistance":   near.MaxDistanceMeters,
+		"spherical":     true,
+		"query":         rideFilterToBSON(filter),
+	}}}}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []RideDistance
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *mongoRideRepository) FindOpenDuplicate(ctx context.Context, ride *models.Ride) (*models.Ride, error) {
+	return r.findOne(ctx, bson.M{
+		"driver_id":         ride.DriverID,
+		"pickup.latitude":   ride.Pickup.Latitude,
+		"pickup.longitude":  ride.Pickup.Longitude,
+		"dropoff.latitude":  ride.Dropoff.Latitude,
+		"dropoff.longitude": ride.Dropoff.Longitude,
+		"status":            models.StatusOpen,
+		"date":              ride.Date,
+	})
+}
+
+func (r *mongoRideRepository) ReserveSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error) {
+	filter := bson.M{
+		"_id":           rideID,
+		"status":        models.StatusOpen,
+		"passenger_ids": bson.M{"$ne": userID},
+		"$expr":         bson.M{"$and": bson.A{segmentHasSeats(segment, seats), hasLuggageExpr(bags)}},
+	}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"segment_seats": adjustSegmentsExpr(segment, -seats),
+			"free_luggage":  bson.M{"$add": bson.A{freeLuggageExpr(), -bags}},
+			"passenger_ids": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$passenger_ids", bson.A{}}},
+				bson.A{userID},
+			}},
+		}}},
+		refreshSeatsStage(),
+	}
+
+	return r.findOneAndUpdate(ctx, filter, update)
+}
+
+func (r *mongoRideRepository) ReleaseSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error) {
+	filter := bson.M{
+		"_id":           rideID,
+		"status":        bson.M{"$in": bson.A{models.StatusOpen, models.StatusBooked}},
+		"passenger_ids": userID,
+		"$expr":         segmentHasSeats(segment, 0),
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"segment_seats": adjustSegmentsExpr(segment, seats),
+			"free_luggage":  bson.M{"$add": bson.A{freeLuggageExpr(), bags}},
+			"passenger_ids": bson.M{"$filter": bson.M{
+				"input": "$passenger_ids",
+				"cond":  bson.M{"$ne": bson.A{"$$this", userID}},
+			}},
+		}}},
+		refreshSeatsStage(),
+	}
+	return r.findOneAndUpdate(ctx, filter, update)
+}
+
+// segmentSeatsExpr evaluates to the ride's free seats per segment. Rides stored before
+// segments existed get one entry per segment, each holding their whole-route seat count.
+func segmentSeatsExpr() bson.M {
+	segments := bson.M{"$add": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$waypoints", bson.A{}}}}, 1}}
+	return bson.M{"$ifNull": bson.A{"$segment_seats", bson.M{"$map": bson.M{
+		"input": bson.M{"$range": bson.A{0, segments}},
+		"in":    "$seats",
+	}}}}
+}
+
+// segmentHasSeats matches rides where the segment exists and every part of it has at least seats free
+func segmentHasSeats(segment models.Segment, seats int) bson.M {
+	if segment.From < 0 || segment.To <= segment.From {
+		return bson.M{"$eq": bson.A{1, 0}}
+	}
+	return bson.M{"$and": bson.A{
+		bson.M{"$lte": bson.A{segment.To, bson.M{"$size": segmentSeatsExpr()}}},
+		bson.M{"$gte": bson.A{
+			bson.M{"$min": bson.M{"$slice": bson.A{segmentSeatsExpr(), segment.From, segment.To - segment.From}}},
+			seats,
+		}},
+	}}
+}
+
+// freeLuggageExpr evaluates to the ride's unclaimed luggage space, which rides created before
+// attributes existed lack
+func freeLuggageExpr() bson.M {
+	return bson.M{"$ifNull": bson.A{"$free_luggage", 0}}
+}
+
+// hasLuggageExpr matches rides with room for at least bags more bags
+func hasLuggageExpr(bags int) bson.M {
+	return bson.M{"$gte": bson.A{freeLuggageExpr(), bags}}
+}
+
+// adjustSegmentsExpr adds delta free seats to every segment in the range
+func adjustSegmentsExpr(segment models.Segment, delta int) bson.M {
+	seatsAt := bson.M{"$arrayElemAt": bson.A{segmentSeatsExpr(), "$$i"}}
+	return bson.M{"$map": bson.M{
+		"input": bson.M{"$range": bson.A{0, bson.M{"$size": segmentSeatsExpr()}}},
+		"as":    "i",
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"$gte": bson.A{"$$i", segment.From}},
+				bson.M{"$lt": bson.A{"$$i", segment.To}},
+			}},
+			bson.M{"$add": bson.A{seatsAt, delta}},
+			seatsAt,
+		}},
+	}}
+}
+
+// refreshSeatsStage recomputes the whole-route seat count from the segments and applies
+// lifecycle.AfterSeatChange: booked once no segment has a seat left, open again once one has
+// and nobody is on the waitlist
+func refreshSeatsStage() bson.D {
+	mostFree := bson.M{"$max": "$segment_seats"}
+	return bson.D{{Key: "$set", Value: bson.M{
+		"seats": bson.M{"$min": "$segment_seats"},
+		"status": bson.M{"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{
+					"case": bson.M{"$and": bson.A{bson.M{"$eq": bson.A{"$status", models.StatusOpen}}, bson.M{"$lte": bson.A{mostFree, 0}}}},
+					"then": models.StatusBooked,
+				},
+				bson.M{
+					"case": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$status", models.StatusBooked}},
+						bson.M{"$gt": bson.A{mostFree, 0}},
+						bson.M{"$lte": bson.A{waitlistCountExpr(), 0}},
+					}},
+					"then": models.StatusOpen,
+				},
+			},
+			"default": "$status",
+		}},
+	}}}
+}
+
+func (r *mongoRideRepository) AdjustWaitlist(ctx context.Context, rideID primitive.ObjectID, delta int) (*models.Ride, error) {
+	filter := bson.M{
+		"_id":    rideID,
+		"status": bson.M{"$in": bson.A{models.StatusOpen, models.StatusBooked}},
+		"$expr":  bson.M{"$gte": bson.A{bson.M{"$add": bson.A{waitlistCountExpr(), delta}}, 0}},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"segment_seats":  segmentSeatsExpr(),
+			"waitlist_count": bson.M{"$add": bson.A{waitlistCountExpr(), delta}},
+		}}},
+		refreshSeatsStage(),
+	}
+	return r.findOneAndUpdate(ctx, filter, update)
+}
+
+func (r *mongoRideRepository) ClaimWaitlistSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error) {
+	filter := bson.M{
+		"_id":            rideID,
+		"status":         bson.M{"$in": bson.A{models.StatusOpen, models.StatusBooked}},
+		"passenger_ids":  bson.M{"$ne": userID},
+		"waitlist_count": bson.M{"$gt": 0},
+		"$expr":          bson.M{"$and": bson.A{segmentHasSeats(segment, seats), hasLuggageExpr(bags)}},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"segment_seats":  adjustSegmentsExpr(segment, -seats),
+			"free_luggage":   bson.M{"$add": bson.A{freeLuggageExpr(), -bags}},
+			"waitlist_count": bson.M{"$add": bson.A{"$waitlist_count", -1}},
+			"passenger_ids": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$passenger_ids", bson.A{}}},
+				bson.A{userID},
+			}},
+		}}},
+		refreshSeatsStage(),
+	}
+	return r.findOneAndUpdate(ctx, filter, update)
+}
+
+// waitlistCountExpr evaluates to the ride's waitlist count, which rides without a waitlist lack
+func waitlistCountExpr() bson.M {
+	return bson.M{"$ifNull": bson.A{"$waitlist_count", 0}}
+}
+
+// refreshRouteStage rebuilds the GeoJSON route from the ride's stops, as Ride.Route does
+func refreshRouteStage() bson.D {
+	point := func(stop string) bson.A {
+		return bson.A{stop + ".longitude", stop + ".latitude"}
+	}
+	stops := bson.M{
+		"type": "MultiPoint",
+		"coordinates": bson.M{"$concatArrays": bson.A{
+			bson.A{point("$pickup")},
+			bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$waypoints", bson.A{}}},
+				"in":    point("$$this"),
+			}},
+			bson.A{point("$dropoff")},
+		}},
+	}
+	// Like models.Ride.Route, a ride with a usable path also gets the path as a LineString
+	withPath := bson.M{
+		"type": "GeometryCollection",
+		"geometries": bson.A{stops, bson.M{
+			"type":        "LineString",
+			"coordinates": bson.M{"$map": bson.M{"input": "$path.points", "in": point("$$this")}},
+		}},
+	}
+	return bson.D{{Key: "$set", Value: bson.M{"route": bson.M{"$cond": bson.A{
+		bson.M{"$gte": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$path.points", bson.A{}}}}, 2}},
+		withPath,
+		stops,
+	}}}}}
+}
+
+func (r *mongoRideRepository) UpdateStatus(ctx context.Context, rideID primitive.ObjectID, from, to models.RideStatus) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": rideID, "status": from},
+		bson.M{"$set": bson.M{"status": to}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoRideRepository) Cancel(ctx context.Context, rideID primitive.ObjectID, from models.RideStatus, cancellation models.RideCancellation) error {
+	if from == models.StatusCancelled {
+		return ErrConflict
+	}
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": rideID, "status": from},
+		bson.M{
+			"$set":   bson.M{"status": models.StatusCancelled, "cancellation": cancellation},
+			"$unset": bson.M{"passenger_ids": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoRideRepository) Complete(ctx context.Context, rideID primitive.ObjectID, from models.RideStatus, at time.Time) error {
+	if from == models.StatusCompleted {
+		return ErrConflict
+	}
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": rideID, "status": from},
+		bson.M{"$set": bson.M{"status": models.StatusCompleted, "completed_at": at}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoRideRepository) Update(ctx context.Context, id primitive.ObjectID, update RideUpdate) (*models.Ride, error) {
+	filter := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": bson.A{models.StatusOpen, models.StatusBooked}},
+	}
+	set := bson.M{}
+	if update.Pickup != nil {
+		set["pickup"] = *update.Pickup
+	}
+	if update.Dropoff != nil {
+		set["dropoff"] = *update.Dropoff
+	}
+	if update.Price != nil {
+		set["price"] = *update.Price
+	}
+	if update.Seats != nil {
+		// Free seats can only be reset while nobody holds one
+		filter["passenger_ids.0"] = bson.M{"$exists": false}
+		set["seats"] = *update.Seats
+		set["segment_seats"] = nil
+	}
+	if update.Date != nil {
+		set["date"] = *update.Date
+	}
+	if update.Detached != nil {
+		set["detached"] = *update.Detached
+	}
+	if update.Notes != nil {
+		set["notes"] = *update.Notes
+	}
+	if update.UpdatedAt != nil {
+		set["updated_at"] = *update.UpdatedAt
+	}
+	if update.Path != nil {
+		if len(update.Path.Points) > 0 {
+			set["path"] = *update.Path
+		} else {
+			set["path"] = nil
+		}
+	}
+
+	// Moving a stop or the path changes the route, and adding seats needs the current segment
+	// counts, so both run as a pipeline with every other value wrapped in $literal
+	moved := update.Pickup != nil || update.Dropoff != nil || update.Path != nil
+	if update.AddSeats != 0 || moved {
+		stage := bson.M{}
+		for field, value := range set {
+			stage[field] = bson.M{"$literal": value}
+		}
+		pipeline := mongo.Pipeline{}
+		if update.AddSeats != 0 {
+			filter["$expr"] = bson.M{"$gte": bson.A{bson.M{"$min": segmentSeatsExpr()}, -update.AddSeats}}
+			stage["segment_seats"] = bson.M{"$map": bson.M{
+				"input": segmentSeatsExpr(),
+				"in":    bson.M{"$add": bson.A{"$$this", update.AddSeats}},
+			}}
+			pipeline = append(pipeline, bson.D{{Key: "$set", Value: stage}}, refreshSeatsStage())
+		} else {
+			pipeline = append(pipeline, bson.D{{Key: "$set", Value: stage}})
+		}
+		if moved {
+			pipeline = append(pipeline, refreshRouteStage())
+		}
+		return r.findOneAndUpdate(ctx, filter, pipeline)
+	}
+
+	if len(set) == 0 {
+		return r.findOne(ctx, filter)
+	}
+	return r.findOneAndUpdate(ctx, filter, bson.M{"$set": set})
+}
+
+func (r *mongoRideRepository) DeleteUnbooked(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{
+		"_id":             id,
+		"status":          models.StatusOpen,
+		"passenger_ids.0": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoRideRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *mongoRideRepository) findOne(ctx context.Context, filter bson.M) (*models.Ride, error) {
+	var ride models.Ride
+	err := r.collection.FindOne(ctx, filter).Decode(&ride)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ride, nil
+}
+
+func (r *mongoRideRepository) findOneAndUpdate(ctx context.Context, filter interface{}, update interface{}) (*models.Ride, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var ride models.Ride
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&ride)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ride, nil
+}
+
+func rideFilterToBSON(f RideFilter) bson.M {
+	filter := bson.M{}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	if !f.DriverID.IsZero() {
+		filter["driver_id"] = f.DriverID
+	}
+	if len(f.ExcludeDriverIDs) > 0 {
+		excluded := bson.M{"$nin": f.ExcludeDriverIDs}
+		if !f.DriverID.IsZero() {
+			excluded["$eq"] = f.DriverID
+		}
+		filter["driver_id"] = excluded
+	}
+	if !f.PassengerID.IsZero() {
+		filter["passenger_ids"] = f.PassengerID
+	}
+	if f.MinSeats > 0 {
+		filter["seats"] = bson.M{"$gte": f.MinSeats}
+	}
+	price := bson.M{}
+	if f.MinPrice > 0 {
+		price["$gte"] = f.MinPrice
+	}
+	if f.MaxPrice > 0 {
+		price["$lte"] = f.MaxPrice
+	}
+	if len(price) > 0 {
+		filter["price"] = price
+	}
+	if date := timeRange(f.DateFrom, f.DateTo); len(date) > 0 {
+		filter["date"] = date
+	}
+	if created := timeRange(f.CreatedFrom, f.CreatedTo); len(created) > 0 {
+		filter["created_at"] = created
+	}
+	if !f.SeriesID.IsZero() {
+		filter["series_id"] = f.SeriesID
+	}
+	if f.MinFreeLuggage > 0 {
+		filter["free_luggage"] = bson.M{"$gte": f.MinFreeLuggage}
+	}
+	if f.PetsAllowed != nil {
+		filter["attributes.pets_allowed"] = attributeFilter(*f.PetsAllowed)
+	}
+	if f.SmokingAllowed != nil {
+		filter["attributes.smoking_allowed"] = attributeFilter(*f.SmokingAllowed)
+	}
+	if f.AirConditioning != nil {
+		filter["attributes.air_conditioning"] = attributeFilter(*f.AirConditioning)
+	}
+	if f.Music != "" {
+		filter["attributes.music"] = f.Music
+	}
+	return filter
+}
+
+// attributeFilter matches a boolean ride attribute, which rides created before attributes
+// existed lack and count as false
+func attributeFilter(want bool) interface{} {
+	if want {
+		return true
+	}
+	return bson.M{"$ne": true}
+}
+
+func timeRange(from, to time.Time) bson.M {
+	r := bson.M{}
+	if !from.IsZero() {
+		r["$gte"] = from
+	}
+	if !to.IsZero() {
+		r["$lt"] = to
+	}
+	return r
+}
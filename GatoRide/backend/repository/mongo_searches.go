@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoSavedSearchRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoSavedSearchRepository returns a SavedSearchRepository backed by the given collection
+func NewMongoSavedSearchRepository(collection *mongo.Collection) SavedSearchRepository {
+	return &mongoSavedSearchRepository{collection: collection}
+}
+
+func (r *mongoSavedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	if search.ID.IsZero() {
+		search.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, search)
+	return err
+}
+
+func (r *mongoSavedSearchRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&search)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (r *mongoSavedSearchRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.SavedSearch, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	return r.find(ctx, bson.M{"user_id": userID}, opts)
+}
+
+func (r *mongoSavedSearchRepository) FindCandidates(ctx context.Context, departure time.Time, seats int, points []models.Location, maxDistance float64) ([]models.SavedSearch, error) {
+	// Each point is a circle the search must start in; the 2dsphere index on from.geo serves
+	// every branch of the $or, and departure_to in the same index narrows it by date
+	near := make(bson.A, len(points))
+	for i, point := range points {
+		near[i] = bson.M{"from.geo": bson.M{"$geoWithin": bson.M{
+			"$centerSphere": bson.A{bson.A{point.Longitude, point.Latitude}, maxDistance / models.EarthRadiusMeters},
+		}}}
+	}
+	return r.find(ctx, bson.M{
+		"departure_to":   bson.M{"$gte": departure},
+		"departure_from": bson.M{"$lte": departure},
+		"seats":          bson.M{"$lte": seats},
+		"$or":            near,
+	}, options.Find())
+}
+
+func (r *mongoSavedSearchRepository) ClaimAlert(ctx context.Context, id primitive.ObjectID, notBefore, at time.Time) (*models.SavedSearch, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var search models.SavedSearch
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"last_alert_at": bson.M{"$exists": false}},
+			bson.M{"last_alert_at": bson.M{"$lte": notBefore}},
+		}},
+		bson.M{"$set": bson.M{"last_alert_at": at}, "$inc": bson.M{"alert_count": 1}},
+		opts,
+	).Decode(&search)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (r *mongoSavedSearchRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// find returns every saved search matching the filter
+func (r *mongoSavedSearchRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.SavedSearch, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var searches []models.SavedSearch
+	if err := cursor.All(ctx, &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRideSeriesRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRideSeriesRepository returns a RideSeriesRepository backed by the given collection
+func NewMongoRideSeriesRepository(collection *mongo.Collection) RideSeriesRepository {
+	return &mongoRideSeriesRepository{collection: collection}
+}
+
+func (r *mongoRideSeriesRepository) Create(ctx context.Context, series *models.RideSeries) error {
+	if series.ID.IsZero() {
+		series.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, series)
+	return err
+}
+
+func (r *mongoRideSeriesRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideSeries, error) {
+	var series models.RideSeries
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&series)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *mongoRideSeriesRepository) Find(ctx context.Context, filter SeriesFilter) ([]models.RideSeries, error) {
+	query := bson.M{}
+	if !filter.DriverID.IsZero() {
+		query["driver_id"] = filter.DriverID
+	}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var series []models.RideSeries
+	if err := cursor.All(ctx, &series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (r *mongoRideSeriesRepository) Replace(ctx context.Context, series *models.RideSeries) error {
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": series.ID}, series)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoRideSeriesRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Sessions carry no bson tags, so the driver stores their fields under the
+// lowercased Go names ("userid", "token", "expiresat").
+type mongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoSessionRepository returns a SessionRepository backed by the given collection
+func NewMongoSessionRepository(collection *mongo.Collection) SessionRepository {
+	return &mongoSessionRepository{collection: collection}
+}
+
+func (r *mongoSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+func (r *mongoSessionRepository) FindByToken(ctx context.Context, token string) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *mongoSessionRepository) DeleteByToken(ctx context.Context, token string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"token": token})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userid": userID})
+	return err
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore wires every repository to its collection in the given database
+func NewMongoStore(db *mongo.Database) Store {
+	return Store{
+		Rides:         NewMongoRideRepository(db.Collection("rides")),
+		Series:        NewMongoRideSeriesRepository(db.Collection("ride_series")),
+		Bookings:      NewMongoBookingRepository(db.Collection("bookings")),
+		Waitlist:      NewMongoWaitlistRepository(db.Collection("waitlist")),
+		Requests:      NewMongoRideRequestRepository(db.Collection("ride_requests")),
+		Offers:        NewMongoRideOfferRepository(db.Collection("ride_offers")),
+		Searches:      NewMongoSavedSearchRepository(db.Collection("saved_searches")),
+		Notifications: NewMongoNotificationRepository(db.Collection("notifications")),
+		Vehicles:      NewMongoVehicleRepository(db.Collection("vehicles")),
+		Drivers:       NewMongoDriverVerificationRepository(db.Collection("driver_verifications")),
+		Reviews:       NewMongoReviewRepository(db.Collection("reviews")),
+		Blocks:        NewMongoBlockRepository(db.Collection("blocks")),
+		Reports:       NewMongoReportRepository(db.Collection("reports")),
+		ModerationLog: NewMongoModerationLogRepository(db.Collection("moderation_log")),
+		Users:         NewMongoUserRepository(db.Collection("users")),
+		Sessions:      NewMongoSessionRepository(db.Collection("sessions")),
+	}
+}
+
+// EnsureIndexes creates the 2dsphere indexes proximity searches need. Rides stored before
+// locations carried GeoJSON are given it first, since FindNear cannot see them otherwise.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	rides := db.Collection("rides")
+
+	withGeo := func(field string) bson.M {
+		return bson.M{"$mergeObjects": bson.A{field, bson.M{"geo": bson.M{
+			"type":        "Point",
+			"coordinates": bson.A{field + ".longitude", field + ".latitude"},
+		}}}}
+	}
+	backfill := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"pickup":  withGeo("$pickup"),
+			"dropoff": withGeo("$dropoff"),
+			"waypoints": bson.M{"$cond": bson.A{
+				bson.M{"$isArray": "$waypoints"},
+				bson.M{"$map": bson.M{"input": "$waypoints", "in": withGeo("$$this")}},
+				"$$REMOVE",
+			}},
+		}}},
+		refreshRouteStage(),
+	}
+	if _, err := rides.UpdateMany(ctx, bson.M{"route": bson.M{"$exists": false}}, backfill); err != nil {
+		return fmt.Errorf("adding GeoJSON to stored rides: %w", err)
+	}
+
+	_, err := rides.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: string(NearPickup), Value: "2dsphere"}}},
+		{Keys: bson.D{{Key: string(NearDropoff), Value: "2dsphere"}}},
+		{Keys: bson.D{{Key: string(NearRoute), Value: "2dsphere"}}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating ride location indexes: %w", err)
+	}
+
+	_, err = db.Collection("ride_requests").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "pickup.geo", Value: "2dsphere"}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating ride request location index: %w", err)
+	}
+
+	// New rides are matched against saved searches by where they start and when they end
+	_, err = db.Collection("saved_searches").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "from.geo", Value: "2dsphere"}, {Key: "departure_to", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating saved search index: %w", err)
+	}
+	// Each participant reviews each other participant of a ride at most once
+	_, err = db.Collection("reviews").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "ride_id", Value: 1}, {Key: "reviewer_id", Value: 1}, {Key: "reviewee_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "reviewee_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating review indexes: %w", err)
+	}
+	// Each user blocks another at most once; searches look blocks up from both sides
+	_, err = db.Collection("blocks").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "blocker_id", Value: 1}, {Key: "blocked_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "blocked_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating block indexes: %w", err)
+	}
+	// Admins work through the queue by status; reporters and subjects list their own
+	_, err = db.Collection("reports").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "reporter_id", Value: 1}, {Key: "target_id", Value: 1}}},
+		{Keys: bson.D{{Key: "subject_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating report indexes: %w", err)
+	}
+	_, err = db.Collection("moderation_log").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "report_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "subject_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating moderation log indexes: %w", err)
+	}
+	_, err = db.Collection("notifications").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating notification index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserRepository returns a UserRepository backed by the given collection
+func NewMongoUserRepository(collection *mongo.Collection) UserRepository {
+	return &mongoUserRepository{collection: collection}
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, user)
+	return err
+}
+
+func (r *mongoUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+func (r *mongoUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"username": username})
+}
+
+func (r *mongoUserRepository) MarkVerified(ctx context.Context, email string) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"email": email}, bson.M{"$set": bson.M{"is_verified": true}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) Update(ctx context.Context, id primitive.ObjectID, update UserUpdate) (*models.User, error) {
+	fields := bson.M{}
+	if update.Name != nil {
+		fields["name"] = *update.Name
+	}
+	if update.Username != nil {
+		fields["username"] = *update.Username
+	}
+	if update.Location != nil {
+		fields["location"] = *update.Location
+	}
+	if len(fields) == 0 {
+		return r.FindByID(ctx, id)
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user models.User
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields}, opts).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *mongoUserRepository) RecordCancellation(ctx context.Context, id primitive.ObjectID, record CancellationRecord) (*models.User, error) {
+	// MongoDB cannot pull from and push to the same array in one update, so old late
+	// cancellations are dropped first
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$pull": bson.M{"reliability.recent_late": bson.M{"$lt": record.Since}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inc := bson.M{"reliability.cancellations": 1, "reliability.penalties": record.Penalty}
+	update := bson.M{"$inc": inc}
+	if record.Late {
+		inc["reliability.late_cancellations"] = 1
+		update["$push"] = bson.M{"reliability.recent_late": record.At}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user models.User
+	err = r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *mongoUserRepository) Restrict(ctx context.Context, id primitive.ObjectID, until time.Time) error {
+	// $max keeps a restriction that already lasts longer
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$max": bson.M{"reliability.restricted_until": until}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) AddRating(ctx context.Context, id primitive.ObjectID, stars int) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"rating.stars": stars, "rating.count": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) Warn(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"moderation.warnings": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) Suspend(ctx context.Context, id primitive.ObjectID, until time.Time) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"moderation.suspended_until": until}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *mongoUserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoVehicleRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoVehicleRepository returns a VehicleRepository backed by the given collection
+func NewMongoVehicleRepository(collection *mongo.Collection) VehicleRepository {
+	return &mongoVehicleRepository{collection: collection}
+}
+
+func (r *mongoVehicleRepository) Create(ctx context.Context, vehicle *models.Vehicle) error {
+	if vehicle.ID.IsZero() {
+		vehicle.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, vehicle)
+	return err
+}
+
+func (r *mongoVehicleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&vehicle)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+func (r *mongoVehicleRepository) FindByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Vehicle, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"owner_id": ownerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var vehicles []models.Vehicle
+	if err := cursor.All(ctx, &vehicles); err != nil {
+		return nil, err
+	}
+	return vehicles, nil
+}
+
+func (r *mongoVehicleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
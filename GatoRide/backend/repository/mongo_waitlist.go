@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoWaitlistRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoWaitlistRepository returns a WaitlistRepository backed by the given collection
+func NewMongoWaitlistRepository(collection *mongo.Collection) WaitlistRepository {
+	return &mongoWaitlistRepository{collection: collection}
+}
+
+func (r *mongoWaitlistRepository) Create(ctx context.Context, entry *models.WaitlistEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+func (r *mongoWaitlistRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *mongoWaitlistRepository) Find(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	// Entries are handed seats in the order they joined, so ties on created_at fall back to _id
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, waitlistFilterToBSON(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.WaitlistEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *mongoWaitlistRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.WaitlistStatus, at time.Time) (*models.WaitlistEntry, error) {
+	if from == to {
+		return nil, ErrConflict
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var entry models.WaitlistEntry
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": from},
+		bson.M{"$set": bson.M{"status": to, "resolved_at": at}},
+		opts,
+	).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *mongoWaitlistRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func waitlistFilterToBSON(f WaitlistFilter) bson.M {
+	filter := bson.M{}
+	if !f.RideID.IsZero() {
+		filter["ride_id"] = f.RideID
+	}
+	if !f.PassengerID.IsZero() {
+		filter["passenger_id"] = f.PassengerID
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	return filter
+}
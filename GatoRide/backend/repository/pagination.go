@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"backend/models"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed or belongs to a different ordering
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortField names the value a listing is ordered by
+type SortField string
+
+const (
+	SortDeparture SortField = "departure" // ride departure date
+	SortPrice     SortField = "price"     // price per seat
+	SortDistance  SortField = "distance"  // proximity searches only, computed per result
+	SortCreated   SortField = "created"   // creation time
+	SortTime      SortField = "time"      // closeness to a preferred departure time, computed per result
+	SortRating    SortField = "rating"    // the driver's average rating, computed per result
+)
+
+// Page asks for one page of a sorted listing. Items with equal sort values are ordered by ID,
+// so every item has a fixed place and pages neither repeat nor skip items.
+type Page struct {
+	Sort       SortField
+	Descending bool
+	After      *Cursor // where the previous page ended; nil for the first page
+	Limit      int     // 0 means everything
+}
+
+// Cursor marks where a page ended: the sort value and ID of its last item, along with the
+// ordering it belongs to. Clients get it as an opaque string from Encode.
+type Cursor struct {
+	Sort       SortField          `json:"s"`
+	Descending bool               `json:"d,omitempty"`
+	Value      float64            `json:"v"`
+	ID         primitive.ObjectID `json:"id"`
+}
+
+// Encode returns the cursor as an opaque, URL-safe string
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor from Encode and checks it continues the given ordering
+func DecodeCursor(token string, sortField SortField, descending bool) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sortField || cursor.Descending != descending {
+		return nil, fmt.Errorf("%w: it was issued for a different sort order", ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+// CursorAt returns the cursor that continues this page's ordering after the given item
+func (p Page) CursorAt(value float64, id primitive.ObjectID) Cursor {
+	return Cursor{Sort: p.Sort, Descending: p.Descending, Value: value, ID: id}
+}
+
+// less reports whether an item with sort value a and ID aID comes before b and bID in the page's order
+func (p Page) less(a float64, aID primitive.ObjectID, b float64, bID primitive.ObjectID) bool {
+	if a == b {
+		if p.Descending {
+			return bytes.Compare(aID[:], bID[:]) > 0
+		}
+		return bytes.Compare(aID[:], bID[:]) < 0
+	}
+	if p.Descending {
+		return a > b
+	}
+	return a < b
+}
+
+// Paginate sorts items in the page's order and returns those after its cursor, up to its limit,
+// along with whether more items follow. key gives an item's sort value and ID.
+func Paginate[T any](items []T, page Page, key func(T) (float64, primitive.ObjectID)) ([]T, bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, aID := key(items[i])
+		b, bID := key(items[j])
+		return page.less(a, aID, b, bID)
+	})
+
+	start := 0
+	if page.After != nil {
+		start = sort.Search(len(items), func(i int) bool {
+			value, id := key(items[i])
+			return page.less(page.After.Value, page.After.ID, value, id)
+		})
+	}
+	items = items[start:]
+
+	if page.Limit > 0 && len(items) > page.Limit {
+		return items[:page.Limit], true
+	}
+	return items, false
+}
+
+// RideSortValue returns the value a ride is ordered by for fields stored on the ride itself.
+// Departure dates are compared as Unix milliseconds, the precision MongoDB stores.
+func RideSortValue(ride *models.Ride, field SortField) float64 {
+	switch field {
+	case SortPrice:
+		return ride.Price
+	case SortCreated:
+		return float64(ride.CreatedAt.UnixMilli())
+	default:
+		return float64(ride.Date.UnixMilli())
+	}
+}
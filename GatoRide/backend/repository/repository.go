@@ -0,0 +1,465 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotFound is returned when the requested document does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a conditional update matched no document,
+	// i.e. the document was no longer in the state the caller expected
+	ErrConflict = errors.New("document was modified concurrently")
+)
+
+// GeoField names the stops of a ride a proximity search measures from
+type GeoField string
+
+const (
+	NearPickup  GeoField = "pickup.geo"
+	NearDropoff GeoField = "dropoff.geo"
+	NearRoute   GeoField = "route" // the closest of all stops, waypoints included, or of the ride's path
+)
+
+// NearQuery asks for rides whose chosen stops lie within a distance of a point
+type NearQuery struct {
+	Field             GeoField
+	Point             models.Location
+	MaxDistanceMeters float64
+}
+
+// RideDistance is a ride found by a proximity search with its distance from the search point
+type RideDistance struct {
+	Ride           models.Ride `bson:",inline"`
+	DistanceMeters float64     `bson:"distance"`
+}
+
+// RideFilter describes a ride query. Zero-valued fields are not applied.
+type RideFilter struct {
+	Statuses    []models.RideStatus
+	DriverID    primitive.ObjectID
+	PassengerID primitive.ObjectID
+	MinSeats    int
+	// MinPrice/MaxPrice bound the price per seat, inclusive
+	MinPrice float64
+	MaxPrice float64
+	// DateFrom/DateTo bound the departure date as [DateFrom, DateTo)
+	DateFrom time.Time
+	DateTo   time.Time
+	// CreatedFrom/CreatedTo bound the creation time as [CreatedFrom, CreatedTo)
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	SeriesID    primitive.ObjectID
+	// MinFreeLuggage matches rides with room for at least that many more bags
+	MinFreeLuggage int
+	// PetsAllowed, SmokingAllowed and AirConditioning match rides whose attribute has that value
+	PetsAllowed     *bool
+	SmokingAllowed  *bool
+	AirConditioning *bool
+	Music           models.MusicPreference
+	// ExcludeDriverIDs leaves out the rides of these drivers
+	ExcludeDriverIDs []primitive.ObjectID
+}
+
+// RideUpdate holds the ride details to change. Nil fields are left untouched.
+type RideUpdate struct {
+	Pickup   *models.Location
+	Dropoff  *models.Location
+	Price    *float64
+	Seats    *int
+	Date     *time.Time
+	Detached *bool
+	Notes    *string
+	// AddSeats adds free seats to every segment, or takes them away when negative.
+	// Unlike Seats it keeps the seats passengers already hold; the two are not combined.
+	AddSeats  int
+	UpdatedAt *time.Time
+	// Path replaces the ride's road route; a path without points removes it
+	Path *models.RoutePath
+}
+
+// SeriesFilter describes a ride series query. Zero-valued fields are not applied.
+type SeriesFilter struct {
+	DriverID primitive.ObjectID
+	Statuses []models.SeriesStatus
+}
+
+// BookingFilter describes a booking query. Zero-valued fields are not applied.
+type BookingFilter struct {
+	RideID      primitive.ObjectID
+	PassengerID primitive.ObjectID
+	Statuses    []models.BookingStatus
+	// ExpiresBefore matches bookings whose ExpiresAt is set and earlier than this time
+	ExpiresBefore time.Time
+}
+
+// WaitlistFilter describes a waitlist query. Zero-valued fields are not applied.
+type WaitlistFilter struct {
+	RideID      primitive.ObjectID
+	PassengerID primitive.ObjectID
+	Statuses    []models.WaitlistStatus
+}
+
+// RideRequestFilter describes a ride request query. Zero-valued fields are not applied.
+type RideRequestFilter struct {
+	PassengerID primitive.ObjectID
+	Statuses    []models.RideRequestStatus
+	// WindowEndsBefore/WindowEndsAfter bound the end of the departure window, exclusive
+	WindowEndsBefore time.Time
+	WindowEndsAfter  time.Time
+}
+
+// RideRequestDistance is a ride request found by a proximity search with the distance of its
+// pickup from the search point
+type RideRequestDistance struct {
+	Request        models.RideRequest `bson:",inline"`
+	DistanceMeters float64            `bson:"distance"`
+}
+
+// RideOfferFilter describes a ride offer query. Zero-valued fields are not applied.
+type RideOfferFilter struct {
+	RequestID primitive.ObjectID
+	DriverID  primitive.ObjectID
+	Statuses  []models.OfferStatus
+}
+
+// NotificationFilter describes an inbox query. Zero-valued fields are not applied.
+type NotificationFilter struct {
+	UserID primitive.ObjectID
+	Unread bool // only notifications not read yet
+}
+
+// UserUpdate holds the profile fields to change. Nil fields are left untouched.
+type UserUpdate struct {
+	Name     *string
+	Username *string
+	Location *models.Location
+}
+
+// CancellationRecord is one cancellation counted against a user's reliability
+type CancellationRecord struct {
+	At      time.Time
+	Late    bool
+	Penalty float64
+	// Since is where the policy's window starts; older late cancellations stop counting as recent
+	Since time.Time
+}
+
+// RideRepository stores rides
+type RideRepository interface {
+	Create(ctx context.Context, ride *models.Ride) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Ride, error)
+	Find(ctx context.Context, filter RideFilter) ([]models.Ride, error)
+	// FindPage returns one page of matching rides in the page's order, and whether more follow.
+	// It sorts by SortDeparture, SortPrice or SortCreated.
+	FindPage(ctx context.Context, filter RideFilter, page Page) ([]models.Ride, bool, error)
+	// FindNear returns rides matching the filter whose stops lie within the query's distance
+	// of its point, nearest first, each with its great-circle distance in metres
+	FindNear(ctx context.Context, near NearQuery, filter RideFilter) ([]RideDistance, error)
+	// FindOpenDuplicate returns an open ride by the same driver with the same route and date
+	FindOpenDuplicate(ctx context.Context, ride *models.Ride) (*models.Ride, error)
+	// ReserveSeats atomically takes seats for the user on every segment between two stops,
+	// along with space for their bags. It only succeeds while the ride is open, has enough free
+	// seats along the segment and luggage space left, and does not list the user yet, and marks
+	// the ride booked once no segment has a seat left. It returns ErrConflict otherwise.
+	ReserveSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error)
+	// ReleaseSeats atomically gives the user's seats on the segment and their luggage space back
+	// and reopens a booked ride. It only succeeds while the ride is open or booked and lists the
+	// user, and returns ErrConflict otherwise.
+	ReleaseSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error)
+	// AdjustWaitlist adds delta to the ride's waitlist count and reopens a booked ride with a free
+	// seat once nobody is waiting. It only succeeds while the ride is open or booked and the count
+	// stays at zero or above, and returns ErrConflict otherwise.
+	AdjustWaitlist(ctx context.Context, rideID primitive.ObjectID, delta int) (*models.Ride, error)
+	// ClaimWaitlistSeats atomically gives seats on the segment and luggage space to a passenger
+	// coming off the waitlist and lowers the waitlist count by one. Unlike ReserveSeats it also
+	// works on a booked ride, whose freed seats are held back for the waitlist. It returns
+	// ErrConflict when the seats or luggage space are gone, the user is already listed or
+	// nobody is waiting.
+	ClaimWaitlistSeats(ctx context.Context, rideID, userID primitive.ObjectID, seats, bags int, segment models.Segment) (*models.Ride, error)
+	// UpdateStatus moves the ride from one status to another, returning ErrConflict
+	// if the ride is not currently in the from status
+	UpdateStatus(ctx context.Context, rideID primitive.ObjectID, from, to models.RideStatus) error
+	// Cancel moves the ride from the given status to cancelled, recording why, and lets go of its
+	// passengers, whose bookings keep the history. It returns ErrConflict if the ride is not
+	// currently in the from status.
+	Cancel(ctx context.Context, rideID primitive.ObjectID, from models.RideStatus, cancellation models.RideCancellation) error
+	// Complete moves the ride from the given status to completed and records when. It returns
+	// ErrConflict if the ride is not currently in the from status.
+	Complete(ctx context.Context, rideID primitive.ObjectID, from models.RideStatus, at time.Time) error
+	// Update changes the details of an open or booked ride, returning ErrConflict otherwise.
+	// Changing Seats resets every segment to that many free seats, so it also requires the
+	// ride to have no passengers. AddSeats requires every segment to keep at least zero free
+	// seats, and books or reopens the ride like ReserveSeats and ReleaseSeats do.
+	Update(ctx context.Context, id primitive.ObjectID, update RideUpdate) (*models.Ride, error)
+	// DeleteUnbooked deletes an open ride without passengers, returning ErrConflict otherwise
+	DeleteUnbooked(ctx context.Context, id primitive.ObjectID) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// BookingRepository stores seat bookings, including cancelled ones
+type BookingRepository interface {
+	Create(ctx context.Context, booking *models.Booking) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Booking, error)
+	// Find returns matching bookings, oldest first
+	Find(ctx context.Context, filter BookingFilter) ([]models.Booking, error)
+	// FindPage returns one page of matching bookings ordered by creation time, and whether more follow
+	FindPage(ctx context.Context, filter BookingFilter, page Page) ([]models.Booking, bool, error)
+	// UpdateStatus moves the booking from one status to another, returning ErrConflict if it
+	// is not currently in the from status. The given time is recorded as CancelledAt for
+	// cancellations, by either side, and as RespondedAt otherwise.
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.BookingStatus, at time.Time) (*models.Booking, error)
+	// MarkRideChanged sets RideChangedAt on every pending or confirmed booking of the ride
+	// and returns how many bookings it marked
+	MarkRideChanged(ctx context.Context, rideID primitive.ObjectID, at time.Time) (int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// WaitlistRepository stores passengers' places in line for seats on full rides
+type WaitlistRepository interface {
+	Create(ctx context.Context, entry *models.WaitlistEntry) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.WaitlistEntry, error)
+	// Find returns matching entries oldest first, which is the order seats are handed out in
+	Find(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error)
+	// UpdateStatus moves the entry from one status to another and records the time as
+	// ResolvedAt, returning ErrConflict if it is not currently in the from status
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.WaitlistStatus, at time.Time) (*models.WaitlistEntry, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// RideRequestRepository stores passengers' requests for rides
+type RideRequestRepository interface {
+	Create(ctx context.Context, request *models.RideRequest) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideRequest, error)
+	// Find returns matching requests, oldest first
+	Find(ctx context.Context, filter RideRequestFilter) ([]models.RideRequest, error)
+	// FindNear returns matching requests whose pickup lies within maxDistance metres of the
+	// point, nearest first
+	FindNear(ctx context.Context, point models.Location, maxDistance float64, filter RideRequestFilter) ([]RideRequestDistance, error)
+	// UpdateStatus moves the request from one status to another and records the time as
+	// ResolvedAt, returning ErrConflict if it is not currently in the from status
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.RideRequestStatus, at time.Time) (*models.RideRequest, error)
+	// Fulfil moves an open request to fulfilled and records the ride and booking created for it.
+	// It returns ErrConflict if the request is no longer open.
+	Fulfil(ctx context.Context, id, rideID, bookingID primitive.ObjectID, at time.Time) (*models.RideRequest, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// RideOfferRepository stores drivers' offers on ride requests
+type RideOfferRepository interface {
+	Create(ctx context.Context, offer *models.RideOffer) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideOffer, error)
+	// Find returns matching offers, oldest first
+	Find(ctx context.Context, filter RideOfferFilter) ([]models.RideOffer, error)
+	// UpdateStatus moves the offer from one status to another and records the time as
+	// RespondedAt, returning ErrConflict if it is not currently in the from status
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.OfferStatus, at time.Time) (*models.RideOffer, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// SavedSearchRepository stores the searches users want alerts for
+type SavedSearchRepository interface {
+	Create(ctx context.Context, search *models.SavedSearch) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.SavedSearch, error)
+	// FindByUser returns the user's saved searches, oldest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.SavedSearch, error)
+	// FindCandidates returns the saved searches a new ride may match: their window contains the
+	// departure, they want no more than the given seats, and they start within maxDistance
+	// metres of one of the points. It narrows the searches down through indexes; whether the
+	// ride really suits each one is left to the caller.
+	FindCandidates(ctx context.Context, departure time.Time, seats int, points []models.Location, maxDistance float64) ([]models.SavedSearch, error)
+	// ClaimAlert records an alert sent at the given time and counts it, unless the search was
+	// already alerted after notBefore; then it returns ErrConflict. Concurrent rides therefore
+	// never alert the same search twice within the interval.
+	ClaimAlert(ctx context.Context, id primitive.ObjectID, notBefore, at time.Time) (*models.SavedSearch, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// VehicleRepository stores the vehicles users registered to offer rides in
+type VehicleRepository interface {
+	Create(ctx context.Context, vehicle *models.Vehicle) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Vehicle, error)
+	// FindByOwner returns the user's vehicles, oldest first
+	FindByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Vehicle, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// DriverFilter narrows down the driver verifications Find returns; zero fields match everything
+type DriverFilter struct {
+	Statuses []models.DriverStatus
+	// ExpiresBefore matches drivers with a document expiring before this time
+	ExpiresBefore time.Time
+}
+
+// DriverVerificationRepository stores the licence and insurance details users submit to offer rides
+type DriverVerificationRepository interface {
+	// Save stores the user's submission, replacing any earlier one
+	Save(ctx context.Context, verification *models.DriverVerification) error
+	FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.DriverVerification, error)
+	// Find returns the matching verifications, oldest submission first
+	Find(ctx context.Context, filter DriverFilter) ([]models.DriverVerification, error)
+	// Review records an admin's decision on the submission made at submittedAt. It returns
+	// ErrConflict if that submission is no longer pending, e.g. the user submitted again or
+	// another admin decided first.
+	Review(ctx context.Context, userID primitive.ObjectID, submittedAt time.Time, status models.DriverStatus, reviewerID primitive.ObjectID, reason string, at time.Time) (*models.DriverVerification, error)
+	// ClaimExpiryWarning records that the driver is warned about documents expiring at
+	// expiresAt, unless they already were; then it returns ErrConflict
+	ClaimExpiryWarning(ctx context.Context, userID primitive.ObjectID, expiresAt time.Time) error
+	Delete(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// ReviewFilter narrows down the reviews FindPage returns; zero fields match everything
+type ReviewFilter struct {
+	RideID     primitive.ObjectID
+	ReviewerID primitive.ObjectID
+	RevieweeID primitive.ObjectID
+	Revealed   *bool
+	// RevealBefore matches reviews not revealed yet whose window closes before this time
+	RevealBefore time.Time
+}
+
+// ReviewRepository stores what drivers and passengers think of each other after a ride
+type ReviewRepository interface {
+	// Create stores the review, or returns ErrConflict if its author already reviewed the same
+	// person on the same ride
+	Create(ctx context.Context, review *models.Review) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Review, error)
+	// FindPage returns one page of matching reviews ordered by creation time, and whether more follow
+	FindPage(ctx context.Context, filter ReviewFilter, page Page) ([]models.Review, bool, error)
+	// Reveal shows the review from the given time on, returning ErrConflict if it already is
+	// shown. Only the call that reveals a review may count it towards a rating.
+	Reveal(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.Review, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// BlockRepository stores which users blocked which
+type BlockRepository interface {
+	// Create stores the block, or returns ErrConflict if the blocker already blocked that user
+	Create(ctx context.Context, block *models.Block) error
+	// IsBlocked reports whether blockerID blocked blockedID
+	IsBlocked(ctx context.Context, blockerID, blockedID primitive.ObjectID) (bool, error)
+	// FindByBlocker returns the blocks the user made, oldest first
+	FindByBlocker(ctx context.Context, blockerID primitive.ObjectID) ([]models.Block, error)
+	// BlockedWith returns every user the given one blocked or was blocked by
+	BlockedWith(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// Delete lifts the block, returning ErrNotFound if there is none
+	Delete(ctx context.Context, blockerID, blockedID primitive.ObjectID) error
+}
+
+// ReportFilter narrows down the reports FindPage returns; zero fields match everything
+type ReportFilter struct {
+	Statuses   []models.ReportStatus
+	ReporterID primitive.ObjectID
+	TargetID   primitive.ObjectID
+	SubjectID  primitive.ObjectID
+}
+
+// ReportRepository stores users' reports, which make up the moderation queue
+type ReportRepository interface {
+	Create(ctx context.Context, report *models.Report) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Report, error)
+	// FindPage returns one page of matching reports ordered by creation time, and whether more follow
+	FindPage(ctx context.Context, filter ReportFilter, page Page) ([]models.Report, bool, error)
+	// UpdateStatus moves the report from one status to another and assigns it to the admin.
+	// Resolving it also records the resolution and the time as ResolvedAt. It returns
+	// ErrConflict if the report is not currently in the from status.
+	UpdateStatus(ctx context.Context, id primitive.ObjectID, from, to models.ReportStatus, adminID primitive.ObjectID, resolution string, at time.Time) (*models.Report, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ModerationFilter narrows down the audit log FindPage returns; zero fields match everything
+type ModerationFilter struct {
+	ReportID  primitive.ObjectID
+	SubjectID primitive.ObjectID
+	AdminID   primitive.ObjectID
+}
+
+// ModerationLogRepository is the audit log of what admins did about reports. Entries are never
+// changed or deleted.
+type ModerationLogRepository interface {
+	Create(ctx context.Context, action *models.ModerationAction) error
+	// FindPage returns one page of matching entries ordered by creation time, and whether more follow
+	FindPage(ctx context.Context, filter ModerationFilter, page Page) ([]models.ModerationAction, bool, error)
+}
+
+// NotificationRepository stores users' in-app inboxes
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	// FindPage returns one page of matching notifications ordered by creation time, and whether more follow
+	FindPage(ctx context.Context, filter NotificationFilter, page Page) ([]models.Notification, bool, error)
+	// MarkRead marks one of the user's notifications read, returning ErrNotFound if the user has
+	// no such notification. Reading it again keeps the first time.
+	MarkRead(ctx context.Context, id, userID primitive.ObjectID, at time.Time) (*models.Notification, error)
+	// MarkAllRead marks every unread notification of the user read and returns how many it marked
+	MarkAllRead(ctx context.Context, userID primitive.ObjectID, at time.Time) (int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// RideSeriesRepository stores recurring ride offers
+type RideSeriesRepository interface {
+	Create(ctx context.Context, series *models.RideSeries) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.RideSeries, error)
+	Find(ctx context.Context, filter SeriesFilter) ([]models.RideSeries, error)
+	// Replace overwrites the stored series, returning ErrNotFound if it does not exist
+	Replace(ctx context.Context, series *models.RideSeries) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	MarkVerified(ctx context.Context, email string) error
+	Update(ctx context.Context, id primitive.ObjectID, update UserUpdate) (*models.User, error)
+	// RecordCancellation counts a cancellation against the user's reliability and returns the
+	// updated user, or ErrNotFound
+	RecordCancellation(ctx context.Context, id primitive.ObjectID, record CancellationRecord) (*models.User, error)
+	// Restrict keeps the user from booking or offering rides until the given time. A restriction
+	// already lasting longer is kept. It returns ErrNotFound if the user does not exist.
+	Restrict(ctx context.Context, id primitive.ObjectID, until time.Time) error
+	// AddRating counts a revealed review's stars towards the user's rating. It returns
+	// ErrNotFound if the user does not exist.
+	AddRating(ctx context.Context, id primitive.ObjectID, stars int) error
+	// Warn counts an admin's warning against the user. It returns ErrNotFound if the user does
+	// not exist.
+	Warn(ctx context.Context, id primitive.ObjectID) error
+	// Suspend keeps the user from logging in until the given time, replacing any earlier
+	// suspension. It returns ErrNotFound if the user does not exist.
+	Suspend(ctx context.Context, id primitive.ObjectID, until time.Time) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// SessionRepository stores login sessions
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByToken(ctx context.Context, token string) (*models.Session, error)
+	DeleteByToken(ctx context.Context, token string) error
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+// Store groups the repositories the application is wired with
+type Store struct {
+	Rides         RideRepository
+	Series        RideSeriesRepository
+	Bookings      BookingRepository
+	Waitlist      WaitlistRepository
+	Requests      RideRequestRepository
+	Offers        RideOfferRepository
+	Searches      SavedSearchRepository
+	Notifications NotificationRepository // users' inboxes, where saved search alerts go
+	Vehicles      VehicleRepository
+	Drivers       DriverVerificationRepository
+	Reviews       ReviewRepository
+	Blocks        BlockRepository
+	Reports       ReportRepository
+	ModerationLog ModerationLogRepository
+	Users         UserRepository
+	Sessions      SessionRepository
+}
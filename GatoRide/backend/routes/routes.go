@@ -1,36 +1,116 @@
 package routes
 
 import (
+	"backend/cancellation"
+	"backend/config"
 	"backend/controllers"
+	"backend/filestore"
 	"backend/middlewares"
+	"backend/repository"
+	"backend/routing"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes() *gin.Engine {
+// NewRideController creates the ride controller the routes and the cleanup job share
+func NewRideController(store repository.Store, cfg *config.Config) *controllers.RideController {
+	alerter := controllers.NewRideAlerter(store.Searches, store.Notifications, store.Users, store.Blocks, cfg.Search, cfg.Alerts)
+	return controllers.NewRideController(store.Rides, store.Users, store.Bookings, store.Waitlist, store.Vehicles, store.Drivers, store.Blocks, cfg.Search, cfg.Bookings, routing.New(cfg.Routing), alerter, cancellation.New(cfg.Cancellation))
+}
+
+func SetupRoutes(store repository.Store, cfg *config.Config, rideController *controllers.RideController) *gin.Engine {
 	r := gin.Default()
 	r.Use(middlewares.RequestResponseLogger())
 
-	r.POST("/signup", controllers.Signup)
-	r.POST("/login", controllers.Login)
-	r.GET("/verify-email", controllers.VerifyEmail)
+	authController := controllers.NewAuthController(store.Users, store.Sessions)
+	userController := controllers.NewUserController(store.Users, store.Rides, store.Bookings, store.Blocks, cfg.Search)
+	requestController := controllers.NewRideRequestController(store.Requests, store.Offers, store.Rides, store.Bookings, store.Users, store.Vehicles, store.Drivers, cfg.Search, routing.New(cfg.Routing))
+	alertController := controllers.NewAlertController(store.Searches, store.Notifications, cfg.Search, cfg.Alerts)
+	seriesController := controllers.NewRideSeriesController(store.Series, store.Rides, store.Users, store.Vehicles, store.Drivers, store.Notifications, rideController, cfg.Scheduler, routing.New(cfg.Routing))
+	vehicleController := controllers.NewVehicleController(store.Vehicles)
+	driverController := controllers.NewDriverController(store.Drivers, store.Users, store.Notifications, filestore.New(cfg.Documents), cfg.Documents)
+	blockController := controllers.NewBlockController(store.Blocks, store.Users)
+	reviewController := controllers.NewReviewController(store.Reviews, store.Rides, store.Users, store.Notifications, cfg.Reviews, cfg.Search)
+	reportController := controllers.NewReportController(store.Reports, store.ModerationLog, store.Users, store.Rides, store.Reviews, store.Sessions, store.Notifications, rideController, cfg.Search)
+
+	r.POST("/signup", authController.Signup)
+	r.POST("/login", authController.Login)
+	r.GET("/verify-email", authController.VerifyEmail)
 
 	protected := r.Group("/")
-	protected.Use(middlewares.AuthMiddleware())
+	protected.Use(middlewares.AuthMiddleware(store.Sessions))
 	{
-		protected.POST("/user/provide-ride", controllers.ProvideRide)
-		protected.POST("/user/location", controllers.UpdateUserLocation)
-		protected.POST("/user/logout", controllers.LogOut)
-		protected.POST("/user/search-ride", controllers.SearchRides)
-		protected.POST("/user/book-ride", controllers.BookRide)
-		protected.POST("/user/cancel-booking", controllers.CancelBooking)
-		protected.POST("/user/cancel-ride", controllers.CancelRide)
-		protected.POST("/user/profile", controllers.GetUserProfile)
-		protected.POST("/user/update-profile", controllers.UpdateUserProfile)
-		protected.POST("/user/rides", controllers.GetUserRides)
-		protected.GET("/home", controllers.HomeHandler)
+		protected.POST("/user/provide-ride", rideController.ProvideRide)
+		protected.POST("/user/location", userController.UpdateUserLocation)
+		protected.POST("/user/logout", authController.LogOut)
+		protected.POST("/user/search-ride", rideController.SearchRides)
+		protected.POST("/user/book-ride", rideController.BookRide)
+		protected.POST("/user/cancel-booking", rideController.CancelBooking)
+		protected.POST("/user/cancel-ride", rideController.CancelRide)
+		protected.POST("/user/profile", userController.GetUserProfile)
+		protected.POST("/user/update-profile", userController.UpdateUserProfile)
+		protected.POST("/user/rides", userController.GetUserRides)
+		protected.PUT("/user/rides/:id", rideController.UpdateRide)
+		protected.POST("/user/rides/:id/start", rideController.StartRide)
+		protected.POST("/user/rides/:id/complete", rideController.CompleteRide)
+		protected.POST("/user/rides/:id/reviews", reviewController.SubmitReview)
+		protected.GET("/user/rides/:id/reviews", reviewController.ListRideReviews)
+		protected.GET("/user/reviews/:user_id", reviewController.ListUserReviews)
+		protected.GET("/user/rides/:id/requests", rideController.ListBookingRequests)
+		protected.POST("/user/rides/:id/waitlist", rideController.JoinWaitlist)
+		protected.GET("/user/rides/:id/waitlist", rideController.GetWaitlist)
+		protected.DELETE("/user/rides/:id/waitlist", rideController.LeaveWaitlist)
+		protected.POST("/user/bookings/:id/accept", rideController.AcceptBookingRequest)
+		protected.POST("/user/bookings/:id/reject", rideController.RejectBookingRequest)
+		protected.POST("/user/ride-series", seriesController.CreateSeries)
+		protected.GET("/user/ride-series", seriesController.ListSeries)
+		protected.PUT("/user/ride-series/:id", seriesController.UpdateSeries)
+		protected.POST("/user/ride-series/:id/cancel", seriesController.CancelSeries)
+		protected.PUT("/user/ride-series/:id/occurrences/:ride_id", seriesController.UpdateOccurrence)
+		protected.POST("/user/ride-requests", requestController.CreateRideRequest)
+		protected.GET("/user/ride-requests", requestController.ListRideRequests)
+		protected.GET("/user/ride-requests/nearby", requestController.NearbyRideRequests)
+		protected.GET("/user/ride-requests/:id", requestController.GetRideRequest)
+		protected.POST("/user/ride-requests/:id/cancel", requestController.CancelRideRequest)
+		protected.POST("/user/ride-requests/:id/offers", requestController.MakeOffer)
+		protected.POST("/user/ride-offers/:id/accept", requestController.AcceptOffer)
+		protected.POST("/user/ride-offers/:id/decline", requestController.DeclineOffer)
+		protected.POST("/user/ride-offers/:id/withdraw", requestController.WithdrawOffer)
+		protected.POST("/user/saved-searches", alertController.SaveSearch)
+		protected.GET("/user/saved-searches", alertController.ListSavedSearches)
+		protected.DELETE("/user/saved-searches/:id", alertController.DeleteSavedSearch)
+		protected.GET("/user/notifications", alertController.ListNotifications)
+		protected.POST("/user/notifications/read-all", alertController.MarkAllNotificationsRead)
+		protected.POST("/user/notifications/:id/read", alertController.MarkNotificationRead)
+		protected.POST("/user/vehicles", vehicleController.RegisterVehicle)
+		protected.GET("/user/vehicles", vehicleController.ListVehicles)
+		protected.DELETE("/user/vehicles/:id", vehicleController.RemoveVehicle)
+		protected.POST("/user/driver/verification", driverController.SubmitVerification)
+		protected.GET("/user/driver/verification", driverController.GetVerification)
+		protected.POST("/user/block", blockController.BlockUser)
+		protected.POST("/user/unblock", blockController.UnblockUser)
+		protected.GET("/user/blocks", blockController.ListBlocks)
+		protected.POST("/user/reports", reportController.SubmitReport)
+		protected.GET("/user/reports", reportController.ListReports)
+		protected.GET("/home", userController.HomeHandler)
 
 	}
 
+	// Reviewing drivers and handling reports is for the admins named in the configuration
+	admin := protected.Group("/admin")
+	admin.Use(middlewares.AdminMiddleware(store.Users, cfg.Admin))
+	{
+		admin.GET("/drivers", driverController.ListVerifications)
+		admin.POST("/drivers/:user_id/approve", driverController.ApproveDriver)
+		admin.POST("/drivers/:user_id/reject", driverController.RejectDriver)
+		admin.GET("/drivers/:user_id/documents/:kind", driverController.GetDocument)
+		admin.GET("/reports", reportController.ListReportQueue)
+		admin.GET("/reports/:id", reportController.GetReport)
+		admin.POST("/reports/:id/investigate", reportController.InvestigateReport)
+		admin.POST("/reports/:id/actions", reportController.TakeAction)
+		admin.POST("/reports/:id/resolve", reportController.ResolveReport)
+		admin.GET("/moderation-log", reportController.ListModerationLog)
+	}
+
 	return r
 }
@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"backend/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OSRM asks an OSRM server's route service (https://project-osrm.org) for driving routes.
+// Paths drawn by the driver are measured by Fallback, since OSRM would snap them to roads.
+type OSRM struct {
+	BaseURL  string
+	Client   *http.Client
+	Fallback Provider
+}
+
+// osrmResponse is the part of an OSRM route response the provider reads
+type osrmResponse struct {
+	Code   string `json:"code"`
+	Routes []struct {
+		Distance float64 `json:"distance"` // metres
+		Duration float64 `json:"duration"` // seconds
+		Geometry struct {
+			Coordinates [][2]float64 `json:"coordinates"` // [longitude, latitude]
+		} `json:"geometry"`
+	} `json:"routes"`
+}
+
+func (o *OSRM) Route(ctx context.Context, stops []models.Location) (*models.RoutePath, error) {
+	if len(stops) < 2 {
+		return nil, ErrNoRoute
+	}
+	coordinates := make([]string, len(stops))
+	for i, stop := range stops {
+		coordinates[i] = fmt.Sprintf("%f,%f", stop.Longitude, stop.Latitude)
+	}
+	endpoint := strings.TrimRight(o.BaseURL, "/") + "/route/v1/driving/" + strings.Join(coordinates, ";") +
+		"?overview=full&geometries=geojson"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting route from OSRM: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body osrmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("reading OSRM response (status %d): %w", resp.StatusCode, err)
+	}
+	if body.Code != "Ok" || len(body.Routes) == 0 {
+		return nil, fmt.Errorf("%w: OSRM answered %q", ErrNoRoute, body.Code)
+	}
+
+	route := body.Routes[0]
+	points := make([]models.Point, len(route.Geometry.Coordinates))
+	for i, coordinate := range route.Geometry.Coordinates {
+		points[i] = models.Point{Latitude: coordinate[1], Longitude: coordinate[0]}
+	}
+	return &models.RoutePath{Points: points, DistanceMeters: route.Distance, DurationSeconds: route.Duration}, nil
+}
+
+func (o *OSRM) Measure(points []models.Point) *models.RoutePath {
+	return o.Fallback.Measure(points)
+}
@@ -0,0 +1,61 @@
+// Package routing plans the road route a ride takes through its stops
+package routing
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrNoRoute is returned when a provider cannot find a route through the stops
+var ErrNoRoute = errors.New("no route found")
+
+// Provider plans and measures ride paths
+type Provider interface {
+	// Route plans the driving route through the stops in order
+	Route(ctx context.Context, stops []models.Location) (*models.RoutePath, error)
+	// Measure fills in the length and driving time of a path the driver drew themselves
+	Measure(points []models.Point) *models.RoutePath
+}
+
+// New returns the provider chosen in the configuration
+func New(cfg config.RoutingConfig) Provider {
+	straight := StraightLine{SpeedKmh: cfg.AverageSpeedKmh}
+	if cfg.Provider == config.RoutingOSRM {
+		return &OSRM{
+			BaseURL:  cfg.OSRMURL,
+			Client:   &http.Client{Timeout: time.Duration(cfg.Timeout)},
+			Fallback: straight,
+		}
+	}
+	return straight
+}
+
+// StraightLine joins the stops with great-circle legs driven at a constant average speed.
+// It needs no external service and underestimates real road distances.
+type StraightLine struct {
+	SpeedKmh float64
+}
+
+func (s StraightLine) Route(ctx context.Context, stops []models.Location) (*models.RoutePath, error) {
+	if len(stops) < 2 {
+		return nil, ErrNoRoute
+	}
+	points := make([]models.Point, len(stops))
+	for i, stop := range stops {
+		points[i] = models.Point{Latitude: stop.Latitude, Longitude: stop.Longitude}
+	}
+	return s.Measure(points), nil
+}
+
+func (s StraightLine) Measure(points []models.Point) *models.RoutePath {
+	distance := models.PathLengthMeters(points)
+	return &models.RoutePath{
+		Points:          points,
+		DistanceMeters:  distance,
+		DurationSeconds: distance / (s.SpeedKmh * 1000 / 3600),
+	}
+}
@@ -1,33 +1,48 @@
 package main
 
 import (
+	"backend/auth"
 	"backend/config"
 	"backend/routes"
 	"backend/utils"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/gorilla/handlers"
 )
 
 func main() {
-	config.ConnectDB()
-	router := routes.SetupRoutes()
-	utils.StartCleanupScheduler()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("❌ ", err)
+	}
+	log.Println("⚙️ Configuration:", cfg)
+
+	auth.SetSecret(cfg.JWT.Secret)
+	utils.SetVerificationSecret(cfg.JWT.VerificationSecret)
+	utils.ConfigureMail(cfg.SMTP, cfg.BaseURL)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "5000"
+	store, err := config.OpenStore(cfg)
+	if err != nil {
+		log.Fatal("❌ ", err)
 	}
+	rideController := routes.NewRideController(store, cfg)
+	router := routes.SetupRoutes(store, cfg, rideController)
+	promoter := utils.NewWaitlistPromoter(store.Rides, store.Bookings, store.Waitlist, store.Users, time.Duration(cfg.Bookings.RequestTimeout))
+	utils.StartCleanupScheduler(store.Rides, store.Bookings, rideController, store.Requests, store.Offers, promoter, time.Duration(cfg.Scheduler.ExpiryGrace), time.Duration(cfg.Scheduler.CleanupInterval))
+	utils.StartRecurringRideScheduler(store.Series, store.Rides, store.Drivers, store.Notifications, time.Duration(cfg.Scheduler.RecurringWindow), time.Duration(cfg.Scheduler.RecurringInterval))
+	utils.StartReviewScheduler(store.Reviews, store.Users, time.Duration(cfg.Scheduler.CleanupInterval))
+	utils.StartDriverExpiryScheduler(store.Drivers, store.Users, store.Notifications, time.Duration(cfg.Drivers.ExpiryWarning), time.Duration(cfg.Scheduler.DriverExpiryInterval))
+
 	corsHandler := handlers.CORS(
-		handlers.AllowedOrigins([]string{"http://localhost:3000"}),                   // Allow frontend
+		handlers.AllowedOrigins(cfg.CORS.AllowedOrigins),                             // Allow frontend
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}), // Allow methods
 		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),           // Allow headers
 	)
-	fmt.Println("Server running on port", port)
-	fmt.Println("http://localhost:5001")
-	log.Fatal(http.ListenAndServe(":"+port, corsHandler(router)))
+	fmt.Println("Server running on port", cfg.Port)
+	fmt.Println("http://localhost:" + cfg.Port)
+	log.Fatal(http.ListenAndServe(":"+cfg.Port, corsHandler(router)))
 
 }
@@ -0,0 +1,183 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"backend/repository"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBlocking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	blocks := newBlockController()
+	rides := newRideController()
+	users := newUserController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/user/block", blocks.BlockUser)
+	router.POST("/user/unblock", blocks.UnblockUser)
+	router.GET("/user/blocks", blocks.ListBlocks)
+	router.POST("/user/provide-ride", rides.ProvideRide)
+	router.POST("/user/search-ride", rides.SearchRides)
+	router.POST("/user/book-ride", rides.BookRide)
+	router.POST("/user/ride-feed", rides.FetchRideFeed)
+	router.POST("/user/rides/:id/waitlist", rides.JoinWaitlist)
+	router.GET("/home", users.HomeHandler)
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	driver := createCancellingUser(t, "blockingdriver")
+	rider := createCancellingUser(t, "blockedrider")
+	approveDriver(t, driver.ID)
+
+	// Somewhere no other test offers rides
+	naples := models.Location{Latitude: 26.1420, Longitude: -81.7948, Address: "Naples"}
+	miami := models.Location{Latitude: 25.7617, Longitude: -80.1918, Address: "Miami"}
+	date := time.Now().AddDate(0, 0, 3).UTC().Truncate(24 * time.Hour).Add(15 * time.Hour)
+	w := send("POST", "/user/provide-ride", driver.ID, map[string]interface{}{
+		"pickup": naples, "dropoff": miami, "price": 18, "seats": 3, "date": date,
+		"vehicle_id": registerVehicle(t, driver.ID).ID.Hex(),
+	})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var offered struct {
+		RideID string `json:"ride_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &offered)
+	rideID, _ := primitive.ObjectIDFromHex(offered.RideID)
+	t.Cleanup(func() {
+		_ = testStore.Rides.Delete(context.TODO(), rideID)
+		_ = testStore.Blocks.Delete(context.TODO(), driver.ID, rider.ID)
+		_ = testStore.Blocks.Delete(context.TODO(), rider.ID, driver.ID)
+	})
+
+	location := naples
+	_, err := testStore.Users.Update(context.TODO(), rider.ID, repository.UserUpdate{Location: &location})
+	assert.NoError(t, err)
+
+	// Whether each listing shows the ride to the user
+	type listing struct {
+		Rides []struct {
+			ID primitive.ObjectID `json:"id"`
+		} `json:"rides"`
+	}
+	shows := func(w *httptest.ResponseRecorder) bool {
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response listing
+		json.Unmarshal(w.Body.Bytes(), &response)
+		for _, ride := range response.Rides {
+			if ride.ID == rideID {
+				return true
+			}
+		}
+		return false
+	}
+	inSearch := func(userID primitive.ObjectID) bool {
+		return shows(send("POST", "/user/search-ride", userID, map[string]interface{}{
+			"from": naples, "to": miami, "date": date.Format("2006-01-02"), "seats": 1,
+		}))
+	}
+	inFeed := func(userID primitive.ObjectID) bool {
+		return shows(send("POST", "/user/ride-feed", userID, map[string]interface{}{
+			"latitude": naples.Latitude, "longitude": naples.Longitude, "date": time.Now(),
+		}))
+	}
+	onHome := func(userID primitive.ObjectID) bool {
+		return shows(send("GET", "/home", userID, nil))
+	}
+
+	t.Run("Blocks are validated", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/user/block", rider.ID, map[string]interface{}{}).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/user/block", rider.ID, map[string]interface{}{"user_id": rider.ID.Hex()}).Code)
+		assert.Equal(t, http.StatusNotFound, send("POST", "/user/block", rider.ID, map[string]interface{}{"user_id": primitive.NewObjectID().Hex()}).Code)
+		assert.Equal(t, http.StatusNotFound, send("POST", "/user/unblock", rider.ID, map[string]interface{}{"user_id": driver.ID.Hex()}).Code)
+	})
+
+	t.Run("Blocked drivers' rides disappear for the passenger", func(t *testing.T) {
+		assert.True(t, inSearch(rider.ID))
+		assert.True(t, inFeed(rider.ID))
+		assert.True(t, onHome(rider.ID))
+
+		w := send("POST", "/user/block", rider.ID, map[string]interface{}{"user_id": driver.ID.Hex()})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusConflict, send("POST", "/user/block", rider.ID, map[string]interface{}{"user_id": driver.ID.Hex()}).Code)
+
+		assert.False(t, inSearch(rider.ID))
+		assert.False(t, inFeed(rider.ID))
+		assert.False(t, onHome(rider.ID))
+		// Everyone else still sees the ride
+		assert.True(t, inSearch(primitive.NewObjectID()))
+
+		w = send("POST", "/user/book-ride?ride_id="+rideID.Hex(), rider.ID, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "You blocked this driver")
+
+		w = send("GET", "/user/blocks", rider.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"username":"blockingdriver"`)
+
+		// The driver is not told, and lists no blocks of their own
+		notifications, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: driver.ID}, repository.Page{Sort: repository.SortCreated})
+		assert.NoError(t, err)
+		assert.Empty(t, notifications)
+		w = send("GET", "/user/blocks", driver.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"blocked_users":[]}`, w.Body.String())
+
+		w = send("POST", "/user/unblock", rider.ID, map[string]interface{}{"user_id": driver.ID.Hex()})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.True(t, inSearch(rider.ID))
+	})
+
+	t.Run("Passengers a driver blocked cannot tell", func(t *testing.T) {
+		w := send("POST", "/user/block", driver.ID, map[string]interface{}{"user_id": rider.ID.Hex()})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		assert.False(t, inSearch(rider.ID))
+		assert.False(t, inFeed(rider.ID))
+		assert.False(t, onHome(rider.ID))
+
+		// The ride looks gone, just like a ride that does not exist
+		w = send("POST", "/user/book-ride?ride_id="+rideID.Hex(), rider.ID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.JSONEq(t, `{"error":"Ride not found"}`, w.Body.String())
+		w = send("POST", "/user/rides/"+rideID.Hex()+"/waitlist", rider.ID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NotContains(t, w.Body.String(), "block")
+
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		assert.Empty(t, ride.PassengerIDs)
+	})
+
+	t.Run("Unblocked users can book again", func(t *testing.T) {
+		w := send("POST", "/user/unblock", driver.ID, map[string]interface{}{"user_id": rider.ID.Hex()})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = send("POST", "/user/book-ride?ride_id="+rideID.Hex(), rider.ID, nil)
+		assert.Contains(t, []int{http.StatusOK, http.StatusAccepted}, w.Code, w.Body.String())
+	})
+}
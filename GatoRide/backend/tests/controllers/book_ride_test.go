@@ -1,19 +1,18 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
+	"backend/repository"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -26,7 +25,7 @@ func TestBookRide(t *testing.T) {
 	router.GET("/rides/book", func(c *gin.Context) {
 		// Mock the auth middleware by setting userID
 		c.Set("userID", mockUserID)
-		controllers.BookRide(c)
+		newRideController().BookRide(c)
 	})
 
 	// Test case: successful booking
@@ -53,10 +52,9 @@ func TestBookRide(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err := collection.InsertOne(context.TODO(), testRide)
+		err := testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/book?ride_id="+rideID.Hex(), nil)
@@ -67,8 +65,7 @@ func TestBookRide(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 
 		// Verify ride was updated in the database
-		var updatedRide models.Ride
-		err = collection.FindOne(context.TODO(), bson.M{"_id": rideID}).Decode(&updatedRide)
+		updatedRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
 		assert.NoError(t, err)
 
 		// Check that seats were decreased
@@ -102,10 +99,9 @@ func TestBookRide(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err := collection.InsertOne(context.TODO(), testRide)
+		err := testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/book?ride_id="+rideID.Hex(), nil)
@@ -116,8 +112,7 @@ func TestBookRide(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 
 		// Verify ride status was updated to booked
-		var updatedRide models.Ride
-		err = collection.FindOne(context.TODO(), bson.M{"_id": rideID}).Decode(&updatedRide)
+		updatedRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
 		assert.NoError(t, err)
 
 		// Status should be changed to booked
@@ -149,10 +144,9 @@ func TestBookRide(t *testing.T) {
 			}
 
 			// Insert test ride
-			collection := config.GetCollection("rides")
-			_, err := collection.InsertOne(context.TODO(), testRide)
+			err := testStore.Rides.Create(context.TODO(), &testRide)
 			assert.NoError(t, err)
-			defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+			defer testStore.Rides.Delete(context.TODO(), rideID)
 
 			// Create request
 			req, _ := http.NewRequest("GET", "/rides/book?ride_id="+rideID.Hex(), nil)
@@ -163,8 +157,7 @@ func TestBookRide(t *testing.T) {
 			assert.Equal(t, http.StatusBadRequest, w.Code, "Should not allow booking ride with status "+string(status))
 
 			// Verify ride was not changed in the database
-			var unchangedRide models.Ride
-			err = collection.FindOne(context.TODO(), bson.M{"_id": rideID}).Decode(&unchangedRide)
+			unchangedRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
 			assert.NoError(t, err)
 
 			// Status and seats should remain unchanged
@@ -189,10 +182,9 @@ func TestBookRide(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err := collection.InsertOne(context.TODO(), testRide)
+		err := testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/book?ride_id="+rideID.Hex(), nil)
@@ -221,10 +213,9 @@ func TestBookRide(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err = collection.InsertOne(context.TODO(), testRide)
+		err = testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/book?ride_id="+rideID.Hex(), nil)
@@ -283,3 +274,78 @@ func TestBookRide(t *testing.T) {
 	})
 
 }
+
+func TestBookRide_ConcurrentBookings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Every request books as a different passenger, taken from a header
+	router.GET("/rides/book", func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+		newRideController().BookRide(c)
+	})
+
+	const seats = 3
+	const passengers = 25
+
+	rideID := primitive.NewObjectID()
+	testRide := models.Ride{
+		ID:           rideID,
+		DriverID:     primitive.NewObjectID(),
+		Status:       models.StatusOpen,
+		Seats:        seats,
+		PassengerIDs: []primitive.ObjectID{},
+		CreatedAt:    time.Now(),
+	}
+
+	err := testStore.Rides.Create(context.TODO(), &testRide)
+	assert.NoError(t, err)
+	defer testStore.Rides.Delete(context.TODO(), rideID)
+
+	// Fire all bookings at once
+	codes := make([]int, passengers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < passengers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			req, _ := http.NewRequest("GET", "/rides/book?ride_id="+rideID.Hex(), nil)
+			req.Header.Set("X-Test-User", primitive.NewObjectID().Hex())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	// Exactly as many bookings succeed as there were seats; the rest are rejected
+	succeeded := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			succeeded++
+		case http.StatusConflict, http.StatusBadRequest:
+		default:
+			t.Errorf("unexpected status code %d", code)
+		}
+	}
+	assert.Equal(t, seats, succeeded)
+
+	// The ride is full, never oversold
+	updatedRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updatedRide.Seats)
+	assert.Len(t, updatedRide.PassengerIDs, seats)
+	assert.Equal(t, models.StatusBooked, updatedRide.Status)
+
+	// One booking was recorded per successful request
+	bookings, err := testStore.Bookings.Find(context.TODO(), repository.BookingFilter{RideID: rideID})
+	assert.NoError(t, err)
+	assert.Len(t, bookings, seats)
+	for _, booking := range bookings {
+		defer testStore.Bookings.Delete(context.TODO(), booking.ID)
+	}
+}
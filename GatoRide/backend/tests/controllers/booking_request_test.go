@@ -0,0 +1,191 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBookingRequests(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/rides/book", controller.BookRide)
+	router.GET("/user/rides/:id/requests", controller.ListBookingRequests)
+	router.POST("/user/bookings/:id/accept", controller.AcceptBookingRequest)
+	router.POST("/user/bookings/:id/reject", controller.RejectBookingRequest)
+
+	driverID := primitive.NewObjectID()
+
+	send := func(method, path string, userID primitive.ObjectID) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	insertRide := func(t *testing.T, seats int) primitive.ObjectID {
+		instantBook := false
+		ride := models.Ride{
+			ID:           primitive.NewObjectID(),
+			DriverID:     driverID,
+			Status:       models.StatusOpen,
+			Price:        10,
+			Seats:        seats,
+			PassengerIDs: []primitive.ObjectID{},
+			Date:         time.Now().Add(48 * time.Hour),
+			CreatedAt:    time.Now(),
+			InstantBook:  &instantBook,
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		t.Cleanup(func() {
+			testStore.Rides.Delete(context.TODO(), ride.ID)
+			bookings, _ := testStore.Bookings.Find(context.TODO(), repository.BookingFilter{RideID: ride.ID})
+			for _, booking := range bookings {
+				testStore.Bookings.Delete(context.TODO(), booking.ID)
+			}
+		})
+		return ride.ID
+	}
+
+	request := func(t *testing.T, rideID, passengerID primitive.ObjectID, seats string) models.Booking {
+		w := send("POST", "/rides/book?ride_id="+rideID.Hex()+"&seats="+seats, passengerID)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var response struct {
+			Booking models.Booking `json:"booking"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Booking
+	}
+
+	seatsLeft := func(t *testing.T, rideID primitive.ObjectID) int {
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		return ride.Seats
+	}
+
+	t.Run("Request holds seats until the driver accepts", func(t *testing.T) {
+		rideID := insertRide(t, 3)
+		passengerID := primitive.NewObjectID()
+
+		booking := request(t, rideID, passengerID, "2")
+		assert.Equal(t, models.BookingPending, booking.Status)
+		assert.NotNil(t, booking.ExpiresAt)
+		assert.Equal(t, 1, seatsLeft(t, rideID))
+
+		// The driver sees the request
+		w := send("GET", "/user/rides/"+rideID.Hex()+"/requests", driverID)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), booking.ID.Hex())
+
+		w = send("POST", "/user/bookings/"+booking.ID.Hex()+"/accept", driverID)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		stored, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.BookingConfirmed, stored.Status)
+		assert.NotNil(t, stored.RespondedAt)
+		assert.Equal(t, 1, seatsLeft(t, rideID))
+
+		// An accepted request can no longer be rejected
+		w = send("POST", "/user/bookings/"+booking.ID.Hex()+"/reject", driverID)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Rejecting a request frees its seats", func(t *testing.T) {
+		rideID := insertRide(t, 2)
+		booking := request(t, rideID, primitive.NewObjectID(), "2")
+		assert.Equal(t, 0, seatsLeft(t, rideID))
+
+		w := send("POST", "/user/bookings/"+booking.ID.Hex()+"/reject", driverID)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		stored, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.BookingRejected, stored.Status)
+
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, ride.Seats)
+		assert.Equal(t, models.StatusOpen, ride.Status)
+		assert.Empty(t, ride.PassengerIDs)
+	})
+
+	t.Run("A request whose seats cannot be released stays pending", func(t *testing.T) {
+		rideID := insertRide(t, 2)
+		booking := request(t, rideID, primitive.NewObjectID(), "2")
+
+		// The ride sets off before the driver answers, so its seats can no longer be given back
+		assert.NoError(t, testStore.Rides.UpdateStatus(context.TODO(), rideID, models.StatusBooked, models.StatusOngoing))
+
+		w := send("POST", "/user/bookings/"+booking.ID.Hex()+"/reject", driverID)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		stored, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.BookingPending, stored.Status)
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		assert.Len(t, ride.PassengerIDs, 1)
+	})
+
+	t.Run("Only the driver can answer requests", func(t *testing.T) {
+		rideID := insertRide(t, 2)
+		passengerID := primitive.NewObjectID()
+		booking := request(t, rideID, passengerID, "1")
+
+		w := send("POST", "/user/bookings/"+booking.ID.Hex()+"/accept", passengerID)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send("GET", "/user/rides/"+rideID.Hex()+"/requests", passengerID)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Unanswered requests expire through the scheduler", func(t *testing.T) {
+		rideID := insertRide(t, 2)
+		passengerID := primitive.NewObjectID()
+		expiresAt := time.Now().Add(-time.Minute)
+
+		// Hold the seat as BookRide would, with a deadline already in the past
+		_, err := testStore.Rides.ReserveSeats(context.TODO(), rideID, passengerID, 1, 0, models.Segment{From: 0, To: 1})
+		assert.NoError(t, err)
+		booking := models.Booking{
+			ID:          primitive.NewObjectID(),
+			RideID:      rideID,
+			PassengerID: passengerID,
+			Seats:       1,
+			Status:      models.BookingPending,
+			CreatedAt:   time.Now().Add(-25 * time.Hour),
+			ExpiresAt:   &expiresAt,
+		}
+		assert.NoError(t, testStore.Bookings.Create(context.TODO(), &booking))
+
+		// Accepting after the deadline is refused even before the scheduler runs
+		w := send("POST", "/user/bookings/"+booking.ID.Hex()+"/accept", driverID)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		utils.ExpireBookingRequests(testStore.Bookings, testStore.Rides, newWaitlistPromoter())
+
+		stored, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.BookingExpired, stored.Status)
+		assert.Equal(t, 2, seatsLeft(t, rideID))
+	})
+}
@@ -0,0 +1,165 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"backend/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMultiSeatBooking(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+
+	// Mock the auth middleware by setting userID
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", mockUserID)
+	})
+	router.POST("/rides/book", controller.BookRide)
+	router.POST("/rides/cancel-booking", controller.CancelBooking)
+	router.POST("/user/rides", newUserController().GetUserRides)
+
+	userObjectID, _ := primitive.ObjectIDFromHex(mockUserID)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	insertRide := func(t *testing.T, seats int) primitive.ObjectID {
+		ride := models.Ride{
+			ID:           primitive.NewObjectID(),
+			DriverID:     primitive.NewObjectID(),
+			Status:       models.StatusOpen,
+			Price:        12.5,
+			Seats:        seats,
+			PassengerIDs: []primitive.ObjectID{},
+			Date:         time.Now().Add(24 * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		t.Cleanup(func() {
+			testStore.Rides.Delete(context.TODO(), ride.ID)
+			bookings, _ := testStore.Bookings.Find(context.TODO(), repository.BookingFilter{RideID: ride.ID})
+			for _, booking := range bookings {
+				testStore.Bookings.Delete(context.TODO(), booking.ID)
+			}
+		})
+		return ride.ID
+	}
+
+	t.Run("Book three seats and cancel them together", func(t *testing.T) {
+		rideID := insertRide(t, 3)
+
+		w := post("/rides/book?ride_id=" + rideID.Hex() + "&seats=3")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Booking models.Booking `json:"booking"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		booking := response.Booking
+		assert.Equal(t, 3, booking.Seats)
+		assert.Equal(t, models.BookingConfirmed, booking.Status)
+		assert.Equal(t, 12.5, booking.PricePerSeat)
+		assert.Equal(t, 37.5, booking.TotalPrice)
+		assert.Equal(t, userObjectID, booking.PassengerID)
+
+		// All three seats are gone in one action
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, ride.Seats)
+		assert.Equal(t, models.StatusBooked, ride.Status)
+
+		// Cancelling returns all three seats and keeps the booking as history
+		w = post("/rides/cancel-booking?booking_id=" + booking.ID.Hex())
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		ride, err = testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, ride.Seats)
+		assert.Equal(t, models.StatusOpen, ride.Status)
+		assert.NotContains(t, ride.PassengerIDs, userObjectID)
+
+		stored, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.BookingCancelled, stored.Status)
+		assert.NotNil(t, stored.CancelledAt)
+
+		// Cancelling again is rejected
+		w = post("/rides/cancel-booking?booking_id=" + booking.ID.Hex())
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// The cancelled booking shows up in the user's history
+		w = post("/user/rides")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var rides struct {
+			Bookings []models.Booking `json:"bookings"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rides))
+		found := false
+		for _, b := range rides.Bookings {
+			if b.ID == booking.ID {
+				found = b.Status == models.BookingCancelled
+			}
+		}
+		assert.True(t, found, "cancelled booking should be listed")
+	})
+
+	t.Run("Cancel by ride ID finds the booking", func(t *testing.T) {
+		rideID := insertRide(t, 4)
+
+		w := post("/rides/book?ride_id=" + rideID.Hex() + "&seats=2")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = post("/rides/cancel-booking?ride_id=" + rideID.Hex())
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, ride.Seats)
+	})
+
+	t.Run("More seats than available", func(t *testing.T) {
+		rideID := insertRide(t, 2)
+
+		w := post("/rides/book?ride_id=" + rideID.Hex() + "&seats=3")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Only 2 seats available")
+	})
+
+	t.Run("Invalid seat count", func(t *testing.T) {
+		rideID := insertRide(t, 2)
+
+		w := post("/rides/book?ride_id=" + rideID.Hex() + "&seats=0")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Cannot cancel another passenger's booking", func(t *testing.T) {
+		rideID := insertRide(t, 2)
+		booking := models.Booking{
+			ID:          primitive.NewObjectID(),
+			RideID:      rideID,
+			PassengerID: primitive.NewObjectID(),
+			Seats:       1,
+			Status:      models.BookingConfirmed,
+			CreatedAt:   time.Now(),
+		}
+		assert.NoError(t, testStore.Bookings.Create(context.TODO(), &booking))
+
+		w := post("/rides/cancel-booking?booking_id=" + booking.ID.Hex())
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
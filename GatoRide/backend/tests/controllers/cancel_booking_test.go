@@ -1,8 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
 	"context"
 	"encoding/json"
@@ -13,7 +11,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -26,7 +23,7 @@ func TestCancelBooking(t *testing.T) {
 	router.GET("/rides/cancel-booking", func(c *gin.Context) {
 		// Mock the auth middleware by setting userID
 		c.Set("userID", mockUserID)
-		controllers.CancelBooking(c)
+		newRideController().CancelBooking(c)
 	})
 
 	// Test case: successful booking cancellation
@@ -47,10 +44,9 @@ func TestCancelBooking(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err = collection.InsertOne(context.TODO(), testRide)
+		err = testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/cancel-booking?ride_id="+rideID.Hex(), nil)
@@ -61,8 +57,7 @@ func TestCancelBooking(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 
 		// Verify ride was updated in the database
-		var updatedRide models.Ride
-		err = collection.FindOne(context.TODO(), bson.M{"_id": rideID}).Decode(&updatedRide)
+		updatedRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
 		assert.NoError(t, err)
 
 		// Check that seats were increased
@@ -97,10 +92,9 @@ func TestCancelBooking(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err = collection.InsertOne(context.TODO(), testRide)
+		err = testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/cancel-booking?ride_id="+rideID.Hex(), nil)
@@ -109,6 +103,12 @@ func TestCancelBooking(t *testing.T) {
 
 		// Assertions - should be successful for booked status
 		assert.Equal(t, http.StatusOK, w.Code)
+
+		// The freed seat reopens the ride for booking
+		updatedRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusOpen, updatedRide.Status)
+		assert.Equal(t, 2, updatedRide.Seats)
 	})
 
 	// Test case: cancellation for a ride with "ongoing" status
@@ -129,10 +129,9 @@ func TestCancelBooking(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err = collection.InsertOne(context.TODO(), testRide)
+		err = testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/cancel-booking?ride_id="+rideID.Hex(), nil)
@@ -143,8 +142,7 @@ func TestCancelBooking(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 
 		// Verify ride was NOT updated in the database (passenger still there)
-		var updatedRide models.Ride
-		err = collection.FindOne(context.TODO(), bson.M{"_id": rideID}).Decode(&updatedRide)
+		updatedRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
 		assert.NoError(t, err)
 
 		// Passenger should still be in the list
@@ -176,10 +174,9 @@ func TestCancelBooking(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err = collection.InsertOne(context.TODO(), testRide)
+		err = testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/cancel-booking?ride_id="+rideID.Hex(), nil)
@@ -204,10 +201,9 @@ func TestCancelBooking(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err := collection.InsertOne(context.TODO(), testRide)
+		err := testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/cancel-booking?ride_id="+rideID.Hex(), nil)
@@ -1,19 +1,19 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
+	"backend/repository"
+	"backend/utils"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -26,7 +26,7 @@ func TestCancelRide(t *testing.T) {
 	router.GET("/rides/cancel", func(c *gin.Context) {
 		// Mock the auth middleware by setting userID
 		c.Set("userID", mockUserID)
-		controllers.CancelRide(c)
+		newRideController().CancelRide(c)
 	})
 
 	// Test case: successful ride cancellation
@@ -46,13 +46,12 @@ func TestCancelRide(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err = collection.InsertOne(context.TODO(), testRide)
+		err = testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
-		req, _ := http.NewRequest("GET", "/rides/cancel?ride_id="+rideID.Hex(), nil)
+		req, _ := http.NewRequest("GET", "/rides/cancel?ride_id="+rideID.Hex(), strings.NewReader(`{"reason": "vehicle_problem", "note": "Flat tyre"}`))
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -60,12 +59,47 @@ func TestCancelRide(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 
 		// Verify ride status was updated in the database
-		var updatedRide models.Ride
-		err = collection.FindOne(context.TODO(), bson.M{"_id": rideID}).Decode(&updatedRide)
+		updatedRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
 		assert.NoError(t, err)
 
-		// Check that status is now cancelled
+		// Check that status is now cancelled, with the driver's reason
 		assert.Equal(t, models.StatusCancelled, updatedRide.Status)
+		if assert.NotNil(t, updatedRide.Cancellation) {
+			assert.Equal(t, models.ReasonVehicleProblem, updatedRide.Cancellation.Reason)
+			assert.Equal(t, "Flat tyre", updatedRide.Cancellation.Note)
+		}
+
+		// Cancelling it again is a conflict, not a server error
+		req, _ = http.NewRequest("GET", "/rides/cancel?ride_id="+rideID.Hex(), strings.NewReader(`{"reason": "weather"}`))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "already cancelled")
+	})
+
+	// Test case: the driver must say why
+	t.Run("Reason required", func(t *testing.T) {
+		userObjectID, _ := primitive.ObjectIDFromHex(mockUserID)
+		rideID := primitive.NewObjectID()
+		testRide := models.Ride{
+			ID:        rideID,
+			DriverID:  userObjectID,
+			Status:    models.StatusOpen,
+			Seats:     2,
+			CreatedAt: time.Now(),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &testRide))
+		defer testStore.Rides.Delete(context.TODO(), rideID)
+
+		for _, body := range []string{``, `{"reason": "bored"}`, `{"reason": "other"}`} {
+			req, _ := http.NewRequest("GET", "/rides/cancel?ride_id="+rideID.Hex(), strings.NewReader(body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+
+		ride, _ := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.Equal(t, models.StatusOpen, ride.Status)
 	})
 
 	// Test case: user not the driver
@@ -81,10 +115,9 @@ func TestCancelRide(t *testing.T) {
 		}
 
 		// Insert test ride
-		collection := config.GetCollection("rides")
-		_, err := collection.InsertOne(context.TODO(), testRide)
+		err := testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/rides/cancel?ride_id="+rideID.Hex(), nil)
@@ -139,10 +172,9 @@ func TestCancelRide(t *testing.T) {
 		}
 
 		// Insert into DB
-		collection := config.GetCollection("rides")
-		_, err := collection.InsertOne(context.TODO(), testRide)
+		err := testStore.Rides.Create(context.TODO(), &testRide)
 		assert.NoError(t, err)
-		defer collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+		defer testStore.Rides.Delete(context.TODO(), rideID)
 
 		// Make request
 		req, _ := http.NewRequest("GET", "/rides/cancel?ride_id="+rideID.Hex(), nil)
@@ -159,3 +191,139 @@ func TestCancelRide(t *testing.T) {
 	})
 
 }
+
+func TestCancelRide_CancelsPassengers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	driver := createCancellingUser(t, "cancelling_driver")
+	booked := createCancellingUser(t, "booked_passenger")
+	legacy := createCancellingUser(t, "legacy_passenger")
+	waiting := createCancellingUser(t, "waiting_passenger")
+
+	// Capture the emails instead of sending them
+	emailed := map[string][]models.Ride{}
+	original := utils.SendRideCancelledFunc
+	utils.SendRideCancelledFunc = func(email string, ride *models.Ride, alternatives []models.Ride) error {
+		emailed[email] = alternatives
+		return nil
+	}
+	defer func() { utils.SendRideCancelledFunc = original }()
+
+	departure := time.Date(2031, 5, 4, 14, 0, 0, 0, time.UTC)
+	pickup := models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"}
+	dropoff := models.Location{Latitude: 28.5383, Longitude: -81.3792, Address: "Orlando"}
+
+	ride := models.Ride{
+		ID:           primitive.NewObjectID(),
+		DriverID:     driver.ID,
+		Pickup:       pickup,
+		Dropoff:      dropoff,
+		Status:       models.StatusBooked,
+		Price:        15,
+		Date:         departure,
+		CreatedAt:    time.Now(),
+		PassengerIDs: []primitive.ObjectID{booked.ID, legacy.ID},
+	}
+	assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+	defer testStore.Rides.Delete(context.TODO(), ride.ID)
+
+	booking := models.Booking{
+		ID:          primitive.NewObjectID(),
+		RideID:      ride.ID,
+		PassengerID: booked.ID,
+		Seats:       1,
+		ToStop:      1,
+		Status:      models.BookingConfirmed,
+		CreatedAt:   time.Now(),
+	}
+	assert.NoError(t, testStore.Bookings.Create(context.TODO(), &booking))
+
+	// Someone else waits for a seat to free up
+	entry := models.WaitlistEntry{
+		ID:          primitive.NewObjectID(),
+		RideID:      ride.ID,
+		PassengerID: waiting.ID,
+		Seats:       1,
+		ToStop:      1,
+		Status:      models.WaitlistWaiting,
+		CreatedAt:   time.Now(),
+	}
+	assert.NoError(t, testStore.Waitlist.Create(context.TODO(), &entry))
+	defer testStore.Waitlist.Delete(context.TODO(), entry.ID)
+
+	// Another driver takes the same route later that day; one the next day does not count
+	alternative := models.Ride{
+		ID:        primitive.NewObjectID(),
+		DriverID:  primitive.NewObjectID(),
+		Pickup:    pickup,
+		Dropoff:   dropoff,
+		Status:    models.StatusOpen,
+		Price:     18,
+		Seats:     2,
+		Date:      departure.Add(3 * time.Hour),
+		CreatedAt: time.Now(),
+	}
+	nextDay := alternative
+	nextDay.ID = primitive.NewObjectID()
+	nextDay.Date = departure.Add(24 * time.Hour)
+	// The booked passenger blocked the driver of yet another same-day ride
+	blockedRide := alternative
+	blockedRide.ID = primitive.NewObjectID()
+	blockedRide.DriverID = primitive.NewObjectID()
+	blockedRide.Date = departure.Add(4 * time.Hour)
+	for _, other := range []*models.Ride{&alternative, &nextDay, &blockedRide} {
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), other))
+		defer testStore.Rides.Delete(context.TODO(), other.ID)
+	}
+	block := models.Block{ID: primitive.NewObjectID(), BlockerID: booked.ID, BlockedID: blockedRide.DriverID, CreatedAt: time.Now()}
+	assert.NoError(t, testStore.Blocks.Create(context.TODO(), &block))
+	defer testStore.Blocks.Delete(context.TODO(), booked.ID, blockedRide.DriverID)
+
+	code, outcome := cancelAs(t, driver.ID, newRideController().CancelRide, "ride_id="+ride.ID.Hex())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "free", string(outcome.Rule))
+
+	// The booking is cancelled by the driver and the ride no longer lists anyone
+	stored, _ := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+	assert.Equal(t, models.BookingCancelledByDriver, stored.Status)
+	assert.NotNil(t, stored.CancelledAt)
+	cancelled, _ := testStore.Rides.FindByID(context.TODO(), ride.ID)
+	assert.Empty(t, cancelled.PassengerIDs)
+
+	// Both passengers hear about it, with the same-day rides suggested, except the one whose
+	// driver the booked passenger blocked
+	if assert.Contains(t, emailed, booked.Email) && assert.Len(t, emailed[booked.Email], 1) {
+		assert.Equal(t, alternative.ID, emailed[booked.Email][0].ID)
+	}
+	if assert.Contains(t, emailed, legacy.Email) && assert.Len(t, emailed[legacy.Email], 2) {
+		assert.ElementsMatch(t, []primitive.ObjectID{alternative.ID, blockedRide.ID},
+			[]primitive.ObjectID{emailed[legacy.Email][0].ID, emailed[legacy.Email][1].ID})
+	}
+	for _, passenger := range []models.User{booked, legacy} {
+
+		inbox, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: passenger.ID}, repository.Page{Sort: repository.SortCreated})
+		assert.NoError(t, err)
+		if assert.Len(t, inbox, 1) {
+			assert.Equal(t, models.NotificationRideCancelled, inbox[0].Kind)
+			assert.Contains(t, inbox[0].Message, "schedule change")
+			assert.Contains(t, inbox[0].Message, "on your route that day")
+			_ = testStore.Notifications.Delete(context.TODO(), inbox[0].ID)
+		}
+	}
+	assert.NotContains(t, emailed, driver.Email)
+
+	// The waitlist is closed and the user waiting on it hears about the cancellation too
+	closed, err := testStore.Waitlist.FindByID(context.TODO(), entry.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.WaitlistClosed, closed.Status)
+	assert.NotNil(t, closed.ResolvedAt)
+	if assert.Contains(t, emailed, waiting.Email) {
+		assert.Len(t, emailed[waiting.Email], 2)
+	}
+	inbox, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: waiting.ID}, repository.Page{Sort: repository.SortCreated})
+	assert.NoError(t, err)
+	if assert.Len(t, inbox, 1) {
+		assert.Equal(t, models.NotificationRideCancelled, inbox[0].Kind)
+		_ = testStore.Notifications.Delete(context.TODO(), inbox[0].ID)
+	}
+}
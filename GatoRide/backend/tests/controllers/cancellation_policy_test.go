@@ -0,0 +1,249 @@
+package controllers_test
+
+import (
+	"backend/cancellation"
+	"backend/controllers"
+	"backend/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// createCancellingUser stores a user whose cancellations the tests count
+func createCancellingUser(t *testing.T, name string) models.User {
+	user := models.User{
+		ID:       primitive.NewObjectID(),
+		Name:     name,
+		Email:    name + "@example.com",
+		Username: name,
+	}
+	assert.NoError(t, testStore.Users.Create(context.TODO(), &user))
+	t.Cleanup(func() { _ = testStore.Users.Delete(context.TODO(), user.ID) })
+	return user
+}
+
+// createBookedRide stores a ride departing after the given delay with the passenger booked on it
+func createBookedRide(t *testing.T, driverID, passengerID primitive.ObjectID, departsIn time.Duration, status models.BookingStatus) (models.Ride, models.Booking) {
+	ride := models.Ride{
+		ID:           primitive.NewObjectID(),
+		DriverID:     driverID,
+		Status:       models.StatusOpen,
+		Price:        20,
+		Seats:        2,
+		Date:         time.Now().Add(departsIn),
+		CreatedAt:    time.Now(),
+		PassengerIDs: []primitive.ObjectID{passengerID},
+	}
+	assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+	t.Cleanup(func() { _ = testStore.Rides.Delete(context.TODO(), ride.ID) })
+
+	booking := models.Booking{
+		ID:           primitive.NewObjectID(),
+		RideID:       ride.ID,
+		PassengerID:  passengerID,
+		Seats:        2,
+		ToStop:       1,
+		Status:       status,
+		PricePerSeat: 20,
+		TotalPrice:   40,
+		CreatedAt:    time.Now(),
+	}
+	assert.NoError(t, testStore.Bookings.Create(context.TODO(), &booking))
+	return ride, booking
+}
+
+// cancelAs sends a cancellation on the user's behalf and decodes what it cost. Ride
+// cancellations give a reason; booking cancellations ignore it.
+func cancelAs(t *testing.T, userID primitive.ObjectID, handler gin.HandlerFunc, query string) (int, controllers.CancellationOutcome) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/cancel", func(c *gin.Context) {
+		c.Set("userID", userID.Hex())
+		handler(c)
+	})
+
+	req, _ := http.NewRequest("POST", "/cancel?"+query, strings.NewReader(`{"reason": "schedule_change"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Cancellation controllers.CancellationOutcome `json:"cancellation"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Cancellation
+}
+
+func TestCancelBooking_Policy(t *testing.T) {
+	cancel := newRideController().CancelBooking
+
+	t.Run("Cancelling early is free", func(t *testing.T) {
+		passenger := createCancellingUser(t, "early_canceller")
+		_, booking := createBookedRide(t, primitive.NewObjectID(), passenger.ID, 72*time.Hour+time.Minute, models.BookingConfirmed)
+
+		code, outcome := cancelAs(t, passenger.ID, cancel, "booking_id="+booking.ID.Hex())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, cancellation.RuleFree, outcome.Rule)
+		assert.False(t, outcome.Late)
+		assert.Zero(t, outcome.Penalty)
+		assert.Contains(t, outcome.Explanation, "3 days before departure")
+
+		stored, _ := testStore.Users.FindByID(context.TODO(), passenger.ID)
+		assert.Equal(t, 1, stored.Reliability.Cancellations)
+		assert.Equal(t, 0, stored.Reliability.LateCancellations)
+	})
+
+	t.Run("Cancelling late costs part of the booking", func(t *testing.T) {
+		passenger := createCancellingUser(t, "late_canceller")
+		_, booking := createBookedRide(t, primitive.NewObjectID(), passenger.ID, 2*time.Hour+time.Minute, models.BookingConfirmed)
+
+		code, outcome := cancelAs(t, passenger.ID, cancel, "booking_id="+booking.ID.Hex())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, cancellation.RuleLate, outcome.Rule)
+		assert.True(t, outcome.Late)
+		assert.Equal(t, 20.0, outcome.Penalty) // 50% of 40
+		assert.Contains(t, outcome.Explanation, "2 hours before departure")
+		assert.Nil(t, outcome.RestrictedUntil)
+		if assert.NotNil(t, outcome.ReliabilityScore) {
+			assert.Equal(t, 0, *outcome.ReliabilityScore) // its one booking was cancelled late
+		}
+
+		stored, _ := testStore.Users.FindByID(context.TODO(), passenger.ID)
+		assert.Equal(t, 1, stored.Reliability.LateCancellations)
+		assert.Equal(t, 20.0, stored.Reliability.Penalties)
+		assert.Len(t, stored.Reliability.RecentLate, 1)
+	})
+
+	t.Run("A changed ride may be cancelled late for free", func(t *testing.T) {
+		passenger := createCancellingUser(t, "changed_canceller")
+		_, booking := createBookedRide(t, primitive.NewObjectID(), passenger.ID, time.Hour, models.BookingConfirmed)
+		changedAt := time.Now()
+		booking.RideChangedAt = &changedAt
+		_ = testStore.Bookings.Delete(context.TODO(), booking.ID)
+		assert.NoError(t, testStore.Bookings.Create(context.TODO(), &booking))
+
+		code, outcome := cancelAs(t, passenger.ID, cancel, "booking_id="+booking.ID.Hex())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, cancellation.RuleRideChanged, outcome.Rule)
+		assert.False(t, outcome.Late)
+	})
+
+	t.Run("Withdrawing a pending request is free", func(t *testing.T) {
+		passenger := createCancellingUser(t, "pending_canceller")
+		_, booking := createBookedRide(t, primitive.NewObjectID(), passenger.ID, time.Hour, models.BookingPending)
+
+		code, outcome := cancelAs(t, passenger.ID, cancel, "booking_id="+booking.ID.Hex())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, cancellation.RulePendingRequest, outcome.Rule)
+		assert.Zero(t, outcome.Penalty)
+	})
+}
+
+func TestCancelRide_Policy(t *testing.T) {
+	cancel := newRideController().CancelRide
+
+	t.Run("Cancelling a ride nobody booked is free", func(t *testing.T) {
+		driver := createCancellingUser(t, "empty_ride_driver")
+		ride := models.Ride{
+			ID:        primitive.NewObjectID(),
+			DriverID:  driver.ID,
+			Status:    models.StatusOpen,
+			Seats:     3,
+			Date:      time.Now().Add(time.Hour),
+			CreatedAt: time.Now(),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		defer testStore.Rides.Delete(context.TODO(), ride.ID)
+
+		code, outcome := cancelAs(t, driver.ID, cancel, "ride_id="+ride.ID.Hex())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, cancellation.RuleNoPassengers, outcome.Rule)
+		assert.False(t, outcome.Late)
+	})
+
+	t.Run("Cancelling a booked ride late counts against the driver", func(t *testing.T) {
+		driver := createCancellingUser(t, "late_driver")
+		ride, _ := createBookedRide(t, driver.ID, primitive.NewObjectID(), time.Hour, models.BookingConfirmed)
+
+		code, outcome := cancelAs(t, driver.ID, cancel, "ride_id="+ride.ID.Hex())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, cancellation.RuleLate, outcome.Rule)
+		assert.True(t, outcome.Late)
+		assert.Zero(t, outcome.Penalty, "drivers are never charged")
+
+		stored, _ := testStore.Users.FindByID(context.TODO(), driver.ID)
+		assert.Equal(t, 1, stored.Reliability.LateCancellations)
+	})
+}
+
+func TestLateCancellations_RestrictUser(t *testing.T) {
+	controller := newRideController()
+	passenger := createCancellingUser(t, "repeat_canceller")
+
+	// The default policy restricts after three late cancellations
+	var outcome controllers.CancellationOutcome
+	for i := 0; i < 3; i++ {
+		_, booking := createBookedRide(t, primitive.NewObjectID(), passenger.ID, time.Hour, models.BookingConfirmed)
+		var code int
+		code, outcome = cancelAs(t, passenger.ID, controller.CancelBooking, "booking_id="+booking.ID.Hex())
+		assert.Equal(t, http.StatusOK, code)
+	}
+	if assert.NotNil(t, outcome.RestrictedUntil) {
+		assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), *outcome.RestrictedUntil, time.Minute)
+	}
+	assert.Contains(t, outcome.Explanation, "cannot book or offer rides")
+
+	// Restricted users can neither book nor offer rides
+	ride := models.Ride{
+		ID:        primitive.NewObjectID(),
+		DriverID:  primitive.NewObjectID(),
+		Status:    models.StatusOpen,
+		Seats:     2,
+		Date:      time.Now().Add(48 * time.Hour),
+		CreatedAt: time.Now(),
+	}
+	assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+	defer testStore.Rides.Delete(context.TODO(), ride.ID)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", passenger.ID.Hex())
+		c.Next()
+	})
+	router.POST("/user/book-ride", controller.BookRide)
+	router.POST("/user/provide-ride", controller.ProvideRide)
+
+	req, _ := http.NewRequest("POST", "/user/book-ride?ride_id="+ride.ID.Hex(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "restricted_until")
+
+	req, _ = http.NewRequest("POST", "/user/provide-ride", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The profile shows the damage
+	router.GET("/user/profile", newUserController().GetUserProfile)
+	req, _ = http.NewRequest("GET", "/user/profile", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var profile struct {
+		User             models.User `json:"user"`
+		ReliabilityScore int         `json:"reliability_score"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+	assert.Equal(t, 3, profile.User.Reliability.LateCancellations)
+	assert.Equal(t, 0, profile.ReliabilityScore)
+}
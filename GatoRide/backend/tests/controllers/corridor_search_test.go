@@ -0,0 +1,165 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCorridorSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/user/provide-ride", controller.ProvideRide)
+	router.PUT("/user/rides/:id", controller.UpdateRide)
+	router.POST("/user/search-ride", controller.SearchRides)
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	gainesville := models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"}
+	ocala := models.Location{Latitude: 29.1872, Longitude: -82.1401, Address: "Ocala"}
+	orlando := models.Location{Latitude: 28.5383, Longitude: -81.3792, Address: "Orlando"}
+	date := time.Date(2034, 9, 8, 15, 0, 0, 0, time.UTC)
+
+	// Offers a ride and returns its ID, removing it when the test ends
+	provide := func(t *testing.T, body map[string]interface{}) primitive.ObjectID {
+		driverID := primitive.NewObjectID()
+		body["price"], body["seats"], body["date"] = 20, 3, date
+		approveDriver(t, driverID)
+		body["vehicle_id"] = registerVehicle(t, driverID).ID.Hex()
+		w := send("POST", "/user/provide-ride", driverID, body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			RideID string `json:"ride_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		rideID, _ := primitive.ObjectIDFromHex(response.RideID)
+		t.Cleanup(func() { testStore.Rides.Delete(context.TODO(), rideID) })
+		return rideID
+	}
+
+	type match struct {
+		ID            primitive.ObjectID `json:"id"`
+		FromStop      int                `json:"from_stop"`
+		ToStop        int                `json:"to_stop"`
+		MatchedBy     string             `json:"matched_by"`
+		DetourMeters  float64            `json:"detour_m"`
+		DetourMinutes float64            `json:"detour_min"`
+		PickupPoint   *models.Point      `json:"pickup_point"`
+	}
+	search := func(from, to models.Location) []match {
+		w := send("POST", "/user/search-ride", primitive.NewObjectID(), map[string]interface{}{
+			"from": from, "to": to, "date": "2034-09-08", "seats": 1,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Rides []match `json:"rides"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Rides
+	}
+
+	// Near campus, and a town about a kilometre off the road between Ocala and Orlando
+	campus := models.Location{Latitude: 29.6480, Longitude: -82.3300}
+	town := models.Location{Latitude: (ocala.Latitude+orlando.Latitude)/2 + 0.009, Longitude: (ocala.Longitude + orlando.Longitude) / 2}
+
+	t.Run("Passengers along a drawn path are picked up on the way", func(t *testing.T) {
+		rideID := provide(t, map[string]interface{}{
+			"pickup": gainesville, "dropoff": orlando,
+			"path": []models.Point{
+				{Latitude: gainesville.Latitude, Longitude: gainesville.Longitude},
+				{Latitude: ocala.Latitude, Longitude: ocala.Longitude},
+				{Latitude: orlando.Latitude, Longitude: orlando.Longitude},
+			},
+		})
+
+		rides := search(campus, town)
+		if assert.Len(t, rides, 1) {
+			assert.Equal(t, rideID, rides[0].ID)
+			assert.Equal(t, "corridor", rides[0].MatchedBy)
+			assert.Equal(t, 0, rides[0].FromStop)
+			assert.Equal(t, 1, rides[0].ToStop)
+			assert.InDelta(t, 2500, rides[0].DetourMeters, 1500)
+			assert.Greater(t, rides[0].DetourMinutes, 0.0)
+			assert.NotNil(t, rides[0].PickupPoint)
+		}
+
+		// Going the other way along the route is no match
+		assert.Empty(t, search(town, campus))
+
+		// The whole trip still matches at the stops, without a detour
+		rides = search(gainesville, orlando)
+		if assert.Len(t, rides, 1) {
+			assert.Equal(t, "stops", rides[0].MatchedBy)
+			assert.Zero(t, rides[0].DetourMeters)
+		}
+	})
+
+	t.Run("Waypoints bound the seats a corridor passenger holds", func(t *testing.T) {
+		rideID := provide(t, map[string]interface{}{"pickup": gainesville, "dropoff": orlando, "waypoints": []models.Location{ocala}})
+
+		// The planned path runs through the waypoint, so the town lies on the Ocala-Orlando leg
+		stored, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, stored.Path) {
+			assert.Len(t, stored.Path.Points, 3)
+			assert.Greater(t, stored.Path.DurationSeconds, 0.0)
+		}
+
+		rides := search(ocala, town)
+		if assert.Len(t, rides, 1) {
+			assert.Equal(t, 1, rides[0].FromStop)
+			assert.Equal(t, 2, rides[0].ToStop)
+		}
+	})
+
+	t.Run("Moving a stop plans a new path", func(t *testing.T) {
+		rideID := provide(t, map[string]interface{}{"pickup": gainesville, "dropoff": orlando})
+		stored, _ := testStore.Rides.FindByID(context.TODO(), rideID)
+
+		w := send("PUT", "/user/rides/"+rideID.Hex(), stored.DriverID, map[string]interface{}{"dropoff": ocala})
+		assert.Equal(t, http.StatusOK, w.Code)
+		stored, _ = testStore.Rides.FindByID(context.TODO(), rideID)
+		if assert.NotNil(t, stored.Path) {
+			last := stored.Path.Points[len(stored.Path.Points)-1]
+			assert.Equal(t, ocala.Latitude, last.Latitude)
+		}
+		assert.Empty(t, search(campus, town))
+	})
+
+	t.Run("A drawn path must pass the stops", func(t *testing.T) {
+		driverID := primitive.NewObjectID()
+		approveDriver(t, driverID)
+		w := send("POST", "/user/provide-ride", driverID, map[string]interface{}{
+			"pickup": gainesville, "dropoff": orlando, "price": 20, "seats": 3, "date": date,
+			"vehicle_id": registerVehicle(t, driverID).ID.Hex(),
+			"path": []models.Point{
+				{Latitude: ocala.Latitude, Longitude: ocala.Longitude},
+				{Latitude: orlando.Latitude, Longitude: orlando.Longitude},
+			},
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,133 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDepartureWindowSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/user/search-ride", newRideController().SearchRides)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	// Gainesville to Orlando on a Friday afternoon, Friday evening and Saturday morning, local time
+	departures := []time.Time{
+		time.Date(2033, 11, 25, 14, 0, 0, 0, newYork),
+		time.Date(2033, 11, 25, 18, 30, 0, 0, newYork),
+		time.Date(2033, 11, 25, 20, 0, 0, 0, newYork), // already the 26th in UTC
+		time.Date(2033, 11, 26, 9, 0, 0, 0, newYork),
+	}
+	var rideIDs []primitive.ObjectID
+	for _, departure := range departures {
+		ride := models.Ride{
+			ID:           primitive.NewObjectID(),
+			DriverID:     primitive.NewObjectID(),
+			Pickup:       models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"},
+			Dropoff:      models.Location{Latitude: 28.5383, Longitude: -81.3792, Address: "Orlando"},
+			Status:       models.StatusOpen,
+			Price:        15,
+			Seats:        3,
+			PassengerIDs: []primitive.ObjectID{},
+			Date:         departure,
+			CreatedAt:    time.Now(),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		rideIDs = append(rideIDs, ride.ID)
+	}
+	t.Cleanup(func() { cleanupTestRides(t, rideIDs) })
+
+	type match struct {
+		models.Ride
+		MinutesFromPreferred *float64 `json:"minutes_from_preferred"`
+	}
+	search := func(extra map[string]interface{}) (int, []match) {
+		body := map[string]interface{}{
+			"from":  map[string]interface{}{"latitude": 29.6516, "longitude": -82.3248},
+			"to":    map[string]interface{}{"latitude": 28.5383, "longitude": -81.3792},
+			"seats": 1,
+		}
+		for key, value := range extra {
+			body[key] = value
+		}
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/user/search-ride", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			Rides []match `json:"rides"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Rides
+	}
+	ids := func(matches []match) []primitive.ObjectID {
+		var result []primitive.ObjectID
+		for _, m := range matches {
+			result = append(result, m.ID)
+		}
+		return result
+	}
+
+	t.Run("A day is read in the passenger's time zone", func(t *testing.T) {
+		code, rides := search(map[string]interface{}{"date": "2033-11-25"})
+		assert.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, rideIDs[:2], ids(rides))
+
+		code, rides = search(map[string]interface{}{"date": "2033-11-25", "time_zone": "America/New_York"})
+		assert.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, rideIDs[:3], ids(rides))
+	})
+
+	t.Run("Flexible hours rank by closeness to the preferred time", func(t *testing.T) {
+		code, rides := search(map[string]interface{}{
+			"date": "2033-11-25", "time": "19:00", "flex_hours": 2, "time_zone": "America/New_York",
+		})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []primitive.ObjectID{rideIDs[1], rideIDs[2]}, ids(rides))
+		if assert.Len(t, rides, 2) && assert.NotNil(t, rides[0].MinutesFromPreferred) {
+			assert.Equal(t, -30.0, *rides[0].MinutesFromPreferred)
+			assert.Equal(t, 60.0, *rides[1].MinutesFromPreferred)
+		}
+
+		// Without flex the whole day is searched, still closest first
+		_, rides = search(map[string]interface{}{"date": "2033-11-25", "time": "15:00", "time_zone": "America/New_York"})
+		assert.Equal(t, []primitive.ObjectID{rideIDs[0], rideIDs[1], rideIDs[2]}, ids(rides))
+	})
+
+	t.Run("A range can span several days", func(t *testing.T) {
+		code, rides := search(map[string]interface{}{
+			"departure_after":  time.Date(2033, 11, 25, 12, 0, 0, 0, newYork),
+			"departure_before": time.Date(2033, 11, 27, 23, 59, 0, 0, newYork),
+		})
+		assert.Equal(t, http.StatusOK, code)
+		assert.ElementsMatch(t, rideIDs, ids(rides))
+	})
+
+	t.Run("Invalid windows", func(t *testing.T) {
+		for _, extra := range []map[string]interface{}{
+			{},
+			{"departure_after": time.Date(2033, 11, 25, 12, 0, 0, 0, newYork)},
+			{"departure_after": time.Date(2033, 11, 1, 0, 0, 0, 0, newYork), "departure_before": time.Date(2033, 11, 20, 0, 0, 0, 0, newYork)},
+			{"date": "2033-11-25", "time_zone": "Mars/Olympus_Mons"},
+			{"date": "2033-11-25", "time": "7pm"},
+			{"date": "2033-11-25", "flex_hours": 500},
+		} {
+			code, _ := search(extra)
+			assert.Equal(t, http.StatusBadRequest, code, "%v", extra)
+		}
+	})
+}
@@ -0,0 +1,302 @@
+package controllers_test
+
+import (
+	"backend/config"
+	"backend/filestore"
+	"backend/middlewares"
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// approveDriver stores an approved driver verification for the user, valid for twenty years,
+// removing it when the test ends
+func approveDriver(t *testing.T, userID primitive.ObjectID) {
+	now := time.Now()
+	verification := models.DriverVerification{
+		UserID:             userID,
+		Status:             models.DriverApproved,
+		LicenceNumber:      "D123-4567",
+		LicenceRegion:      "FL",
+		LicenceExpiresAt:   now.AddDate(20, 0, 0),
+		InsuranceExpiresAt: now.AddDate(20, 0, 0),
+		SubmittedAt:        now,
+	}
+	assert.NoError(t, testStore.Drivers.Save(context.TODO(), &verification))
+	t.Cleanup(func() { _ = testStore.Drivers.Delete(context.TODO(), userID) })
+}
+
+// Just enough of each format for its content type to be recognised
+var (
+	pngScan = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+	pdfScan = []byte("%PDF-1.4\n%scan of an insurance certificate\n")
+)
+
+func TestDriverVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	documents := filestore.NewMemory()
+	drivers := newDriverController(documents)
+	rides := newRideController()
+
+	driver := createCancellingUser(t, "verifyingdriver")
+	admin := createCancellingUser(t, "driveradmin")
+	assert.NoError(t, testStore.Users.MarkVerified(context.TODO(), admin.Email))
+	t.Cleanup(func() { _ = testStore.Drivers.Delete(context.TODO(), driver.ID) })
+
+	// Record review emails instead of sending them
+	var reviewed []models.DriverStatus
+	originalSend := utils.SendDriverReviewFunc
+	utils.SendDriverReviewFunc = func(email string, verification *models.DriverVerification) error {
+		assert.Equal(t, driver.Email, email)
+		reviewed = append(reviewed, verification.Status)
+		return nil
+	}
+	t.Cleanup(func() { utils.SendDriverReviewFunc = originalSend })
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/user/driver/verification", drivers.SubmitVerification)
+	router.GET("/user/driver/verification", drivers.GetVerification)
+	router.POST("/user/provide-ride", rides.ProvideRide)
+	adminRoutes := router.Group("/admin")
+	adminRoutes.Use(middlewares.AdminMiddleware(testStore.Users, config.AdminConfig{Emails: []string{"DriverAdmin@example.com"}}))
+	adminRoutes.GET("/drivers", drivers.ListVerifications)
+	adminRoutes.POST("/drivers/:user_id/approve", drivers.ApproveDriver)
+	adminRoutes.POST("/drivers/:user_id/reject", drivers.RejectDriver)
+	adminRoutes.GET("/drivers/:user_id/documents/:kind", drivers.GetDocument)
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	submit := func(fields map[string]string, files map[string][]byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for name, value := range fields {
+			form.WriteField(name, value)
+		}
+		for name, content := range files {
+			part, _ := form.CreateFormFile(name, name+".bin")
+			part.Write(content)
+		}
+		form.Close()
+		req, _ := http.NewRequest("POST", "/user/driver/verification", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set("X-Test-User", driver.ID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	expiry := time.Now().AddDate(2, 0, 0).Format("2006-01-02")
+	fields := map[string]string{
+		"licence_number": " d123-4567 ", "licence_region": "FL", "licence_expires_at": expiry, "insurance_expires_at": expiry,
+	}
+	scans := map[string][]byte{"licence": pngScan, "insurance": pdfScan}
+
+	vehicle := registerVehicle(t, driver.ID)
+	offer := func() *httptest.ResponseRecorder {
+		return send("POST", "/user/provide-ride", driver.ID, map[string]interface{}{
+			"pickup":     models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"},
+			"dropoff":    models.Location{Latitude: 30.3322, Longitude: -81.6557, Address: "Jacksonville"},
+			"price":      15,
+			"seats":      2,
+			"date":       time.Now().AddDate(0, 1, 0),
+			"vehicle_id": vehicle.ID.Hex(),
+		})
+	}
+
+	t.Run("Unverified users cannot offer rides", func(t *testing.T) {
+		w := offer()
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "licence and insurance")
+	})
+
+	t.Run("Invalid submissions are rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, submit(fields, map[string][]byte{"licence": pngScan}).Code)
+		assert.Equal(t, http.StatusBadRequest, submit(fields, map[string][]byte{"licence": pngScan, "insurance": []byte("just some text")}).Code)
+
+		expired := map[string]string{}
+		for name, value := range fields {
+			expired[name] = value
+		}
+		expired["insurance_expires_at"] = time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+		assert.Equal(t, http.StatusBadRequest, submit(expired, scans).Code)
+
+		tooLarge := map[string][]byte{"licence": pngScan, "insurance": append(pdfScan, make([]byte, config.Defaults().Documents.MaxUploadBytes)...)}
+		assert.Equal(t, http.StatusRequestEntityTooLarge, submit(fields, tooLarge).Code)
+
+		_, err := testStore.Drivers.FindByUser(context.TODO(), driver.ID)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+
+	var first *models.DriverVerification
+	t.Run("Submitted documents wait for review", func(t *testing.T) {
+		w := submit(fields, scans)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		w = send("GET", "/user/driver/verification", driver.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Verification models.DriverVerification `json:"verification"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, models.DriverPending, response.Verification.Status)
+		assert.Equal(t, "D123-4567", response.Verification.LicenceNumber)
+		assert.Len(t, response.Verification.Documents, 2)
+
+		first, _ = testStore.Drivers.FindByUser(context.TODO(), driver.ID)
+		assert.Equal(t, "application/pdf", first.Document(models.DocumentInsurance).ContentType)
+		assert.Contains(t, offer().Body.String(), "still being reviewed")
+	})
+
+	t.Run("Only admins review drivers", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, send("GET", "/admin/drivers", driver.ID, nil).Code)
+		assert.Equal(t, http.StatusForbidden, send("POST", "/admin/drivers/"+driver.ID.Hex()+"/approve", driver.ID, nil).Code)
+
+		w := send("GET", "/admin/drivers?status=pending", admin.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), driver.ID.Hex())
+
+		w = send("GET", "/admin/drivers/"+driver.ID.Hex()+"/documents/licence", admin.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, pngScan, w.Body.Bytes())
+		assert.Equal(t, http.StatusNotFound, send("GET", "/admin/drivers/"+driver.ID.Hex()+"/documents/passport", admin.ID, nil).Code)
+	})
+
+	t.Run("Rejected drivers are told why and may submit again", func(t *testing.T) {
+		path := "/admin/drivers/" + driver.ID.Hex()
+		assert.Equal(t, http.StatusBadRequest, send("POST", path+"/reject", admin.ID, map[string]string{"reason": " "}).Code)
+		w := send("POST", path+"/reject", admin.ID, map[string]string{"reason": "The licence scan is blurry"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusConflict, send("POST", path+"/approve", admin.ID, nil).Code)
+		assert.Contains(t, offer().Body.String(), "The licence scan is blurry")
+
+		inbox, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: driver.ID}, repository.Page{Sort: repository.SortCreated})
+		if assert.NoError(t, err) && assert.Len(t, inbox, 1) {
+			assert.Equal(t, models.NotificationDriverReview, inbox[0].Kind)
+			assert.Contains(t, inbox[0].Message, "blurry")
+		}
+
+		// A new submission replaces the old one and its scans
+		assert.Equal(t, http.StatusCreated, submit(fields, scans).Code)
+		for _, document := range first.Documents {
+			_, _, err := documents.Open(context.TODO(), document.Key)
+			assert.ErrorIs(t, err, filestore.ErrNotFound)
+		}
+	})
+
+	t.Run("Approved drivers can offer rides", func(t *testing.T) {
+		w := send("POST", "/admin/drivers/"+driver.ID.Hex()+"/approve", admin.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, []models.DriverStatus{models.DriverRejected, models.DriverApproved}, reviewed)
+
+		w = offer()
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			RideID string `json:"ride_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		rideID, _ := primitive.ObjectIDFromHex(response.RideID)
+		t.Cleanup(func() { testStore.Rides.Delete(context.TODO(), rideID) })
+
+		stored, _ := testStore.Drivers.FindByUser(context.TODO(), driver.ID)
+		if assert.NotNil(t, stored.ReviewedBy) {
+			assert.Equal(t, admin.ID, *stored.ReviewedBy)
+		}
+		content, contentType, err := documents.Open(context.TODO(), stored.Document(models.DocumentLicence).Key)
+		if assert.NoError(t, err) {
+			defer content.Close()
+			data, _ := io.ReadAll(content)
+			assert.Equal(t, pngScan, data)
+			assert.Equal(t, "image/png", contentType)
+		}
+	})
+}
+
+func TestProvideRide_ExpiringDocuments(t *testing.T) {
+	router := setupRouter()
+	driverID, _ := primitive.ObjectIDFromHex(mockUserID)
+	approveDriver(t, driverID)
+	vehicle := registerVehicle(t, driverID)
+
+	// The insurance runs out before the ride departs
+	verification, _ := testStore.Drivers.FindByUser(context.TODO(), driverID)
+	verification.InsuranceExpiresAt = time.Now().AddDate(0, 0, 10)
+	assert.NoError(t, testStore.Drivers.Save(context.TODO(), verification))
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"pickup":     map[string]interface{}{"latitude": 41.8781, "longitude": -87.6298, "address": "Chicago, IL"},
+		"dropoff":    map[string]interface{}{"latitude": 42.0451, "longitude": -87.6877, "address": "Evanston, IL"},
+		"price":      12,
+		"seats":      2,
+		"date":       time.Now().AddDate(0, 0, 20).Format(time.RFC3339),
+		"vehicle_id": vehicle.ID.Hex(),
+	})
+	req, _ := http.NewRequest("POST", "/user/provide-ride", bytes.NewBuffer(requestBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "expires before the ride")
+}
+
+func TestWarnExpiringDriverDocuments(t *testing.T) {
+	soon := createCancellingUser(t, "expiringdriver")
+	later := createCancellingUser(t, "renewedbydriver")
+	approveDriver(t, soon.ID)
+	approveDriver(t, later.ID)
+
+	verification, _ := testStore.Drivers.FindByUser(context.TODO(), soon.ID)
+	verification.LicenceExpiresAt = time.Now().AddDate(0, 0, 10)
+	assert.NoError(t, testStore.Drivers.Save(context.TODO(), verification))
+
+	var warned []string
+	originalSend := utils.SendDocumentsExpiringFunc
+	utils.SendDocumentsExpiringFunc = func(email string, verification *models.DriverVerification) error {
+		warned = append(warned, email)
+		return nil
+	}
+	t.Cleanup(func() { utils.SendDocumentsExpiringFunc = originalSend })
+
+	// Drivers are warned once per expiry, however often the scheduler runs
+	utils.WarnExpiringDriverDocuments(testStore.Drivers, testStore.Users, testStore.Notifications, 30*24*time.Hour)
+	utils.WarnExpiringDriverDocuments(testStore.Drivers, testStore.Users, testStore.Notifications, 30*24*time.Hour)
+	assert.Equal(t, []string{soon.Email}, warned)
+
+	inbox, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: soon.ID}, repository.Page{Sort: repository.SortCreated})
+	if assert.NoError(t, err) && assert.Len(t, inbox, 1) {
+		assert.Equal(t, models.NotificationDocuments, inbox[0].Kind)
+	}
+
+	// Renewed documents are warned about again when they near their own expiry
+	verification.LicenceExpiresAt = time.Now().AddDate(0, 0, 20)
+	assert.NoError(t, testStore.Drivers.Save(context.TODO(), verification))
+	utils.WarnExpiringDriverDocuments(testStore.Drivers, testStore.Users, testStore.Notifications, 30*24*time.Hour)
+	assert.Equal(t, []string{soon.Email, soon.Email}, warned)
+}
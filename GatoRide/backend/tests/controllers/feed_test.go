@@ -1,8 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
 	"bytes"
 	"context"
@@ -14,7 +12,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -22,11 +19,11 @@ func TestFetchRideFeed(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/rides/feed", controllers.FetchRideFeed)
+	router.POST("/rides/feed", newRideController().FetchRideFeed)
 
 	t.Run("Fetch ride feed with valid data", func(t *testing.T) {
 		// Insert test rides
-		insertTestRidesForFeed(t)
+		rideIDs := insertTestRidesForFeed(t)
 
 		// Create request
 		requestBody := map[string]interface{}{
@@ -55,7 +52,7 @@ func TestFetchRideFeed(t *testing.T) {
 		assert.NotNil(t, rides)
 
 		// Clean up
-		cleanupTestRidesForFeed(t)
+		cleanupTestRidesForFeed(t, rideIDs)
 	})
 
 	t.Run("Fetch ride feed with invalid data", func(t *testing.T) {
@@ -77,10 +74,7 @@ func TestFetchRideFeed(t *testing.T) {
 	})
 
 	t.Run("Fetch ride feed with no rides available", func(t *testing.T) {
-		// Skip this test for now with an explanation
-		t.Skip("This test is skipped due to mock limitations. The mock collection doesn't respect location filters.")
-
-		// Or, modify the assertion to expect what the mock actually returns
+		// Far away from every inserted ride
 		requestBody := map[string]interface{}{
 			"latitude":  80.0000,
 			"longitude": -150.0000,
@@ -99,22 +93,20 @@ func TestFetchRideFeed(t *testing.T) {
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		assert.NoError(t, err)
 
-		// Accept that the mock returns one ride even for far away locations
+		// The feed is an empty array, not null
 		rides, exists := response["rides"]
 		assert.True(t, exists)
-		// Don't check the length, just verify the structure is as expected
-		_, ok := rides.([]interface{})
+		rideList, ok := rides.([]interface{})
 		assert.True(t, ok)
+		assert.Empty(t, rideList)
 	})
 }
 
 // Helper function to insert test rides for feed
-func insertTestRidesForFeed(t *testing.T) {
-	collection := config.GetCollection("rides")
-
+func insertTestRidesForFeed(t *testing.T) []primitive.ObjectID {
 	// Create rides for today
-	todayRides := []interface{}{
-		models.Ride{
+	todayRides := []models.Ride{
+		{
 			ID:       primitive.NewObjectID(),
 			DriverID: primitive.NewObjectID(),
 			Pickup: models.Location{
@@ -131,7 +123,7 @@ func insertTestRidesForFeed(t *testing.T) {
 			Price:     25.99,
 			CreatedAt: time.Now(),
 		},
-		models.Ride{
+		{
 			ID:       primitive.NewObjectID(),
 			DriverID: primitive.NewObjectID(),
 			Pickup: models.Location{
@@ -150,16 +142,20 @@ func insertTestRidesForFeed(t *testing.T) {
 		},
 	}
 
-	_, err := collection.InsertMany(context.TODO(), todayRides)
-	assert.NoError(t, err)
+	var rideIDs []primitive.ObjectID
+	for i := range todayRides {
+		err := testStore.Rides.Create(context.TODO(), &todayRides[i])
+		assert.NoError(t, err)
+		rideIDs = append(rideIDs, todayRides[i].ID)
+	}
+	return rideIDs
 }
 
 // Helper function to clean up test rides for feed
-func cleanupTestRidesForFeed(t *testing.T) {
-	collection := config.GetCollection("rides")
-	// Delete all open status rides to ensure clean test environment
-	_, err := collection.DeleteMany(context.TODO(), bson.M{"status": "open"})
-	if err != nil {
-		t.Logf("Error cleaning up test rides for feed: %v", err)
+func cleanupTestRidesForFeed(t *testing.T, rideIDs []primitive.ObjectID) {
+	for _, rideID := range rideIDs {
+		if err := testStore.Rides.Delete(context.TODO(), rideID); err != nil {
+			t.Logf("Error cleaning up test rides for feed: %v", err)
+		}
 	}
 }
@@ -0,0 +1,127 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDistanceMeters(t *testing.T) {
+	gainesville := models.Location{Latitude: 29.6516, Longitude: -82.3248}
+	orlando := models.Location{Latitude: 28.5383, Longitude: -81.3792}
+
+	// Roughly 155 km as the crow flies
+	assert.InDelta(t, 155000, models.DistanceMeters(gainesville, orlando), 3000)
+	assert.Equal(t, 0.0, models.DistanceMeters(gainesville, gainesville))
+}
+
+func TestGeoSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+	router.POST("/user/search-ride", controller.SearchRides)
+	router.POST("/user/ride-feed", controller.FetchRideFeed)
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// About 3 km north of the pickup and dropoff the passenger asks about
+	date := time.Date(2031, 5, 12, 9, 0, 0, 0, time.UTC)
+	ride := models.Ride{
+		ID:           primitive.NewObjectID(),
+		DriverID:     primitive.NewObjectID(),
+		Pickup:       models.Location{Latitude: 41.907, Longitude: 12.4964, Address: "Rome"},
+		Dropoff:      models.Location{Latitude: 40.8788, Longitude: 14.2681, Address: "Naples"},
+		Status:       models.StatusOpen,
+		Price:        15,
+		Seats:        2,
+		PassengerIDs: []primitive.ObjectID{},
+		Date:         date,
+		CreatedAt:    date.Add(-24 * time.Hour),
+	}
+	assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+	t.Cleanup(func() { testStore.Rides.Delete(context.TODO(), ride.ID) })
+
+	from := map[string]interface{}{"latitude": 41.88, "longitude": 12.4964}
+	to := map[string]interface{}{"latitude": 40.8518, "longitude": 14.2681}
+
+	search := func(radius float64) *httptest.ResponseRecorder {
+		return post("/user/search-ride", map[string]interface{}{
+			"from": from, "to": to, "date": "2031-05-12", "seats": 1, "radius_m": radius,
+		})
+	}
+
+	t.Run("Search reports distances in metres", func(t *testing.T) {
+		w := search(0)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Rides []struct {
+				ID              primitive.ObjectID `json:"id"`
+				PickupDistance  float64            `json:"pickup_distance_m"`
+				DropoffDistance float64            `json:"dropoff_distance_m"`
+			} `json:"rides"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if assert.Len(t, response.Rides, 1) {
+			assert.Equal(t, ride.ID, response.Rides[0].ID)
+			assert.InDelta(t, 3000, response.Rides[0].PickupDistance, 100)
+			assert.InDelta(t, 3000, response.Rides[0].DropoffDistance, 100)
+		}
+	})
+
+	t.Run("Search respects the caller's radius", func(t *testing.T) {
+		w := search(2000)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Rides []models.Ride `json:"rides"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.Rides)
+
+		assert.Equal(t, http.StatusBadRequest, search(-1).Code)
+		assert.Equal(t, http.StatusBadRequest, search(10_000_000).Code)
+	})
+
+	t.Run("Feed reports the pickup distance", func(t *testing.T) {
+		feed := func(radius float64) *httptest.ResponseRecorder {
+			return post("/user/ride-feed", map[string]interface{}{
+				"latitude": 41.88, "longitude": 12.4964, "date": date.Add(-24 * time.Hour), "radius_m": radius,
+			})
+		}
+
+		w := feed(0)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Rides []struct {
+				ID       primitive.ObjectID `json:"id"`
+				Distance float64            `json:"distance_m"`
+			} `json:"rides"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if assert.Len(t, response.Rides, 1) {
+			assert.InDelta(t, 3000, response.Rides[0].Distance, 100)
+		}
+
+		w = feed(1000)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"rides": [], "total": 0, "has_more": false}`, w.Body.String())
+
+		assert.Equal(t, http.StatusBadRequest, feed(10_000_000).Code)
+	})
+}
@@ -1,31 +1,88 @@
 package controllers_test
 
 import (
+	"backend/cancellation"
 	"backend/config"
+	"backend/controllers"
+	"backend/filestore"
+	"backend/routing"
+	"backend/utils"
 	"context"
 	"testing"
-
-	"go.mongodb.org/mongo-driver/bson"
+	"time"
 )
 
+// Controllers wired to the shared test store
+func newAuthController() *controllers.AuthController {
+	return controllers.NewAuthController(testStore.Users, testStore.Sessions)
+}
+
+func newUserController() *controllers.UserController {
+	return controllers.NewUserController(testStore.Users, testStore.Rides, testStore.Bookings, testStore.Blocks, config.Defaults().Search)
+}
+
+func newRideController() *controllers.RideController {
+	cfg := config.Defaults()
+	return controllers.NewRideController(testStore.Rides, testStore.Users, testStore.Bookings, testStore.Waitlist, testStore.Vehicles, testStore.Drivers, testStore.Blocks, cfg.Search, cfg.Bookings, routing.New(cfg.Routing), newRideAlerter(), cancellation.New(cfg.Cancellation))
+}
+
+func newVehicleController() *controllers.VehicleController {
+	return controllers.NewVehicleController(testStore.Vehicles)
+}
+
+func newDriverController(documents filestore.Store) *controllers.DriverController {
+	return controllers.NewDriverController(testStore.Drivers, testStore.Users, testStore.Notifications, documents, config.Defaults().Documents)
+}
+
+func newBlockController() *controllers.BlockController {
+	return controllers.NewBlockController(testStore.Blocks, testStore.Users)
+}
+
+func newReviewController() *controllers.ReviewController {
+	cfg := config.Defaults()
+	return controllers.NewReviewController(testStore.Reviews, testStore.Rides, testStore.Users, testStore.Notifications, cfg.Reviews, cfg.Search)
+}
+
+func newReportController() *controllers.ReportController {
+	return controllers.NewReportController(testStore.Reports, testStore.ModerationLog, testStore.Users, testStore.Rides, testStore.Reviews, testStore.Sessions, testStore.Notifications, newRideController(), config.Defaults().Search)
+}
+
+func newRideAlerter() *controllers.RideAlerter {
+	cfg := config.Defaults()
+	return controllers.NewRideAlerter(testStore.Searches, testStore.Notifications, testStore.Users, testStore.Blocks, cfg.Search, cfg.Alerts)
+}
+
+func newAlertController() *controllers.AlertController {
+	cfg := config.Defaults()
+	return controllers.NewAlertController(testStore.Searches, testStore.Notifications, cfg.Search, cfg.Alerts)
+}
+
+func newRideRequestController() *controllers.RideRequestController {
+	cfg := config.Defaults()
+	return controllers.NewRideRequestController(testStore.Requests, testStore.Offers, testStore.Rides, testStore.Bookings, testStore.Users, testStore.Vehicles, testStore.Drivers, cfg.Search, routing.New(cfg.Routing))
+}
+
+func newRideSeriesController() *controllers.RideSeriesController {
+	cfg := config.Defaults()
+	return controllers.NewRideSeriesController(testStore.Series, testStore.Rides, testStore.Users, testStore.Vehicles, testStore.Drivers, testStore.Notifications, newRideController(), cfg.Scheduler, routing.New(cfg.Routing))
+}
+
+func newWaitlistPromoter() *utils.WaitlistPromoter {
+	timeout := time.Duration(config.Defaults().Bookings.RequestTimeout)
+	return utils.NewWaitlistPromoter(testStore.Rides, testStore.Bookings, testStore.Waitlist, testStore.Users, timeout)
+}
+
 // Helper function to clean up test users
 func cleanupTestUser(t *testing.T, email string, username string) {
-	collection := config.GetCollection("users")
-	filter := bson.M{}
-
-	if email != "" && username != "" {
-		filter = bson.M{"$or": []bson.M{
-			{"email": email},
-			{"username": username},
-		}}
-	} else if email != "" {
-		filter = bson.M{"email": email}
-	} else if username != "" {
-		filter = bson.M{"username": username}
+	if email != "" {
+		if user, err := testStore.Users.FindByEmail(context.TODO(), email); err == nil {
+			_ = testStore.Users.Delete(context.TODO(), user.ID)
+		}
 	}
-
-	if len(filter) > 0 {
-		_, _ = collection.DeleteMany(context.TODO(), filter)
+	if username != "" {
+		if user, err := testStore.Users.FindByUsername(context.TODO(), username); err == nil {
+			_ = testStore.Users.Delete(context.TODO(), user.ID)
+		}
 	}
 }
 
@@ -35,6 +92,5 @@ func cleanupUserSessions(t *testing.T, userID string) {
 		return
 	}
 
-	collection := config.GetCollection("sessions")
-	_, _ = collection.DeleteMany(context.TODO(), bson.M{"user_id": userID})
+	_ = testStore.Sessions.DeleteByUser(context.TODO(), userID)
 }
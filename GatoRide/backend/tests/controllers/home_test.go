@@ -1,8 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
 	"context"
 	"encoding/json"
@@ -13,7 +11,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -29,7 +26,7 @@ func TestHomeHandler(t *testing.T) {
 		c.Next()
 	})
 
-	router.GET("/home", controllers.HomeHandler)
+	router.GET("/home", newUserController().HomeHandler)
 
 	t.Run("Home handler with valid user and location", func(t *testing.T) {
 		// Create user with location first
@@ -40,7 +37,7 @@ func TestHomeHandler(t *testing.T) {
 		insertTestUserWithLocation(t, userID)
 
 		// Insert test rides
-		insertTestRides(t)
+		rideIDs := insertTestRides(t)
 
 		// Make request
 		req, _ := http.NewRequest("GET", "/home", nil)
@@ -62,7 +59,7 @@ func TestHomeHandler(t *testing.T) {
 
 		// Clean up
 		cleanupTestUser(t, "home-test@example.com", "hometest")
-		cleanupTestRides(t)
+		cleanupTestRides(t, rideIDs)
 	})
 
 	t.Run("Home handler with user without location", func(t *testing.T) {
@@ -88,7 +85,6 @@ func TestHomeHandler(t *testing.T) {
 
 // Helper function to insert a test user with location
 func insertTestUserWithLocation(t *testing.T, userID primitive.ObjectID) {
-	collection := config.GetCollection("users")
 	user := models.User{
 		ID:       userID,
 		Name:     "Home Test User",
@@ -102,13 +98,13 @@ func insertTestUserWithLocation(t *testing.T, userID primitive.ObjectID) {
 		},
 		IsVerified: true,
 	}
-	_, err := collection.InsertOne(context.TODO(), user)
+
+	err := testStore.Users.Create(context.TODO(), &user)
 	assert.NoError(t, err)
 }
 
 // Helper function to insert a test user without location
 func insertTestUserWithoutLocation(t *testing.T, userID primitive.ObjectID) {
-	collection := config.GetCollection("users")
 	user := models.User{
 		ID:         userID,
 		Name:       "Home Test User",
@@ -117,17 +113,16 @@ func insertTestUserWithoutLocation(t *testing.T, userID primitive.ObjectID) {
 		Password:   "password",
 		IsVerified: true,
 	}
-	_, err := collection.InsertOne(context.TODO(), user)
+
+	err := testStore.Users.Create(context.TODO(), &user)
 	assert.NoError(t, err)
 }
 
 // Helper function to insert test rides
-func insertTestRides(t *testing.T) {
-	collection := config.GetCollection("rides")
-
+func insertTestRides(t *testing.T) []primitive.ObjectID {
 	// Create rides near our test user location
-	rides := []interface{}{
-		models.Ride{
+	rides := []models.Ride{
+		{
 			ID:       primitive.NewObjectID(),
 			DriverID: primitive.NewObjectID(),
 			Pickup: models.Location{
@@ -144,7 +139,7 @@ func insertTestRides(t *testing.T) {
 			Price:     25.99,
 			CreatedAt: time.Now(),
 		},
-		models.Ride{
+		{
 			ID:       primitive.NewObjectID(),
 			DriverID: primitive.NewObjectID(),
 			Pickup: models.Location{
@@ -163,15 +158,20 @@ func insertTestRides(t *testing.T) {
 		},
 	}
 
-	_, err := collection.InsertMany(context.TODO(), rides)
-	assert.NoError(t, err)
+	var rideIDs []primitive.ObjectID
+	for i := range rides {
+		err := testStore.Rides.Create(context.TODO(), &rides[i])
+		assert.NoError(t, err)
+		rideIDs = append(rideIDs, rides[i].ID)
+	}
+	return rideIDs
 }
 
 // Helper function to clean up test rides
-func cleanupTestRides(t *testing.T) {
-	collection := config.GetCollection("rides")
-	_, err := collection.DeleteMany(context.TODO(), bson.M{"status": "open"})
-	if err != nil {
-		t.Logf("Error cleaning up test rides: %v", err)
+func cleanupTestRides(t *testing.T, rideIDs []primitive.ObjectID) {
+	for _, rideID := range rideIDs {
+		if err := testStore.Rides.Delete(context.TODO(), rideID); err != nil {
+			t.Logf("Error cleaning up test rides: %v", err)
+		}
 	}
 }
@@ -1,8 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
 	"backend/utils"
 	"bytes"
@@ -22,7 +20,7 @@ func TestLogin(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/login", controllers.Login)
+	router.POST("/login", newAuthController().Login)
 
 	// Test case 1: Successful login
 	t.Run("Successful login", func(t *testing.T) {
@@ -40,11 +38,10 @@ func TestLogin(t *testing.T) {
 		cleanupUserSessions(t, testUser.ID.Hex())
 
 		// Insert test user into DB
-		collection := config.GetCollection("users")
 		hashedPassword, _ := utils.HashPassword(testUser.Password)
 		testUser.Password = hashedPassword
 		testUser.ID = primitive.NewObjectID()
-		_, err := collection.InsertOne(context.TODO(), testUser)
+		err := testStore.Users.Create(context.TODO(), &testUser)
 		assert.NoError(t, err)
 
 		// Create login request
@@ -88,11 +85,10 @@ func TestLogin(t *testing.T) {
 		cleanupUserSessions(t, testUser.ID.Hex())
 
 		// Insert test user into DB
-		collection := config.GetCollection("users")
 		hashedPassword, _ := utils.HashPassword(testUser.Password)
 		testUser.Password = hashedPassword
 		testUser.ID = primitive.NewObjectID()
-		_, err := collection.InsertOne(context.TODO(), testUser)
+		err := testStore.Users.Create(context.TODO(), &testUser)
 		assert.NoError(t, err)
 
 		// Create login request with incorrect password
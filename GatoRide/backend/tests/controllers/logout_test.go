@@ -1,9 +1,8 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
+	"backend/repository"
 	"context"
 	"net/http"
 	"net/http/httptest"
@@ -20,12 +19,11 @@ func TestLogout(t *testing.T) {
 	// Set up
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/users/logout", controllers.LogOut)
+	router.POST("/users/logout", newAuthController().LogOut)
 
 	// Test successful logout
 	t.Run("Successful logout", func(t *testing.T) {
 		// Create a test session in the database
-		sessionCollection := config.GetCollection("sessions")
 		testToken := "test-token-12345"
 		testSession := models.Session{
 			UserID:    primitive.NewObjectID().Hex(),
@@ -33,7 +31,7 @@ func TestLogout(t *testing.T) {
 			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
 		}
 
-		_, err := sessionCollection.InsertOne(context.TODO(), testSession)
+		err := testStore.Sessions.Create(context.TODO(), &testSession)
 		assert.NoError(t, err)
 
 		// Create request with the token
@@ -47,12 +45,8 @@ func TestLogout(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 
 		// Verify session was deleted
-		var count int64
-		count, err = sessionCollection.CountDocuments(context.TODO(), map[string]interface{}{
-			"token": testToken,
-		})
-		assert.NoError(t, err)
-		assert.Equal(t, int64(0), count)
+		_, err = testStore.Sessions.FindByToken(context.TODO(), testToken)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
 	})
 
 	// Test logout with invalid token
@@ -0,0 +1,196 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPaginatedSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/user/search-ride", newRideController().SearchRides)
+
+	// Five rides between the same towns, an hour apart and each a little cheaper than the last
+	day := time.Date(2032, 3, 4, 0, 0, 0, 0, time.UTC)
+	var rideIDs []primitive.ObjectID
+	for i := 0; i < 5; i++ {
+		ride := models.Ride{
+			ID:           primitive.NewObjectID(),
+			DriverID:     primitive.NewObjectID(),
+			Pickup:       models.Location{Latitude: 52.3676, Longitude: 4.9041, Address: "Amsterdam"},
+			Dropoff:      models.Location{Latitude: 52.0907, Longitude: 5.1214, Address: "Utrecht"},
+			Status:       models.StatusOpen,
+			Price:        float64(30 - i*5),
+			Seats:        2,
+			PassengerIDs: []primitive.ObjectID{},
+			Date:         day.Add(time.Duration(8+i) * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		rideIDs = append(rideIDs, ride.ID)
+	}
+	t.Cleanup(func() { cleanupTestRides(t, rideIDs) })
+
+	type searchPage struct {
+		Rides      []models.Ride `json:"rides"`
+		Total      int           `json:"total"`
+		HasMore    bool          `json:"has_more"`
+		NextCursor string        `json:"next_cursor"`
+	}
+
+	search := func(extra map[string]interface{}) (int, searchPage) {
+		body := map[string]interface{}{
+			"from":  map[string]interface{}{"latitude": 52.3676, "longitude": 4.9041},
+			"to":    map[string]interface{}{"latitude": 52.0907, "longitude": 5.1214},
+			"date":  "2032-03-04",
+			"seats": 1,
+		}
+		for key, value := range extra {
+			body[key] = value
+		}
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/user/search-ride", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var page searchPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return w.Code, page
+	}
+
+	prices := func(rides []models.Ride) []float64 {
+		var result []float64
+		for _, ride := range rides {
+			result = append(result, ride.Price)
+		}
+		return result
+	}
+
+	t.Run("Cursors walk every page once", func(t *testing.T) {
+		code, page := search(map[string]interface{}{"sort": "price", "limit": 2})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 5, page.Total)
+		assert.True(t, page.HasMore)
+		assert.Equal(t, []float64{10, 15}, prices(page.Rides))
+
+		code, page = search(map[string]interface{}{"sort": "price", "limit": 2, "cursor": page.NextCursor})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []float64{20, 25}, prices(page.Rides))
+
+		code, page = search(map[string]interface{}{"sort": "price", "limit": 2, "cursor": page.NextCursor})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []float64{30}, prices(page.Rides))
+		assert.False(t, page.HasMore)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Departure order and descending", func(t *testing.T) {
+		_, page := search(map[string]interface{}{"sort": "departure", "order": "desc", "limit": 3})
+		if assert.Len(t, page.Rides, 3) {
+			assert.Equal(t, day.Add(12*time.Hour), page.Rides[0].Date.UTC())
+			assert.Equal(t, day.Add(10*time.Hour), page.Rides[2].Date.UTC())
+		}
+	})
+
+	t.Run("Price and departure filters", func(t *testing.T) {
+		_, page := search(map[string]interface{}{"sort": "price", "min_price": 15, "max_price": 25})
+		assert.Equal(t, []float64{15, 20, 25}, prices(page.Rides))
+
+		// 09:00 up to 11:00 keeps the 09:00 and 10:00 rides
+		_, page = search(map[string]interface{}{
+			"sort":             "departure",
+			"departure_after":  day.Add(9 * time.Hour),
+			"departure_before": day.Add(11 * time.Hour),
+		})
+		assert.Equal(t, []float64{25, 20}, prices(page.Rides))
+	})
+
+	t.Run("Invalid paging and filters", func(t *testing.T) {
+		_, first := search(map[string]interface{}{"sort": "price", "limit": 2})
+
+		for _, extra := range []map[string]interface{}{
+			{"sort": "seats"},
+			{"order": "sideways"},
+			{"limit": 1000},
+			{"cursor": "not-a-cursor"},
+			{"sort": "departure", "limit": 2, "cursor": first.NextCursor}, // cursor from another order
+			{"min_price": 30, "max_price": 10},
+			{"departure_after": day.Add(48 * time.Hour)},
+		} {
+			code, _ := search(extra)
+			assert.Equal(t, http.StatusBadRequest, code, "%v", extra)
+		}
+	})
+}
+
+func TestPaginatedUserRides(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	driverID := primitive.NewObjectID()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", driverID.Hex())
+	})
+	router.POST("/user/rides", newUserController().GetUserRides)
+
+	var rideIDs []primitive.ObjectID
+	for i := 0; i < 3; i++ {
+		ride := models.Ride{
+			ID:        primitive.NewObjectID(),
+			DriverID:  driverID,
+			Status:    models.StatusOpen,
+			Price:     10,
+			Seats:     1,
+			Date:      time.Now().Add(time.Duration(i+1) * 24 * time.Hour).Truncate(time.Millisecond),
+			CreatedAt: time.Now(),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		rideIDs = append(rideIDs, ride.ID)
+	}
+	t.Cleanup(func() { cleanupTestRides(t, rideIDs) })
+
+	type userRides struct {
+		RidesOffered []models.Ride     `json:"rides_offered"`
+		HasMore      map[string]bool   `json:"has_more"`
+		NextCursors  map[string]string `json:"next_cursors"`
+	}
+	fetch := func(query string) (int, userRides) {
+		req, _ := http.NewRequest("POST", "/user/rides?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response userRides
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// Latest departure first by default
+	code, page := fetch("limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, page.RidesOffered, 2) {
+		assert.Equal(t, rideIDs[2], page.RidesOffered[0].ID)
+		assert.Equal(t, rideIDs[1], page.RidesOffered[1].ID)
+	}
+	assert.True(t, page.HasMore["rides_offered"])
+	assert.False(t, page.HasMore["bookings"])
+
+	code, page = fetch("limit=2&offered_cursor=" + page.NextCursors["rides_offered"])
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, page.RidesOffered, 1) {
+		assert.Equal(t, rideIDs[0], page.RidesOffered[0].ID)
+	}
+	assert.False(t, page.HasMore["rides_offered"])
+
+	code, _ = fetch("sort=distance")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -1,8 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
 	"context"
 	"encoding/json"
@@ -28,8 +26,7 @@ func TestGetUserProfile(t *testing.T) {
 		Username: "profile_user",
 		Password: "hashedpass",
 	}
-	collection := config.GetCollection("users")
-	_, _ = collection.InsertOne(context.TODO(), testUser)
+	_ = testStore.Users.Create(context.TODO(), &testUser)
 	defer cleanupTestUser(t, testUser.Email, testUser.Username)
 
 	// Inject mock userID
@@ -37,7 +34,7 @@ func TestGetUserProfile(t *testing.T) {
 		c.Set("userID", testUser.ID.Hex())
 		c.Next()
 	})
-	router.GET("/user/profile", controllers.GetUserProfile)
+	router.GET("/user/profile", newUserController().GetUserProfile)
 
 	req, _ := http.NewRequest("GET", "/user/profile", nil)
 	w := httptest.NewRecorder()
@@ -62,22 +59,21 @@ func TestGetUserRides(t *testing.T) {
 		Username: "ridetester",
 		Password: "secret",
 	}
-	userCol := config.GetCollection("users")
-	_, _ = userCol.InsertOne(context.TODO(), testUser)
+	_ = testStore.Users.Create(context.TODO(), &testUser)
 	defer cleanupTestUser(t, testUser.Email, testUser.Username)
 
 	ride := models.Ride{
 		DriverID: testUserID,
 		Status:   "open",
 	}
-	rideCol := config.GetCollection("rides")
-	_, _ = rideCol.InsertOne(context.TODO(), ride)
+	_ = testStore.Rides.Create(context.TODO(), &ride)
+	defer testStore.Rides.Delete(context.TODO(), ride.ID)
 
 	router.Use(func(c *gin.Context) {
 		c.Set("userID", testUserID.Hex())
 		c.Next()
 	})
-	router.GET("/user/profile/rides", controllers.GetUserRides)
+	router.GET("/user/profile/rides", newUserController().GetUserRides)
 
 	req, _ := http.NewRequest("GET", "/user/profile/rides", nil)
 	w := httptest.NewRecorder()
@@ -89,4 +85,5 @@ func TestGetUserRides(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &response)
 	assert.Contains(t, response, "rides_offered")
 	assert.Contains(t, response, "rides_taken")
+	assert.Contains(t, response, "bookings")
 }
@@ -1,9 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
-	"backend/models"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -14,7 +11,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -32,13 +28,16 @@ func setupRouter() *gin.Engine {
 		c.Next()
 	})
 
-	router.POST("/user/provide-ride", controllers.ProvideRide)
+	router.POST("/user/provide-ride", newRideController().ProvideRide)
 	return router
 }
 
 // ✅ **Test: Successful Ride Creation**
 func TestProvideRide_Success(t *testing.T) {
 	router := setupRouter()
+	driverID, _ := primitive.ObjectIDFromHex(mockUserID)
+	approveDriver(t, driverID)
+	vehicle := registerVehicle(t, driverID)
 
 	requestBody, _ := json.Marshal(map[string]interface{}{
 		"pickup": map[string]interface{}{
@@ -51,9 +50,10 @@ func TestProvideRide_Success(t *testing.T) {
 			"longitude": -73.9352,
 			"address":   "Brooklyn, NY",
 		},
-		"price": 25.5,
-		"seats": 3,
-		"date":  time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+		"price":      25.5,
+		"seats":      3,
+		"date":       time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+		"vehicle_id": vehicle.ID.Hex(),
 	})
 
 	req, _ := http.NewRequest("POST", "/user/provide-ride", bytes.NewBuffer(requestBody))
@@ -74,12 +74,13 @@ func TestProvideRide_Success(t *testing.T) {
 	rideID, err := primitive.ObjectIDFromHex(rideIDStr)
 	assert.Nil(t, err)
 
-	collection := config.GetCollection("rides")
-	var createdRide models.Ride
-	err = collection.FindOne(context.TODO(), bson.M{"_id": rideID}).Decode(&createdRide)
+	createdRide, err := testStore.Rides.FindByID(context.TODO(), rideID)
 	assert.Nil(t, err)
 	assert.Equal(t, createdRide.DriverID.Hex(), mockUserID)
 	assert.Equal(t, 3, createdRide.Seats)
+	if assert.NotNil(t, createdRide.Vehicle) {
+		assert.Equal(t, vehicle.Plate, createdRide.Vehicle.Plate)
+	}
 	assert.WithinDuration(t, time.Now().Add(24*time.Hour), createdRide.Date, time.Hour*24)
 
 	cleanupTestRide(t, rideID)
@@ -89,7 +90,7 @@ func TestProvideRide_Success(t *testing.T) {
 func TestProvideRide_Unauthorized(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/user/provide-ride", controllers.ProvideRide)
+	router.POST("/user/provide-ride", newRideController().ProvideRide)
 
 	req, _ := http.NewRequest("POST", "/user/provide-ride", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -131,8 +132,7 @@ func TestProvideRide_InvalidData(t *testing.T) {
 
 // 🛠 **Helper Function: Clean Up Test Ride**
 func cleanupTestRide(t *testing.T, rideID primitive.ObjectID) {
-	collection := config.GetCollection("rides")
-	_, err := collection.DeleteOne(context.TODO(), bson.M{"_id": rideID})
+	err := testStore.Rides.Delete(context.TODO(), rideID)
 	if err != nil {
 		t.Logf("⚠️ Failed to clean up test ride: %v", err)
 	}
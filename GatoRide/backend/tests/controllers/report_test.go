@@ -0,0 +1,304 @@
+package controllers_test
+
+import (
+	"backend/config"
+	"backend/middlewares"
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	reports := newReportController()
+	router.POST("/login", newAuthController().Login)
+
+	// The reported driver can log in, so the suspension can keep them out
+	password, _ := utils.HashPassword("Password")
+	driver := models.User{
+		ID:         primitive.NewObjectID(),
+		Name:       "reporteddriver",
+		Email:      "reporteddriver@example.com",
+		Username:   "reporteddriver",
+		Password:   password,
+		IsVerified: true,
+	}
+	assert.NoError(t, testStore.Users.Create(context.TODO(), &driver))
+	t.Cleanup(func() {
+		_ = testStore.Users.Delete(context.TODO(), driver.ID)
+		cleanupUserSessions(t, driver.ID.Hex())
+	})
+	rider := createCancellingUser(t, "reportingrider")
+	admin := createCancellingUser(t, "moderatoradmin")
+	assert.NoError(t, testStore.Users.MarkVerified(context.TODO(), admin.Email))
+	ride, booking := createBookedRide(t, driver.ID, rider.ID, 48*time.Hour, models.BookingConfirmed)
+
+	// Record the emails instead of sending them
+	var suspendedUntil []time.Time
+	originalSuspended := utils.SendAccountSuspendedFunc
+	utils.SendAccountSuspendedFunc = func(email string, until time.Time, reason string) error {
+		assert.Equal(t, driver.Email, email)
+		suspendedUntil = append(suspendedUntil, until)
+		return nil
+	}
+	var cancelled []*models.Ride
+	originalCancelled := utils.SendRideCancelledFunc
+	utils.SendRideCancelledFunc = func(email string, ride *models.Ride, alternatives []models.Ride) error {
+		assert.Equal(t, rider.Email, email)
+		cancelled = append(cancelled, ride)
+		return nil
+	}
+	t.Cleanup(func() {
+		utils.SendAccountSuspendedFunc = originalSuspended
+		utils.SendRideCancelledFunc = originalCancelled
+	})
+
+	// Every request acts as the user named in a header
+	user := router.Group("/")
+	user.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	user.POST("/user/reports", reports.SubmitReport)
+	user.GET("/user/reports", reports.ListReports)
+	adminRoutes := user.Group("/admin")
+	adminRoutes.Use(middlewares.AdminMiddleware(testStore.Users, config.AdminConfig{Emails: []string{"ModeratorAdmin@example.com"}}))
+	adminRoutes.GET("/reports", reports.ListReportQueue)
+	adminRoutes.GET("/reports/:id", reports.GetReport)
+	adminRoutes.POST("/reports/:id/investigate", reports.InvestigateReport)
+	adminRoutes.POST("/reports/:id/actions", reports.TakeAction)
+	adminRoutes.POST("/reports/:id/resolve", reports.ResolveReport)
+	adminRoutes.GET("/moderation-log", reports.ListModerationLog)
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	report := func(userID primitive.ObjectID, target models.ReportTarget, targetID primitive.ObjectID, category models.ReportCategory) *httptest.ResponseRecorder {
+		return send("POST", "/user/reports", userID, map[string]interface{}{
+			"target": target, "target_id": targetID.Hex(), "category": category, "description": "Nobody showed up at the pickup",
+		})
+	}
+	inbox := func(userID primitive.ObjectID) []models.Notification {
+		notifications, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: userID}, repository.Page{Sort: repository.SortCreated})
+		assert.NoError(t, err)
+		t.Cleanup(func() {
+			for _, notification := range notifications {
+				_ = testStore.Notifications.Delete(context.TODO(), notification.ID)
+			}
+		})
+		return notifications
+	}
+
+	// A review the rider wrote about the driver, and one not revealed yet
+	now := time.Now()
+	revealed := models.Review{RideID: ride.ID, ReviewerID: rider.ID, RevieweeID: driver.ID, Role: models.ReviewByPassenger, Rating: 1, Comment: "Awful", CreatedAt: now, RevealAt: now, RevealedAt: &now}
+	hidden := models.Review{RideID: ride.ID, ReviewerID: driver.ID, RevieweeID: rider.ID, Role: models.ReviewByDriver, Rating: 1, CreatedAt: now, RevealAt: now.Add(time.Hour)}
+	assert.NoError(t, testStore.Reviews.Create(context.TODO(), &revealed))
+	assert.NoError(t, testStore.Reviews.Create(context.TODO(), &hidden))
+	t.Cleanup(func() {
+		_ = testStore.Reviews.Delete(context.TODO(), revealed.ID)
+		_ = testStore.Reviews.Delete(context.TODO(), hidden.ID)
+	})
+
+	var reportID primitive.ObjectID
+	t.Cleanup(func() { _ = testStore.Reports.Delete(context.TODO(), reportID) })
+
+	t.Run("Reports are validated", func(t *testing.T) {
+		w := send("POST", "/user/reports", rider.ID, map[string]interface{}{"target": "car", "target_id": ride.ID.Hex(), "category": "scam", "description": "x"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = send("POST", "/user/reports", rider.ID, map[string]interface{}{"target": "ride", "target_id": "nope", "category": "scam", "description": "x"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, http.StatusBadRequest, report(rider.ID, models.ReportRide, ride.ID, "rude").Code)
+		w = send("POST", "/user/reports", rider.ID, map[string]interface{}{"target": "ride", "target_id": ride.ID.Hex(), "category": "scam", "description": "  "})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		assert.Equal(t, http.StatusNotFound, report(rider.ID, models.ReportUser, primitive.NewObjectID(), models.CategoryHarassment).Code)
+		assert.Equal(t, http.StatusNotFound, report(rider.ID, models.ReportReview, hidden.ID, models.CategoryInappropriate).Code)
+		assert.Equal(t, http.StatusBadRequest, report(driver.ID, models.ReportRide, ride.ID, models.CategoryFakeListing).Code)
+		assert.Equal(t, http.StatusBadRequest, report(rider.ID, models.ReportReview, revealed.ID, models.CategoryInappropriate).Code)
+	})
+
+	t.Run("Users report a ride once", func(t *testing.T) {
+		w := report(rider.ID, models.ReportRide, ride.ID, models.CategoryFakeListing)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Report models.Report `json:"report"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		reportID = response.Report.ID
+		assert.Equal(t, driver.ID, response.Report.SubjectID)
+		assert.Equal(t, models.ReportOpen, response.Report.Status)
+
+		assert.Equal(t, http.StatusConflict, report(rider.ID, models.ReportRide, ride.ID, models.CategoryScam).Code)
+
+		w = send("GET", "/user/reports", rider.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), reportID.Hex())
+	})
+
+	t.Run("Revealed reviews can be reported", func(t *testing.T) {
+		w := report(driver.ID, models.ReportReview, revealed.ID, models.CategoryInappropriate)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Report models.Report `json:"report"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, rider.ID, response.Report.SubjectID)
+		_ = testStore.Reports.Delete(context.TODO(), response.Report.ID)
+	})
+
+	t.Run("Only admins handle reports", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, send("GET", "/admin/reports", rider.ID, nil).Code)
+		assert.Equal(t, http.StatusForbidden, send("POST", "/admin/reports/"+reportID.Hex()+"/investigate", driver.ID, nil).Code)
+	})
+
+	t.Run("Admins take reports on", func(t *testing.T) {
+		w := send("GET", "/admin/reports", admin.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), reportID.Hex())
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/reports?status=closed", admin.ID, nil).Code)
+
+		w = send("POST", "/admin/reports/"+reportID.Hex()+"/investigate", admin.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusConflict, send("POST", "/admin/reports/"+reportID.Hex()+"/investigate", admin.ID, nil).Code)
+
+		w = send("GET", "/admin/reports", admin.ID, nil)
+		assert.NotContains(t, w.Body.String(), reportID.Hex())
+		w = send("GET", "/admin/reports?status=investigating&subject_id="+driver.ID.Hex(), admin.ID, nil)
+		assert.Contains(t, w.Body.String(), reportID.Hex())
+	})
+
+	t.Run("Admins warn and suspend the subject", func(t *testing.T) {
+		path := "/admin/reports/" + reportID.Hex() + "/actions"
+		assert.Equal(t, http.StatusBadRequest, send("POST", path, admin.ID, map[string]interface{}{"action": "ban", "note": "Fake ride"}).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", path, admin.ID, map[string]interface{}{"action": "warn"}).Code)
+
+		w := send("POST", path, admin.ID, map[string]interface{}{"action": "warn", "note": "Only offer rides you will drive"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		messages := inbox(driver.ID)
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, models.NotificationModeration, messages[0].Kind)
+			assert.Contains(t, messages[0].Message, "Only offer rides you will drive")
+		}
+
+		session := models.Session{UserID: driver.ID.Hex(), Token: "reported-driver-token", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+		assert.NoError(t, testStore.Sessions.Create(context.TODO(), &session))
+
+		assert.Equal(t, http.StatusBadRequest, send("POST", path, admin.ID, map[string]interface{}{"action": "suspend", "note": "Repeated fake rides"}).Code)
+		w = send("POST", path, admin.ID, map[string]interface{}{"action": "suspend", "note": "Repeated fake rides", "days": 7})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, suspendedUntil, 1)
+
+		updated, err := testStore.Users.FindByID(context.TODO(), driver.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, updated.Moderation.Warnings)
+		assert.True(t, updated.Moderation.Suspended(time.Now().AddDate(0, 0, 6)))
+		assert.False(t, updated.Moderation.Suspended(time.Now().AddDate(0, 0, 8)))
+
+		// The driver is logged out and cannot log back in
+		_, err = testStore.Sessions.FindByToken(context.TODO(), session.Token)
+		assert.Error(t, err)
+		payload, _ := json.Marshal(map[string]string{"email": driver.Email, "password": "Password"})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "suspended")
+	})
+
+	t.Run("Admins remove reported rides", func(t *testing.T) {
+		path := "/admin/reports/" + reportID.Hex() + "/actions"
+		w := send("POST", path, admin.ID, map[string]interface{}{"action": "remove_ride", "note": "This ride was never going to happen"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"passengers_notified":1`)
+
+		removed, err := testStore.Rides.FindByID(context.TODO(), ride.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusCancelled, removed.Status)
+		assert.Equal(t, models.ReasonRemoved, removed.Cancellation.Reason)
+		cancelledBooking, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.BookingCancelledByDriver, cancelledBooking.Status)
+
+		// The passenger hears the moderators took it down, not that the driver cancelled
+		assert.Len(t, cancelled, 1)
+		messages := inbox(rider.ID)
+		if assert.Len(t, messages, 1) {
+			assert.Contains(t, messages[0].Message, "taken down by our moderators")
+		}
+		// The removal does not count as the driver cancelling
+		updated, err := testStore.Users.FindByID(context.TODO(), driver.ID)
+		assert.NoError(t, err)
+		assert.Zero(t, updated.Reliability.Cancellations)
+
+		assert.Equal(t, http.StatusConflict, send("POST", path, admin.ID, map[string]interface{}{"action": "remove_ride", "note": "Again"}).Code)
+	})
+
+	t.Run("Resolving closes the report", func(t *testing.T) {
+		path := "/admin/reports/" + reportID.Hex() + "/resolve"
+		assert.Equal(t, http.StatusBadRequest, send("POST", path, admin.ID, map[string]interface{}{}).Code)
+		w := send("POST", path, admin.ID, map[string]interface{}{"resolution": "We removed the ride and suspended the driver"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusConflict, send("POST", path, admin.ID, map[string]interface{}{"resolution": "Again"}).Code)
+		w = send("POST", "/admin/reports/"+reportID.Hex()+"/actions", admin.ID, map[string]interface{}{"action": "warn", "note": "Too late"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// After the ride's cancellation comes the outcome of the report
+		messages := inbox(rider.ID)
+		if assert.Len(t, messages, 2) {
+			assert.Equal(t, models.NotificationModeration, messages[1].Kind)
+			assert.Contains(t, messages[1].Message, "We removed the ride and suspended the driver")
+		}
+
+		// The reporter sees the outcome, but not who handled it
+		w = send("GET", "/user/reports", rider.ID, nil)
+		assert.Contains(t, w.Body.String(), `"status":"resolved"`)
+		assert.NotContains(t, w.Body.String(), "assignee_id")
+	})
+
+	t.Run("Every admin action is audit-logged", func(t *testing.T) {
+		w := send("GET", "/admin/moderation-log?order=asc&report_id="+reportID.Hex(), admin.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Actions []models.ModerationAction `json:"actions"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		var kinds []models.ModerationActionKind
+		for _, action := range response.Actions {
+			assert.Equal(t, admin.ID, action.AdminID)
+			assert.Equal(t, driver.ID, action.SubjectID)
+			kinds = append(kinds, action.Action)
+		}
+		assert.Equal(t, []models.ModerationActionKind{
+			models.ActionInvestigate, models.ActionWarn, models.ActionSuspend, models.ActionRemoveRide, models.ActionResolve,
+		}, kinds)
+
+		w = send("GET", "/admin/reports/"+reportID.Hex(), admin.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"warnings":1`)
+		assert.Contains(t, w.Body.String(), `"action":"resolve"`)
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/moderation-log?admin_id=nope", admin.ID, nil).Code)
+	})
+}
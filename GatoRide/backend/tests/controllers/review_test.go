@@ -0,0 +1,327 @@
+package controllers_test
+
+import (
+	"backend/config"
+	"backend/controllers"
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// completeRide stores a completed ride that left the given time ago with the passengers on it
+func completeRide(t *testing.T, driverID primitive.ObjectID, leftAgo time.Duration, passengerIDs ...primitive.ObjectID) models.Ride {
+	ride := models.Ride{
+		ID:           primitive.NewObjectID(),
+		DriverID:     driverID,
+		Pickup:       models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"},
+		Dropoff:      models.Location{Latitude: 30.3322, Longitude: -81.6557, Address: "Jacksonville"},
+		Status:       models.StatusCompleted,
+		Price:        20,
+		Seats:        3,
+		Date:         time.Now().Add(-leftAgo),
+		CreatedAt:    time.Now().Add(-leftAgo - time.Hour),
+		PassengerIDs: passengerIDs,
+	}
+	assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+	t.Cleanup(func() {
+		_ = testStore.Rides.Delete(context.TODO(), ride.ID)
+		reviews, _, _ := testStore.Reviews.FindPage(context.TODO(), repository.ReviewFilter{RideID: ride.ID}, repository.Page{Sort: repository.SortCreated})
+		for _, review := range reviews {
+			_ = testStore.Reviews.Delete(context.TODO(), review.ID)
+		}
+	})
+	return ride
+}
+
+func TestReviews(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newReviewController()
+	users := newUserController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/user/rides/:id/reviews", controller.SubmitReview)
+	router.GET("/user/rides/:id/reviews", controller.ListRideReviews)
+	router.GET("/user/reviews/:user_id", controller.ListUserReviews)
+	router.POST("/user/profile", users.GetUserProfile)
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	review := func(ride models.Ride, reviewerID primitive.ObjectID, body map[string]interface{}) *httptest.ResponseRecorder {
+		return send("POST", "/user/rides/"+ride.ID.Hex()+"/reviews", reviewerID, body)
+	}
+	rideReviews := func(ride models.Ride, userID primitive.ObjectID) []models.Review {
+		w := send("GET", "/user/rides/"+ride.ID.Hex()+"/reviews", userID, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Reviews []models.Review `json:"reviews"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Reviews
+	}
+	ratingOf := func(userID primitive.ObjectID) models.RatingSummary {
+		w := send("GET", "/user/reviews/"+userID.Hex(), userID, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Rating models.RatingSummary `json:"rating"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Rating
+	}
+
+	driver := createCancellingUser(t, "revieweddriver")
+	rider := createCancellingUser(t, "reviewingrider")
+	other := createCancellingUser(t, "otherrider")
+
+	t.Run("Only completed rides can be reviewed by those on them", func(t *testing.T) {
+		ride := completeRide(t, driver.ID, 2*time.Hour, rider.ID)
+
+		w := review(ride, other.ID, map[string]interface{}{"rating": 5})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, http.StatusForbidden, send("GET", "/user/rides/"+ride.ID.Hex()+"/reviews", other.ID, nil).Code)
+
+		open, _ := createBookedRide(t, driver.ID, rider.ID, time.Hour, models.BookingConfirmed)
+		w = review(open, rider.ID, map[string]interface{}{"rating": 5})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "completed")
+	})
+
+	t.Run("Invalid reviews are rejected", func(t *testing.T) {
+		ride := completeRide(t, driver.ID, 2*time.Hour, rider.ID)
+
+		assert.Equal(t, http.StatusBadRequest, review(ride, rider.ID, map[string]interface{}{"rating": 0}).Code)
+		assert.Equal(t, http.StatusBadRequest, review(ride, rider.ID, map[string]interface{}{"rating": 6}).Code)
+		assert.Equal(t, http.StatusBadRequest, review(ride, rider.ID, map[string]interface{}{"rating": 4, "reviewee_id": other.ID.Hex()}).Code)
+		// Drivers name the passenger, who must have been on the ride
+		assert.Equal(t, http.StatusBadRequest, review(ride, driver.ID, map[string]interface{}{"rating": 4}).Code)
+		assert.Equal(t, http.StatusBadRequest, review(ride, driver.ID, map[string]interface{}{"rating": 4, "reviewee_id": other.ID.Hex()}).Code)
+		// The window closes two weeks after completion, or after departure for rides completed
+		// before completion times were recorded
+		late := completeRide(t, driver.ID, 15*24*time.Hour, rider.ID)
+		assert.Equal(t, http.StatusBadRequest, review(late, rider.ID, map[string]interface{}{"rating": 4}).Code)
+	})
+
+	t.Run("The window runs from completion, not departure", func(t *testing.T) {
+		// A long trip that left fifteen days ago and was completed yesterday
+		ride := completeRide(t, driver.ID, 15*24*time.Hour, rider.ID)
+		completedAt := time.Now().Add(-24 * time.Hour)
+		assert.NoError(t, testStore.Rides.UpdateStatus(context.TODO(), ride.ID, models.StatusCompleted, models.StatusOngoing))
+		assert.NoError(t, testStore.Rides.Complete(context.TODO(), ride.ID, models.StatusOngoing, completedAt))
+
+		w := review(ride, rider.ID, map[string]interface{}{"rating": 4})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Review models.Review `json:"review"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.WithinDuration(t, completedAt.Add(time.Duration(config.Defaults().Reviews.Window)), response.Review.RevealAt, time.Second)
+	})
+
+	t.Run("Reviews stay hidden until both sides review", func(t *testing.T) {
+		ride := completeRide(t, driver.ID, 2*time.Hour, rider.ID, other.ID)
+
+		w := review(ride, rider.ID, map[string]interface{}{"rating": 4, "comment": "  Smooth drive  "})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"revealed":false`)
+		assert.Contains(t, w.Body.String(), "Smooth drive")
+
+		// Only once per ride
+		assert.Equal(t, http.StatusConflict, review(ride, rider.ID, map[string]interface{}{"rating": 1}).Code)
+
+		// The reviewer sees their own review, the driver sees nothing yet
+		assert.Len(t, rideReviews(ride, rider.ID), 1)
+		assert.Empty(t, rideReviews(ride, driver.ID))
+		assert.Equal(t, models.RatingSummary{}, ratingOf(driver.ID))
+
+		// The driver is told, without learning the rating
+		notifications, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: driver.ID}, repository.Page{Sort: repository.SortCreated})
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, notifications) {
+			assert.Equal(t, models.NotificationReview, notifications[0].Kind)
+			assert.Contains(t, notifications[0].Message, "Review them back")
+		}
+
+		// Reviewing another passenger reveals nothing
+		w = review(ride, driver.ID, map[string]interface{}{"rating": 2, "reviewee_id": other.ID.Hex()})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"revealed":false`)
+
+		// Reviewing back reveals both
+		w = review(ride, driver.ID, map[string]interface{}{"rating": 5, "reviewee_id": rider.ID.Hex()})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"revealed":true`)
+
+		// The driver also sees their hidden review of the other passenger
+		assert.Len(t, rideReviews(ride, driver.ID), 3)
+		assert.Len(t, rideReviews(ride, other.ID), 2)
+		assert.Equal(t, models.RatingSummary{Average: 4, Count: 1}, ratingOf(driver.ID))
+		assert.Equal(t, models.RatingSummary{Average: 5, Count: 1}, ratingOf(rider.ID))
+		assert.Equal(t, models.RatingSummary{}, ratingOf(other.ID))
+
+		w = send("POST", "/user/profile", driver.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"rating":{"average":4,"count":1}`)
+	})
+
+	t.Run("Revealed reviews count once", func(t *testing.T) {
+		ride := completeRide(t, driver.ID, 13*24*time.Hour, other.ID)
+		w := review(ride, driver.ID, map[string]interface{}{"rating": 3, "reviewee_id": other.ID.Hex()})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var response struct {
+			Review models.Review `json:"review"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		// Its window is still open
+		utils.RevealDueReviews(testStore.Reviews, testStore.Users)
+		assert.Equal(t, models.RatingSummary{}, ratingOf(other.ID))
+
+		revealed, err := utils.RevealReview(context.TODO(), testStore.Reviews, testStore.Users, response.Review.ID, time.Now())
+		assert.NoError(t, err)
+		assert.True(t, revealed)
+		// Revealing again counts nothing
+		revealed, err = utils.RevealReview(context.TODO(), testStore.Reviews, testStore.Users, response.Review.ID, time.Now())
+		assert.NoError(t, err)
+		assert.False(t, revealed)
+
+		// The driver's earlier review of this passenger is still hidden
+		assert.Equal(t, models.RatingSummary{Average: 3, Count: 1}, ratingOf(other.ID))
+	})
+
+	t.Run("The scheduler reveals reviews past their window", func(t *testing.T) {
+		ride := completeRide(t, driver.ID, 2*time.Hour, other.ID)
+		hidden := models.Review{
+			ID:         primitive.NewObjectID(),
+			RideID:     ride.ID,
+			ReviewerID: other.ID,
+			RevieweeID: driver.ID,
+			Role:       models.ReviewByPassenger,
+			Rating:     1,
+			CreatedAt:  time.Now().Add(-15 * 24 * time.Hour),
+			RevealAt:   time.Now().Add(-time.Minute),
+		}
+		assert.NoError(t, testStore.Reviews.Create(context.TODO(), &hidden))
+
+		utils.RevealDueReviews(testStore.Reviews, testStore.Users)
+		assert.Equal(t, models.RatingSummary{Average: 2.5, Count: 2}, ratingOf(driver.ID))
+
+		// Already revealed, so running again changes nothing
+		utils.RevealDueReviews(testStore.Reviews, testStore.Users)
+		assert.Equal(t, models.RatingSummary{Average: 2.5, Count: 2}, ratingOf(driver.ID))
+	})
+}
+
+func TestSearchShowsDriverRating(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/user/provide-ride", controller.ProvideRide)
+	router.POST("/user/search-ride", controller.SearchRides)
+
+	send := func(path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tallahassee := models.Location{Latitude: 30.4383, Longitude: -84.2807, Address: "Tallahassee"}
+	pensacola := models.Location{Latitude: 30.4213, Longitude: -87.2169, Address: "Pensacola"}
+	// A driver rated 4.5, one rated 3 and one nobody rated yet, each offering the same trip
+	offer := func(name string, ratings ...int) primitive.ObjectID {
+		driver := createCancellingUser(t, name)
+		approveDriver(t, driver.ID)
+		for _, stars := range ratings {
+			assert.NoError(t, testStore.Users.AddRating(context.TODO(), driver.ID, stars))
+		}
+		w := send("/user/provide-ride", driver.ID, map[string]interface{}{
+			"pickup": tallahassee, "dropoff": pensacola, "price": 25, "seats": 2,
+			"date":       time.Date(2035, 3, 14, 9, 0, 0, 0, time.UTC),
+			"vehicle_id": registerVehicle(t, driver.ID).ID.Hex(),
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var offered struct {
+			RideID string `json:"ride_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &offered)
+		if rideID, err := primitive.ObjectIDFromHex(offered.RideID); err == nil {
+			t.Cleanup(func() { _ = testStore.Rides.Delete(context.TODO(), rideID) })
+		}
+		return driver.ID
+	}
+	best := offer("rateddriver", 5, 4)
+	worse := offer("lowerrateddriver", 3)
+	unrated := offer("unrateddriver")
+
+	type searchPage struct {
+		Rides      []controllers.RideMatch `json:"rides"`
+		NextCursor string                  `json:"next_cursor"`
+	}
+	search := func(extra map[string]interface{}) searchPage {
+		body := map[string]interface{}{"from": tallahassee, "to": pensacola, "date": "2035-03-14", "seats": 1}
+		for key, value := range extra {
+			body[key] = value
+		}
+		w := send("/user/search-ride", primitive.NewObjectID(), body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response searchPage
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+	drivers := func(rides []controllers.RideMatch) []primitive.ObjectID {
+		ids := []primitive.ObjectID{}
+		for _, ride := range rides {
+			ids = append(ids, ride.DriverID)
+		}
+		return ids
+	}
+
+	t.Run("Matches show their driver's rating", func(t *testing.T) {
+		for _, ride := range search(nil).Rides {
+			if ride.DriverID == best {
+				assert.Equal(t, models.RatingSummary{Average: 4.5, Count: 2}, ride.DriverRating)
+			}
+		}
+	})
+
+	t.Run("Best rated drivers come first", func(t *testing.T) {
+		first := search(map[string]interface{}{"sort": "rating", "limit": 2})
+		assert.Equal(t, []primitive.ObjectID{best, worse}, drivers(first.Rides))
+		rest := search(map[string]interface{}{"sort": "rating", "limit": 2, "cursor": first.NextCursor})
+		assert.Equal(t, []primitive.ObjectID{unrated}, drivers(rest.Rides))
+
+		ascending := search(map[string]interface{}{"sort": "rating", "order": "asc"})
+		assert.Equal(t, []primitive.ObjectID{unrated, worse, best}, drivers(ascending.Rides))
+	})
+}
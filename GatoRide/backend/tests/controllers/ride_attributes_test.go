@@ -0,0 +1,197 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProvideRide_Attributes(t *testing.T) {
+	router := setupRouter()
+	driverID, _ := primitive.ObjectIDFromHex(mockUserID)
+	approveDriver(t, driverID)
+	vehicle := registerVehicle(t, driverID)
+
+	post := func(attributes map[string]interface{}) *httptest.ResponseRecorder {
+		requestBody, _ := json.Marshal(map[string]interface{}{
+			"pickup":     map[string]interface{}{"latitude": 41.8781, "longitude": -87.6298, "address": "Chicago, IL"},
+			"dropoff":    map[string]interface{}{"latitude": 42.0451, "longitude": -87.6877, "address": "Evanston, IL"},
+			"price":      12,
+			"seats":      3,
+			"date":       time.Now().Add(30 * time.Hour).Format(time.RFC3339),
+			"attributes": attributes,
+			"vehicle_id": vehicle.ID.Hex(),
+		})
+		req, _ := http.NewRequest("POST", "/user/provide-ride", bytes.NewBuffer(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Attributes are stored with the ride", func(t *testing.T) {
+		w := post(map[string]interface{}{"luggage_bags": 3, "pets_allowed": true, "music": "quiet"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			RideID string `json:"ride_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		rideID, _ := primitive.ObjectIDFromHex(response.RideID)
+		defer testStore.Rides.Delete(context.TODO(), rideID)
+
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 3, ride.Attributes.LuggageBags)
+			assert.Equal(t, 3, ride.FreeLuggage)
+			assert.True(t, ride.Attributes.PetsAllowed)
+			assert.False(t, ride.Attributes.SmokingAllowed)
+			assert.Equal(t, models.MusicQuiet, ride.Attributes.Music)
+		}
+	})
+
+	t.Run("Invalid attributes are rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post(map[string]interface{}{"luggage_bags": -1}).Code)
+		assert.Equal(t, http.StatusBadRequest, post(map[string]interface{}{"music": "heavy_metal"}).Code)
+	})
+}
+
+func TestSearchRides_AttributeFilters(t *testing.T) {
+	router := setupSearchRideRouter()
+
+	day := time.Now().AddDate(0, 0, 2).Truncate(24 * time.Hour)
+	newRide := func(attributes models.RideAttributes) models.Ride {
+		ride := models.Ride{
+			ID:          primitive.NewObjectID(),
+			DriverID:    primitive.NewObjectID(),
+			Pickup:      models.Location{Latitude: 45.5017, Longitude: -73.5673, Address: "Montreal"},
+			Dropoff:     models.Location{Latitude: 45.5088, Longitude: -73.5540, Address: "Old Port"},
+			Status:      models.StatusOpen,
+			Price:       10,
+			Seats:       2,
+			Date:        day.Add(9 * time.Hour),
+			CreatedAt:   time.Now(),
+			Attributes:  attributes,
+			FreeLuggage: attributes.LuggageBags,
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		t.Cleanup(func() { _ = testStore.Rides.Delete(context.TODO(), ride.ID) })
+		return ride
+	}
+	petFriendly := newRide(models.RideAttributes{LuggageBags: 4, PetsAllowed: true, Music: models.MusicPassengers})
+	smoky := newRide(models.RideAttributes{LuggageBags: 1, SmokingAllowed: true, AirConditioning: true})
+	legacy := newRide(models.RideAttributes{})
+
+	search := func(filters map[string]interface{}) (int, []string) {
+		body := map[string]interface{}{
+			"from":  map[string]interface{}{"latitude": 45.5017, "longitude": -73.5673},
+			"to":    map[string]interface{}{"latitude": 45.5088, "longitude": -73.5540},
+			"date":  day.Format("2006-01-02"),
+			"seats": 1,
+		}
+		for key, value := range filters {
+			body[key] = value
+		}
+		requestBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/user/search-ride", bytes.NewBuffer(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			Rides []models.Ride `json:"rides"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		ids := []string{}
+		for _, ride := range response.Rides {
+			ids = append(ids, ride.ID.Hex())
+		}
+		return w.Code, ids
+	}
+
+	code, ids := search(map[string]interface{}{"pets_allowed": true})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{petFriendly.ID.Hex()}, ids)
+
+	_, ids = search(map[string]interface{}{"smoking_allowed": false})
+	assert.ElementsMatch(t, []string{petFriendly.ID.Hex(), legacy.ID.Hex()}, ids)
+
+	_, ids = search(map[string]interface{}{"luggage_bags": 2})
+	assert.Equal(t, []string{petFriendly.ID.Hex()}, ids)
+
+	_, ids = search(map[string]interface{}{"air_conditioning": true, "luggage_bags": 1})
+	assert.Equal(t, []string{smoky.ID.Hex()}, ids)
+
+	_, ids = search(map[string]interface{}{"music": "passengers"})
+	assert.Equal(t, []string{petFriendly.ID.Hex()}, ids)
+
+	code, _ = search(map[string]interface{}{"music": "opera"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestBookRide_Luggage(t *testing.T) {
+	ride := models.Ride{
+		ID:          primitive.NewObjectID(),
+		DriverID:    primitive.NewObjectID(),
+		Status:      models.StatusOpen,
+		Price:       15,
+		Seats:       3,
+		Date:        time.Now().Add(48 * time.Hour),
+		CreatedAt:   time.Now(),
+		Attributes:  models.RideAttributes{LuggageBags: 3},
+		FreeLuggage: 3,
+	}
+	assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+	defer testStore.Rides.Delete(context.TODO(), ride.ID)
+
+	controller := newRideController()
+	book := func(passengerID primitive.ObjectID, query string) *httptest.ResponseRecorder {
+		gin.SetMode(gin.TestMode)
+		router := gin.Default()
+		router.POST("/user/book-ride", func(c *gin.Context) {
+			c.Set("userID", passengerID.Hex())
+			controller.BookRide(c)
+		})
+		req, _ := http.NewRequest("POST", "/user/book-ride?ride_id="+ride.ID.Hex()+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := primitive.NewObjectID()
+	w := book(first, "&bags=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Booking models.Booking `json:"booking"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 2, response.Booking.Bags)
+
+	stored, _ := testStore.Rides.FindByID(context.TODO(), ride.ID)
+	assert.Equal(t, 1, stored.FreeLuggage)
+
+	// The remaining space is too small for two more bags, and no ride takes more than it has
+	second := primitive.NewObjectID()
+	w = book(second, "&bags=2")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Only 1 bags fit")
+	assert.Equal(t, http.StatusBadRequest, book(second, "&bags=4").Code)
+	assert.Equal(t, http.StatusBadRequest, book(second, "&bags=-1").Code)
+
+	// Cancelling gives the space back
+	code, _ := cancelAs(t, first, controller.CancelBooking, "booking_id="+response.Booking.ID.Hex())
+	assert.Equal(t, http.StatusOK, code)
+
+	stored, _ = testStore.Rides.FindByID(context.TODO(), ride.ID)
+	assert.Equal(t, 3, stored.FreeLuggage)
+	assert.Equal(t, http.StatusOK, book(second, "&bags=3").Code)
+}
@@ -0,0 +1,120 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRideLifecycle(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+
+	// Mock the auth middleware by setting userID
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", mockUserID)
+	})
+	router.POST("/user/rides/:id/start", controller.StartRide)
+	router.POST("/user/rides/:id/complete", controller.CompleteRide)
+	router.POST("/rides/cancel", controller.CancelRide)
+
+	driverID, _ := primitive.ObjectIDFromHex(mockUserID)
+
+	insertRide := func(t *testing.T, driver primitive.ObjectID, status models.RideStatus) primitive.ObjectID {
+		ride := models.Ride{
+			ID:        primitive.NewObjectID(),
+			DriverID:  driver,
+			Status:    status,
+			Seats:     1,
+			Date:      time.Now().Add(time.Hour),
+			CreatedAt: time.Now(),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		t.Cleanup(func() { testStore.Rides.Delete(context.TODO(), ride.ID) })
+		return ride.ID
+	}
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	statusOf := func(t *testing.T, rideID primitive.ObjectID) models.RideStatus {
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		return ride.Status
+	}
+
+	t.Run("Driver starts and completes a booked ride", func(t *testing.T) {
+		rideID := insertRide(t, driverID, models.StatusBooked)
+
+		w := post("/user/rides/" + rideID.Hex() + "/start")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, models.StatusOngoing, statusOf(t, rideID))
+
+		w = post("/user/rides/" + rideID.Hex() + "/complete")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, models.StatusCompleted, statusOf(t, rideID))
+
+		// Completion is recorded; the review window runs from it
+		ride, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, ride.CompletedAt) {
+			assert.WithinDuration(t, time.Now(), *ride.CompletedAt, time.Minute)
+		}
+	})
+
+	t.Run("Cannot complete a ride that has not started", func(t *testing.T) {
+		rideID := insertRide(t, driverID, models.StatusOpen)
+
+		w := post("/user/rides/" + rideID.Hex() + "/complete")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "cannot move a ride from 'open' to 'completed'")
+		assert.Equal(t, models.StatusOpen, statusOf(t, rideID))
+	})
+
+	t.Run("Cannot start a cancelled ride", func(t *testing.T) {
+		rideID := insertRide(t, driverID, models.StatusCancelled)
+
+		w := post("/user/rides/" + rideID.Hex() + "/start")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "already cancelled")
+	})
+
+	t.Run("Cannot cancel a completed ride", func(t *testing.T) {
+		rideID := insertRide(t, driverID, models.StatusCompleted)
+
+		w := post("/rides/cancel?ride_id=" + rideID.Hex())
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, models.StatusCompleted, statusOf(t, rideID))
+	})
+
+	t.Run("Only the driver can start a ride", func(t *testing.T) {
+		rideID := insertRide(t, primitive.NewObjectID(), models.StatusOpen)
+
+		w := post("/user/rides/" + rideID.Hex() + "/start")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, models.StatusOpen, statusOf(t, rideID))
+	})
+
+	t.Run("Ride not found", func(t *testing.T) {
+		w := post("/user/rides/" + primitive.NewObjectID().Hex() + "/start")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Invalid ride ID", func(t *testing.T) {
+		w := post("/user/rides/not-an-id/start")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,305 @@
+package controllers_test
+
+import (
+	"backend/controllers"
+	"backend/models"
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRideRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideRequestController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/user/ride-requests", controller.CreateRideRequest)
+	router.GET("/user/ride-requests", controller.ListRideRequests)
+	router.GET("/user/ride-requests/nearby", controller.NearbyRideRequests)
+	router.GET("/user/ride-requests/:id", controller.GetRideRequest)
+	router.POST("/user/ride-requests/:id/cancel", controller.CancelRideRequest)
+	router.POST("/user/ride-requests/:id/offers", controller.MakeOffer)
+	router.POST("/user/ride-offers/:id/accept", controller.AcceptOffer)
+	router.POST("/user/ride-offers/:id/decline", controller.DeclineOffer)
+	router.POST("/user/ride-offers/:id/withdraw", controller.WithdrawOffer)
+
+	// Record notifications instead of sending them
+	var offerEmails, acceptedEmails []string
+	originalOffer, originalAccepted := utils.SendRideOfferFunc, utils.SendOfferAcceptedFunc
+	utils.SendRideOfferFunc = func(email string, request *models.RideRequest, offer *models.RideOffer) error {
+		offerEmails = append(offerEmails, email)
+		return nil
+	}
+	utils.SendOfferAcceptedFunc = func(email string, ride *models.Ride, booking *models.Booking) error {
+		acceptedEmails = append(acceptedEmails, email)
+		return nil
+	}
+	t.Cleanup(func() { utils.SendRideOfferFunc, utils.SendOfferAcceptedFunc = originalOffer, originalAccepted })
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	insertUser := func(t *testing.T, email string) primitive.ObjectID {
+		user := models.User{ID: primitive.NewObjectID(), Name: "User", Email: email, Username: email}
+		assert.NoError(t, testStore.Users.Create(context.TODO(), &user))
+		t.Cleanup(func() { testStore.Users.Delete(context.TODO(), user.ID) })
+		return user.ID
+	}
+
+	pickup := models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"}
+	dropoff := models.Location{Latitude: 28.5383, Longitude: -81.3792, Address: "Orlando"}
+	windowStart := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+
+	// Posts a request as the passenger and returns its ID
+	post := func(t *testing.T, passengerID primitive.ObjectID) primitive.ObjectID {
+		w := send("POST", "/user/ride-requests", passengerID, map[string]interface{}{
+			"pickup": pickup, "dropoff": dropoff, "seats": 2, "max_price": 25,
+			"departure_from": windowStart, "departure_to": windowStart.Add(4 * time.Hour),
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Request models.RideRequest `json:"request"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		t.Cleanup(func() { testStore.Requests.Delete(context.TODO(), response.Request.ID) })
+		return response.Request.ID
+	}
+
+	// Makes an offer as a verified driver, in a car registered for it, and returns its ID
+	offer := func(t *testing.T, requestID, driverID primitive.ObjectID, price float64) primitive.ObjectID {
+		approveDriver(t, driverID)
+		w := send("POST", "/user/ride-requests/"+requestID.Hex()+"/offers", driverID, map[string]interface{}{
+			"price": price, "date": windowStart.Add(time.Hour), "seats": 3,
+			"attributes": map[string]interface{}{"luggage_bags": 2}, "vehicle_id": registerVehicle(t, driverID).ID.Hex(),
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Offer models.RideOffer `json:"offer"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		t.Cleanup(func() { testStore.Offers.Delete(context.TODO(), response.Offer.ID) })
+		return response.Offer.ID
+	}
+
+	t.Run("Requests need a future window of at most a week", func(t *testing.T) {
+		passengerID := primitive.NewObjectID()
+		for _, window := range [][2]time.Time{
+			{windowStart, windowStart.Add(-time.Hour)},
+			{time.Now().Add(-3 * time.Hour), time.Now().Add(-time.Hour)},
+			{windowStart, windowStart.Add(8 * 24 * time.Hour)},
+		} {
+			w := send("POST", "/user/ride-requests", passengerID, map[string]interface{}{
+				"pickup": pickup, "dropoff": dropoff, "max_price": 25,
+				"departure_from": window[0], "departure_to": window[1],
+			})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Drivers find nearby requests and make offers", func(t *testing.T) {
+		passengerID := insertUser(t, "requester@ufl.edu")
+		driverID := primitive.NewObjectID()
+		requestID := post(t, passengerID)
+
+		nearby := func(userID primitive.ObjectID) []controllers.NearbyRideRequest {
+			w := send("GET", "/user/ride-requests/nearby?latitude=29.65&longitude=-82.32", userID, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var response struct {
+				Requests []controllers.NearbyRideRequest `json:"requests"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			return response.Requests
+		}
+		found := nearby(driverID)
+		if assert.Len(t, found, 1) {
+			assert.Equal(t, requestID, found[0].ID)
+			assert.Less(t, found[0].DistanceMeters, 1000.0)
+		}
+		// Passengers do not see their own requests
+		assert.Empty(t, nearby(passengerID))
+
+		path := "/user/ride-requests/" + requestID.Hex() + "/offers"
+		w := send("POST", path, driverID, map[string]interface{}{"price": 30, "date": windowStart.Add(time.Hour)})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "price above the passenger's maximum")
+		w = send("POST", path, driverID, map[string]interface{}{"price": 20, "date": windowStart.Add(5 * time.Hour)})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "departure outside the window")
+		w = send("POST", path, driverID, map[string]interface{}{"price": 20, "date": windowStart, "seats": 1})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "fewer seats than requested")
+		w = send("POST", path, passengerID, map[string]interface{}{"price": 20, "date": windowStart})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "offer on own request")
+		w = send("POST", path, driverID, map[string]interface{}{"price": 20, "date": windowStart})
+		assert.Equal(t, http.StatusForbidden, w.Code, "driver not verified")
+		approveDriver(t, driverID)
+		w = send("POST", path, driverID, map[string]interface{}{"price": 20, "date": windowStart})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "no vehicle")
+		vehicleID := registerVehicle(t, driverID).ID.Hex()
+		w = send("POST", path, driverID, map[string]interface{}{"price": 20, "date": windowStart, "seats": 5, "vehicle_id": vehicleID})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "more seats than the car takes")
+
+		offer(t, requestID, driverID, 20)
+		assert.Equal(t, []string{"requester@ufl.edu"}, offerEmails)
+		w = send("POST", path, driverID, map[string]interface{}{"price": 18, "date": windowStart, "vehicle_id": vehicleID})
+		assert.Equal(t, http.StatusConflict, w.Code, "second pending offer by the same driver")
+
+		// The passenger sees every offer, other drivers only their own
+		otherID := primitive.NewObjectID()
+		offer(t, requestID, otherID, 22)
+		var details struct {
+			Offers []models.RideOffer `json:"offers"`
+		}
+		w = send("GET", "/user/ride-requests/"+requestID.Hex(), passengerID, nil)
+		json.Unmarshal(w.Body.Bytes(), &details)
+		assert.Len(t, details.Offers, 2)
+		w = send("GET", "/user/ride-requests/"+requestID.Hex(), otherID, nil)
+		json.Unmarshal(w.Body.Bytes(), &details)
+		if assert.Len(t, details.Offers, 1) {
+			assert.Equal(t, 22.0, details.Offers[0].Price)
+		}
+	})
+
+	t.Run("Accepting an offer creates the ride with the passenger booked", func(t *testing.T) {
+		passengerID := primitive.NewObjectID()
+		driverID := insertUser(t, "offering-driver@ufl.edu")
+		otherID := primitive.NewObjectID()
+		requestID := post(t, passengerID)
+		offerID := offer(t, requestID, driverID, 20)
+		otherOfferID := offer(t, requestID, otherID, 24)
+
+		w := send("POST", "/user/ride-offers/"+offerID.Hex()+"/accept", driverID, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send("POST", "/user/ride-offers/"+offerID.Hex()+"/accept", passengerID, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Ride    models.Ride    `json:"ride"`
+			Booking models.Booking `json:"booking"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		t.Cleanup(func() {
+			testStore.Rides.Delete(context.TODO(), response.Ride.ID)
+			testStore.Bookings.Delete(context.TODO(), response.Booking.ID)
+		})
+
+		ride, err := testStore.Rides.FindByID(context.TODO(), response.Ride.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, driverID, ride.DriverID)
+			assert.Equal(t, "Orlando", ride.Dropoff.Address)
+			assert.Equal(t, 20.0, ride.Price)
+			assert.Equal(t, 1, ride.Seats, "the seat the passenger did not need stays open")
+			assert.Equal(t, []primitive.ObjectID{passengerID}, ride.PassengerIDs)
+			assert.NotNil(t, ride.Path)
+			// The ride is offered in the driver's car with the luggage space they gave
+			assert.NotNil(t, ride.VehicleID)
+			if assert.NotNil(t, ride.Vehicle) {
+				assert.Equal(t, "GATOR 1", ride.Vehicle.Plate)
+			}
+			assert.Equal(t, 2, ride.Attributes.LuggageBags)
+			assert.Equal(t, 2, ride.FreeLuggage)
+		}
+		booking, err := testStore.Bookings.FindByID(context.TODO(), response.Booking.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.BookingConfirmed, booking.Status)
+			assert.Equal(t, 2, booking.Seats)
+			assert.Equal(t, 40.0, booking.TotalPrice)
+		}
+
+		request, _ := testStore.Requests.FindByID(context.TODO(), requestID)
+		assert.Equal(t, models.RequestFulfilled, request.Status)
+		assert.Equal(t, ride.ID, *request.RideID)
+		assert.Equal(t, booking.ID, *request.BookingID)
+		other, _ := testStore.Offers.FindByID(context.TODO(), otherOfferID)
+		assert.Equal(t, models.OfferDeclined, other.Status)
+		assert.Equal(t, []string{"offering-driver@ufl.edu"}, acceptedEmails)
+
+		// The request is fulfilled, so nothing else can be accepted or offered
+		w = send("POST", "/user/ride-offers/"+otherOfferID.Hex()+"/accept", passengerID, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = send("POST", "/user/ride-requests/"+requestID.Hex()+"/offers", otherID, map[string]interface{}{"price": 20, "date": windowStart})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Offers can be declined and withdrawn", func(t *testing.T) {
+		passengerID := primitive.NewObjectID()
+		driverID := primitive.NewObjectID()
+		requestID := post(t, passengerID)
+
+		offerID := offer(t, requestID, driverID, 20)
+		w := send("POST", "/user/ride-offers/"+offerID.Hex()+"/withdraw", passengerID, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = send("POST", "/user/ride-offers/"+offerID.Hex()+"/withdraw", driverID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = send("POST", "/user/ride-offers/"+offerID.Hex()+"/accept", passengerID, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// A withdrawn offer no longer blocks a new one
+		offerID = offer(t, requestID, driverID, 19)
+		w = send("POST", "/user/ride-offers/"+offerID.Hex()+"/decline", passengerID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		declined, _ := testStore.Offers.FindByID(context.TODO(), offerID)
+		assert.Equal(t, models.OfferDeclined, declined.Status)
+	})
+
+	t.Run("Offers from drivers no longer verified cannot be accepted", func(t *testing.T) {
+		passengerID := primitive.NewObjectID()
+		driverID := primitive.NewObjectID()
+		requestID := post(t, passengerID)
+		offerID := offer(t, requestID, driverID, 20)
+
+		// The driver's licence runs out before the offered departure
+		verification, err := testStore.Drivers.FindByUser(context.TODO(), driverID)
+		assert.NoError(t, err)
+		verification.LicenceExpiresAt = time.Now().Add(24 * time.Hour)
+		assert.NoError(t, testStore.Drivers.Save(context.TODO(), verification))
+
+		w := send("POST", "/user/ride-offers/"+offerID.Hex()+"/accept", passengerID, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		request, _ := testStore.Requests.FindByID(context.TODO(), requestID)
+		assert.Equal(t, models.RequestOpen, request.Status)
+	})
+
+	t.Run("Cancelling a request expires its offers", func(t *testing.T) {
+		passengerID := primitive.NewObjectID()
+		requestID := post(t, passengerID)
+		offerID := offer(t, requestID, primitive.NewObjectID(), 20)
+
+		w := send("POST", "/user/ride-requests/"+requestID.Hex()+"/cancel", primitive.NewObjectID(), nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = send("POST", "/user/ride-requests/"+requestID.Hex()+"/cancel", passengerID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = send("POST", "/user/ride-requests/"+requestID.Hex()+"/cancel", passengerID, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		expired, _ := testStore.Offers.FindByID(context.TODO(), offerID)
+		assert.Equal(t, models.OfferExpired, expired.Status)
+
+		var listed struct {
+			Requests []models.RideRequest `json:"requests"`
+		}
+		w = send("GET", "/user/ride-requests?status=cancelled", passengerID, nil)
+		json.Unmarshal(w.Body.Bytes(), &listed)
+		assert.Len(t, listed.Requests, 1)
+	})
+}
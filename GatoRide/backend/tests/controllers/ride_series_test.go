@@ -0,0 +1,442 @@
+package controllers_test
+
+import (
+	"backend/config"
+	"backend/controllers"
+	"backend/models"
+	"backend/recurrence"
+	"backend/repository"
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRideSeries(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideSeriesController()
+
+	// Mock the auth middleware by setting userID
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", mockUserID)
+	})
+	router.POST("/user/ride-series", controller.CreateSeries)
+	router.GET("/user/ride-series", controller.ListSeries)
+	router.PUT("/user/ride-series/:id", controller.UpdateSeries)
+	router.POST("/user/ride-series/:id/cancel", controller.CancelSeries)
+	router.PUT("/user/ride-series/:id/occurrences/:ride_id", controller.UpdateOccurrence)
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(recurrence.DateLayout)
+	endDate := time.Now().UTC().AddDate(0, 0, 60).Format(recurrence.DateLayout)
+	everyDay := []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+	driverID, _ := primitive.ObjectIDFromHex(mockUserID)
+	vehicle := registerVehicle(t, driverID)
+	approveDriver(t, driverID)
+
+	seriesBody := func(price float64, exceptions []string) gin.H {
+		return gin.H{
+			"pickup":     gin.H{"latitude": 29.6516, "longitude": -82.3248, "address": "Campus"},
+			"dropoff":    gin.H{"latitude": 29.6800, "longitude": -82.3500, "address": "Downtown"},
+			"price":      price,
+			"seats":      3,
+			"attributes": gin.H{"luggage_bags": 2, "pets_allowed": true},
+			"vehicle_id": vehicle.ID.Hex(),
+			"recurrence": gin.H{
+				"weekdays":        everyDay,
+				"departure_time":  "08:15",
+				"time_zone":       "UTC",
+				"start_date":      tomorrow,
+				"end_date":        endDate,
+				"exception_dates": exceptions,
+			},
+		}
+	}
+
+	createSeries := func(t *testing.T, body gin.H) models.RideSeries {
+		w := send("POST", "/user/ride-series", body)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Series       models.RideSeries `json:"series"`
+			RidesCreated int               `json:"rides_created"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		t.Cleanup(func() {
+			testStore.Series.Delete(context.TODO(), response.Series.ID)
+			rides, _ := testStore.Rides.Find(context.TODO(), repository.RideFilter{SeriesID: response.Series.ID})
+			for _, ride := range rides {
+				testStore.Rides.Delete(context.TODO(), ride.ID)
+			}
+		})
+		return response.Series
+	}
+
+	seriesRides := func(t *testing.T, seriesID primitive.ObjectID) []models.Ride {
+		rides, err := testStore.Rides.Find(context.TODO(), repository.RideFilter{SeriesID: seriesID})
+		assert.NoError(t, err)
+		return rides
+	}
+
+	window := time.Duration(config.Defaults().Scheduler.RecurringWindow)
+
+	// bookRide gives the passenger a confirmed seat on the ride
+	bookRide := func(t *testing.T, ride models.Ride, passengerID primitive.ObjectID) models.Booking {
+		_, err := testStore.Rides.ReserveSeats(context.TODO(), ride.ID, passengerID, 1, 0, ride.FullRoute())
+		assert.NoError(t, err)
+		booking := models.Booking{
+			ID:          primitive.NewObjectID(),
+			RideID:      ride.ID,
+			PassengerID: passengerID,
+			Seats:       1,
+			ToStop:      ride.FullRoute().To,
+			Status:      models.BookingConfirmed,
+			CreatedAt:   time.Now(),
+		}
+		assert.NoError(t, testStore.Bookings.Create(context.TODO(), &booking))
+		t.Cleanup(func() { _ = testStore.Bookings.Delete(context.TODO(), booking.ID) })
+		return booking
+	}
+
+	// Record ride update emails instead of sending them
+	var changeEmails []string
+	originalSend := utils.SendRideUpdateFunc
+	utils.SendRideUpdateFunc = func(email string, ride *models.Ride, changes []string) error {
+		changeEmails = append(changeEmails, email)
+		return nil
+	}
+	t.Cleanup(func() { utils.SendRideUpdateFunc = originalSend })
+
+	t.Run("Creating a series generates rides for the window", func(t *testing.T) {
+		exception := time.Now().UTC().AddDate(0, 0, 3).Format(recurrence.DateLayout)
+		series := createSeries(t, seriesBody(5, []string{exception}))
+		assert.Equal(t, models.SeriesActive, series.Status)
+		assert.NotNil(t, series.Path, "the path is planned once for the series")
+
+		expected, err := recurrence.Occurrences(series.Recurrence, time.Now(), time.Now().Add(window))
+		assert.NoError(t, err)
+
+		rides := seriesRides(t, series.ID)
+		assert.Len(t, rides, len(expected))
+		for _, ride := range rides {
+			assert.NotEqual(t, exception, ride.OccurrenceDate)
+			assert.Equal(t, 8, ride.Date.Hour())
+			assert.Equal(t, 5.0, ride.Price)
+			assert.Equal(t, models.StatusOpen, ride.Status)
+			// Every ride is offered in the series' car with its luggage space
+			assert.Equal(t, vehicle.ID, *ride.VehicleID)
+			assert.Equal(t, vehicle.VehicleDetails, *ride.Vehicle)
+			assert.True(t, ride.Attributes.PetsAllowed)
+			assert.Equal(t, 2, ride.FreeLuggage)
+			// Every ride follows the series' path, so corridor search can match it
+			assert.Equal(t, series.Path, ride.Path)
+		}
+
+		// Running the job again for the same window creates nothing new
+		utils.GenerateRecurringRides(testStore.Series, testStore.Rides, testStore.Drivers, testStore.Notifications, window)
+		assert.Len(t, seriesRides(t, series.ID), len(expected))
+
+		// A longer window tops the series up
+		utils.GenerateRecurringRides(testStore.Series, testStore.Rides, testStore.Drivers, testStore.Notifications, window+7*24*time.Hour)
+		assert.Greater(t, len(seriesRides(t, series.ID)), len(expected))
+	})
+
+	t.Run("Invalid pattern is rejected", func(t *testing.T) {
+		body := seriesBody(5, nil)
+		body["recurrence"].(gin.H)["weekdays"] = []string{"someday"}
+		w := send("POST", "/user/ride-series", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unknown weekday")
+	})
+
+	t.Run("A series needs documents valid for every ride it generates", func(t *testing.T) {
+		// The licence runs out before the last ride of the window departs
+		verification, err := testStore.Drivers.FindByUser(context.TODO(), driverID)
+		assert.NoError(t, err)
+		valid := verification.LicenceExpiresAt
+		verification.LicenceExpiresAt = time.Now().Add(window / 2)
+		assert.NoError(t, testStore.Drivers.Save(context.TODO(), verification))
+		defer func() {
+			verification.LicenceExpiresAt = valid
+			_ = testStore.Drivers.Save(context.TODO(), verification)
+		}()
+
+		w := send("POST", "/user/ride-series", seriesBody(5, nil))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "expires before the ride")
+	})
+
+	t.Run("Generation holds at the documents' expiry and resumes once they are renewed", func(t *testing.T) {
+		series := createSeries(t, seriesBody(5, nil))
+		generated := len(seriesRides(t, series.ID))
+
+		// The licence runs out a few days after the current window
+		verification, err := testStore.Drivers.FindByUser(context.TODO(), driverID)
+		assert.NoError(t, err)
+		valid := verification.LicenceExpiresAt
+		expiry := time.Now().Add(window + 3*24*time.Hour)
+		verification.LicenceExpiresAt = expiry
+		assert.NoError(t, testStore.Drivers.Save(context.TODO(), verification))
+		defer func() {
+			verification.LicenceExpiresAt = valid
+			_ = testStore.Drivers.Save(context.TODO(), verification)
+		}()
+
+		inbox := func() []models.Notification {
+			notifications, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: driverID}, repository.Page{Sort: repository.SortCreated})
+			assert.NoError(t, err)
+			var held []models.Notification
+			for _, notification := range notifications {
+				if notification.Kind == models.NotificationDocuments {
+					held = append(held, notification)
+				}
+			}
+			return held
+		}
+
+		utils.GenerateRecurringRides(testStore.Series, testStore.Rides, testStore.Drivers, testStore.Notifications, window+14*24*time.Hour)
+		rides := seriesRides(t, series.ID)
+		assert.Greater(t, len(rides), generated)
+		for _, ride := range rides {
+			assert.True(t, ride.Date.Before(expiry), "no ride departs after the licence expires")
+		}
+		stored, err := testStore.Series.FindByID(context.TODO(), series.ID)
+		assert.NoError(t, err)
+		assert.NotNil(t, stored.HeldSince)
+		held := inbox()
+		if assert.Len(t, held, 1) {
+			assert.Contains(t, held[0].Message, "Submit renewed documents")
+		}
+		defer func() {
+			for _, notification := range inbox() {
+				_ = testStore.Notifications.Delete(context.TODO(), notification.ID)
+			}
+		}()
+
+		// The driver is told once, however often the job runs
+		utils.GenerateRecurringRides(testStore.Series, testStore.Rides, testStore.Drivers, testStore.Notifications, window+14*24*time.Hour)
+		assert.Len(t, inbox(), 1)
+		assert.Len(t, seriesRides(t, series.ID), len(rides))
+
+		// Renewed documents let the series carry on from where it stopped
+		verification.LicenceExpiresAt = valid
+		assert.NoError(t, testStore.Drivers.Save(context.TODO(), verification))
+		utils.GenerateRecurringRides(testStore.Series, testStore.Rides, testStore.Drivers, testStore.Notifications, window+14*24*time.Hour)
+		assert.Greater(t, len(seriesRides(t, series.ID)), len(rides))
+		stored, err = testStore.Series.FindByID(context.TODO(), series.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, stored.HeldSince)
+	})
+
+	t.Run("A series needs a car that takes its seats", func(t *testing.T) {
+		body := seriesBody(5, nil)
+		delete(body, "vehicle_id")
+		w := send("POST", "/user/ride-series", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		body = seriesBody(5, nil)
+		body["vehicle_id"] = registerVehicle(t, primitive.NewObjectID()).ID.Hex()
+		w = send("POST", "/user/ride-series", body)
+		assert.Equal(t, http.StatusNotFound, w.Code, "someone else's car")
+
+		body = seriesBody(5, nil)
+		body["seats"] = 5
+		w = send("POST", "/user/ride-series", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "takes at most 4 passengers")
+
+		// Nor can a single occurrence offer more seats than the car takes
+		series := createSeries(t, seriesBody(5, nil))
+		ride := seriesRides(t, series.ID)[0]
+		w = send("PUT", "/user/ride-series/"+series.ID.Hex()+"/occurrences/"+ride.ID.Hex(), gin.H{"seats": 5})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Editing one occurrence leaves the others alone", func(t *testing.T) {
+		series := createSeries(t, seriesBody(5, nil))
+		rides := seriesRides(t, series.ID)
+		target := rides[1]
+
+		w := send("PUT", "/user/ride-series/"+series.ID.Hex()+"/occurrences/"+target.ID.Hex(), gin.H{
+			"price":          7.5,
+			"departure_time": "09:30",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		edited, err := testStore.Rides.FindByID(context.TODO(), target.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 7.5, edited.Price)
+		assert.Equal(t, 9, edited.Date.Hour())
+		assert.Equal(t, 30, edited.Date.Minute())
+		assert.True(t, edited.Detached)
+
+		other, err := testStore.Rides.FindByID(context.TODO(), rides[2].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 5.0, other.Price)
+
+		// A series edit keeps the edited and the booked occurrences, and recreates the rest
+		passenger := createCancellingUser(t, "series_passenger")
+		booking := bookRide(t, rides[0], passenger.ID)
+		changeEmails = nil
+
+		w = send("PUT", "/user/ride-series/"+series.ID.Hex(), seriesBody(6, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			RidesRemoved       int `json:"rides_removed"`
+			RidesKept          int `json:"rides_kept"`
+			RidesCreated       int `json:"rides_created"`
+			NotifiedPassengers int `json:"notified_passengers"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, len(rides)-2, response.RidesRemoved)
+		assert.Equal(t, response.RidesRemoved, response.RidesCreated)
+		assert.Equal(t, 1, response.RidesKept)
+
+		for _, ride := range seriesRides(t, series.ID) {
+			switch ride.ID {
+			case target.ID:
+				assert.Equal(t, 7.5, ride.Price)
+			default:
+				// The booked ride takes the new price too
+				assert.Equal(t, 6.0, ride.Price)
+			}
+		}
+
+		// Its passenger is told and may cancel without penalty
+		assert.Equal(t, 1, response.NotifiedPassengers)
+		assert.Equal(t, []string{passenger.Email}, changeEmails)
+		stored, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.NotNil(t, stored.RideChangedAt)
+	})
+
+	t.Run("Moving a booked occurrence's stop plans a new path and tells its passengers", func(t *testing.T) {
+		series := createSeries(t, seriesBody(5, nil))
+		ride := seriesRides(t, series.ID)[0]
+		passenger := createCancellingUser(t, "occurrence_passenger")
+		booking := bookRide(t, ride, passenger.ID)
+		changeEmails = nil
+
+		w := send("PUT", "/user/ride-series/"+series.ID.Hex()+"/occurrences/"+ride.ID.Hex(), gin.H{
+			"dropoff": gin.H{"latitude": 29.7200, "longitude": -82.4000, "address": "Airport"},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Ride               models.Ride `json:"ride"`
+			Changes            []string    `json:"changes"`
+			NotifiedPassengers int         `json:"notified_passengers"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []string{"dropoff"}, response.Changes)
+		assert.Equal(t, 1, response.NotifiedPassengers)
+		assert.Equal(t, []string{passenger.Email}, changeEmails)
+
+		edited, err := testStore.Rides.FindByID(context.TODO(), ride.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Airport", edited.Dropoff.Address)
+		if assert.NotNil(t, edited.Path) {
+			assert.NotEqual(t, series.Path, edited.Path, "the path follows the moved dropoff")
+		}
+		stored, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.NotNil(t, stored.RideChangedAt)
+	})
+
+	t.Run("Cancelling the series cancels upcoming rides", func(t *testing.T) {
+		series := createSeries(t, seriesBody(5, nil))
+		path := "/user/ride-series/" + series.ID.Hex() + "/cancel"
+
+		// A passenger booked the first ride
+		first := seriesRides(t, series.ID)[0]
+		passengerID := primitive.NewObjectID()
+		_, err := testStore.Rides.ReserveSeats(context.TODO(), first.ID, passengerID, 1, 0, first.FullRoute())
+		assert.NoError(t, err)
+		booking := models.Booking{
+			ID:          primitive.NewObjectID(),
+			RideID:      first.ID,
+			PassengerID: passengerID,
+			Seats:       1,
+			ToStop:      first.FullRoute().To,
+			Status:      models.BookingConfirmed,
+			CreatedAt:   time.Now(),
+		}
+		assert.NoError(t, testStore.Bookings.Create(context.TODO(), &booking))
+		defer testStore.Bookings.Delete(context.TODO(), booking.ID)
+
+		// The driver has to say why, as for a single ride
+		w := send("POST", path, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = send("POST", path, gin.H{"reason": models.ReasonScheduleChange})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			PassengersNotified int                               `json:"passengers_notified"`
+			Cancellations      []controllers.CancellationOutcome `json:"cancellations"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.PassengersNotified)
+		assert.Len(t, response.Cancellations, 1)
+
+		for _, ride := range seriesRides(t, series.ID) {
+			assert.Equal(t, models.StatusCancelled, ride.Status)
+			if assert.NotNil(t, ride.Cancellation) {
+				assert.Equal(t, models.ReasonScheduleChange, ride.Cancellation.Reason)
+			}
+			assert.Empty(t, ride.PassengerIDs)
+		}
+		stored, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.BookingCancelledByDriver, stored.Status)
+		inbox, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: passengerID}, repository.Page{Sort: repository.SortCreated})
+		assert.NoError(t, err)
+		if assert.Len(t, inbox, 1) {
+			_ = testStore.Notifications.Delete(context.TODO(), inbox[0].ID)
+		}
+
+		storedSeries, err := testStore.Series.FindByID(context.TODO(), series.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.SeriesCancelled, storedSeries.Status)
+
+		// A cancelled series generates nothing more and cannot be edited
+		before := len(seriesRides(t, series.ID))
+		utils.GenerateRecurringRides(testStore.Series, testStore.Rides, testStore.Drivers, testStore.Notifications, window+30*24*time.Hour)
+		assert.Len(t, seriesRides(t, series.ID), before)
+
+		w = send("PUT", "/user/ride-series/"+series.ID.Hex(), seriesBody(6, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Only the driver can change a series", func(t *testing.T) {
+		other := models.RideSeries{
+			ID:       primitive.NewObjectID(),
+			DriverID: primitive.NewObjectID(),
+			Status:   models.SeriesActive,
+		}
+		assert.NoError(t, testStore.Series.Create(context.TODO(), &other))
+		defer testStore.Series.Delete(context.TODO(), other.ID)
+
+		w := send("POST", "/user/ride-series/"+other.ID.Hex()+"/cancel", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
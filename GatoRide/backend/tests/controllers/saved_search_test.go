@@ -0,0 +1,232 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSavedSearchAlerts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	rides := newRideController()
+	alerts := newAlertController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/user/provide-ride", rides.ProvideRide)
+	router.POST("/user/saved-searches", alerts.SaveSearch)
+	router.GET("/user/saved-searches", alerts.ListSavedSearches)
+	router.DELETE("/user/saved-searches/:id", alerts.DeleteSavedSearch)
+	router.GET("/user/notifications", alerts.ListNotifications)
+	router.POST("/user/notifications/read-all", alerts.MarkAllNotificationsRead)
+	router.POST("/user/notifications/:id/read", alerts.MarkNotificationRead)
+
+	// Record alert emails instead of sending them
+	var alertEmails []string
+	originalSend := utils.SendRideAlertFunc
+	utils.SendRideAlertFunc = func(email string, ride *models.Ride, search *models.SavedSearch) error {
+		alertEmails = append(alertEmails, email)
+		return nil
+	}
+	t.Cleanup(func() { utils.SendRideAlertFunc = originalSend })
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	insertUser := func(t *testing.T, email string) primitive.ObjectID {
+		user := models.User{ID: primitive.NewObjectID(), Name: "User", Email: email, Username: email}
+		assert.NoError(t, testStore.Users.Create(context.TODO(), &user))
+		t.Cleanup(func() { testStore.Users.Delete(context.TODO(), user.ID) })
+		return user.ID
+	}
+
+	gainesville := models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"}
+	miami := models.Location{Latitude: 25.7617, Longitude: -80.1918, Address: "Miami"}
+	tampa := models.Location{Latitude: 27.9506, Longitude: -82.4572, Address: "Tampa"}
+	thanksgiving := time.Date(2034, 11, 22, 0, 0, 0, 0, time.UTC)
+
+	save := func(t *testing.T, userID primitive.ObjectID, body map[string]interface{}) models.SavedSearch {
+		w := send("POST", "/user/saved-searches", userID, body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Search models.SavedSearch `json:"search"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		t.Cleanup(func() { testStore.Searches.Delete(context.TODO(), response.Search.ID) })
+		return response.Search
+	}
+
+	// Offers a ride as a new driver and returns its ID
+	provide := func(t *testing.T, to models.Location, date time.Time) primitive.ObjectID {
+		driverID := primitive.NewObjectID()
+		approveDriver(t, driverID)
+		w := send("POST", "/user/provide-ride", driverID, map[string]interface{}{
+			"pickup": gainesville, "dropoff": to, "price": 30, "seats": 3, "date": date,
+			"vehicle_id": registerVehicle(t, driverID).ID.Hex(),
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			RideID string `json:"ride_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		rideID, _ := primitive.ObjectIDFromHex(response.RideID)
+		t.Cleanup(func() { testStore.Rides.Delete(context.TODO(), rideID) })
+		return rideID
+	}
+
+	type inbox struct {
+		Notifications []models.Notification `json:"notifications"`
+		HasMore       bool                  `json:"has_more"`
+		NextCursor    string                `json:"next_cursor"`
+	}
+	readInbox := func(userID primitive.ObjectID, query string) inbox {
+		w := send("GET", "/user/notifications"+query, userID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response inbox
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	t.Run("Invalid saved searches are rejected", func(t *testing.T) {
+		userID := primitive.NewObjectID()
+		base := func() map[string]interface{} {
+			return map[string]interface{}{
+				"from": gainesville, "to": miami,
+				"departure_from": thanksgiving, "departure_to": thanksgiving.Add(48 * time.Hour),
+			}
+		}
+		tooLong := base()
+		tooLong["departure_to"] = thanksgiving.Add(40 * 24 * time.Hour)
+		badChannel := base()
+		badChannel["channels"] = []string{"sms"}
+		noChannel := base()
+		noChannel["channels"] = []string{}
+		for _, body := range []map[string]interface{}{tooLong, badChannel, noChannel} {
+			assert.Equal(t, http.StatusBadRequest, send("POST", "/user/saved-searches", userID, body).Code)
+		}
+	})
+
+	t.Run("Matching rides alert the user, at most once per interval", func(t *testing.T) {
+		userID := insertUser(t, "homeward@ufl.edu")
+		alertEmails = nil
+		search := save(t, userID, map[string]interface{}{
+			"from": gainesville, "to": miami, "seats": 2,
+			"departure_from": thanksgiving, "departure_to": thanksgiving.Add(48 * time.Hour),
+		})
+		assert.Equal(t, []string{models.AlertEmail, models.AlertInbox}, search.Channels)
+		assert.Equal(t, 5000.0, search.RadiusMeters)
+
+		// Wrong destination, and outside the window: no alert
+		provide(t, tampa, thanksgiving.Add(10*time.Hour))
+		provide(t, miami, thanksgiving.Add(72*time.Hour))
+		assert.Empty(t, alertEmails)
+		assert.Empty(t, readInbox(userID, "").Notifications)
+
+		rideID := provide(t, miami, thanksgiving.Add(10*time.Hour))
+		assert.Equal(t, []string{"homeward@ufl.edu"}, alertEmails)
+		notifications := readInbox(userID, "").Notifications
+		if assert.Len(t, notifications, 1) {
+			assert.Equal(t, models.NotificationNewRide, notifications[0].Kind)
+			assert.Equal(t, rideID, *notifications[0].RideID)
+			assert.Equal(t, search.ID, *notifications[0].SavedSearchID)
+			assert.Contains(t, notifications[0].Message, "Miami")
+		}
+
+		// A second match right away is held back by the rate limit
+		provide(t, miami, thanksgiving.Add(20*time.Hour))
+		assert.Len(t, alertEmails, 1)
+		assert.Len(t, readInbox(userID, "").Notifications, 1)
+		stored, _ := testStore.Searches.FindByID(context.TODO(), search.ID)
+		assert.Equal(t, 1, stored.AlertCount)
+		assert.NotNil(t, stored.LastAlertAt)
+	})
+
+	t.Run("Alerts go only through the chosen channels", func(t *testing.T) {
+		userID := insertUser(t, "inbox-only@ufl.edu")
+		alertEmails = nil
+		save(t, userID, map[string]interface{}{
+			"from": gainesville, "to": tampa, "channels": []string{"inbox"},
+			"departure_from": thanksgiving, "departure_to": thanksgiving.Add(24 * time.Hour),
+		})
+		provide(t, tampa, thanksgiving.Add(9*time.Hour))
+		assert.Empty(t, alertEmails)
+		assert.Len(t, readInbox(userID, "").Notifications, 1)
+	})
+
+	t.Run("The inbox can be paged and read", func(t *testing.T) {
+		userID := primitive.NewObjectID()
+		for i := 0; i < 3; i++ {
+			notification := models.Notification{
+				UserID: userID, Kind: models.NotificationNewRide, Message: "New ride",
+				CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
+			}
+			assert.NoError(t, testStore.Notifications.Create(context.TODO(), &notification))
+			t.Cleanup(func() { testStore.Notifications.Delete(context.TODO(), notification.ID) })
+		}
+
+		first := readInbox(userID, "?limit=2")
+		assert.Len(t, first.Notifications, 2)
+		assert.True(t, first.HasMore)
+		assert.True(t, first.Notifications[0].CreatedAt.After(first.Notifications[1].CreatedAt), "newest first")
+		rest := readInbox(userID, "?limit=2&cursor="+first.NextCursor)
+		assert.Len(t, rest.Notifications, 1)
+		assert.False(t, rest.HasMore)
+
+		newest := first.Notifications[0].ID
+		w := send("POST", "/user/notifications/"+newest.Hex()+"/read", primitive.NewObjectID(), nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, "someone else's notification")
+		w = send("POST", "/user/notifications/"+newest.Hex()+"/read", userID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, readInbox(userID, "?unread=true").Notifications, 2)
+
+		w = send("POST", "/user/notifications/read-all", userID, nil)
+		assert.JSONEq(t, `{"message": "Notifications marked read", "marked": 2}`, w.Body.String())
+		assert.Empty(t, readInbox(userID, "?unread=true").Notifications)
+	})
+
+	t.Run("Deleted searches stop alerting", func(t *testing.T) {
+		userID := insertUser(t, "changed-plans@ufl.edu")
+		alertEmails = nil
+		search := save(t, userID, map[string]interface{}{
+			"from": gainesville, "to": miami,
+			"departure_from": thanksgiving.Add(-72 * time.Hour), "departure_to": thanksgiving.Add(-48 * time.Hour),
+		})
+
+		w := send("DELETE", "/user/saved-searches/"+search.ID.Hex(), primitive.NewObjectID(), nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = send("DELETE", "/user/saved-searches/"+search.ID.Hex(), userID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var listed struct {
+			Searches []models.SavedSearch `json:"searches"`
+		}
+		json.Unmarshal(send("GET", "/user/saved-searches", userID, nil).Body.Bytes(), &listed)
+		assert.Empty(t, listed.Searches)
+
+		provide(t, miami, thanksgiving.Add(-60*time.Hour))
+		assert.Empty(t, alertEmails)
+	})
+}
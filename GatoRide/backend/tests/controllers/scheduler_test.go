@@ -1,46 +1,133 @@
 package controllers_test
 
 import (
-	"backend/config"
 	"backend/models"
+	"backend/repository"
 	"backend/utils"
 	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestCleanupOldRides(t *testing.T) {
-	collection := config.GetCollection("rides")
-
-	// Insert a test ride scheduled 48 hours in the past
-	pastRide := models.Ride{
-		ID:        primitive.NewObjectID(),
-		DriverID:  primitive.NewObjectID(),
-		Pickup:    models.Location{Latitude: 10.0, Longitude: 10.0, Address: "Test From"},
-		Dropoff:   models.Location{Latitude: 20.0, Longitude: 20.0, Address: "Test To"},
-		Status:    models.StatusOpen,
-		Price:     10.0,
-		Seats:     2,
-		Date:      time.Now().Add(-48 * time.Hour),
-		CreatedAt: time.Now().Add(-72 * time.Hour),
+	newRide := func(departed time.Duration) models.Ride {
+		ride := models.Ride{
+			ID:        primitive.NewObjectID(),
+			DriverID:  primitive.NewObjectID(),
+			Pickup:    models.Location{Latitude: 10.0, Longitude: 10.0, Address: "Test From"},
+			Dropoff:   models.Location{Latitude: 20.0, Longitude: 20.0, Address: "Test To"},
+			Status:    models.StatusOpen,
+			Price:     10.0,
+			Seats:     2,
+			Date:      time.Now().Add(-departed),
+			CreatedAt: time.Now().Add(-72 * time.Hour),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		t.Cleanup(func() { _ = testStore.Rides.Delete(context.TODO(), ride.ID) })
+		return ride
 	}
+	// One ride that left two days ago, and one whose driver is a few minutes late starting it
+	pastRide := newRide(48 * time.Hour)
+	lateRide := newRide(5 * time.Minute)
 
-	_, err := collection.InsertOne(context.TODO(), pastRide)
+	// The old ride was fully booked by a passenger who never got their ride
+	passengerID := createCancellingUser(t, "stranded_passenger").ID
+	_, err := testStore.Rides.ReserveSeats(context.TODO(), pastRide.ID, passengerID, 2, 0, pastRide.FullRoute())
 	assert.NoError(t, err)
+	booking := models.Booking{
+		ID:          primitive.NewObjectID(),
+		RideID:      pastRide.ID,
+		PassengerID: passengerID,
+		Seats:       2,
+		Status:      models.BookingConfirmed,
+		CreatedAt:   time.Now().Add(-72 * time.Hour),
+	}
+	assert.NoError(t, testStore.Bookings.Create(context.TODO(), &booking))
+	t.Cleanup(func() { _ = testStore.Bookings.Delete(context.TODO(), booking.ID) })
+
+	// Capture the emails instead of sending them
+	var emailed []string
+	original := utils.SendRideCancelledFunc
+	utils.SendRideCancelledFunc = func(email string, ride *models.Ride, alternatives []models.Ride) error {
+		emailed = append(emailed, email)
+		return nil
+	}
+	defer func() { utils.SendRideCancelledFunc = original }()
 
-	// Run cleanup
-	utils.CleanupOldRides()
+	utils.CleanupOldRides(testStore.Rides, newRideController(), time.Hour)
 
-	// Fetch the ride again and verify status is now "cancelled"
-	var updatedRide models.Ride
-	err = collection.FindOne(context.TODO(), bson.M{"_id": pastRide.ID}).Decode(&updatedRide)
+	// Fetch the rides again: only the old one is cancelled, and it says why
+	updatedRide, err := testStore.Rides.FindByID(context.TODO(), pastRide.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, models.StatusCancelled, updatedRide.Status)
+	if assert.NotNil(t, updatedRide.Cancellation) {
+		assert.Equal(t, models.ReasonExpired, updatedRide.Cancellation.Reason)
+	}
+	assert.Empty(t, updatedRide.PassengerIDs)
+
+	// Its passenger is let go and told why
+	updatedBooking, err := testStore.Bookings.FindByID(context.TODO(), booking.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.BookingCancelledByDriver, updatedBooking.Status)
+	inbox, _, err := testStore.Notifications.FindPage(context.TODO(), repository.NotificationFilter{UserID: passengerID}, repository.Page{Sort: repository.SortCreated})
+	assert.NoError(t, err)
+	if assert.Len(t, inbox, 1) {
+		assert.Equal(t, models.NotificationRideCancelled, inbox[0].Kind)
+		assert.Contains(t, inbox[0].Message, "never started")
+		_ = testStore.Notifications.Delete(context.TODO(), inbox[0].ID)
+	}
+	assert.Equal(t, []string{"stranded_passenger@example.com"}, emailed)
+
+	stillOpen, err := testStore.Rides.FindByID(context.TODO(), lateRide.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusOpen, stillOpen.Status)
+}
+
+func TestExpireRideRequests(t *testing.T) {
+	// One request whose window closed an hour ago, and one still open for a day
+	newRequest := func(windowEnd time.Time) models.RideRequest {
+		request := models.RideRequest{
+			ID:            primitive.NewObjectID(),
+			PassengerID:   primitive.NewObjectID(),
+			Pickup:        models.Location{Latitude: 10.0, Longitude: 10.0, Address: "Test From"},
+			Dropoff:       models.Location{Latitude: 20.0, Longitude: 20.0, Address: "Test To"},
+			DepartureFrom: windowEnd.Add(-2 * time.Hour),
+			DepartureTo:   windowEnd,
+			Seats:         1,
+			MaxPrice:      15,
+			Status:        models.RequestOpen,
+			CreatedAt:     time.Now().Add(-24 * time.Hour),
+		}
+		assert.NoError(t, testStore.Requests.Create(context.TODO(), &request))
+		t.Cleanup(func() { testStore.Requests.Delete(context.TODO(), request.ID) })
+		return request
+	}
+	past := newRequest(time.Now().Add(-time.Hour))
+	future := newRequest(time.Now().Add(24 * time.Hour))
+
+	offer := models.RideOffer{
+		ID:        primitive.NewObjectID(),
+		RequestID: past.ID,
+		DriverID:  primitive.NewObjectID(),
+		Price:     12,
+		Date:      past.DepartureFrom,
+		Seats:     1,
+		Status:    models.OfferPending,
+		CreatedAt: time.Now().Add(-12 * time.Hour),
+	}
+	assert.NoError(t, testStore.Offers.Create(context.TODO(), &offer))
+	t.Cleanup(func() { testStore.Offers.Delete(context.TODO(), offer.ID) })
+
+	utils.ExpireRideRequests(testStore.Requests, testStore.Offers)
 
-	// Clean up: delete the test ride
-	_, _ = collection.DeleteOne(context.TODO(), bson.M{"_id": pastRide.ID})
+	expired, _ := testStore.Requests.FindByID(context.TODO(), past.ID)
+	assert.Equal(t, models.RequestExpired, expired.Status)
+	assert.NotNil(t, expired.ResolvedAt)
+	stillOpen, _ := testStore.Requests.FindByID(context.TODO(), future.ID)
+	assert.Equal(t, models.RequestOpen, stillOpen.Status)
+	expiredOffer, _ := testStore.Offers.FindByID(context.TODO(), offer.ID)
+	assert.Equal(t, models.OfferExpired, expiredOffer.Status)
 }
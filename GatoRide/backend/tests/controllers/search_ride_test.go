@@ -1,8 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
 	"bytes"
 	"context"
@@ -14,14 +12,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func setupSearchRideRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/user/search-ride", controllers.SearchRides)
+	router.POST("/user/search-ride", newRideController().SearchRides)
 	return router
 }
 
@@ -44,8 +41,8 @@ func TestSearchRides_Success(t *testing.T) {
 		Date:      testDate,
 		CreatedAt: time.Now(),
 	}
-	collection := config.GetCollection("rides")
-	_, err := collection.InsertOne(context.TODO(), ride)
+
+	err := testStore.Rides.Create(context.TODO(), &ride)
 	assert.NoError(t, err)
 
 	// ✅ Prepare request with matching location + date
@@ -69,13 +66,15 @@ func TestSearchRides_Success(t *testing.T) {
 
 	// ✅ Assert response
 
-	var response map[string][]models.Ride
+	var response struct {
+		Rides []models.Ride `json:"rides"`
+	}
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, len(response["rides"]), 1)
+	assert.GreaterOrEqual(t, len(response.Rides), 1)
 
 	// ✅ Cleanup
-	_, _ = collection.DeleteOne(context.TODO(), bson.M{"_id": ride.ID})
+	_ = testStore.Rides.Delete(context.TODO(), ride.ID)
 }
 
 func TestSearchRides_SeatFilter(t *testing.T) {
@@ -93,8 +92,8 @@ func TestSearchRides_SeatFilter(t *testing.T) {
 		Date:      today,
 		CreatedAt: time.Now(),
 	}
-	collection := config.GetCollection("rides")
-	_, err := collection.InsertOne(context.TODO(), ride)
+
+	err := testStore.Rides.Create(context.TODO(), &ride)
 	assert.NoError(t, err)
 
 	// Requesting more seats than available
@@ -118,12 +117,14 @@ func TestSearchRides_SeatFilter(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var response map[string][]models.Ride
+	var response struct {
+		Rides []models.Ride `json:"rides"`
+	}
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Len(t, response["rides"], 0)
+	assert.Len(t, response.Rides, 0)
 
-	_, _ = collection.DeleteOne(context.TODO(), bson.M{"_id": ride.ID})
+	_ = testStore.Rides.Delete(context.TODO(), ride.ID)
 }
 
 func TestSearchRides_InvalidDate(t *testing.T) {
@@ -0,0 +1,161 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"backend/repository"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSegmentBooking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+
+	// Each request acts as the user named in the header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/rides/book", controller.BookRide)
+	router.POST("/rides/cancel-booking", controller.CancelBooking)
+	router.POST("/user/search-ride", controller.SearchRides)
+
+	postAs := func(userID primitive.ObjectID, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, nil)
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Gainesville → Ocala → Orlando with a single seat
+	insertRide := func(t *testing.T, date time.Time) models.Ride {
+		ride := models.Ride{
+			ID:           primitive.NewObjectID(),
+			DriverID:     primitive.NewObjectID(),
+			Pickup:       models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"},
+			Waypoints:    []models.Location{{Latitude: 29.1872, Longitude: -82.1401, Address: "Ocala"}},
+			Dropoff:      models.Location{Latitude: 28.5383, Longitude: -81.3792, Address: "Orlando"},
+			Status:       models.StatusOpen,
+			Price:        15,
+			Seats:        1,
+			PassengerIDs: []primitive.ObjectID{},
+			Date:         date,
+			CreatedAt:    time.Now(),
+		}
+		ride.SegmentSeats = ride.FreeSeatsBySegment()
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		t.Cleanup(func() {
+			testStore.Rides.Delete(context.TODO(), ride.ID)
+			bookings, _ := testStore.Bookings.Find(context.TODO(), repository.BookingFilter{RideID: ride.ID})
+			for _, booking := range bookings {
+				testStore.Bookings.Delete(context.TODO(), booking.ID)
+			}
+		})
+		return ride
+	}
+
+	t.Run("A seat is resold once the first passenger gets off", func(t *testing.T) {
+		ride := insertRide(t, time.Now().Add(24*time.Hour))
+		first, second, third := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+		w := postAs(first, "/rides/book?ride_id="+ride.ID.Hex()+"&from_stop=0&to_stop=1")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// The second leg still has its seat, so the ride stays open
+		stored, err := testStore.Rides.FindByID(context.TODO(), ride.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 1}, stored.SegmentSeats)
+		assert.Equal(t, 0, stored.Seats)
+		assert.Equal(t, models.StatusOpen, stored.Status)
+
+		// Nobody can ride the whole way any more
+		w = postAs(third, "/rides/book?ride_id="+ride.ID.Hex())
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = postAs(second, "/rides/book?ride_id="+ride.ID.Hex()+"&from_stop=1&to_stop=2")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Booking models.Booking `json:"booking"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Booking.FromStop)
+		assert.Equal(t, 2, response.Booking.ToStop)
+
+		// Every segment is now full
+		stored, err = testStore.Rides.FindByID(context.TODO(), ride.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 0}, stored.SegmentSeats)
+		assert.Equal(t, models.StatusBooked, stored.Status)
+
+		// Cancelling the first leg frees only that leg and reopens the ride
+		w = postAs(first, "/rides/cancel-booking?ride_id="+ride.ID.Hex())
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		stored, err = testStore.Rides.FindByID(context.TODO(), ride.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 0}, stored.SegmentSeats)
+		assert.Equal(t, models.StatusOpen, stored.Status)
+	})
+
+	t.Run("Invalid segments are rejected", func(t *testing.T) {
+		ride := insertRide(t, time.Now().Add(24*time.Hour))
+
+		for _, query := range []string{"&from_stop=1&to_stop=1", "&from_stop=2&to_stop=1", "&from_stop=0&to_stop=3", "&from_stop=x"} {
+			w := postAs(primitive.NewObjectID(), "/rides/book?ride_id="+ride.ID.Hex()+query)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("Search matches a trip between intermediate stops", func(t *testing.T) {
+		dateStr := "2025-05-14"
+		date, _ := time.Parse("2006-01-02", dateStr)
+		ride := insertRide(t, date.Add(9*time.Hour))
+
+		// Take the first leg so only Ocala → Orlando is left
+		w := postAs(primitive.NewObjectID(), "/rides/book?ride_id="+ride.ID.Hex()+"&from_stop=0&to_stop=1")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		search := func(from, to models.Location) []map[string]interface{} {
+			body, _ := json.Marshal(map[string]interface{}{
+				"from":  from,
+				"to":    to,
+				"date":  dateStr,
+				"seats": 1,
+			})
+			req, _ := http.NewRequest("POST", "/user/search-ride", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Test-User", primitive.NewObjectID().Hex())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response struct {
+				Rides []map[string]interface{} `json:"rides"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return response.Rides
+		}
+
+		rides := search(ride.Waypoints[0], ride.Dropoff)
+		if assert.Len(t, rides, 1) {
+			assert.Equal(t, ride.ID.Hex(), rides[0]["id"])
+			assert.Equal(t, float64(1), rides[0]["from_stop"])
+			assert.Equal(t, float64(2), rides[0]["to_stop"])
+			assert.Equal(t, float64(1), rides[0]["available_seats"])
+		}
+
+		// The full route has no seat left
+		assert.Empty(t, search(ride.Pickup, ride.Dropoff))
+	})
+}
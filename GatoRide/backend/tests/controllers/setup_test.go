@@ -1,18 +1,22 @@
 package controllers_test
 
 import (
-	"backend/config"
+	"backend/auth"
+	"backend/repository"
 	"backend/utils"
 	"fmt"
 	"os"
 	"testing"
 )
 
-// ✅ Ensure MongoDB is connected before running tests
+// testStore holds the repositories every test handler is built with
+var testStore repository.Store
+
+// ✅ Run every test against in-memory storage so no database is needed
 func TestMain(m *testing.M) {
-	// Connect to the database
-	fmt.Println("🔄 Connecting to MongoDB for tests...")
-	config.ConnectDB()
+	testStore = repository.NewMemoryStore()
+	auth.SetSecret("test-jwt-secret")
+	utils.SetVerificationSecret("test-verification-secret")
 
 	// ✅ Override SendEmailFunc to prevent real emails
 	originalSendEmail := utils.SendEmailFunc
@@ -1,7 +1,6 @@
 package controllers_test
 
 import (
-	"backend/controllers"
 	"backend/utils"
 	"bytes"
 	"encoding/json"
@@ -28,7 +27,7 @@ func TestSignup(t *testing.T) {
 	}
 	defer func() { utils.SendEmailFunc = originalSendEmail }() // Restore after test
 
-	router.POST("/signup", controllers.Signup)
+	router.POST("/signup", newAuthController().Signup)
 
 	t.Run("Successful signup", func(t *testing.T) {
 		testEmail := "radime7497@lassora.com"
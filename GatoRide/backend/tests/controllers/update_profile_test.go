@@ -1,8 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
 	"bytes"
 	"context"
@@ -13,7 +11,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -26,7 +23,7 @@ func TestUpdateUserProfile(t *testing.T) {
 	router.POST("/user/update-profile", func(c *gin.Context) {
 		// Mock the auth middleware by setting userID
 		c.Set("userID", mockUserID)
-		controllers.UpdateUserProfile(c)
+		newUserController().UpdateUserProfile(c)
 	})
 
 	// Test updating name and username
@@ -55,8 +52,7 @@ func TestUpdateUserProfile(t *testing.T) {
 		defer cleanupTestUser(t, testUser.Email, testUser.Username)
 
 		// Insert user into DB
-		collection := config.GetCollection("users")
-		_, err = collection.InsertOne(context.TODO(), testUser)
+		err = testStore.Users.Create(context.TODO(), &testUser)
 		assert.NoError(t, err)
 
 		// Create update request
@@ -90,8 +86,7 @@ func TestUpdateUserProfile(t *testing.T) {
 		assert.Equal(t, "updatedusername", user["username"])
 
 		// Verify database was updated
-		var updatedUser models.User
-		err = collection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&updatedUser)
+		updatedUser, err := testStore.Users.FindByID(context.TODO(), userID)
 		assert.NoError(t, err)
 		assert.Equal(t, "Updated Name", updatedUser.Name)
 		assert.Equal(t, "updatedusername", updatedUser.Username)
@@ -123,8 +118,7 @@ func TestUpdateUserProfile(t *testing.T) {
 		defer cleanupTestUser(t, testUser.Email, testUser.Username)
 
 		// Insert user into DB
-		collection := config.GetCollection("users")
-		_, err = collection.InsertOne(context.TODO(), testUser)
+		err = testStore.Users.Create(context.TODO(), &testUser)
 		assert.NoError(t, err)
 
 		// Create update request with location
@@ -147,8 +141,7 @@ func TestUpdateUserProfile(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 
 		// Verify database was updated
-		var updatedUser models.User
-		err = collection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&updatedUser)
+		updatedUser, err := testStore.Users.FindByID(context.TODO(), userID)
 		assert.NoError(t, err)
 		assert.Equal(t, 40.7128, updatedUser.Location.Latitude)
 		assert.Equal(t, -74.0060, updatedUser.Location.Longitude)
@@ -188,10 +181,9 @@ func TestUpdateUserProfile(t *testing.T) {
 		defer cleanupTestUser(t, testUser2.Email, testUser2.Username)
 
 		// Insert users into DB
-		collection := config.GetCollection("users")
-		_, err = collection.InsertOne(context.TODO(), testUser1)
+		err = testStore.Users.Create(context.TODO(), &testUser1)
 		assert.NoError(t, err)
-		_, err = collection.InsertOne(context.TODO(), testUser2)
+		err = testStore.Users.Create(context.TODO(), &testUser2)
 		assert.NoError(t, err)
 
 		// Create update request with duplicate username
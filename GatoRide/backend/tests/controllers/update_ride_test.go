@@ -0,0 +1,184 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUpdateRide(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/rides/book", controller.BookRide)
+	router.PUT("/user/rides/:id", controller.UpdateRide)
+
+	// Record ride update emails instead of sending them
+	var notified []string
+	originalSend := utils.SendRideUpdateFunc
+	utils.SendRideUpdateFunc = func(email string, ride *models.Ride, changes []string) error {
+		notified = append(notified, email)
+		return nil
+	}
+	t.Cleanup(func() { utils.SendRideUpdateFunc = originalSend })
+
+	driverID := primitive.NewObjectID()
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	insertPassenger := func(t *testing.T, email string) primitive.ObjectID {
+		user := models.User{ID: primitive.NewObjectID(), Name: "Passenger", Email: email, Username: email}
+		assert.NoError(t, testStore.Users.Create(context.TODO(), &user))
+		t.Cleanup(func() { testStore.Users.Delete(context.TODO(), user.ID) })
+		return user.ID
+	}
+
+	insertRide := func(t *testing.T, seats int, status models.RideStatus) primitive.ObjectID {
+		ride := models.Ride{
+			ID:           primitive.NewObjectID(),
+			DriverID:     driverID,
+			Pickup:       models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"},
+			Dropoff:      models.Location{Latitude: 28.5383, Longitude: -81.3792, Address: "Orlando"},
+			Status:       status,
+			Price:        20,
+			Seats:        seats,
+			PassengerIDs: []primitive.ObjectID{},
+			Date:         time.Now().Add(48 * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		t.Cleanup(func() {
+			testStore.Rides.Delete(context.TODO(), ride.ID)
+			bookings, _ := testStore.Bookings.Find(context.TODO(), repository.BookingFilter{RideID: ride.ID})
+			for _, booking := range bookings {
+				testStore.Bookings.Delete(context.TODO(), booking.ID)
+			}
+		})
+		return ride.ID
+	}
+
+	t.Run("Material changes notify booked passengers", func(t *testing.T) {
+		notified = nil
+		rideID := insertRide(t, 3, models.StatusOpen)
+		alice := insertPassenger(t, "alice-update@example.com")
+		bob := insertPassenger(t, "bob-update@example.com")
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/book?ride_id="+rideID.Hex(), alice, nil).Code)
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/book?ride_id="+rideID.Hex(), bob, nil).Code)
+
+		newDate := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+		w := send("PUT", "/user/rides/"+rideID.Hex(), driverID, map[string]interface{}{
+			"price": 25,
+			"date":  newDate,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Ride     models.Ride `json:"ride"`
+			Changes  []string    `json:"changes"`
+			Notified int         `json:"notified_passengers"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 25.0, response.Ride.Price)
+		assert.True(t, newDate.Equal(response.Ride.Date))
+		assert.ElementsMatch(t, []string{"price", "departure time"}, response.Changes)
+		assert.Equal(t, 2, response.Notified)
+		assert.ElementsMatch(t, []string{"alice-update@example.com", "bob-update@example.com"}, notified)
+
+		// Both bookings are marked so the passengers can cancel without penalty
+		bookings, err := testStore.Bookings.Find(context.TODO(), repository.BookingFilter{RideID: rideID})
+		assert.NoError(t, err)
+		assert.Len(t, bookings, 2)
+		for _, booking := range bookings {
+			assert.NotNil(t, booking.RideChangedAt)
+			assert.Equal(t, 20.0, booking.PricePerSeat)
+		}
+	})
+
+	t.Run("Notes alone do not notify anyone", func(t *testing.T) {
+		notified = nil
+		rideID := insertRide(t, 2, models.StatusOpen)
+		passengerID := insertPassenger(t, "carol-update@example.com")
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/book?ride_id="+rideID.Hex(), passengerID, nil).Code)
+
+		w := send("PUT", "/user/rides/"+rideID.Hex(), driverID, map[string]interface{}{"notes": "Room for one suitcase each"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, notified)
+
+		stored, err := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Room for one suitcase each", stored.Notes)
+		assert.NotNil(t, stored.UpdatedAt)
+	})
+
+	t.Run("Seats keep existing bookings", func(t *testing.T) {
+		rideID := insertRide(t, 3, models.StatusOpen)
+		passengerID := insertPassenger(t, "dave-update@example.com")
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/book?ride_id="+rideID.Hex()+"&seats=2", passengerID, nil).Code)
+
+		// Two seats are taken, so the ride cannot shrink to one
+		w := send("PUT", "/user/rides/"+rideID.Hex(), driverID, map[string]interface{}{"seats": 1})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// Shrinking to exactly the booked seats fills the ride
+		w = send("PUT", "/user/rides/"+rideID.Hex(), driverID, map[string]interface{}{"seats": 2})
+		assert.Equal(t, http.StatusOK, w.Code)
+		stored, _ := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.Equal(t, 0, stored.Seats)
+		assert.Equal(t, models.StatusBooked, stored.Status)
+		assert.Equal(t, []primitive.ObjectID{passengerID}, stored.PassengerIDs)
+
+		// Adding seats reopens it
+		w = send("PUT", "/user/rides/"+rideID.Hex(), driverID, map[string]interface{}{"seats": 4})
+		assert.Equal(t, http.StatusOK, w.Code)
+		stored, _ = testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.Equal(t, 2, stored.Seats)
+		assert.Equal(t, models.StatusOpen, stored.Status)
+	})
+
+	t.Run("Rejected edits", func(t *testing.T) {
+		rideID := insertRide(t, 2, models.StatusOpen)
+		ongoingID := insertRide(t, 2, models.StatusOngoing)
+		completedID := insertRide(t, 2, models.StatusCompleted)
+		price := map[string]interface{}{"price": 30}
+
+		assert.Equal(t, http.StatusForbidden, send("PUT", "/user/rides/"+rideID.Hex(), primitive.NewObjectID(), price).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/user/rides/"+ongoingID.Hex(), driverID, price).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/user/rides/"+completedID.Hex(), driverID, price).Code)
+		assert.Equal(t, http.StatusNotFound, send("PUT", "/user/rides/"+primitive.NewObjectID().Hex(), driverID, price).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/user/rides/"+rideID.Hex(), driverID, map[string]interface{}{}).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/user/rides/"+rideID.Hex(), driverID, map[string]interface{}{"price": -5}).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/user/rides/"+rideID.Hex(), driverID, map[string]interface{}{
+			"date": time.Now().Add(-time.Hour),
+		}).Code)
+
+		stored, _ := testStore.Rides.FindByID(context.TODO(), ongoingID)
+		assert.Equal(t, 20.0, stored.Price)
+	})
+}
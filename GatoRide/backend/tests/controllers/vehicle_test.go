@@ -0,0 +1,160 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// registerVehicle stores a four-seat car for the owner, removing it when the test ends
+func registerVehicle(t *testing.T, ownerID primitive.ObjectID) models.Vehicle {
+	vehicle := models.Vehicle{
+		ID:      primitive.NewObjectID(),
+		OwnerID: ownerID,
+		VehicleDetails: models.VehicleDetails{
+			Make:   "Toyota",
+			Model:  "Corolla",
+			Colour: "Blue",
+			Plate:  "GATOR 1",
+			Seats:  4,
+		},
+		CreatedAt: time.Now(),
+	}
+	assert.NoError(t, testStore.Vehicles.Create(context.TODO(), &vehicle))
+	t.Cleanup(func() { _ = testStore.Vehicles.Delete(context.TODO(), vehicle.ID) })
+	return vehicle
+}
+
+func TestVehicles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	vehicles := newVehicleController()
+	rides := newRideController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/user/vehicles", vehicles.RegisterVehicle)
+	router.GET("/user/vehicles", vehicles.ListVehicles)
+	router.DELETE("/user/vehicles/:id", vehicles.RemoveVehicle)
+	router.POST("/user/provide-ride", rides.ProvideRide)
+	router.PUT("/user/rides/:id", rides.UpdateRide)
+	router.POST("/user/search-ride", rides.SearchRides)
+
+	send := func(method, path string, userID primitive.ObjectID, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	driver := primitive.NewObjectID()
+	approveDriver(t, driver)
+	var vehicle models.Vehicle
+	t.Cleanup(func() { testStore.Vehicles.Delete(context.TODO(), vehicle.ID) })
+
+	t.Run("Register a vehicle", func(t *testing.T) {
+		w := send("POST", "/user/vehicles", driver, map[string]interface{}{
+			"make": "Honda", "model": "Civic", "colour": "Silver", "plate": " abc  123 ", "seats": 3, "fuel_economy": 6.5,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Vehicle models.Vehicle `json:"vehicle"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		vehicle = response.Vehicle
+		assert.Equal(t, "ABC 123", vehicle.Plate)
+		assert.Equal(t, driver, vehicle.OwnerID)
+		if assert.NotNil(t, vehicle.FuelEconomy) {
+			assert.Equal(t, 6.5, *vehicle.FuelEconomy)
+		}
+
+		// The same plate, however it is typed, is only registered once
+		w = send("POST", "/user/vehicles", driver, map[string]interface{}{
+			"make": "Honda", "model": "Civic", "colour": "Silver", "plate": "Abc 123", "seats": 3,
+		})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = send("GET", "/user/vehicles", driver, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), vehicle.ID.Hex())
+	})
+
+	t.Run("Invalid vehicles are rejected", func(t *testing.T) {
+		for _, body := range []map[string]interface{}{
+			{"make": "Honda", "model": "Civic", "colour": "Silver", "plate": "", "seats": 3},
+			{"make": "Honda", "model": "Civic", "colour": "Silver", "plate": "XYZ", "seats": 0},
+			{"make": "Honda", "model": "Civic", "colour": "Silver", "plate": "XYZ", "seats": 30},
+			{"make": "Honda", "model": "Civic", "colour": "Silver", "plate": "XYZ", "seats": 3, "fuel_economy": -1},
+		} {
+			assert.Equal(t, http.StatusBadRequest, send("POST", "/user/vehicles", driver, body).Code, body)
+		}
+	})
+
+	pickup := models.Location{Latitude: 36.1627, Longitude: -86.7816, Address: "Nashville"}
+	dropoff := models.Location{Latitude: 35.9606, Longitude: -83.9207, Address: "Knoxville"}
+	date := time.Date(2034, 3, 14, 10, 0, 0, 0, time.UTC)
+	offer := func(userID primitive.ObjectID, vehicleID string, seats int) *httptest.ResponseRecorder {
+		return send("POST", "/user/provide-ride", userID, map[string]interface{}{
+			"pickup": pickup, "dropoff": dropoff, "price": 25, "seats": seats, "date": date, "vehicle_id": vehicleID,
+		})
+	}
+
+	t.Run("Rides need a vehicle with room for their seats", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, offer(driver, "", 2).Code)
+		assert.Equal(t, http.StatusBadRequest, offer(driver, vehicle.ID.Hex(), 4).Code)
+		// Nobody can offer rides in someone else's car
+		stranger := primitive.NewObjectID()
+		approveDriver(t, stranger)
+		assert.Equal(t, http.StatusNotFound, offer(stranger, vehicle.ID.Hex(), 2).Code)
+	})
+
+	t.Run("Passengers see the vehicle", func(t *testing.T) {
+		w := offer(driver, vehicle.ID.Hex(), 2)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			RideID string `json:"ride_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		rideID, _ := primitive.ObjectIDFromHex(response.RideID)
+		t.Cleanup(func() { testStore.Rides.Delete(context.TODO(), rideID) })
+
+		// The driver cannot add seats the car does not have
+		w = send("PUT", "/user/rides/"+rideID.Hex(), driver, map[string]interface{}{"seats": 5})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = send("POST", "/user/search-ride", primitive.NewObjectID(), map[string]interface{}{
+			"from": pickup, "to": dropoff, "date": "2034-03-14", "seats": 1,
+		})
+		var results struct {
+			Rides []models.Ride `json:"rides"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &results)
+		if assert.Len(t, results.Rides, 1) && assert.NotNil(t, results.Rides[0].Vehicle) {
+			assert.Equal(t, "Civic", results.Rides[0].Vehicle.Model)
+			assert.Equal(t, "ABC 123", results.Rides[0].Vehicle.Plate)
+		}
+
+		// Removing the vehicle leaves the ride's copy of it alone
+		assert.Equal(t, http.StatusNotFound, send("DELETE", "/user/vehicles/"+vehicle.ID.Hex(), primitive.NewObjectID(), nil).Code)
+		assert.Equal(t, http.StatusOK, send("DELETE", "/user/vehicles/"+vehicle.ID.Hex(), driver, nil).Code)
+		stored, _ := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.Equal(t, "Silver", stored.Vehicle.Colour)
+	})
+}
@@ -1,8 +1,6 @@
 package controllers_test
 
 import (
-	"backend/config"
-	"backend/controllers"
 	"backend/models"
 	"backend/utils"
 	"context"
@@ -18,7 +16,7 @@ import (
 func TestVerifyEmail(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.GET("/verify-email", controllers.VerifyEmail)
+	router.GET("/verify-email", newAuthController().VerifyEmail)
 
 	t.Run("Successful verification", func(t *testing.T) {
 		email := "verify@example.com"
@@ -34,12 +32,10 @@ func TestVerifyEmail(t *testing.T) {
 
 		cleanupTestUser(t, testUser.Email, testUser.Username)
 		defer cleanupTestUser(t, testUser.Email, testUser.Username)
-
-		collection := config.GetCollection("users")
 		hashedPassword, _ := utils.HashPassword(testUser.Password)
 		testUser.Password = hashedPassword
 		testUser.ID = primitive.NewObjectID()
-		_, _ = collection.InsertOne(context.TODO(), testUser)
+		_ = testStore.Users.Create(context.TODO(), &testUser)
 
 		req, _ := http.NewRequest("GET", "/verify-email?token="+verificationToken, nil)
 
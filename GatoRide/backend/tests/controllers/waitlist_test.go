@@ -0,0 +1,234 @@
+package controllers_test
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWaitlist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := newRideController()
+
+	// Every request acts as the user named in a header
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	router.POST("/rides/book", controller.BookRide)
+	router.POST("/rides/cancel-booking", controller.CancelBooking)
+	router.POST("/user/rides/:id/waitlist", controller.JoinWaitlist)
+	router.GET("/user/rides/:id/waitlist", controller.GetWaitlist)
+	router.DELETE("/user/rides/:id/waitlist", controller.LeaveWaitlist)
+
+	// Record promotion emails instead of sending them
+	var promotedEmails []string
+	originalSend := utils.SendWaitlistPromotionFunc
+	utils.SendWaitlistPromotionFunc = func(email string, ride *models.Ride, booking *models.Booking) error {
+		promotedEmails = append(promotedEmails, email)
+		return nil
+	}
+	t.Cleanup(func() { utils.SendWaitlistPromotionFunc = originalSend })
+
+	driverID := primitive.NewObjectID()
+
+	send := func(method, path string, userID primitive.ObjectID) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-Test-User", userID.Hex())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	insertPassenger := func(t *testing.T, email string) primitive.ObjectID {
+		user := models.User{ID: primitive.NewObjectID(), Name: "Passenger", Email: email, Username: email}
+		assert.NoError(t, testStore.Users.Create(context.TODO(), &user))
+		t.Cleanup(func() { testStore.Users.Delete(context.TODO(), user.ID) })
+		return user.ID
+	}
+
+	insertRide := func(t *testing.T, seats int, instantBook bool) primitive.ObjectID {
+		ride := models.Ride{
+			ID:           primitive.NewObjectID(),
+			DriverID:     driverID,
+			Status:       models.StatusOpen,
+			Price:        10,
+			Seats:        seats,
+			PassengerIDs: []primitive.ObjectID{},
+			Date:         time.Now().Add(48 * time.Hour),
+			CreatedAt:    time.Now(),
+			InstantBook:  &instantBook,
+		}
+		assert.NoError(t, testStore.Rides.Create(context.TODO(), &ride))
+		t.Cleanup(func() {
+			testStore.Rides.Delete(context.TODO(), ride.ID)
+			bookings, _ := testStore.Bookings.Find(context.TODO(), repository.BookingFilter{RideID: ride.ID})
+			for _, booking := range bookings {
+				testStore.Bookings.Delete(context.TODO(), booking.ID)
+			}
+			entries, _ := testStore.Waitlist.Find(context.TODO(), repository.WaitlistFilter{RideID: ride.ID})
+			for _, entry := range entries {
+				testStore.Waitlist.Delete(context.TODO(), entry.ID)
+			}
+		})
+		return ride.ID
+	}
+
+	activeBooking := func(t *testing.T, rideID, passengerID primitive.ObjectID) *models.Booking {
+		bookings, err := testStore.Bookings.Find(context.TODO(), repository.BookingFilter{
+			RideID:      rideID,
+			PassengerID: passengerID,
+			Statuses:    []models.BookingStatus{models.BookingPending, models.BookingConfirmed},
+		})
+		assert.NoError(t, err)
+		if len(bookings) == 0 {
+			return nil
+		}
+		return &bookings[0]
+	}
+
+	position := func(w *httptest.ResponseRecorder) int {
+		var response struct {
+			Position int `json:"position"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Position
+	}
+
+	t.Run("Freed seats go to the waitlist in order", func(t *testing.T) {
+		promotedEmails = nil
+		rideID := insertRide(t, 1, true)
+		first := insertPassenger(t, "first-waitlist@example.com")
+		second := insertPassenger(t, "second-waitlist@example.com")
+		third := insertPassenger(t, "third-waitlist@example.com")
+		ridePath := "/user/rides/" + rideID.Hex() + "/waitlist"
+
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/book?ride_id="+rideID.Hex(), first).Code)
+
+		w := send("POST", ridePath, second)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, 1, position(w))
+		w = send("POST", ridePath, third)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, 2, position(w))
+		assert.Equal(t, http.StatusConflict, send("POST", ridePath, second).Code)
+
+		w = send("GET", ridePath, third)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, position(w))
+
+		// The first cancellation books the seat for the first passenger in line
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/cancel-booking?ride_id="+rideID.Hex(), first).Code)
+		booking := activeBooking(t, rideID, second)
+		if assert.NotNil(t, booking) {
+			assert.Equal(t, models.BookingConfirmed, booking.Status)
+		}
+		assert.Equal(t, []string{"second-waitlist@example.com"}, promotedEmails)
+
+		stored, _ := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.Equal(t, models.StatusBooked, stored.Status)
+		assert.Equal(t, 1, stored.WaitlistCount)
+
+		w = send("GET", ridePath, third)
+		assert.Equal(t, 1, position(w))
+
+		// The next one goes to the third passenger
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/cancel-booking?ride_id="+rideID.Hex(), second).Code)
+		assert.NotNil(t, activeBooking(t, rideID, third))
+
+		// With nobody left waiting, the ride reopens
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/cancel-booking?ride_id="+rideID.Hex(), third).Code)
+		stored, _ = testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.Equal(t, models.StatusOpen, stored.Status)
+		assert.Equal(t, 0, stored.WaitlistCount)
+		assert.Equal(t, 1, stored.Seats)
+	})
+
+	t.Run("Seats are held until the waiting passenger fits", func(t *testing.T) {
+		rideID := insertRide(t, 2, true)
+		first := insertPassenger(t, "a-waitlist@example.com")
+		second := insertPassenger(t, "b-waitlist@example.com")
+		group := insertPassenger(t, "group-waitlist@example.com")
+
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/book?ride_id="+rideID.Hex(), first).Code)
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/book?ride_id="+rideID.Hex(), second).Code)
+		assert.Equal(t, http.StatusCreated, send("POST", "/user/rides/"+rideID.Hex()+"/waitlist?seats=2", group).Code)
+
+		// One seat is not enough for the group, and it is not handed to anyone else either
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/cancel-booking?ride_id="+rideID.Hex(), first).Code)
+		assert.Nil(t, activeBooking(t, rideID, group))
+		stored, _ := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.Equal(t, models.StatusBooked, stored.Status)
+		assert.Equal(t, 1, stored.Seats)
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/rides/book?ride_id="+rideID.Hex(), primitive.NewObjectID()).Code)
+
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/cancel-booking?ride_id="+rideID.Hex(), second).Code)
+		booking := activeBooking(t, rideID, group)
+		if assert.NotNil(t, booking) {
+			assert.Equal(t, 2, booking.Seats)
+		}
+	})
+
+	t.Run("Rides with approval get a booking request", func(t *testing.T) {
+		rideID := insertRide(t, 1, false)
+		first := insertPassenger(t, "approval-first@example.com")
+		waiting := insertPassenger(t, "approval-waiting@example.com")
+
+		assert.Equal(t, http.StatusAccepted, send("POST", "/rides/book?ride_id="+rideID.Hex(), first).Code)
+		assert.Equal(t, http.StatusCreated, send("POST", "/user/rides/"+rideID.Hex()+"/waitlist", waiting).Code)
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/cancel-booking?ride_id="+rideID.Hex(), first).Code)
+
+		booking := activeBooking(t, rideID, waiting)
+		if assert.NotNil(t, booking) {
+			assert.Equal(t, models.BookingPending, booking.Status)
+			assert.NotNil(t, booking.ExpiresAt)
+		}
+	})
+
+	t.Run("Leaving the waitlist reopens the ride", func(t *testing.T) {
+		rideID := insertRide(t, 1, true)
+		first := insertPassenger(t, "leave-first@example.com")
+		waiting := insertPassenger(t, "leave-waiting@example.com")
+		ridePath := "/user/rides/" + rideID.Hex() + "/waitlist"
+
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/book?ride_id="+rideID.Hex(), first).Code)
+		assert.Equal(t, http.StatusCreated, send("POST", ridePath, waiting).Code)
+
+		// The driver sees who is waiting
+		w := send("GET", ridePath, driverID)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Waitlist []models.WaitlistEntry `json:"waitlist"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Waitlist, 1)
+
+		assert.Equal(t, http.StatusOK, send("DELETE", ridePath, waiting).Code)
+		assert.Equal(t, http.StatusNotFound, send("DELETE", ridePath, waiting).Code)
+
+		assert.Equal(t, http.StatusOK, send("POST", "/rides/cancel-booking?ride_id="+rideID.Hex(), first).Code)
+		assert.Nil(t, activeBooking(t, rideID, waiting))
+		stored, _ := testStore.Rides.FindByID(context.TODO(), rideID)
+		assert.Equal(t, models.StatusOpen, stored.Status)
+	})
+
+	t.Run("Joining is refused while seats are free", func(t *testing.T) {
+		rideID := insertRide(t, 2, true)
+		ridePath := "/user/rides/" + rideID.Hex() + "/waitlist"
+
+		assert.Equal(t, http.StatusBadRequest, send("POST", ridePath, primitive.NewObjectID()).Code)
+		assert.Equal(t, http.StatusCreated, send("POST", ridePath+"?seats=3", primitive.NewObjectID()).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", ridePath+"?seats=3", driverID).Code)
+		assert.Equal(t, http.StatusNotFound, send("POST", "/user/rides/"+primitive.NewObjectID().Hex()+"/waitlist", primitive.NewObjectID()).Code)
+	})
+}
@@ -0,0 +1,101 @@
+package filestore_test
+
+import (
+	"backend/config"
+	"backend/filestore"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTrip stores a file, reads it back and deletes it, as every store must allow
+func roundTrip(t *testing.T, store filestore.Store) {
+	ctx := context.Background()
+	assert.NoError(t, store.Put(ctx, "drivers/abc/licence-1", "image/png", strings.NewReader("scan")))
+
+	content, contentType, err := store.Open(ctx, "drivers/abc/licence-1")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(content)
+		content.Close()
+		assert.Equal(t, "scan", string(data))
+		assert.Equal(t, "image/png", contentType)
+	}
+
+	assert.NoError(t, store.Delete(ctx, "drivers/abc/licence-1"))
+	_, _, err = store.Open(ctx, "drivers/abc/licence-1")
+	assert.ErrorIs(t, err, filestore.ErrNotFound)
+	// Deleting twice is fine
+	assert.NoError(t, store.Delete(ctx, "drivers/abc/licence-1"))
+}
+
+func TestMemory(t *testing.T) {
+	roundTrip(t, filestore.New(config.DocumentConfig{Store: config.DocumentStoreMemory}))
+}
+
+func TestLocal(t *testing.T) {
+	store := filestore.New(config.DocumentConfig{Store: config.DocumentStoreLocal, LocalDir: t.TempDir()})
+	roundTrip(t, store)
+
+	// Keys cannot reach outside the directory
+	assert.Error(t, store.Put(context.Background(), "../escaped", "text/plain", strings.NewReader("x")))
+	_, _, err := store.Open(context.Background(), "/etc/passwd")
+	assert.Error(t, err)
+}
+
+func TestBlob(t *testing.T) {
+	var mu sync.Mutex
+	blobs := map[string]string{}
+	types := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Query().Get("sig") != "secret" || r.Header.Get("x-ms-version") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			assert.Equal(t, "BlockBlob", r.Header.Get("x-ms-blob-type"))
+			data, _ := io.ReadAll(r.Body)
+			blobs[r.URL.Path], types[r.URL.Path] = string(data), r.Header.Get("Content-Type")
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			data, ok := blobs[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", types[r.URL.Path])
+			io.WriteString(w, data)
+		case http.MethodDelete:
+			if _, ok := blobs[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(blobs, r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults().Documents
+	cfg.Store = config.DocumentStoreBlob
+	cfg.BlobContainerURL = server.URL + "/documents/"
+	cfg.BlobSASToken = "?sv=2021-08-06&sig=secret"
+	roundTrip(t, filestore.New(cfg))
+	assert.Empty(t, blobs)
+
+	// Failures never reveal the SAS token
+	cfg.BlobSASToken = "sig=wrong"
+	err := filestore.New(cfg).Put(context.Background(), "drivers/abc/licence-1", "image/png", strings.NewReader("scan"))
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), "wrong")
+		assert.Contains(t, err.Error(), "403")
+	}
+}
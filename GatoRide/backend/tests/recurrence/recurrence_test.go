@@ -0,0 +1,74 @@
+package recurrence_test
+
+import (
+	"backend/models"
+	"backend/recurrence"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOccurrences_WeekdaysAndExceptions(t *testing.T) {
+	pattern := models.Recurrence{
+		Weekdays:       []string{"mon", "wed", "fri"},
+		DepartureTime:  "08:15",
+		TimeZone:       "America/New_York",
+		StartDate:      "2026-03-02", // a Monday
+		EndDate:        "2026-03-13",
+		ExceptionDates: []string{"2026-03-11"},
+	}
+	assert.NoError(t, recurrence.Validate(pattern))
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	occurrences, err := recurrence.Occurrences(pattern, from, to)
+	assert.NoError(t, err)
+
+	var dates []string
+	for _, o := range occurrences {
+		dates = append(dates, o.Date)
+		// 08:15 local time on both sides of the DST change on 2026-03-08
+		assert.Equal(t, 8, o.Departure.Hour())
+		assert.Equal(t, 15, o.Departure.Minute())
+	}
+	assert.Equal(t, []string{"2026-03-02", "2026-03-04", "2026-03-06", "2026-03-09", "2026-03-13"}, dates)
+
+	// New York is UTC-5 before the DST change and UTC-4 after it
+	assert.Equal(t, 13, occurrences[0].Departure.UTC().Hour())
+	assert.Equal(t, 12, occurrences[3].Departure.UTC().Hour())
+}
+
+func TestOccurrences_Window(t *testing.T) {
+	pattern := models.Recurrence{
+		Weekdays:      []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"},
+		DepartureTime: "10:00",
+		TimeZone:      "UTC",
+		StartDate:     "2026-05-01",
+		EndDate:       "2026-05-31",
+	}
+
+	// Only departures in [from, to) are returned
+	from := time.Date(2026, 5, 10, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	occurrences, err := recurrence.Occurrences(pattern, from, to)
+	assert.NoError(t, err)
+	assert.Len(t, occurrences, 3)
+	assert.Equal(t, "2026-05-10", occurrences[0].Date)
+}
+
+func TestNormalize_RejectsBadPatterns(t *testing.T) {
+	pattern := models.Recurrence{
+		Weekdays:      []string{"Mon", "funday"},
+		DepartureTime: "25:00",
+		StartDate:     "2026-06-01",
+		EndDate:       "2026-05-01",
+	}
+	err := recurrence.Normalize(&pattern)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown weekday "funday"`)
+	assert.Contains(t, err.Error(), "departure_time")
+	assert.Contains(t, err.Error(), "end_date must not be before start_date")
+	assert.Equal(t, "mon", pattern.Weekdays[0])
+	assert.Equal(t, "UTC", pattern.TimeZone)
+}
@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+	"backend/lifecycle"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// stores returns every backend to test: memory always, and MongoDB when MONGO_TEST_URI names a
+// server to create a throwaway database on
+func stores(t *testing.T) map[string]repository.Store {
+	stores := map[string]repository.Store{"memory": repository.NewMemoryStore()}
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Log("MONGO_TEST_URI not set; testing the memory store only")
+		return stores
+	}
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(uri))
+	if !assert.NoError(t, err) {
+		return stores
+	}
+	db := client.Database("gatorides_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		_ = db.Drop(context.TODO())
+		_ = client.Disconnect(context.TODO())
+	})
+	stores["mongo"] = repository.NewMongoStore(db)
+	return stores
+}
+
+// Both backends must move rides between open and booked exactly as lifecycle.AfterSeatChange
+// says, so a seat freed while passengers wait goes to the waitlist and not to a new booking
+func TestSeatChangesFollowLifecycle(t *testing.T) {
+	passenger := primitive.NewObjectID()
+	segment := models.Segment{From: 0, To: 1}
+
+	tests := []struct {
+		name     string
+		status   models.RideStatus
+		seats    int // free seats before the change
+		booked   bool
+		waiting  int
+		change   func(rides repository.RideRepository, id primitive.ObjectID) (*models.Ride, error)
+		expected models.RideStatus
+	}{
+		{
+			name: "taking the last seat books the ride", status: models.StatusOpen, seats: 1,
+			change: func(rides repository.RideRepository, id primitive.ObjectID) (*models.Ride, error) {
+				return rides.ReserveSeats(context.TODO(), id, passenger, 1, 0, segment)
+			},
+			expected: models.StatusBooked,
+		},
+		{
+			name: "a freed seat reopens the ride", status: models.StatusBooked, booked: true,
+			change: func(rides repository.RideRepository, id primitive.ObjectID) (*models.Ride, error) {
+				return rides.ReleaseSeats(context.TODO(), id, passenger, 1, 0, segment)
+			},
+			expected: models.StatusOpen,
+		},
+		{
+			name: "a freed seat is held for the waitlist", status: models.StatusBooked, booked: true, waiting: 1,
+			change: func(rides repository.RideRepository, id primitive.ObjectID) (*models.Ride, error) {
+				return rides.ReleaseSeats(context.TODO(), id, passenger, 1, 0, segment)
+			},
+			expected: models.StatusBooked,
+		},
+		{
+			name: "the ride reopens once nobody waits", status: models.StatusBooked, seats: 1, waiting: 1,
+			change: func(rides repository.RideRepository, id primitive.ObjectID) (*models.Ride, error) {
+				return rides.AdjustWaitlist(context.TODO(), id, -1)
+			},
+			expected: models.StatusOpen,
+		},
+	}
+
+	for backend, store := range stores(t) {
+		for _, tt := range tests {
+			t.Run(backend+": "+tt.name, func(t *testing.T) {
+				ride := models.Ride{
+					ID:            primitive.NewObjectID(),
+					DriverID:      primitive.NewObjectID(),
+					Pickup:        models.Location{Latitude: 29.6516, Longitude: -82.3248, Address: "Gainesville"},
+					Dropoff:       models.Location{Latitude: 28.5383, Longitude: -81.3792, Address: "Orlando"},
+					Status:        tt.status,
+					Price:         15,
+					Seats:         tt.seats,
+					SegmentSeats:  []int{tt.seats},
+					WaitlistCount: tt.waiting,
+					Date:          time.Now().Add(24 * time.Hour),
+					CreatedAt:     time.Now(),
+				}
+				if tt.booked {
+					ride.PassengerIDs = []primitive.ObjectID{passenger}
+				}
+				assert.NoError(t, store.Rides.Create(context.TODO(), &ride))
+				defer store.Rides.Delete(context.TODO(), ride.ID)
+
+				updated, err := tt.change(store.Rides, ride.ID)
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Equal(t, tt.expected, updated.Status)
+				assert.Equal(t, lifecycle.AfterSeatChange(tt.status, updated.Seats, updated.WaitlistCount), updated.Status)
+			})
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package routing_test
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/routing"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	gainesville = models.Location{Latitude: 29.6516, Longitude: -82.3248}
+	orlando     = models.Location{Latitude: 28.5383, Longitude: -81.3792}
+)
+
+func TestStraightLine(t *testing.T) {
+	provider := routing.New(config.Defaults().Routing)
+
+	path, err := provider.Route(context.Background(), []models.Location{gainesville, orlando})
+	assert.NoError(t, err)
+	assert.Len(t, path.Points, 2)
+	assert.InDelta(t, 155000, path.DistanceMeters, 3000)
+	// 60 km/h by default
+	assert.InDelta(t, path.DistanceMeters/1000, path.DurationSeconds/60, 1)
+
+	_, err = provider.Route(context.Background(), []models.Location{gainesville})
+	assert.True(t, errors.Is(err, routing.ErrNoRoute))
+}
+
+func TestOSRM(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		if strings.Contains(r.URL.Path, "0.000000") {
+			w.Write([]byte(`{"code": "NoRoute", "routes": []}`))
+			return
+		}
+		w.Write([]byte(`{"code": "Ok", "routes": [{"distance": 180000, "duration": 7200,
+			"geometry": {"coordinates": [[-82.3248, 29.6516], [-82.14, 29.19], [-81.3792, 28.5383]]}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults().Routing
+	cfg.Provider = config.RoutingOSRM
+	cfg.OSRMURL = server.URL + "/"
+	cfg.Timeout = config.Duration(time.Second)
+	provider := routing.New(cfg)
+
+	path, err := provider.Route(context.Background(), []models.Location{gainesville, orlando})
+	assert.NoError(t, err)
+	assert.Equal(t, "/route/v1/driving/-82.324800,29.651600;-81.379200,28.538300", requested)
+	assert.Len(t, path.Points, 3)
+	assert.Equal(t, 29.19, path.Points[1].Latitude)
+	assert.Equal(t, 180000.0, path.DistanceMeters)
+	assert.Equal(t, 25.0, path.SpeedMetersPerSecond())
+
+	_, err = provider.Route(context.Background(), []models.Location{gainesville, {}})
+	assert.True(t, errors.Is(err, routing.ErrNoRoute))
+
+	// Drawn paths are measured without asking the server
+	measured := provider.Measure([]models.Point{{Latitude: 29.6516, Longitude: -82.3248}, {Latitude: 28.5383, Longitude: -81.3792}})
+	assert.InDelta(t, 155000, measured.DistanceMeters, 3000)
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewBooking builds the booking for seats just reserved on a ride. It is confirmed straight
+// away on rides with instant booking; otherwise it is a request the driver has until the
+// timeout, or departure if sooner, to answer.
+func NewBooking(ride *models.Ride, passengerID primitive.ObjectID, seats, bags int, segment models.Segment, requestTimeout time.Duration, now time.Time) models.Booking {
+	booking := models.Booking{
+		ID:           primitive.NewObjectID(),
+		RideID:       ride.ID,
+		PassengerID:  passengerID,
+		Seats:        seats,
+		Bags:         bags,
+		FromStop:     segment.From,
+		ToStop:       segment.To,
+		Status:       models.BookingConfirmed,
+		PricePerSeat: ride.Price,
+		TotalPrice:   ride.Price * float64(seats),
+		CreatedAt:    now,
+	}
+	if !ride.BooksInstantly() {
+		expiresAt := now.Add(requestTimeout)
+		if ride.Date.After(now) && ride.Date.Before(expiresAt) {
+			expiresAt = ride.Date
+		}
+		booking.Status = models.BookingPending
+		booking.ExpiresAt = &expiresAt
+	}
+	return booking
+}
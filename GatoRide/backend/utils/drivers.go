@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// WarnExpiringDriverDocuments warns approved drivers whose licence or insurance expires within
+// the warning period, by email and in their inbox. Each driver is warned once per expiry date,
+// so renewed documents are warned about again when they near their own expiry.
+func WarnExpiringDriverDocuments(drivers repository.DriverVerificationRepository, users repository.UserRepository, notifications repository.NotificationRepository, warning time.Duration) {
+	now := time.Now()
+	expiring, err := drivers.Find(context.TODO(), repository.DriverFilter{
+		Statuses:      []models.DriverStatus{models.DriverApproved},
+		ExpiresBefore: now.Add(warning),
+	})
+	if err != nil {
+		log.Printf("❌ Failed to find drivers with expiring documents: %v\n", err)
+		return
+	}
+
+	warned := 0
+	for i := range expiring {
+		verification := &expiring[i]
+		// Another run may have warned the driver since we looked; then the claim conflicts
+		err := drivers.ClaimExpiryWarning(context.TODO(), verification.UserID, verification.ExpiresAt())
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ Failed to record the expiry warning of driver %s: %v\n", verification.UserID.Hex(), err)
+			continue
+		}
+
+		message := fmt.Sprintf("Your driver's licence or insurance expires on %s. Submit renewed documents to keep offering rides.",
+			verification.ExpiresAt().Format("Jan 2, 2006"))
+		if verification.Expired(now) {
+			message = fmt.Sprintf("Your driver's licence or insurance expired on %s. Submit renewed documents to offer rides again.",
+				verification.ExpiresAt().Format("Jan 2, 2006"))
+		}
+		if err := notifications.Create(context.TODO(), &models.Notification{
+			UserID:    verification.UserID,
+			Kind:      models.NotificationDocuments,
+			Message:   message,
+			CreatedAt: now,
+		}); err != nil {
+			log.Printf("❌ Failed to add an expiry warning to the inbox of %s: %v\n", verification.UserID.Hex(), err)
+		}
+
+		driver, err := users.FindByID(context.TODO(), verification.UserID)
+		if err != nil {
+			log.Printf("❌ Could not load driver %s: %v\n", verification.UserID.Hex(), err)
+		} else if err := SendDocumentsExpiringFunc(driver.Email, verification); err != nil {
+			log.Printf("❌ Failed to warn %s about expiring documents: %v\n", driver.Email, err)
+		}
+		warned++
+	}
+	log.Printf("✅ Warned %d drivers about expiring documents\n", warned)
+}
+
+func StartDriverExpiryScheduler(drivers repository.DriverVerificationRepository, users repository.UserRepository, notifications repository.NotificationRepository, warning, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		WarnExpiringDriverDocuments(drivers, users, notifications, warning)
+		for range ticker.C {
+			log.Println("🕐 Looking for drivers whose documents expire soon...")
+			WarnExpiringDriverDocuments(drivers, users, notifications, warning)
+		}
+	}()
+}
@@ -1,13 +1,18 @@
 package utils
 
 import (
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
+// jwtKey signs and verifies email verification tokens. It is set from the configuration at startup.
+var jwtKey []byte
+
+// SetVerificationSecret sets the key used for email verification tokens
+func SetVerificationSecret(secret string) {
+	jwtKey = []byte(secret)
+}
 
 func GenerateJWT(username string) (string, error) {
 	claims := &jwt.MapClaims{
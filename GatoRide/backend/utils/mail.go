@@ -1,32 +1,258 @@
 package utils
 
 import (
+	"backend/config"
+	"backend/models"
 	"fmt"
+	"log"
 	"net/smtp"
+	"strings"
+	"time"
 )
 
 var SendEmailFunc = SendVerificationEmail
 
+// SendRideUpdateFunc notifies a passenger about changes to a ride they booked; tests replace it
+var SendRideUpdateFunc = SendRideUpdateEmail
+
+// SendWaitlistPromotionFunc tells a waitlisted passenger they got a seat; tests replace it
+var SendWaitlistPromotionFunc = SendWaitlistPromotionEmail
+
+// SendRideOfferFunc tells a passenger a driver made an offer on their ride request; tests replace it
+var SendRideOfferFunc = SendRideOfferEmail
+
+// SendOfferAcceptedFunc tells a driver the passenger accepted their offer; tests replace it
+var SendOfferAcceptedFunc = SendOfferAcceptedEmail
+
+// SendRideAlertFunc tells a user about a new ride matching their saved search; tests replace it
+var SendRideAlertFunc = SendRideAlertEmail
+
+// SendRideCancelledFunc tells a passenger the driver cancelled their ride; tests replace it
+var SendRideCancelledFunc = SendRideCancelledEmail
+
+// SendDriverReviewFunc tells a user an admin approved or rejected them as a driver; tests replace it
+var SendDriverReviewFunc = SendDriverReviewEmail
+
+// SendDocumentsExpiringFunc warns a driver their licence or insurance expires soon; tests replace it
+var SendDocumentsExpiringFunc = SendDocumentsExpiringEmail
+
+// SendAccountSuspendedFunc tells a user an admin suspended their account; tests replace it
+var SendAccountSuspendedFunc = SendAccountSuspendedEmail
+
+// mailConfig and baseURL are set from the configuration at startup
+var (
+	mailConfig config.SMTPConfig
+	baseURL    = "http://localhost:5001"
+)
+
+// ConfigureMail sets the SMTP server and the public API URL used in verification links
+func ConfigureMail(smtpConfig config.SMTPConfig, apiBaseURL string) {
+	mailConfig = smtpConfig
+	baseURL = apiBaseURL
+}
+
 func SendVerificationEmail(email string, verificationToken string) error {
-	smtpHost := "smtp.gmail.com"
-	smtpPort := "587"
-	from := "shahidshareef4457@gmail.com"
-	password := "ldvqpjhklisopenq"
+	link := fmt.Sprintf("%s/verify-email?token=%s", baseURL, verificationToken)
 
-	to := []string{email}
-	subject := "Verify Your Email"
-	body := fmt.Sprintf("Click the link to verify your email: http://localhost:5001/verify-email?token=%s", verificationToken)
+	// Without SMTP (e.g. local development) the link is logged so the account can still be verified
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Verification link for %s: %s\n", email, link)
+		return nil
+	}
 
+	if err := sendMail(email, "Verify Your Email", "Click the link to verify your email: "+link); err != nil {
+		return err
+	}
+
+	fmt.Println("Verification email sent successfully to:", email)
+	return nil
+}
+
+// SendRideUpdateEmail tells a passenger which details of their booked ride the driver changed
+func SendRideUpdateEmail(email string, ride *models.Ride, changes []string) error {
+	body := fmt.Sprintf(
+		"The driver changed the %s of your ride from %s to %s, now departing %s at $%.2f per seat.\r\n"+
+			"If the new details no longer suit you, you can cancel your booking without penalty.",
+		strings.Join(changes, ", "), ride.Pickup.Address, ride.Dropoff.Address,
+		ride.Date.Format("Mon Jan 2 15:04 MST"), ride.Price,
+	)
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Ride %s update for %s: %s\n", ride.ID.Hex(), email, strings.Join(changes, ", "))
+		return nil
+	}
+	return sendMail(email, "Your ride has changed", body)
+}
+
+// SendWaitlistPromotionEmail tells a passenger that a seat freed up and was booked for them
+// from the waitlist
+func SendWaitlistPromotionEmail(email string, ride *models.Ride, booking *models.Booking) error {
+	outcome := "Your booking is confirmed."
+	if booking.Status == models.BookingPending {
+		outcome = "Your booking request was sent to the driver, who has to accept it."
+	}
+	body := fmt.Sprintf(
+		"A seat freed up on the ride from %s to %s departing %s, and you were next on the waitlist.\r\n"+
+			"%s You can cancel it if you no longer need the ride.",
+		ride.Pickup.Address, ride.Dropoff.Address, ride.Date.Format("Mon Jan 2 15:04 MST"), outcome,
+	)
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Waitlist seat on ride %s for %s: %s\n", ride.ID.Hex(), email, booking.Status)
+		return nil
+	}
+	return sendMail(email, "A seat opened up on your ride", body)
+}
+
+// SendRideOfferEmail tells a passenger about a driver's offer on their ride request
+func SendRideOfferEmail(email string, request *models.RideRequest, offer *models.RideOffer) error {
+	body := fmt.Sprintf(
+		"A driver offered you a ride from %s to %s departing %s at $%.2f per seat.\r\n"+
+			"Accept it to be booked on the ride, or wait for more offers.",
+		request.Pickup.Address, request.Dropoff.Address, offer.Date.Format("Mon Jan 2 15:04 MST"), offer.Price,
+	)
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Offer %s on ride request %s for %s\n", offer.ID.Hex(), request.ID.Hex(), email)
+		return nil
+	}
+	return sendMail(email, "You have a ride offer", body)
+}
+
+// SendOfferAcceptedEmail tells a driver that the passenger accepted their offer and the ride was created
+func SendOfferAcceptedEmail(email string, ride *models.Ride, booking *models.Booking) error {
+	body := fmt.Sprintf(
+		"The passenger accepted your offer. Your ride from %s to %s departing %s is created with %d seats booked.",
+		ride.Pickup.Address, ride.Dropoff.Address, ride.Date.Format("Mon Jan 2 15:04 MST"), booking.Seats,
+	)
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Offer accepted for %s: ride %s\n", email, ride.ID.Hex())
+		return nil
+	}
+	return sendMail(email, "Your ride offer was accepted", body)
+}
+
+// SendRideAlertEmail tells a user about a new ride matching one of their saved searches
+func SendRideAlertEmail(email string, ride *models.Ride, search *models.SavedSearch) error {
+	body := fmt.Sprintf(
+		"A new ride matches your saved search: from %s to %s departing %s at $%.2f per seat.\r\n"+
+			"Book it before the seats are gone, or delete the saved search to stop these alerts.",
+		ride.Pickup.Address, ride.Dropoff.Address, ride.Date.Format("Mon Jan 2 15:04 MST"), ride.Price,
+	)
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Ride %s matches saved search %s of %s\n", ride.ID.Hex(), search.ID.Hex(), email)
+		return nil
+	}
+	return sendMail(email, "A new ride matches your search", body)
+}
+
+// SendRideCancelledEmail tells a passenger the driver cancelled their ride and why, or that an
+// admin removed it, suggesting other rides on the same route and day
+func SendRideCancelledEmail(email string, ride *models.Ride, alternatives []models.Ride) error {
+	body := fmt.Sprintf(
+		"The driver cancelled your ride from %s to %s departing %s.\r\n",
+		ride.Pickup.Address, ride.Dropoff.Address, ride.Date.Format("Mon Jan 2 15:04 MST"),
+	)
+	if ride.Cancellation != nil && ride.Cancellation.Reason == models.ReasonRemoved {
+		body = fmt.Sprintf(
+			"Your ride from %s to %s departing %s was taken down by our moderators.\r\n",
+			ride.Pickup.Address, ride.Dropoff.Address, ride.Date.Format("Mon Jan 2 15:04 MST"),
+		)
+	} else if ride.Cancellation != nil && ride.Cancellation.Reason == models.ReasonExpired {
+		body = fmt.Sprintf(
+			"Your ride from %s to %s departing %s never started, so it was cancelled.\r\n",
+			ride.Pickup.Address, ride.Dropoff.Address, ride.Date.Format("Mon Jan 2 15:04 MST"),
+		)
+	} else if ride.Cancellation != nil {
+		body += "Reason: " + strings.ReplaceAll(string(ride.Cancellation.Reason), "_", " ")
+		if ride.Cancellation.Note != "" {
+			body += " (" + ride.Cancellation.Note + ")"
+		}
+		body += "\r\n"
+	}
+	if len(alternatives) == 0 {
+		body += "We found no other rides on your route that day, but you can post a ride request for drivers to answer."
+	} else {
+		body += "These rides still have seats on your route that day:\r\n"
+		for _, alternative := range alternatives {
+			body += fmt.Sprintf("- from %s to %s departing %s at $%.2f per seat\r\n",
+				alternative.Pickup.Address, alternative.Dropoff.Address, alternative.Date.Format("Mon Jan 2 15:04 MST"), alternative.Price)
+		}
+	}
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Ride %s cancelled for %s, %d alternatives\n", ride.ID.Hex(), email, len(alternatives))
+		return nil
+	}
+	return sendMail(email, "Your ride was cancelled", body)
+}
+
+// SendDriverReviewEmail tells a user whether their driver verification was approved, and if
+// not, why
+func SendDriverReviewEmail(email string, verification *models.DriverVerification) error {
+	subject := "You are verified as a driver"
+	body := fmt.Sprintf(
+		"Your driver's licence and insurance were approved. You can offer rides until %s, when the first of them expires.",
+		verification.ExpiresAt().Format("Jan 2, 2006"),
+	)
+	if verification.Status == models.DriverRejected {
+		subject = "Your driver verification was rejected"
+		body = "Your driver's licence and insurance could not be approved: " + verification.RejectionReason + "\r\n" +
+			"You can submit them again once this is fixed."
+	}
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Driver verification of %s %s\n", email, verification.Status)
+		return nil
+	}
+	return sendMail(email, subject, body)
+}
+
+// SendDocumentsExpiringEmail warns a driver that their licence or insurance expires soon, after
+// which they cannot offer rides until they submit renewed documents
+func SendDocumentsExpiringEmail(email string, verification *models.DriverVerification) error {
+	body := fmt.Sprintf(
+		"Your driver's licence expires on %s and your insurance on %s.\r\n"+
+			"Submit your renewed documents before then to keep offering rides.",
+		verification.LicenceExpiresAt.Format("Jan 2, 2006"), verification.InsuranceExpiresAt.Format("Jan 2, 2006"),
+	)
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Driver documents of %s expire %s\n", email, verification.ExpiresAt().Format("2006-01-02"))
+		return nil
+	}
+	return sendMail(email, "Your driver documents expire soon", body)
+}
+
+// SendAccountSuspendedEmail tells a user an admin suspended their account after a report, until
+// when and why
+func SendAccountSuspendedEmail(email string, until time.Time, reason string) error {
+	body := fmt.Sprintf(
+		"Your account was suspended after a report about you, until %s. You cannot log in until then.\r\n"+
+			"Reason: %s",
+		until.Format("Jan 2, 2006 15:04 MST"), reason,
+	)
+
+	if !mailConfig.Enabled() {
+		log.Printf("⚠️ SMTP not configured. Account of %s suspended until %s\n", email, until.Format("2006-01-02"))
+		return nil
+	}
+	return sendMail(email, "Your account was suspended", body)
+}
+
+// sendMail delivers a plain-text message through the configured SMTP server
+func sendMail(email, subject, body string) error {
+	to := []string{email}
 	message := []byte("Subject: " + subject + "\r\n" + "\r\n" + body + "\r\n")
 
-	auth := smtp.PlainAuth("", from, password, smtpHost)
+	auth := smtp.PlainAuth("", mailConfig.From, mailConfig.Password, mailConfig.Host)
 
-	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, to, message)
+	err := smtp.SendMail(mailConfig.Host+":"+mailConfig.Port, auth, mailConfig.From, to, message)
 	if err != nil {
 		fmt.Println("SMTP Error:", err) // Debugging
 		return err
 	}
-
-	fmt.Println("Verification email sent successfully to:", email)
 	return nil
 }
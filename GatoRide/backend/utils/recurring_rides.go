@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"backend/models"
+	"backend/recurrence"
+	"backend/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GenerateSeriesRides creates the series' rides departing between now and until that do not
+// exist yet, and records how far the series has been generated. Occurrence days that already
+// have a ride, including cancelled or edited ones, are never created again. Generation is held
+// at the first occurrence the driver may not drive, because they are not approved or their
+// documents expire by then, and resumes from there once they are verified again.
+func GenerateSeriesRides(ctx context.Context, seriesRepo repository.RideSeriesRepository, rides repository.RideRepository, drivers repository.DriverVerificationRepository, notifications repository.NotificationRepository, series *models.RideSeries, until time.Time) (int, error) {
+	if series.Status != models.SeriesActive {
+		return 0, nil
+	}
+
+	from := time.Now()
+	if series.GeneratedUntil.After(from) {
+		from = series.GeneratedUntil
+	}
+	if !until.After(from) {
+		return 0, nil
+	}
+
+	occurrences, err := recurrence.Occurrences(series.Recurrence, from, until)
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := rides.Find(ctx, repository.RideFilter{SeriesID: series.ID})
+	if err != nil {
+		return 0, err
+	}
+	taken := map[string]bool{}
+	for _, ride := range existing {
+		taken[ride.OccurrenceDate] = true
+	}
+
+	verification, err := drivers.FindByUser(ctx, series.DriverID)
+	if errors.Is(err, repository.ErrNotFound) {
+		verification = nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	var held *recurrence.Occurrence
+	for i, occurrence := range occurrences {
+		if taken[occurrence.Date] {
+			continue
+		}
+		if verification == nil || verification.Status != models.DriverApproved || verification.Expired(occurrence.Departure) {
+			held = &occurrences[i]
+			break
+		}
+		seriesID := series.ID
+		instantBook := series.InstantBook
+		ride := models.Ride{
+			ID:             primitive.NewObjectID(),
+			DriverID:       series.DriverID,
+			Pickup:         series.Pickup,
+			Dropoff:        series.Dropoff,
+			Waypoints:      series.Waypoints,
+			Status:         models.StatusOpen,
+			Price:          series.Price,
+			Seats:          series.Seats,
+			Date:           occurrence.Departure,
+			CreatedAt:      time.Now(),
+			PassengerIDs:   []primitive.ObjectID{},
+			InstantBook:    &instantBook,
+			SeriesID:       &seriesID,
+			OccurrenceDate: occurrence.Date,
+			Attributes:     series.Attributes,
+			FreeLuggage:    series.Attributes.LuggageBags,
+			VehicleID:      series.VehicleID,
+			Vehicle:        series.Vehicle,
+			Path:           series.Path,
+		}
+		ride.SegmentSeats = ride.FreeSeatsBySegment()
+		if err := rides.Create(ctx, &ride); err != nil {
+			return created, err
+		}
+		created++
+	}
+
+	if held == nil {
+		series.GeneratedUntil = until
+		series.HeldSince = nil
+		return created, seriesRepo.Replace(ctx, series)
+	}
+
+	// The held occurrence is the first one looked at again on the next run
+	series.GeneratedUntil = held.Departure
+	alreadyHeld := series.HeldSince != nil
+	if !alreadyHeld {
+		now := time.Now()
+		series.HeldSince = &now
+	}
+	if err := seriesRepo.Replace(ctx, series); err != nil {
+		return created, err
+	}
+	if !alreadyHeld {
+		notifySeriesHeld(ctx, notifications, series, verification, held.Departure)
+	}
+	return created, nil
+}
+
+// notifySeriesHeld tells the driver in their inbox that no more rides of the series are being
+// generated until their documents are verified again
+func notifySeriesHeld(ctx context.Context, notifications repository.NotificationRepository, series *models.RideSeries, verification *models.DriverVerification, departure time.Time) {
+	message := fmt.Sprintf("No more rides from %s to %s are being added to your series because your driver documents are not approved. Submit them to resume the series.",
+		series.Pickup.Address, series.Dropoff.Address)
+	if verification != nil && verification.Status == models.DriverApproved {
+		message = fmt.Sprintf("Your rides from %s to %s stop on %s because your driver's licence or insurance expires on %s. Submit renewed documents to resume the series.",
+			series.Pickup.Address, series.Dropoff.Address, departure.Format("Jan 2, 2006"), verification.ExpiresAt().Format("Jan 2, 2006"))
+	}
+	if err := notifications.Create(ctx, &models.Notification{
+		UserID:    series.DriverID,
+		Kind:      models.NotificationDocuments,
+		Message:   message,
+		CreatedAt: *series.HeldSince,
+	}); err != nil {
+		log.Printf("❌ Failed to tell the driver of series %s that it is held: %v\n", series.ID.Hex(), err)
+	}
+}
+
+// GenerateRecurringRides tops up every active series so its rides exist for the coming window
+func GenerateRecurringRides(seriesRepo repository.RideSeriesRepository, rides repository.RideRepository, drivers repository.DriverVerificationRepository, notifications repository.NotificationRepository, window time.Duration) {
+	active, err := seriesRepo.Find(context.TODO(), repository.SeriesFilter{Statuses: []models.SeriesStatus{models.SeriesActive}})
+	if err != nil {
+		log.Printf("❌ Failed to load ride series: %v\n", err)
+		return
+	}
+
+	until := time.Now().Add(window)
+	total := 0
+	for i := range active {
+		created, err := GenerateSeriesRides(context.TODO(), seriesRepo, rides, drivers, notifications, &active[i], until)
+		if err != nil {
+			log.Printf("❌ Failed to generate rides for series %s: %v\n", active[i].ID.Hex(), err)
+		}
+		total += created
+	}
+	log.Printf("✅ Generated %d rides from %d recurring series\n", total, len(active))
+}
+
+func StartRecurringRideScheduler(seriesRepo repository.RideSeriesRepository, rides repository.RideRepository, drivers repository.DriverVerificationRepository, notifications repository.NotificationRepository, window, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		GenerateRecurringRides(seriesRepo, rides, drivers, notifications, window)
+		for range ticker.C {
+			log.Println("🕐 Generating rides from recurring series...")
+			GenerateRecurringRides(seriesRepo, rides, drivers, notifications, window)
+		}
+	}()
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"backend/repository"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RevealReview shows the review and counts it towards the reviewee's rating. It reports false
+// without an error when the review was already shown, so no review is ever counted twice.
+func RevealReview(ctx context.Context, reviews repository.ReviewRepository, users repository.UserRepository, id primitive.ObjectID, at time.Time) (bool, error) {
+	review, err := reviews.Reveal(ctx, id, at)
+	if errors.Is(err, repository.ErrConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := users.AddRating(ctx, review.RevieweeID, review.Rating); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return true, err
+	}
+	return true, nil
+}
+
+// RevealDueReviews shows the reviews whose window closed without the other side reviewing back
+func RevealDueReviews(reviews repository.ReviewRepository, users repository.UserRepository) {
+	now := time.Now()
+	due, _, err := reviews.FindPage(context.TODO(), repository.ReviewFilter{RevealBefore: now}, repository.Page{Sort: repository.SortCreated})
+	if err != nil {
+		log.Printf("❌ Failed to find reviews to reveal: %v\n", err)
+		return
+	}
+
+	revealed := 0
+	for _, review := range due {
+		shown, err := RevealReview(context.TODO(), reviews, users, review.ID, now)
+		if err != nil {
+			log.Printf("❌ Failed to reveal review %s: %v\n", review.ID.Hex(), err)
+		}
+		if shown {
+			revealed++
+		}
+	}
+	log.Printf("✅ Revealed %d reviews whose window closed\n", revealed)
+}
+
+func StartReviewScheduler(reviews repository.ReviewRepository, users repository.UserRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			RevealDueReviews(reviews, users)
+		}
+	}()
+}
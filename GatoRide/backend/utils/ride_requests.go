@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CloseOffers moves every pending offer on a ride request except keep to the given status. It is
+// used once a request is fulfilled, cancelled or expires; failures are only logged.
+func CloseOffers(ctx context.Context, offers repository.RideOfferRepository, requestID primitive.ObjectID, to models.OfferStatus, keep primitive.ObjectID) {
+	pending, err := offers.Find(ctx, repository.RideOfferFilter{
+		RequestID: requestID,
+		Statuses:  []models.OfferStatus{models.OfferPending},
+	})
+	if err != nil {
+		log.Printf("❌ Failed to find the offers on ride request %s: %v\n", requestID.Hex(), err)
+		return
+	}
+	now := time.Now()
+	for _, offer := range pending {
+		if offer.ID == keep {
+			continue
+		}
+		// The driver may have withdrawn it since we looked; then there is nothing left to do
+		if _, err := offers.UpdateStatus(ctx, offer.ID, models.OfferPending, to, now); err != nil && !errors.Is(err, repository.ErrConflict) {
+			log.Printf("❌ Failed to close offer %s: %v\n", offer.ID.Hex(), err)
+		}
+	}
+}
+
+// ExpireRideRequests expires open ride requests whose departure window has passed, along with
+// the offers still waiting on them
+func ExpireRideRequests(requests repository.RideRequestRepository, offers repository.RideOfferRepository) {
+	now := time.Now()
+	open, err := requests.Find(context.TODO(), repository.RideRequestFilter{
+		Statuses:         []models.RideRequestStatus{models.RequestOpen},
+		WindowEndsBefore: now,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to find expired ride requests: %v\n", err)
+		return
+	}
+
+	expired := 0
+	for _, request := range open {
+		// The passenger may have accepted an offer or cancelled since we looked; then we skip it
+		_, err := requests.UpdateStatus(context.TODO(), request.ID, models.RequestOpen, models.RequestExpired, now)
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ Failed to expire ride request %s: %v\n", request.ID.Hex(), err)
+			continue
+		}
+		CloseOffers(context.TODO(), offers, request.ID, models.OfferExpired, primitive.NilObjectID)
+		expired++
+	}
+	log.Printf("✅ Expired %d ride requests\n", expired)
+}
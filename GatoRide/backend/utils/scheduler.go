@@ -1,45 +1,104 @@
 package utils
 
 import (
-	"backend/config"
+	"backend/lifecycle"
+	"backend/models"
+	"backend/repository"
 	"context"
+	"errors"
 	"log"
 	"time"
-
-	"go.mongodb.org/mongo-driver/bson"
 )
 
-func CleanupOldRides() {
-	collection := config.GetCollection("rides")
-	now := time.Now()
+// RideCanceller lets go of everyone on a ride that was just cancelled and tells each of them,
+// returning how many it told. The ride controller does this for drivers' cancellations as well.
+type RideCanceller interface {
+	CancelPassengers(ctx context.Context, ride *models.Ride, at time.Time) int
+}
 
-	filter := bson.M{
-		"status": "open",
-		"date": bson.M{
-			"$lt": now,
-		},
+// CleanupOldRides cancels the open and booked rides that never started once their departure is
+// more than grace ago, and lets their passengers go the way a driver's cancellation would. The
+// grace leaves drivers who run late time to start the ride.
+func CleanupOldRides(rides repository.RideRepository, canceller RideCanceller, grace time.Duration) {
+	now := time.Now()
+	expired, err := rides.Find(context.TODO(), repository.RideFilter{
+		Statuses: []models.RideStatus{models.StatusOpen, models.StatusBooked},
+		DateTo:   now.Add(-grace),
+	})
+	if err != nil {
+		log.Printf("❌ Failed to cleanup old rides: %v\n", err)
+		return
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"status": "cancelled",
-		},
+	cancelled, notified := 0, 0
+	for i := range expired {
+		ride := &expired[i]
+		if err := lifecycle.Check(ride.Status, models.StatusCancelled, lifecycle.System); err != nil {
+			continue
+		}
+		// The driver may have started the ride since we looked; then the update conflicts and we skip it
+		cancellation := models.RideCancellation{Reason: models.ReasonExpired, At: now}
+		err := rides.Cancel(context.TODO(), ride.ID, ride.Status, cancellation)
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ Failed to cancel expired ride %s: %v\n", ride.ID.Hex(), err)
+			continue
+		}
+		ride.Status = models.StatusCancelled
+		ride.Cancellation = &cancellation
+		notified += canceller.CancelPassengers(context.TODO(), ride, now)
+		cancelled++
 	}
+	log.Printf("✅ Cleaned up %d expired rides and told %d passengers\n", cancelled, notified)
+}
 
-	result, err := collection.UpdateMany(context.TODO(), filter, update)
+// ExpireBookingRequests expires pending booking requests the driver did not answer in
+// time and gives their seats back to the ride, or to the next passenger on its waitlist
+func ExpireBookingRequests(bookings repository.BookingRepository, rides repository.RideRepository, promoter *WaitlistPromoter) {
+	now := time.Now()
+	pending, err := bookings.Find(context.TODO(), repository.BookingFilter{
+		Statuses:      []models.BookingStatus{models.BookingPending},
+		ExpiresBefore: now,
+	})
 	if err != nil {
-		log.Printf("❌ Failed to cleanup old rides: %v\n", err)
-	} else {
-		log.Printf("✅ Cleaned up %d expired rides\n", result.ModifiedCount)
+		log.Printf("❌ Failed to find expired booking requests: %v\n", err)
+		return
+	}
+
+	expired := 0
+	for _, booking := range pending {
+		if err := lifecycle.CheckBooking(booking.Status, models.BookingExpired, lifecycle.System); err != nil {
+			continue
+		}
+		// The driver may have answered since we looked; then the update conflicts and we skip it
+		_, err := bookings.UpdateStatus(context.TODO(), booking.ID, models.BookingPending, models.BookingExpired, now)
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ Failed to expire booking %s: %v\n", booking.ID.Hex(), err)
+			continue
+		}
+		if _, err := rides.ReleaseSeats(context.TODO(), booking.RideID, booking.PassengerID, booking.Seats, booking.Bags, booking.Segment()); err != nil {
+			log.Printf("❌ Booking %s expired but its seats were not released: %v\n", booking.ID.Hex(), err)
+		} else if _, err := promoter.Promote(context.TODO(), booking.RideID); err != nil {
+			log.Printf("❌ Failed to promote the waitlist of ride %s: %v\n", booking.RideID.Hex(), err)
+		}
+		expired++
 	}
+	log.Printf("✅ Expired %d booking requests\n", expired)
 }
 
-func StartCleanupScheduler() {
-	ticker := time.NewTicker(5 * time.Second) //less time for demo purposes
+func StartCleanupScheduler(rides repository.RideRepository, bookings repository.BookingRepository, canceller RideCanceller, requests repository.RideRequestRepository, offers repository.RideOfferRepository, promoter *WaitlistPromoter, grace, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	go func() {
 		for range ticker.C {
 			log.Println("🕐 Running scheduled cleanup for expired rides...")
-			CleanupOldRides()
+			CleanupOldRides(rides, canceller, grace)
+			ExpireBookingRequests(bookings, rides, promoter)
+			ExpireRideRequests(requests, offers)
 		}
 	}()
 }
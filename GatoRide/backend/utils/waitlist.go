@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"backend/lifecycle"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WaitlistPromoter hands seats that free up on a ride to the passengers waiting for them
+type WaitlistPromoter struct {
+	rides          repository.RideRepository
+	bookings       repository.BookingRepository
+	waitlist       repository.WaitlistRepository
+	users          repository.UserRepository
+	requestTimeout time.Duration
+}
+
+// NewWaitlistPromoter creates a WaitlistPromoter; requestTimeout applies to bookings it makes
+// on rides where the driver approves passengers
+func NewWaitlistPromoter(rides repository.RideRepository, bookings repository.BookingRepository, waitlist repository.WaitlistRepository, users repository.UserRepository, requestTimeout time.Duration) *WaitlistPromoter {
+	return &WaitlistPromoter{rides: rides, bookings: bookings, waitlist: waitlist, users: users, requestTimeout: requestTimeout}
+}
+
+// Promote books free seats on the ride for waiting passengers in the order they joined and
+// notifies each of them. A passenger whose seats or segment do not fit is skipped, so a
+// single freed seat goes to the first passenger waiting for one. It returns the new bookings.
+func (p *WaitlistPromoter) Promote(ctx context.Context, rideID primitive.ObjectID) ([]models.Booking, error) {
+	entries, err := p.waitlist.Find(ctx, repository.WaitlistFilter{
+		RideID:   rideID,
+		Statuses: []models.WaitlistStatus{models.WaitlistWaiting},
+	})
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+
+	ride, err := p.rides.FindByID(ctx, rideID)
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []models.Booking
+	for _, entry := range entries {
+		if !lifecycle.AcceptsWaitlist(ride.Status) {
+			break
+		}
+		if slices.Contains(ride.PassengerIDs, entry.PassengerID) {
+			// They got a seat some other way in the meantime and no longer wait
+			p.drop(ctx, &entry)
+			continue
+		}
+		if ride.FreeSeats(entry.Segment()) < entry.Seats || ride.FreeLuggage < entry.Bags {
+			continue
+		}
+
+		// ClaimWaitlistSeats re-checks the seats atomically, so concurrent promotions cannot
+		// hand out the same seat twice
+		updated, err := p.rides.ClaimWaitlistSeats(ctx, rideID, entry.PassengerID, entry.Seats, entry.Bags, entry.Segment())
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return promoted, err
+		}
+		ride = updated
+
+		now := time.Now()
+		if _, err := p.waitlist.UpdateStatus(ctx, entry.ID, models.WaitlistWaiting, models.WaitlistPromoted, now); err != nil {
+			log.Printf("❌ Seats on ride %s went to waitlist entry %s but it was not marked promoted: %v\n", rideID.Hex(), entry.ID.Hex(), err)
+		}
+
+		booking := NewBooking(ride, entry.PassengerID, entry.Seats, entry.Bags, entry.Segment(), p.requestTimeout, now)
+		if err := p.bookings.Create(ctx, &booking); err != nil {
+			log.Printf("❌ Failed to record waitlist booking on ride %s for %s: %v\n", rideID.Hex(), entry.PassengerID.Hex(), err)
+			continue
+		}
+		promoted = append(promoted, booking)
+		p.notify(ctx, ride, &booking)
+	}
+	return promoted, nil
+}
+
+// drop takes an entry off the waitlist without a booking
+func (p *WaitlistPromoter) drop(ctx context.Context, entry *models.WaitlistEntry) {
+	if _, err := p.waitlist.UpdateStatus(ctx, entry.ID, models.WaitlistWaiting, models.WaitlistLeft, time.Now()); err != nil {
+		return
+	}
+	if _, err := p.rides.AdjustWaitlist(ctx, entry.RideID, -1); err != nil {
+		log.Printf("❌ Waitlist entry %s was dropped but ride %s still counts it: %v\n", entry.ID.Hex(), entry.RideID.Hex(), err)
+	}
+}
+
+// notify emails the passenger about their new booking; failures are only logged
+func (p *WaitlistPromoter) notify(ctx context.Context, ride *models.Ride, booking *models.Booking) {
+	passenger, err := p.users.FindByID(ctx, booking.PassengerID)
+	if err != nil {
+		log.Printf("❌ Could not load waitlisted passenger %s: %v\n", booking.PassengerID.Hex(), err)
+		return
+	}
+	if err := SendWaitlistPromotionFunc(passenger.Email, ride, booking); err != nil {
+		log.Printf("❌ Failed to notify %s about a seat on ride %s: %v\n", passenger.Email, ride.ID.Hex(), err)
+	}
+}